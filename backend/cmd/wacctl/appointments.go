@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/app"
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+const appointmentsUsage = `usage: wacctl appointments <cancel | reassign> [flags]
+
+Selects the requested and scheduled appointments of a doctor in a date range,
+e.g. when the doctor is on sick leave, and cancels them or reassigns them to
+another doctor.
+`
+
+// runAppointments cancels or reassigns appointments in bulk.
+func runAppointments(ctx context.Context, args []string) error {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, appointmentsUsage)
+		os.Exit(2)
+	}
+
+	action := args[0]
+	if action != "cancel" && action != "reassign" {
+		fmt.Fprintf(os.Stderr, "unknown action %q\n\n%s", action, appointmentsUsage)
+		os.Exit(2)
+	}
+
+	var doctor, newDoctor, clinic uuidFlag
+	fs := flag.NewFlagSet("appointments "+action, flag.ExitOnError)
+	fs.Var(&doctor, "doctor", "id of the doctor whose appointments are selected (required)")
+	from := fs.String("from", "", "first day of the range, YYYY-MM-DD (required)")
+	until := fs.String("until", "", "last day of the range, YYYY-MM-DD, defaults to -from")
+	dryRun := fs.Bool("dry-run", false, "only list the selected appointments")
+	reason := fs.String("reason", "", "cancellation reason")
+	fs.Var(&clinic, "clinic", "id of the doctor's clinic, defaults to the default clinic")
+	if action == "reassign" {
+		fs.Var(&newDoctor, "new-doctor", "id of the doctor taking over the appointments (required)")
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: wacctl appointments %s [flags]\n", action)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args[1:])
+	if !doctor.set || *from == "" || (action == "reassign" && !newDoctor.set) {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	db, a, err := connect(ctx)
+	if err != nil {
+		return fmt.Errorf("appointments: %w", err)
+	}
+	defer db.Disconnect(context.Background())
+
+	if ctx, err = withClinic(ctx, db, clinic); err != nil {
+		return fmt.Errorf("appointments: %w", err)
+	}
+
+	fromDate, err := time.ParseInLocation(time.DateOnly, *from, time.Local)
+	if err != nil {
+		return fmt.Errorf("appointments: invalid -from: %w", err)
+	}
+	untilDate := fromDate
+	if *until != "" {
+		if untilDate, err = time.ParseInLocation(time.DateOnly, *until, time.Local); err != nil {
+			return fmt.Errorf("appointments: invalid -until: %w", err)
+		}
+	}
+	to := untilDate.AddDate(0, 0, 1)
+
+	appts, err := db.AppointmentsByDoctorId(ctx, doctor.id, fromDate, &to)
+	if err != nil {
+		return fmt.Errorf("appointments: %w", err)
+	}
+
+	var failed int
+	for _, appt := range appts {
+		if appt.Status != string(api.Requested) && appt.Status != string(api.Scheduled) {
+			continue
+		}
+		if *dryRun {
+			fmt.Printf("%s %s %s\n", appt.Id, appt.AppointmentDateTime.Format(time.DateTime), appt.Status)
+			continue
+		}
+
+		err := bulkAppointmentAction(ctx, a, action, appt, newDoctor.id, *reason)
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "%s: %s\n", appt.Id, err)
+			continue
+		}
+		fmt.Printf("%s %s %s\n", appt.Id, appt.AppointmentDateTime.Format(time.DateTime), action)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("appointments: %d appointments failed", failed)
+	}
+	return nil
+}
+
+func bulkAppointmentAction(
+	ctx context.Context,
+	a app.App,
+	action string,
+	appt data.Appointment,
+	newDoctor uuid.UUID,
+	reason string,
+) error {
+	if action == "reassign" {
+		_, err := a.ReassignAppointment(ctx, appt.Id, newDoctor)
+		return err
+	}
+
+	cancellation := api.AppointmentCancellation{By: api.UserRoleDoctor}
+	if reason != "" {
+		cancellation.Reason = &reason
+	}
+	return a.CancelAppointment(ctx, appt.Id, cancellation)
+}
+
+// uuidFlag is a flag holding a required uuid.
+type uuidFlag struct {
+	id  uuid.UUID
+	set bool
+}
+
+func (f *uuidFlag) String() string {
+	if !f.set {
+		return ""
+	}
+	return f.id.String()
+}
+
+func (f *uuidFlag) Set(value string) error {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return err
+	}
+	f.id, f.set = id, true
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/app"
+	"github.com/Nesquiko/wac/pkg/data"
+	"github.com/Nesquiko/wac/pkg/fhir"
+)
+
+// runImport imports a FHIR Bundle from a file, or stdin when the file is "-",
+// and prints the import report. Imports are done as an administrator.
+func runImport(ctx context.Context, args []string) error {
+	var admin, clinic uuidFlag
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only validate the bundle and report what would be imported")
+	fs.Var(&admin, "admin", "id of the administrator the import is done as (required)")
+	fs.Var(&clinic, "clinic", "id of the clinic, defaults to the default clinic")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(),
+			"usage: wacctl import -admin <id> [-clinic <id>] [-dry-run] <bundle.json | ->")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 || !admin.set {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var in io.Reader = os.Stdin
+	if path := fs.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("import: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	entries, err := fhir.ParseBundle(in)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	db, a, err := connect(ctx)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	defer db.Disconnect(context.Background())
+
+	if ctx, err = withClinic(ctx, db, clinic); err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	ctx = data.WithActor(ctx, data.Actor{Id: admin.id, Role: string(api.UserRoleAdmin)})
+	report, importErr := a.ImportFhirBundle(ctx, entries, *dryRun)
+	if importErr != nil &&
+		!errors.Is(importErr, app.ErrInvalidImport) &&
+		!errors.Is(importErr, app.ErrImportFailed) {
+		return fmt.Errorf("import: %w", importErr)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("import print report: %w", err)
+	}
+
+	if importErr != nil {
+		return fmt.Errorf("import: %w", importErr)
+	}
+	return nil
+}
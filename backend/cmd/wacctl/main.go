@@ -0,0 +1,96 @@
+// Command wacctl runs administrative tasks against the clinic's database.
+// It reads the same WAC_* environment variables as the server.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/Nesquiko/wac/pkg/app"
+	"github.com/Nesquiko/wac/pkg/data"
+	"github.com/Nesquiko/wac/pkg/server"
+)
+
+const usage = `usage: wacctl <command> [flags] [args]
+
+commands:
+  appointments  cancel or reassign a doctor's appointments in bulk
+  import        import a FHIR Bundle of a transferred patient
+  reindex       rebuild the indexes of all collections, drop stale ones
+  resources     create resources
+  seed          fill the database with synthetic data
+  staff         create a nurse, receptionist or administrator
+  validate      check and repair the integrity of the data
+`
+
+type command func(ctx context.Context, args []string) error
+
+var commands = map[string]command{
+	"appointments": runAppointments,
+	"import":       runImport,
+	"reindex":      runReindex,
+	"resources":    runResources,
+	"seed":         runSeed,
+	"staff":        runStaff,
+	"validate":     runValidate,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err := cmd(ctx, os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+}
+
+// connect loads the config, applies its timezone and connects to the database.
+// The caller must disconnect the returned database.
+func connect(ctx context.Context) (*data.MongoDb, app.App, error) {
+	cfg, err := server.LoadConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	server.SetupLogger(cfg.Log.Level)
+
+	loc, err := time.LoadLocation(cfg.App.Timezone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load timezone: %w", err)
+	}
+	time.Local = loc
+
+	db, err := data.ConnectMongo(ctx, cfg.MongoURI(), cfg.Mongo.Db)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	return db, app.New(db, cfg.RateLimit.MaxRequested), nil
+}
+
+// withClinic makes the context act in the clinic of the flag, if it is set.
+// Without it the command acts in the default clinic.
+func withClinic(ctx context.Context, db *data.MongoDb, clinic uuidFlag) (context.Context, error) {
+	if !clinic.set {
+		return ctx, nil
+	}
+	if _, err := db.ClinicById(ctx, clinic.id); err != nil {
+		return nil, fmt.Errorf("clinic %s: %w", clinic.id, err)
+	}
+	return data.WithClinic(ctx, clinic.id), nil
+}
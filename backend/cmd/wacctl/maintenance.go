@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+// runResources creates resources of one type, one for each name argument.
+func runResources(ctx context.Context, args []string) error {
+	var clinic uuidFlag
+	fs := flag.NewFlagSet("resources", flag.ExitOnError)
+	typ := fs.String("type", "", "type of the resources: facility, equipment or medicine (required)")
+	fs.Var(&clinic, "clinic", "id of the clinic, defaults to the default clinic")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: wacctl resources -type <type> [-clinic <id>] <name>...")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	resourceType := api.ResourceType(*typ)
+	switch resourceType {
+	case api.ResourceTypeFacility, api.ResourceTypeEquipment, api.ResourceTypeMedicine:
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	db, _, err := connect(ctx)
+	if err != nil {
+		return fmt.Errorf("resources: %w", err)
+	}
+	defer db.Disconnect(context.Background())
+
+	if ctx, err = withClinic(ctx, db, clinic); err != nil {
+		return fmt.Errorf("resources: %w", err)
+	}
+
+	// created directly, the app requires an acting admin
+	for _, name := range fs.Args() {
+		res, err := db.CreateResource(ctx, name, data.ResourceType(resourceType), nil)
+		if err != nil {
+			return fmt.Errorf("resources: %w", err)
+		}
+		fmt.Printf("%s %s %q\n", res.Id, res.Type, res.Name)
+	}
+	return nil
+}
+
+// runReindex rebuilds the indexes of all collections and drops stale ones.
+func runReindex(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("reindex", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	db, _, err := connect(ctx)
+	if err != nil {
+		return fmt.Errorf("reindex: %w", err)
+	}
+	defer db.Disconnect(context.Background())
+
+	if err := db.Reindex(ctx); err != nil {
+		return fmt.Errorf("reindex: %w", err)
+	}
+	return nil
+}
+
+// runValidate prints integrity issues of the database and fails if there are
+// any, with -repair the issues are repaired instead.
+func runValidate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	repair := fs.Bool("repair", false, "repair the found issues")
+	_ = fs.Parse(args)
+
+	db, _, err := connect(ctx)
+	if err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+	defer db.Disconnect(context.Background())
+
+	if *repair {
+		repaired, err := db.RepairIntegrity(ctx)
+		for _, issue := range repaired {
+			fmt.Printf("repaired %s\n", issue)
+		}
+		if err != nil {
+			return fmt.Errorf("validate: %w", err)
+		}
+		return nil
+	}
+
+	issues, err := db.CheckIntegrity(ctx)
+	if err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("validate: found %d integrity issues", len(issues))
+	}
+	return nil
+}
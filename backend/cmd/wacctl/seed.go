@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"time"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/app"
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+var (
+	seedFirstNames = []string{
+		"Adam", "Anna", "Boris", "Eva", "Filip", "Jana", "Juraj", "Katarína", "Lukáš",
+		"Mária", "Martin", "Monika", "Peter", "Simona", "Tomáš", "Zuzana",
+	}
+	seedLastNames = []string{
+		"Baláž", "Horváth", "Kováč", "Lukáč", "Molnár", "Nagy", "Novák", "Oravec",
+		"Polák", "Sedlák", "Tóth", "Varga",
+	}
+	seedConditions = []string{
+		"Allergic rhinitis", "Asthma", "Back pain", "Bronchitis", "Diabetes mellitus type 2",
+		"Hypertension", "Influenza", "Migraine", "Sprained ankle", "Tonsillitis",
+	}
+	seedMedicines = []string{
+		"Amoxicillin", "Cetirizine", "Ibuprofen", "Metformin", "Paracetamol", "Ramipril",
+	}
+	seedReasons = []string{
+		"Annual check-up", "Follow-up visit", "Persistent cough", "Headaches",
+		"Test results consultation", "Skin rash",
+	}
+	seedFileTypes = []string{
+		"lab_result", "medical_report", "discharge_summary", "consultation_note",
+		"radiology_report", "referral_letter", "immunization_record", "surgical_report",
+	}
+	seedSpecializations = []api.SpecializationEnum{
+		api.Cardiologist, api.Dermatologist, api.GeneralPractitioner, api.Neurologist,
+		api.Orthopedist, api.Pediatrician,
+	}
+)
+
+// Working hours in which the doctors have their time slots.
+const (
+	seedFirstSlotHour = 8
+	seedLastSlotHour  = 14
+)
+
+// runSeed fills the database with synthetic doctors, patients, their
+// conditions, appointments, prescriptions and medical history files. The same
+// seed generates the same data, emails contain the seed so seeding with
+// different seeds doesn't clash.
+func runSeed(ctx context.Context, args []string) error {
+	var clinic uuidFlag
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	seed := fs.Uint64("seed", 1, "seed of the random generator")
+	doctors := fs.Int("doctors", 5, "number of doctors")
+	patients := fs.Int("patients", 20, "number of patients")
+	appointments := fs.Int("appointments", 60, "number of appointments")
+	days := fs.Int("days", 14, "appointments are spread over this many days around today")
+	fs.Var(&clinic, "clinic", "id of the clinic, defaults to the default clinic")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: wacctl seed [flags]")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if *doctors < 1 || *patients < 1 || *appointments < 0 || *days < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	db, a, err := connect(ctx)
+	if err != nil {
+		return fmt.Errorf("seed: %w", err)
+	}
+	defer db.Disconnect(context.Background())
+
+	if ctx, err = withClinic(ctx, db, clinic); err != nil {
+		return fmt.Errorf("seed: %w", err)
+	}
+
+	s := seeder{
+		db:    db,
+		app:   a,
+		rand:  rand.New(rand.NewPCG(*seed, *seed)),
+		seed:  *seed,
+		today: today(),
+	}
+	if err := s.run(ctx, *doctors, *patients, *appointments, *days); err != nil {
+		return fmt.Errorf("seed: %w", err)
+	}
+
+	fmt.Printf(
+		"seeded %d doctors, %d patients, %d conditions, %d appointments, %d prescriptions"+
+			" and %d medical history files\n",
+		len(s.doctors),
+		len(s.patients),
+		s.conditions,
+		s.appointments,
+		s.prescriptions,
+		s.files,
+	)
+	return nil
+}
+
+type seeder struct {
+	db    data.Db
+	app   app.App
+	rand  *rand.Rand
+	seed  uint64
+	today time.Time
+
+	doctors       []api.Doctor
+	patients      []api.Patient
+	conditions    int
+	appointments  int
+	prescriptions int
+	files         int
+}
+
+func (s *seeder) run(ctx context.Context, doctors, patients, appointments, days int) error {
+	for i := range doctors {
+		if err := s.doctor(ctx, i); err != nil {
+			return err
+		}
+	}
+	for i := range patients {
+		if err := s.patient(ctx, i); err != nil {
+			return err
+		}
+	}
+	for range appointments {
+		if err := s.appointment(ctx, days); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *seeder) doctor(ctx context.Context, i int) error {
+	first, last := s.name()
+	doctor, err := s.app.CreateDoctor(ctx, api.DoctorRegistration{
+		Email:          s.email("dr", first, last, i),
+		FirstName:      first,
+		LastName:       last,
+		Role:           api.UserRoleDoctor,
+		Specialization: pick(s.rand, seedSpecializations),
+	})
+	if err != nil {
+		return fmt.Errorf("doctor %d: %w", i, err)
+	}
+	s.doctors = append(s.doctors, doctor)
+	return nil
+}
+
+func (s *seeder) patient(ctx context.Context, i int) error {
+	first, last := s.name()
+	patient, err := s.app.CreatePatient(ctx, api.PatientRegistration{
+		Email:     s.email("pt", first, last, i),
+		FirstName: first,
+		LastName:  last,
+		Role:      api.UserRolePatient,
+	})
+	if err != nil {
+		return fmt.Errorf("patient %d: %w", i, err)
+	}
+	s.patients = append(s.patients, patient)
+
+	// files can only be added directly, the API only lists them
+	for range s.rand.IntN(6) {
+		uploadedAt := s.today.AddDate(0, 0, -s.rand.IntN(365))
+		fileType := pick(s.rand, seedFileTypes)
+		name := fmt.Sprintf("%s_%s.pdf", fileType, uploadedAt.Format(time.DateOnly))
+		_, err := s.db.CreateMedicalHistoryFile(ctx, data.MedicalHistoryFile{
+			PatientId:  patient.Id,
+			Name:       name,
+			UploadedAt: uploadedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("medical history file of patient %d: %w", i, err)
+		}
+		s.files++
+	}
+	return nil
+}
+
+// appointment books a random free slot of a random doctor, about half of the
+// appointments are for a new condition of the patient. Past appointments are
+// accepted and some of them get a prescription, future ones are left in
+// various states.
+func (s *seeder) appointment(ctx context.Context, days int) error {
+	patient := pick(s.rand, s.patients)
+	doctor := pick(s.rand, s.doctors)
+	day := s.today.AddDate(0, 0, s.rand.IntN(days)-days/2)
+	hour := seedFirstSlotHour + s.rand.IntN(seedLastSlotHour-seedFirstSlotHour+1)
+	at := time.Date(day.Year(), day.Month(), day.Day(), hour, 0, 0, 0, time.Local)
+
+	req := api.NewAppointmentRequest{
+		PatientId:           patient.Id,
+		DoctorId:            doctor.Id,
+		AppointmentDateTime: at,
+		Reason:              asPtr(pick(s.rand, seedReasons)),
+	}
+
+	if s.rand.IntN(2) == 0 {
+		cond, err := s.app.CreatePatientCondition(ctx, api.NewCondition{
+			PatientId: patient.Id,
+			Name:      pick(s.rand, seedConditions),
+			Start:     at.AddDate(0, 0, -s.rand.IntN(30)),
+		})
+		if err != nil {
+			return fmt.Errorf("condition: %w", err)
+		}
+		req.ConditionId = cond.Id
+	}
+
+	appt, err := s.app.CreateAppointment(ctx, req)
+	if errors.Is(err, app.ErrDoctorUnavailable) || errors.Is(err, app.ErrTooManyRequested) {
+		// The slot is taken or the patient waits for enough decisions, a few
+		// missing appointments don't matter, but their conditions would be
+		// left behind.
+		if req.ConditionId != nil {
+			if err := s.db.DeleteCondition(ctx, *req.ConditionId); err != nil {
+				return fmt.Errorf("delete condition %s: %w", *req.ConditionId, err)
+			}
+		}
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("appointment: %w", err)
+	}
+	s.appointments++
+	if req.ConditionId != nil {
+		s.conditions++
+	}
+
+	accept := at.Before(s.today) || s.rand.IntN(3) > 0
+	if !accept {
+		return nil
+	}
+
+	_, err = s.app.DecideAppointment(ctx, *appt.Id, api.AppointmentDecision{
+		Action: api.Accept,
+	})
+	if err != nil {
+		return fmt.Errorf("decide appointment %s: %w", *appt.Id, err)
+	}
+
+	if at.Before(s.today) && s.rand.IntN(2) == 0 {
+		_, err := s.app.CreatePatientPrescription(ctx, api.NewPrescription{
+			PatientId:     patient.Id,
+			AppointmentId: appt.Id,
+			Name:          pick(s.rand, seedMedicines),
+			Start:         at,
+			End:           at.AddDate(0, 0, 7+s.rand.IntN(21)),
+		})
+		if err != nil {
+			return fmt.Errorf("prescription for appointment %s: %w", *appt.Id, err)
+		}
+		s.prescriptions++
+	} else if s.rand.IntN(10) == 0 {
+		err := s.app.CancelAppointment(ctx, *appt.Id, api.AppointmentCancellation{
+			By:     api.UserRolePatient,
+			Reason: asPtr("Feeling better"),
+		})
+		if err != nil {
+			return fmt.Errorf("cancel appointment %s: %w", *appt.Id, err)
+		}
+	}
+
+	return nil
+}
+
+func today() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+}
+
+func (s *seeder) name() (string, string) {
+	return pick(s.rand, seedFirstNames), pick(s.rand, seedLastNames)
+}
+
+func (s *seeder) email(prefix, first, last string, i int) openapi_types.Email {
+	local := strings.ToLower(fmt.Sprintf("%s.%s.%s.%d.%d", prefix, first, last, s.seed, i))
+	return openapi_types.Email(asciiFold(local) + "@seed.wac.local")
+}
+
+// asciiFold replaces Slovak diacritics so names can be used in emails.
+func asciiFold(s string) string {
+	return strings.NewReplacer(
+		"á", "a", "ä", "a", "č", "c", "ď", "d", "é", "e", "í", "i", "ľ", "l", "ĺ", "l",
+		"ň", "n", "ó", "o", "ô", "o", "ŕ", "r", "š", "s", "ť", "t", "ú", "u", "ý", "y", "ž", "z",
+	).Replace(s)
+}
+
+func pick[T any](r *rand.Rand, values []T) T {
+	return values[r.IntN(len(values))]
+}
+
+func asPtr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+// runStaff creates a staff member directly in the database. Through the API
+// only administrators create staff, so the first administrator of a clinic is
+// created with this command.
+func runStaff(ctx context.Context, args []string) error {
+	var clinic uuidFlag
+	fs := flag.NewFlagSet("staff", flag.ExitOnError)
+	role := fs.String("role", "admin", "role of the staff member: nurse, receptionist or admin")
+	email := fs.String("email", "", "email of the staff member (required)")
+	firstName := fs.String("first-name", "", "first name of the staff member (required)")
+	lastName := fs.String("last-name", "", "last name of the staff member (required)")
+	fs.Var(&clinic, "clinic", "id of the clinic, defaults to the default clinic")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: wacctl staff [flags]")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	staffRole := data.StaffRole(*role)
+	switch staffRole {
+	case data.StaffNurse, data.StaffReceptionist, data.StaffAdmin:
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *email == "" || *firstName == "" || *lastName == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	db, _, err := connect(ctx)
+	if err != nil {
+		return fmt.Errorf("staff: %w", err)
+	}
+	defer db.Disconnect(context.Background())
+
+	if ctx, err = withClinic(ctx, db, clinic); err != nil {
+		return fmt.Errorf("staff: %w", err)
+	}
+
+	staff, err := db.CreateStaff(ctx, data.Staff{
+		Email:     *email,
+		FirstName: *firstName,
+		LastName:  *lastName,
+		Role:      staffRole,
+	})
+	if errors.Is(err, data.ErrDuplicateEmail) {
+		return fmt.Errorf("staff: a staff member with email %q already exists", *email)
+	} else if err != nil {
+		return fmt.Errorf("staff: %w", err)
+	}
+
+	fmt.Printf("%s %s %s %s\n", staff.Id, staff.Role, staff.Email, staff.ClinicId)
+	return nil
+}
@@ -3,12 +3,14 @@ package app
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/Nesquiko/wac/pkg/api"
 	"github.com/Nesquiko/wac/pkg/data"
+	"github.com/Nesquiko/wac/pkg/fhir"
 )
 
 var (
@@ -16,6 +18,12 @@ var (
 	ErrNotFound            = errors.New("resource not found")
 	ErrDoctorUnavailable   = errors.New("doctor unavailable at the specified time")
 	ErrResourceUnavailable = errors.New("resource is unavailable during the requested time slot")
+	ErrForbidden           = errors.New("acting user isn't allowed to do this")
+	ErrNotScheduled        = errors.New("appointment is not scheduled")
+	ErrVisitNoteLocked     = errors.New("visit note was signed and can't be changed")
+	ErrReferralNotActive   = errors.New("referral isn't active")
+	ErrLastClinic          = errors.New("patient must be shared with at least one clinic")
+	ErrTooManyRequested    = errors.New("patient has too many appointments awaiting a decision")
 )
 
 type App interface {
@@ -23,6 +31,7 @@ type App interface {
 		ctx context.Context,
 		appt api.NewAppointmentRequest,
 	) (api.PatientAppointment, error)
+	BookAppointment(ctx context.Context, req api.StaffBooking) (api.DoctorAppointment, error)
 	CancelAppointment(
 		ctx context.Context,
 		appointmentId uuid.UUID,
@@ -48,15 +57,35 @@ type App interface {
 		appointmentId api.AppointmentId,
 		newDateTime time.Time,
 	) (api.PatientAppointment, error)
+	ReassignAppointment(
+		ctx context.Context,
+		appointmentId uuid.UUID,
+		doctorId uuid.UUID,
+	) (api.DoctorAppointment, error)
+	CompleteAppointment(ctx context.Context, appointmentId uuid.UUID) (api.DoctorAppointment, error)
+
+	VisitNote(ctx context.Context, appointmentId uuid.UUID) (api.VisitNote, error)
+	SaveVisitNote(
+		ctx context.Context,
+		appointmentId uuid.UUID,
+		note api.UpdateVisitNote,
+	) (api.VisitNote, error)
 
 	CreatePatient(ctx context.Context, p api.PatientRegistration) (api.Patient, error)
 	PatientById(ctx context.Context, id uuid.UUID) (api.Patient, error)
 	PatientByEmail(ctx context.Context, email string) (api.Patient, error)
+	SearchPatients(ctx context.Context, params api.SearchPatientsParams) (api.PatientList, error)
+	UpdatePatientProfile(
+		ctx context.Context,
+		patientId uuid.UUID,
+		req api.PatientProfileUpdate,
+		ifMatch Precondition,
+	) (api.Patient, error)
 	PatientsCalendar(
 		ctx context.Context,
 		patientId uuid.UUID,
-		from api.From,
-		to *api.To,
+		params api.PatientsCalendarParams,
+		list ListParams,
 	) (api.PatientsCalendar, error)
 	PatientMedicalHistoryFiles(
 		ctx context.Context,
@@ -64,6 +93,31 @@ type App interface {
 		page int,
 		pageSize int,
 	) (api.MedicalHistoryFileList, error)
+	SharePatient(ctx context.Context, patientId uuid.UUID, clinicId uuid.UUID) (api.Patient, error)
+	UnsharePatient(
+		ctx context.Context,
+		patientId uuid.UUID,
+		clinicId uuid.UUID,
+	) (api.Patient, error)
+	ExportPatientData(ctx context.Context, patientId uuid.UUID) (PatientDataExport, error)
+	ErasePatientData(
+		ctx context.Context,
+		patientId uuid.UUID,
+		req api.PatientErasureRequest,
+	) (api.PatientErasureReport, error)
+
+	CreateClinic(ctx context.Context, req api.NewClinic) (api.Clinic, error)
+	ClinicById(ctx context.Context, id uuid.UUID) (api.Clinic, error)
+	AuthorizeClinic(ctx context.Context) error
+
+	CreateLocation(ctx context.Context, req api.NewLocation) (api.Location, error)
+	LocationById(ctx context.Context, id uuid.UUID) (api.Location, error)
+	Locations(ctx context.Context) ([]api.Location, error)
+
+	CreateStaff(ctx context.Context, req api.NewStaff) (api.Staff, error)
+	StaffById(ctx context.Context, id uuid.UUID) (api.Staff, error)
+	StaffByEmail(ctx context.Context, email string) (api.Staff, error)
+	ClinicStaff(ctx context.Context) ([]api.Staff, error)
 
 	CreateDoctor(ctx context.Context, d api.DoctorRegistration) (api.Doctor, error)
 	DoctorById(ctx context.Context, id uuid.UUID) (api.Doctor, error)
@@ -71,8 +125,8 @@ type App interface {
 	DoctorsCalendar(
 		ctx context.Context,
 		doctorId uuid.UUID,
-		from api.From,
-		to *api.To,
+		params api.DoctorsCalendarParams,
+		list ListParams,
 	) (api.DoctorCalendar, error)
 	DoctorTimeSlots(
 		ctx context.Context,
@@ -80,7 +134,21 @@ type App interface {
 		date time.Time,
 	) (api.DoctorTimeslots, error)
 	AvailableDoctors(ctx context.Context, dateTime time.Time) ([]api.Doctor, error)
-	GetAllDoctors(ctx context.Context) ([]api.Doctor, error)
+	SuggestAppointments(
+		ctx context.Context,
+		params api.SuggestAppointmentsParams,
+	) ([]api.AppointmentSuggestion, error)
+	Doctors(
+		ctx context.Context,
+		params api.GetDoctorsParams,
+		list ListParams,
+	) (api.Doctors, error)
+	UpdateDoctorProfile(
+		ctx context.Context,
+		doctorId uuid.UUID,
+		req api.DoctorProfileUpdate,
+		ifMatch Precondition,
+	) (api.Doctor, error)
 
 	CreatePatientCondition(ctx context.Context, cond api.NewCondition) (api.ConditionDisplay, error)
 	ConditionById(ctx context.Context, id uuid.UUID) (api.Condition, error)
@@ -88,12 +156,23 @@ type App interface {
 		ctx context.Context,
 		conditionId uuid.UUID,
 		updateData api.UpdateCondition,
+		ifMatch Precondition,
 	) (api.Condition, error)
 	PatientConditionsOnDate(
 		ctx context.Context,
 		patientId uuid.UUID,
 		date time.Time,
-	) ([]api.ConditionDisplay, error)
+		list ListParams,
+	) (api.Conditions, error)
+	ConditionHistory(ctx context.Context, conditionId uuid.UUID) ([]api.ConditionRevision, error)
+	ConditionAsOf(ctx context.Context, conditionId uuid.UUID, at time.Time) (api.Condition, error)
+
+	SearchIcd10(query string, limit int) []api.Icd10Code
+
+	CreateReferral(ctx context.Context, req api.NewReferral) (api.Referral, error)
+	ReferralById(ctx context.Context, id uuid.UUID) (api.Referral, error)
+	PatientReferrals(ctx context.Context, patientId uuid.UUID) ([]api.Referral, error)
+	CancelReferral(ctx context.Context, id uuid.UUID) (api.Referral, error)
 
 	CreatePatientPrescription(
 		ctx context.Context,
@@ -103,9 +182,19 @@ type App interface {
 		ctx context.Context,
 		prescriptionId uuid.UUID,
 		updateData api.UpdatePrescription,
+		ifMatch Precondition,
 	) (api.Prescription, error)
 	PrescriptionById(ctx context.Context, prescriptionId uuid.UUID) (api.Prescription, error)
-	DeletePrescription(ctx context.Context, id uuid.UUID) error
+	DeletePrescription(ctx context.Context, id uuid.UUID, ifMatch Precondition) error
+	PrescriptionHistory(
+		ctx context.Context,
+		prescriptionId uuid.UUID,
+	) ([]api.PrescriptionRevision, error)
+	PrescriptionAsOf(
+		ctx context.Context,
+		prescriptionId uuid.UUID,
+		at time.Time,
+	) (api.Prescription, error)
 
 	CreateResource(ctx context.Context, resource api.NewResource) (api.NewResource, error)
 	ReserveResource(
@@ -113,18 +202,67 @@ type App interface {
 		resourceId uuid.UUID,
 		reservation api.ResourceReservation,
 	) error
-	AvailableResources(ctx context.Context, dateTime time.Time) (api.AvailableResources, error)
+	AssignResourceLocation(
+		ctx context.Context,
+		resourceId uuid.UUID,
+		req api.ResourceLocation,
+	) (api.NewResource, error)
+	AvailableResources(
+		ctx context.Context,
+		dateTime time.Time,
+		locationId *uuid.UUID,
+	) (api.AvailableResources, error)
 	ReserveAppointmentResources(
 		ctx context.Context,
 		appointmentId uuid.UUID,
 		payload api.ReserveAppointmentResourcesJSONBody,
 	) (api.DoctorAppointment, error)
+
+	FhirPatient(ctx context.Context, id uuid.UUID) (fhir.Patient, error)
+	FhirPatients(ctx context.Context, search fhir.PatientSearch) ([]fhir.Patient, error)
+	FhirPractitioner(ctx context.Context, id uuid.UUID) (fhir.Practitioner, error)
+	FhirPractitioners(
+		ctx context.Context,
+		search fhir.PractitionerSearch,
+	) ([]fhir.Practitioner, error)
+	FhirAppointment(ctx context.Context, id uuid.UUID) (fhir.Appointment, error)
+	FhirAppointments(
+		ctx context.Context,
+		search fhir.AppointmentSearch,
+	) ([]fhir.Appointment, error)
+	FhirCondition(ctx context.Context, id uuid.UUID) (fhir.Condition, error)
+	FhirConditions(ctx context.Context, search fhir.ConditionSearch) ([]fhir.Condition, error)
+	FhirMedicationRequest(ctx context.Context, id uuid.UUID) (fhir.MedicationRequest, error)
+	FhirMedicationRequests(
+		ctx context.Context,
+		search fhir.MedicationRequestSearch,
+	) ([]fhir.MedicationRequest, error)
+	ImportFhirBundle(
+		ctx context.Context,
+		entries []fhir.Entry,
+		dryRun bool,
+	) (fhir.ImportReport, error)
+
+	// Ready returns an error if the app can't serve requests, e.g. when the
+	// database is unreachable.
+	Ready(ctx context.Context) error
 }
 
-func New(db data.Db) App {
-	return monolithApp{db}
+// New returns the App over the database. A patient can have at most
+// maxRequested upcoming appointments awaiting doctors' decisions, so they can't
+// block a doctor's day with requests, zero doesn't cap them.
+func New(db data.Db, maxRequested int) App {
+	return monolithApp{db: db, maxRequested: maxRequested}
 }
 
 type monolithApp struct {
-	db data.Db
+	db           data.Db
+	maxRequested int
+}
+
+func (a monolithApp) Ready(ctx context.Context) error {
+	if err := a.db.Ping(ctx); err != nil {
+		return fmt.Errorf("Ready: %w", err)
+	}
+	return nil
 }
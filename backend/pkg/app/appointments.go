@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,14 +15,39 @@ import (
 	"github.com/Nesquiko/wac/pkg/data"
 )
 
+// Doctors work in one hour slots, the first one starts at firstSlotHour and
+// the last one at lastSlotHour.
+const (
+	firstSlotHour       = 8
+	lastSlotHour        = 14
+	appointmentDuration = time.Hour
+)
+
+const (
+	// bookingLockLease bounds how long a booking holds the patient's lock if
+	// it isn't released, e.g. because the server stopped.
+	bookingLockLease = 10 * time.Second
+	// bookingLockRetry is how often a booking waiting for the lock retries.
+	bookingLockRetry = 50 * time.Millisecond
+)
+
 // CreateAppointment implements App.
 func (a monolithApp) CreateAppointment(
 	ctx context.Context,
 	appt api.NewAppointmentRequest,
 ) (api.PatientAppointment, error) {
-	appointment, err := a.db.CreateAppointment(ctx, newApptToDataAppt(appt))
+	newAppt := newApptToDataAppt(appt)
+	if err := a.authorizeBooking(ctx, newAppt); err != nil {
+		return api.PatientAppointment{}, fmt.Errorf("CreateAppointment: %w", err)
+	}
+	unlock, err := a.lockPatientBookings(ctx, newAppt.PatientId)
+	if err != nil {
+		return api.PatientAppointment{}, fmt.Errorf("CreateAppointment: %w", err)
+	}
+	appointment, err := a.createRequestedAppointment(ctx, newAppt)
+	unlock()
 	if err != nil {
-		return api.PatientAppointment{}, fmt.Errorf("CreateAppointment create appointment: %w", err)
+		return api.PatientAppointment{}, fmt.Errorf("CreateAppointment: %w", err)
 	}
 
 	doc, err := a.db.DoctorById(ctx, appointment.DoctorId)
@@ -44,7 +72,108 @@ func (a monolithApp) CreateAppointment(
 		)
 	}
 
-	return dataApptToPatientAppt(appointment, doc, cond, prescriptions), nil
+	location, err := a.appointmentLocation(ctx, appointment)
+	if err != nil {
+		return api.PatientAppointment{}, fmt.Errorf("CreateAppointment fetch location: %w", err)
+	}
+
+	patientAppt := dataApptToPatientAppt(appointment, doc, cond, prescriptions)
+	patientAppt.Location = location
+	return patientAppt, nil
+}
+
+// createRequestedAppointment creates the appointment unless the patient has
+// too many requested ones. The caller must hold the patient's booking lock, so
+// concurrent requests can't all pass the check.
+func (a monolithApp) createRequestedAppointment(
+	ctx context.Context,
+	appt data.Appointment,
+) (data.Appointment, error) {
+	if err := a.checkRequestedAppointments(ctx, appt.PatientId); err != nil {
+		return data.Appointment{}, err
+	}
+	return a.createAppointment(ctx, appt)
+}
+
+// lockPatientBookings waits for the patient's booking lock, the returned func
+// releases it.
+func (a monolithApp) lockPatientBookings(ctx context.Context, patientId uuid.UUID) (func(), error) {
+	for {
+		lock, locked, err := a.db.LockPatientBookings(ctx, patientId, bookingLockLease)
+		if err != nil {
+			return nil, fmt.Errorf("lockPatientBookings: %w", err)
+		}
+		if locked {
+			return func() {
+				// released even if the request was cancelled meanwhile
+				err := a.db.UnlockPatientBookings(context.WithoutCancel(ctx), lock)
+				if err != nil {
+					slog.Error(
+						"failed to release booking lock",
+						"error", err.Error(),
+						"patientId", patientId.String(),
+					)
+				}
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("lockPatientBookings: %w", ctx.Err())
+		case <-time.After(bookingLockRetry):
+		}
+	}
+}
+
+// checkRequestedAppointments returns ErrTooManyRequested if the patient has
+// maxRequested upcoming appointments awaiting a decision.
+func (a monolithApp) checkRequestedAppointments(ctx context.Context, patientId uuid.UUID) error {
+	if a.maxRequested == 0 {
+		return nil
+	}
+	page, err := a.db.ListAppointments(
+		ctx,
+		data.AppointmentFilter{
+			PatientId: &patientId,
+			From:      time.Now(),
+			Status:    []string{string(api.Requested)},
+		},
+		data.ListQuery{SortBy: []string{"appointmentDateTime"}, Limit: 1},
+	)
+	if err != nil {
+		return fmt.Errorf("checkRequestedAppointments: %w", err)
+	}
+	if page.Total >= int64(a.maxRequested) {
+		return ErrTooManyRequested
+	}
+	return nil
+}
+
+// createAppointment validates the referral and location of the appointment
+// and creates it.
+func (a monolithApp) createAppointment(
+	ctx context.Context,
+	appt data.Appointment,
+) (data.Appointment, error) {
+	if appt.ReferralId != nil {
+		if err := a.checkReferral(ctx, &appt); err != nil {
+			return data.Appointment{}, err
+		}
+	}
+	if err := a.checkDoctor(ctx, appt); err != nil {
+		return data.Appointment{}, err
+	}
+	if err := a.checkLocation(ctx, appt); err != nil {
+		return data.Appointment{}, err
+	}
+
+	appointment, err := a.db.CreateAppointment(ctx, appt)
+	if errors.Is(err, data.ErrDoctorUnavailable) {
+		return data.Appointment{}, ErrDoctorUnavailable
+	} else if err != nil {
+		return data.Appointment{}, fmt.Errorf("create appointment: %w", referralError(err))
+	}
+	return appointment, nil
 }
 
 func (a monolithApp) CancelAppointment(
@@ -52,6 +181,16 @@ func (a monolithApp) CancelAppointment(
 	appointmentId uuid.UUID,
 	req api.AppointmentCancellation,
 ) error {
+	if _, ok := data.ActorFromContext(ctx); ok {
+		appointment, err := a.db.AppointmentById(ctx, appointmentId)
+		if err != nil {
+			return fmt.Errorf("CancelAppointment find appointment: %w", err)
+		}
+		if err := a.authorizeCancellation(ctx, appointment); err != nil {
+			return fmt.Errorf("CancelAppointment: %w", err)
+		}
+	}
+
 	err := a.db.CancelAppointment(ctx, appointmentId, string(req.By), req.Reason)
 	if err != nil {
 		return fmt.Errorf("CancelAppointment: %w", err)
@@ -94,7 +233,14 @@ func (a monolithApp) PatientsAppointmentById(
 		)
 	}
 
-	return dataApptToPatientAppt(appointment, doc, cond, prescriptions), nil
+	location, err := a.appointmentLocation(ctx, appointment)
+	if err != nil {
+		return api.PatientAppointment{}, fmt.Errorf("PatientsAppointmentById fetch location: %w", err)
+	}
+
+	patientAppt := dataApptToPatientAppt(appointment, doc, cond, prescriptions)
+	patientAppt.Location = location
+	return patientAppt, nil
 }
 
 func (a monolithApp) DoctorsAppointmentById(
@@ -152,7 +298,27 @@ func (a monolithApp) DoctorsAppointmentById(
 		)
 	}
 
-	return dataApptToDoctorAppt(
+	note, err := a.visitNote(ctx, appointmentId)
+	if err != nil {
+		return api.DoctorAppointment{}, fmt.Errorf("DoctorsAppointmentById fetch visit note: %w", err)
+	}
+
+	referral, err := a.referral(ctx, appointment)
+	if err != nil {
+		return api.DoctorAppointment{}, fmt.Errorf("DoctorsAppointmentById fetch referral: %w", err)
+	}
+
+	location, err := a.appointmentLocation(ctx, appointment)
+	if err != nil {
+		return api.DoctorAppointment{}, fmt.Errorf("DoctorsAppointmentById fetch location: %w", err)
+	}
+
+	bookedBy, err := a.actingUser(ctx, appointment.BookedBy)
+	if err != nil {
+		return api.DoctorAppointment{}, fmt.Errorf("DoctorsAppointmentById fetch booked by: %w", err)
+	}
+
+	doctorAppt := dataApptToDoctorAppt(
 		appointment,
 		patient,
 		cond,
@@ -160,7 +326,12 @@ func (a monolithApp) DoctorsAppointmentById(
 		equipment,
 		medicine,
 		prescriptions,
-	), nil
+		note,
+	)
+	doctorAppt.Referral = referral
+	doctorAppt.Location = location
+	doctorAppt.BookedBy = bookedBy
+	return doctorAppt, nil
 }
 
 func (a monolithApp) DecideAppointment(
@@ -197,6 +368,21 @@ func (a monolithApp) DecideAppointment(
 		}
 	}
 
+	if decision.Action == api.Accept && decision.Facilities != nil {
+		appointment, err := a.db.AppointmentById(ctx, appointmentId)
+		if err != nil {
+			if errors.Is(err, data.ErrNotFound) {
+				return api.DoctorAppointment{}, fmt.Errorf("DecideAppointment: %w", ErrNotFound)
+			}
+			return api.DoctorAppointment{}, fmt.Errorf("DecideAppointment find appointment: %w", err)
+		}
+
+		facilityIds := Map(*decision.Facilities, func(f api.Facility) uuid.UUID { return f.Id })
+		if err := a.checkFacilities(ctx, appointment, facilityIds); err != nil {
+			return api.DoctorAppointment{}, fmt.Errorf("DecideAppointment: %w", err)
+		}
+	}
+
 	appointment, err := a.db.DecideAppointment(
 		ctx,
 		appointmentId,
@@ -248,6 +434,21 @@ func (a monolithApp) DecideAppointment(
 		)
 	}
 
+	note, err := a.visitNote(ctx, appointmentId)
+	if err != nil {
+		return api.DoctorAppointment{}, fmt.Errorf("DecideAppointment fetch visit note: %w", err)
+	}
+
+	location, err := a.appointmentLocation(ctx, appointment)
+	if err != nil {
+		return api.DoctorAppointment{}, fmt.Errorf("DecideAppointment fetch location: %w", err)
+	}
+
+	bookedBy, err := a.actingUser(ctx, appointment.BookedBy)
+	if err != nil {
+		return api.DoctorAppointment{}, fmt.Errorf("DecideAppointment fetch booked by: %w", err)
+	}
+
 	doctorAppointment := dataApptToDoctorAppt(
 		appointment,
 		patient,
@@ -256,11 +457,37 @@ func (a monolithApp) DecideAppointment(
 		equipment,
 		medicine,
 		prescriptions,
+		note,
 	)
+	doctorAppointment.Location = location
+	doctorAppointment.BookedBy = bookedBy
 
 	return doctorAppointment, nil
 }
 
+// ReassignAppointment moves an appointment to another doctor at the same time.
+func (a monolithApp) ReassignAppointment(
+	ctx context.Context,
+	appointmentId uuid.UUID,
+	doctorId uuid.UUID,
+) (api.DoctorAppointment, error) {
+	_, err := a.db.ReassignAppointment(ctx, appointmentId, doctorId)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNotFound):
+			return api.DoctorAppointment{}, fmt.Errorf("ReassignAppointment: %w", ErrNotFound)
+		case errors.Is(err, data.ErrDoctorUnavailable):
+			return api.DoctorAppointment{}, fmt.Errorf(
+				"ReassignAppointment: %w",
+				ErrDoctorUnavailable,
+			)
+		}
+		return api.DoctorAppointment{}, fmt.Errorf("ReassignAppointment: %w", err)
+	}
+
+	return a.DoctorsAppointmentById(ctx, doctorId, appointmentId)
+}
+
 func (a monolithApp) DoctorTimeSlots(
 	ctx context.Context,
 	doctorId uuid.UUID,
@@ -277,7 +504,7 @@ func (a monolithApp) DoctorTimeSlots(
 	}
 
 	var slots []api.TimeSlot
-	for hour := 8; hour <= 14; hour++ {
+	for hour := firstSlotHour; hour <= lastSlotHour; hour++ {
 		status := api.Available
 		if bookedHours[hour] {
 			status = api.Unavailable
@@ -300,6 +527,22 @@ func (a monolithApp) RescheduleAppointment(
 	appointmentId api.AppointmentId,
 	newDateTime time.Time,
 ) (api.PatientAppointment, error) {
+	current, err := a.db.AppointmentById(ctx, appointmentId)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return api.PatientAppointment{}, fmt.Errorf("RescheduleAppointment: %w", ErrNotFound)
+		}
+		return api.PatientAppointment{}, fmt.Errorf("RescheduleAppointment find appointment: %w", err)
+	}
+	if err := a.authorizeBooking(ctx, current); err != nil {
+		return api.PatientAppointment{}, fmt.Errorf("RescheduleAppointment: %w", err)
+	}
+	current.EndTime = newDateTime.Add(current.EndTime.Sub(current.AppointmentDateTime))
+	current.AppointmentDateTime = newDateTime
+	if err := a.checkLocation(ctx, current); err != nil {
+		return api.PatientAppointment{}, fmt.Errorf("RescheduleAppointment: %w", err)
+	}
+
 	appt, err := a.db.RescheduleAppointment(ctx, appointmentId, newDateTime)
 	if err != nil {
 		if errors.Is(err, data.ErrDoctorUnavailable) {
@@ -336,5 +579,91 @@ func (a monolithApp) RescheduleAppointment(
 		)
 	}
 
-	return dataApptToPatientAppt(appt, doc, cond, prescriptions), nil
+	location, err := a.appointmentLocation(ctx, appt)
+	if err != nil {
+		return api.PatientAppointment{}, fmt.Errorf("RescheduleAppointment fetch location: %w", err)
+	}
+
+	patientAppt := dataApptToPatientAppt(appt, doc, cond, prescriptions)
+	patientAppt.Location = location
+	return patientAppt, nil
+}
+
+// appointmentFilter returns the filter of calendar params.
+func appointmentFilter(
+	from api.From,
+	to *api.To,
+	status *api.AppointmentStatuses,
+	types *api.AppointmentTypes,
+) data.AppointmentFilter {
+	filter := data.AppointmentFilter{From: from.Time}
+	if to != nil {
+		filter.To = &to.Time
+	}
+	if status != nil {
+		filter.Status = Map(*status, func(s api.AppointmentStatus) string { return string(s) })
+	}
+	if types != nil {
+		filter.Type = Map(*types, func(t api.AppointmentType) string { return string(t) })
+	}
+	return filter
+}
+
+// appointmentDisplays returns the displays of the appointments. Their doctors,
+// patients and locations are looked up in a constant number of queries,
+// regardless of the number of appointments.
+func (a monolithApp) appointmentDisplays(
+	ctx context.Context,
+	appts []data.Appointment,
+) ([]api.AppointmentDisplay, error) {
+	displays := make([]api.AppointmentDisplay, len(appts))
+	if len(appts) == 0 {
+		return displays, nil
+	}
+
+	doctorIds := make(map[uuid.UUID]bool)
+	patientIds := make(map[uuid.UUID]bool)
+	for _, appt := range appts {
+		doctorIds[appt.DoctorId] = true
+		patientIds[appt.PatientId] = true
+	}
+
+	doctors, err := a.db.DoctorsByIds(ctx, slices.Collect(maps.Keys(doctorIds)))
+	if err != nil {
+		return nil, fmt.Errorf("appointmentDisplays doctors: %w", err)
+	}
+	patients, err := a.db.PatientsByIds(ctx, slices.Collect(maps.Keys(patientIds)))
+	if err != nil {
+		return nil, fmt.Errorf("appointmentDisplays patients: %w", err)
+	}
+	locations, err := a.locationTree(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("appointmentDisplays locations: %w", err)
+	}
+
+	doctorsById := Index(doctors, func(d data.Doctor) uuid.UUID { return d.Id })
+	patientsById := Index(patients, func(p data.Patient) uuid.UUID { return p.Id })
+	for i, appt := range appts {
+		doctor, ok := doctorsById[appt.DoctorId]
+		if !ok {
+			return nil, fmt.Errorf(
+				"appointmentDisplays doctor %s of appointment %s: %w",
+				appt.DoctorId,
+				appt.Id,
+				data.ErrNotFound,
+			)
+		}
+		patient, ok := patientsById[appt.PatientId]
+		if !ok {
+			return nil, fmt.Errorf(
+				"appointmentDisplays patient %s of appointment %s: %w",
+				appt.PatientId,
+				appt.Id,
+				data.ErrNotFound,
+			)
+		}
+		displays[i] = dataApptToApptDisplay(appt, patient, doctor)
+		displays[i].Location = locations.display(appt.LocationId)
+	}
+	return displays, nil
 }
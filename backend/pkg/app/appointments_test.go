@@ -0,0 +1,265 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+func TestPatientsCalendar_DoctorOfEachAppointment(t *testing.T) {
+	db := newCountingDb(0)
+	patient := db.addPatient("Jana", "Nováková")
+	house := db.addDoctor("Gregory", "House")
+	wilson := db.addDoctor("James", "Wilson")
+	start := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	db.addAppointment(patient, house, start)
+	db.addAppointment(patient, wilson, start.Add(24*time.Hour))
+
+	calendar, err := New(db, 0).PatientsCalendar(
+		context.Background(),
+		patient,
+		api.PatientsCalendarParams{From: api.From{Time: start}},
+		ListParams{},
+	)
+	require.NoError(t, err)
+	require.NotNil(t, calendar.Appointments)
+	require.Len(t, *calendar.Appointments, 2)
+	assert.Equal(t, "Gregory House", (*calendar.Appointments)[0].DoctorName)
+	assert.Equal(t, "James Wilson", (*calendar.Appointments)[1].DoctorName)
+	assert.Equal(t, "Jana Nováková", (*calendar.Appointments)[1].PatientName)
+}
+
+func TestPatientsCalendar_ConstantQueries(t *testing.T) {
+	queries := func(appointments int) int {
+		db := newCountingDb(0)
+		patient := db.addPatient("Jana", "Nováková")
+		start := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+		for i := range appointments {
+			doctor := db.addDoctor("Gregory", fmt.Sprintf("House %d", i))
+			db.addAppointment(patient, doctor, start.Add(time.Duration(i)*time.Hour))
+		}
+
+		_, err := New(db, 0).PatientsCalendar(
+			context.Background(),
+			patient,
+			api.PatientsCalendarParams{From: api.From{Time: start}},
+			ListParams{},
+		)
+		require.NoError(t, err)
+		return db.queries
+	}
+
+	assert.Equal(t, queries(2), queries(40), "Queries don't grow with appointments")
+}
+
+func BenchmarkAppointmentDisplays(b *testing.B) {
+	for _, appointments := range []int{10, 100, 1000} {
+		db := newCountingDb(20 * time.Microsecond)
+		start := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+		doctors := make([]uuid.UUID, 10)
+		for i := range doctors {
+			doctors[i] = db.addDoctor("Gregory", fmt.Sprintf("House %d", i))
+		}
+		appts := make([]data.Appointment, appointments)
+		for i := range appts {
+			patient := db.addPatient("Jana", fmt.Sprintf("Nováková %d", i))
+			appts[i] = db.addAppointment(patient, doctors[i%len(doctors)], start)
+		}
+		app := monolithApp{db: db}
+		ctx := context.Background()
+
+		b.Run(fmt.Sprintf("batched/%d", appointments), func(b *testing.B) {
+			db.queries = 0
+			for b.Loop() {
+				if _, err := app.appointmentDisplays(ctx, appts); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(db.queries)/float64(b.N), "queries/op")
+		})
+		b.Run(fmt.Sprintf("perAppointment/%d", appointments), func(b *testing.B) {
+			db.queries = 0
+			for b.Loop() {
+				if _, err := app.appointmentDisplaysEach(ctx, appts); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(db.queries)/float64(b.N), "queries/op")
+		})
+	}
+}
+
+// appointmentDisplaysEach looks up the doctor and patient of every appointment
+// on its own, as calendars did before appointmentDisplays. It's the baseline
+// of BenchmarkAppointmentDisplays.
+func (a monolithApp) appointmentDisplaysEach(
+	ctx context.Context,
+	appts []data.Appointment,
+) ([]api.AppointmentDisplay, error) {
+	locations, err := a.locationTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	displays := make([]api.AppointmentDisplay, len(appts))
+	for i, appt := range appts {
+		doctor, err := a.db.DoctorById(ctx, appt.DoctorId)
+		if err != nil {
+			return nil, err
+		}
+		patient, err := a.db.PatientById(ctx, appt.PatientId)
+		if err != nil {
+			return nil, err
+		}
+		displays[i] = dataApptToApptDisplay(appt, patient, doctor)
+		displays[i].Location = locations.display(appt.LocationId)
+	}
+	return displays, nil
+}
+
+// countingDb is an in-memory data.Db counting the queries it answers, each
+// one takes the latency of a round trip to the database. Methods calendars
+// don't use panic.
+type countingDb struct {
+	data.Db
+	latency time.Duration
+	queries int
+
+	doctors      map[uuid.UUID]data.Doctor
+	patients     map[uuid.UUID]data.Patient
+	appointments []data.Appointment
+}
+
+func newCountingDb(latency time.Duration) *countingDb {
+	return &countingDb{
+		latency:  latency,
+		doctors:  make(map[uuid.UUID]data.Doctor),
+		patients: make(map[uuid.UUID]data.Patient),
+	}
+}
+
+func (db *countingDb) query() {
+	db.queries++
+	time.Sleep(db.latency)
+}
+
+func (db *countingDb) addDoctor(firstName, lastName string) uuid.UUID {
+	id := uuid.New()
+	db.doctors[id] = data.Doctor{Id: id, FirstName: firstName, LastName: lastName}
+	return id
+}
+
+func (db *countingDb) addPatient(firstName, lastName string) uuid.UUID {
+	id := uuid.New()
+	db.patients[id] = data.Patient{Id: id, FirstName: firstName, LastName: lastName}
+	return id
+}
+
+func (db *countingDb) addAppointment(
+	patientId uuid.UUID,
+	doctorId uuid.UUID,
+	at time.Time,
+) data.Appointment {
+	appt := data.Appointment{
+		Id:                  uuid.New(),
+		PatientId:           patientId,
+		DoctorId:            doctorId,
+		AppointmentDateTime: at,
+		EndTime:             at.Add(appointmentDuration),
+		Type:                "regular_check",
+		Status:              "scheduled",
+	}
+	db.appointments = append(db.appointments, appt)
+	return appt
+}
+
+// ListAppointments returns the appointments of the filter's doctor or patient
+// in the order they were added, other filters and the query are ignored.
+func (db *countingDb) ListAppointments(
+	_ context.Context,
+	f data.AppointmentFilter,
+	_ data.ListQuery,
+) (data.Page[data.Appointment], error) {
+	db.query()
+	page := data.Page[data.Appointment]{Items: make([]data.Appointment, 0)}
+	for _, appt := range db.appointments {
+		if (f.DoctorId == nil || *f.DoctorId == appt.DoctorId) &&
+			(f.PatientId == nil || *f.PatientId == appt.PatientId) {
+			page.Items = append(page.Items, appt)
+		}
+	}
+	page.Total = int64(len(page.Items))
+	return page, nil
+}
+
+func (db *countingDb) FindConditionsByPatientId(
+	context.Context,
+	uuid.UUID,
+	time.Time,
+	*time.Time,
+) ([]data.Condition, error) {
+	db.query()
+	return nil, nil
+}
+
+func (db *countingDb) FindPrescriptionsByPatientId(
+	context.Context,
+	uuid.UUID,
+	time.Time,
+	*time.Time,
+) ([]data.Prescription, error) {
+	db.query()
+	return nil, nil
+}
+
+func (db *countingDb) Locations(context.Context) ([]data.Location, error) {
+	db.query()
+	return nil, nil
+}
+
+func (db *countingDb) DoctorById(_ context.Context, id uuid.UUID) (data.Doctor, error) {
+	db.query()
+	doctor, ok := db.doctors[id]
+	if !ok {
+		return data.Doctor{}, data.ErrNotFound
+	}
+	return doctor, nil
+}
+
+func (db *countingDb) DoctorsByIds(_ context.Context, ids []uuid.UUID) ([]data.Doctor, error) {
+	db.query()
+	doctors := make([]data.Doctor, 0, len(ids))
+	for _, id := range ids {
+		if doctor, ok := db.doctors[id]; ok {
+			doctors = append(doctors, doctor)
+		}
+	}
+	return doctors, nil
+}
+
+func (db *countingDb) PatientById(_ context.Context, id uuid.UUID) (data.Patient, error) {
+	db.query()
+	patient, ok := db.patients[id]
+	if !ok {
+		return data.Patient{}, data.ErrNotFound
+	}
+	return patient, nil
+}
+
+func (db *countingDb) PatientsByIds(_ context.Context, ids []uuid.UUID) ([]data.Patient, error) {
+	db.query()
+	patients := make([]data.Patient, 0, len(ids))
+	for _, id := range ids {
+		if patient, ok := db.patients[id]; ok {
+			patients = append(patients, patient)
+		}
+	}
+	return patients, nil
+}
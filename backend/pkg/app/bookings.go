@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+const InvalidBookingPatientCode = "booking.invalid-patient"
+
+// BookAppointment books an appointment on behalf of a patient, the acting
+// user must be allowed to book for patients and is recorded as the one who
+// booked it. The patient is registered if they aren't found by email.
+func (a monolithApp) BookAppointment(
+	ctx context.Context,
+	req api.StaffBooking,
+) (api.DoctorAppointment, error) {
+	if err := a.requirePermission(ctx, permBookForPatients); err != nil {
+		return api.DoctorAppointment{}, fmt.Errorf("BookAppointment: %w", err)
+	}
+
+	patient, err := a.bookingPatient(ctx, req.Patient)
+	if err != nil {
+		return api.DoctorAppointment{}, fmt.Errorf("BookAppointment: %w", err)
+	}
+
+	appt := staffBookingToDataAppt(req, patient.Id)
+	appointment, err := a.createAppointment(ctx, appt)
+	if err != nil {
+		return api.DoctorAppointment{}, fmt.Errorf("BookAppointment: %w", err)
+	}
+
+	return a.DoctorsAppointmentById(ctx, appointment.DoctorId, appointment.Id)
+}
+
+// bookingPatient finds the patient of a staff booking by id or email, or
+// registers them if the email isn't known and both names are given.
+func (a monolithApp) bookingPatient(
+	ctx context.Context,
+	req api.BookingPatient,
+) (data.Patient, error) {
+	if req.Id != nil {
+		patient, err := a.db.PatientById(ctx, *req.Id)
+		if errors.Is(err, data.ErrNotFound) {
+			return data.Patient{}, invalidBookingPatient("Patient doesn't exist")
+		} else if err != nil {
+			return data.Patient{}, fmt.Errorf("bookingPatient find by id: %w", err)
+		}
+		return patient, nil
+	}
+	if req.Email == nil {
+		return data.Patient{}, invalidBookingPatient("Patient must be given by id or email")
+	}
+
+	patient, err := a.db.PatientByEmail(ctx, string(*req.Email))
+	if err == nil {
+		return patient, nil
+	} else if !errors.Is(err, data.ErrNotFound) {
+		return data.Patient{}, fmt.Errorf("bookingPatient find by email: %w", err)
+	}
+
+	if req.FirstName == nil || req.LastName == nil {
+		return data.Patient{}, invalidBookingPatient(
+			"Patient with the email doesn't exist, give both names to register them",
+		)
+	}
+	patient, err = a.db.CreatePatient(ctx, data.Patient{
+		Email:     string(*req.Email),
+		FirstName: *req.FirstName,
+		LastName:  *req.LastName,
+	})
+	if errors.Is(err, data.ErrDuplicateEmail) {
+		return data.Patient{}, ErrDuplicateEmail
+	} else if err != nil {
+		return data.Patient{}, fmt.Errorf("bookingPatient register: %w", err)
+	}
+	return patient, nil
+}
+
+// actingUser returns the user who acted on an appointment with their names,
+// nil if no user is recorded.
+func (a monolithApp) actingUser(ctx context.Context, actor *data.Actor) (*api.ActingUser, error) {
+	if actor == nil {
+		return nil, nil
+	}
+
+	user := &api.ActingUser{Id: actor.Id, Role: api.UserRole(actor.Role)}
+	var firstName, lastName string
+	var err error
+	switch user.Role {
+	case api.UserRolePatient:
+		var patient data.Patient
+		patient, err = a.db.PatientById(ctx, actor.Id)
+		firstName, lastName = patient.FirstName, patient.LastName
+	case api.UserRoleDoctor:
+		var doctor data.Doctor
+		doctor, err = a.db.DoctorById(ctx, actor.Id)
+		firstName, lastName = doctor.FirstName, doctor.LastName
+	default:
+		var staff data.Staff
+		staff, err = a.db.StaffById(ctx, actor.Id)
+		firstName, lastName = staff.FirstName, staff.LastName
+	}
+	if errors.Is(err, data.ErrNotFound) {
+		return user, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("actingUser: %w", err)
+	}
+
+	user.FirstName, user.LastName = &firstName, &lastName
+	return user, nil
+}
+
+func invalidBookingPatient(detail string) *ValidationError {
+	return &ValidationError{ErrorDetail: api.ErrorDetail{
+		Code:   InvalidBookingPatientCode,
+		Title:  "Invalid patient",
+		Detail: detail,
+		Status: http.StatusBadRequest,
+	}}
+}
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+// CreateClinic creates a new tenant, only administrators can create them.
+func (a monolithApp) CreateClinic(ctx context.Context, req api.NewClinic) (api.Clinic, error) {
+	if err := a.requirePermission(ctx, permManageClinics); err != nil {
+		return api.Clinic{}, fmt.Errorf("CreateClinic: %w", err)
+	}
+
+	clinic, err := a.db.CreateClinic(ctx, data.Clinic{Name: req.Name})
+	if err != nil {
+		return api.Clinic{}, fmt.Errorf("CreateClinic: %w", err)
+	}
+	return dataClinicToApi(clinic), nil
+}
+
+func (a monolithApp) ClinicById(ctx context.Context, id uuid.UUID) (api.Clinic, error) {
+	clinic, err := a.db.ClinicById(ctx, id)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return api.Clinic{}, fmt.Errorf("ClinicById: %w", ErrNotFound)
+		}
+		return api.Clinic{}, fmt.Errorf("ClinicById: %w", err)
+	}
+	return dataClinicToApi(clinic), nil
+}
+
+// AuthorizeClinic checks that the acting doctor or staff member works in the
+// context's clinic, they can't act in other clinics. Patients act in any
+// clinic, their records are visible only in the clinics they shared them with.
+func (a monolithApp) AuthorizeClinic(ctx context.Context) error {
+	actor, ok := data.ActorFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	var err error
+	switch api.UserRole(actor.Role) {
+	case api.UserRolePatient:
+		return nil
+	case api.UserRoleDoctor:
+		_, err = a.db.DoctorById(ctx, actor.Id)
+	default:
+		_, err = a.db.StaffById(ctx, actor.Id)
+	}
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return fmt.Errorf("AuthorizeClinic: %w", ErrForbidden)
+		}
+		return fmt.Errorf("AuthorizeClinic: %w", err)
+	}
+	return nil
+}
+
+// SharePatient shares the patient's records with the clinic, only the patient
+// can consent to it.
+func (a monolithApp) SharePatient(
+	ctx context.Context,
+	patientId uuid.UUID,
+	clinicId uuid.UUID,
+) (api.Patient, error) {
+	if !isActingPatient(ctx, patientId) {
+		return api.Patient{}, fmt.Errorf("SharePatient: %w", ErrForbidden)
+	}
+
+	patient, err := a.db.SharePatient(ctx, patientId, clinicId)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return api.Patient{}, fmt.Errorf("SharePatient: %w", ErrNotFound)
+		}
+		return api.Patient{}, fmt.Errorf("SharePatient: %w", err)
+	}
+	return dataPatientToApiPatient(patient), nil
+}
+
+// UnsharePatient withdraws the patient's consent to share their records with
+// the clinic, only the patient can withdraw it.
+func (a monolithApp) UnsharePatient(
+	ctx context.Context,
+	patientId uuid.UUID,
+	clinicId uuid.UUID,
+) (api.Patient, error) {
+	if !isActingPatient(ctx, patientId) {
+		return api.Patient{}, fmt.Errorf("UnsharePatient: %w", ErrForbidden)
+	}
+
+	patient, err := a.db.UnsharePatient(ctx, patientId, clinicId)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNotFound):
+			return api.Patient{}, fmt.Errorf("UnsharePatient: %w", ErrNotFound)
+		case errors.Is(err, data.ErrLastClinic):
+			return api.Patient{}, fmt.Errorf("UnsharePatient: %w", ErrLastClinic)
+		}
+		return api.Patient{}, fmt.Errorf("UnsharePatient: %w", err)
+	}
+	return dataPatientToApiPatient(patient), nil
+}
+
+func isActingPatient(ctx context.Context, patientId uuid.UUID) bool {
+	actor, ok := data.ActorFromContext(ctx)
+	return ok && actor.Role == string(api.UserRolePatient) && actor.Id == patientId
+}
@@ -16,7 +16,16 @@ func (a monolithApp) CreatePatientCondition(
 	ctx context.Context,
 	c api.NewCondition,
 ) (api.ConditionDisplay, error) {
-	cond, err := a.db.CreateCondition(ctx, newCondToDataCond(c))
+	cond := newCondToDataCond(c)
+	if c.DiagnosisCode != nil {
+		diagnosis, err := diagnosisOf(*c.DiagnosisCode)
+		if err != nil {
+			return api.ConditionDisplay{}, fmt.Errorf("CreatePatientCondition: %w", err)
+		}
+		cond.Diagnosis = diagnosis
+	}
+
+	cond, err := a.db.CreateCondition(ctx, cond)
 	if err != nil {
 		return api.ConditionDisplay{}, fmt.Errorf("CreatePatientCondition: %w", err)
 	}
@@ -26,26 +35,32 @@ func (a monolithApp) CreatePatientCondition(
 func (a monolithApp) ConditionById(ctx context.Context, id uuid.UUID) (api.Condition, error) {
 	cond, err := a.db.ConditionById(ctx, id)
 	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return api.Condition{}, fmt.Errorf("ConditionById: %w", ErrNotFound)
+		}
 		return api.Condition{}, fmt.Errorf("ConditionById: %w", err)
 	}
 
-	appts, err := a.db.AppointmentsByConditionId(ctx, cond.Id)
+	condition, err := a.conditionDetail(ctx, cond)
 	if err != nil {
-		return api.Condition{}, fmt.Errorf("ConditionById find appts: %w", err)
+		return api.Condition{}, fmt.Errorf("ConditionById: %w", err)
 	}
+	return condition, nil
+}
 
-	patient, err := a.db.PatientById(ctx, cond.PatientId)
+// conditionDetail adds the appointments of the condition.
+func (a monolithApp) conditionDetail(
+	ctx context.Context,
+	cond data.Condition,
+) (api.Condition, error) {
+	appts, err := a.db.AppointmentsByConditionId(ctx, cond.Id)
 	if err != nil {
-		return api.Condition{}, fmt.Errorf("ConditionById find patient: %w", err)
+		return api.Condition{}, fmt.Errorf("conditionDetail find appts: %w", err)
 	}
 
-	appointments := make([]api.AppointmentDisplay, len(appts))
-	for i, appt := range appts {
-		doc, err := a.db.DoctorById(ctx, appt.DoctorId)
-		if err != nil {
-			return api.Condition{}, fmt.Errorf("ConditionById find doctor: %w", err)
-		}
-		appointments[i] = dataApptToApptDisplay(appt, patient, doc)
+	appointments, err := a.appointmentDisplays(ctx, appts)
+	if err != nil {
+		return api.Condition{}, fmt.Errorf("conditionDetail: %w", err)
 	}
 
 	return dataCondToCond(cond, appointments), nil
@@ -55,6 +70,7 @@ func (a monolithApp) UpdatePatientCondition(
 	ctx context.Context,
 	conditionId uuid.UUID,
 	updateData api.UpdateCondition,
+	ifMatch Precondition,
 ) (api.Condition, error) {
 	existingCondition, err := a.db.ConditionById(ctx, conditionId)
 	if err != nil {
@@ -63,6 +79,9 @@ func (a monolithApp) UpdatePatientCondition(
 		}
 		return api.Condition{}, fmt.Errorf("UpdatePatientCondition fetch failed: %w", err)
 	}
+	if !ifMatch.Matches(existingCondition.Version) {
+		return api.Condition{}, fmt.Errorf("UpdatePatientCondition: %w", ErrPreconditionFailed)
+	}
 
 	updated := false
 	if updateData.End != nil {
@@ -92,6 +111,21 @@ func (a monolithApp) UpdatePatientCondition(
 			updated = true
 		}
 	}
+	if updateData.DiagnosisCode != nil {
+		if updateData.DiagnosisCode.IsNull() && existingCondition.Diagnosis != nil {
+			existingCondition.Diagnosis = nil
+			updated = true
+		} else if updateData.DiagnosisCode.IsSpecified() && !updateData.DiagnosisCode.IsNull() {
+			diagnosis, err := diagnosisOf(updateData.DiagnosisCode.MustGet())
+			if err != nil {
+				return api.Condition{}, fmt.Errorf("UpdatePatientCondition: %w", err)
+			}
+			if existingCondition.Diagnosis == nil || *existingCondition.Diagnosis != *diagnosis {
+				existingCondition.Diagnosis = diagnosis
+				updated = true
+			}
+		}
+	}
 
 	var finalConditionData data.Condition
 	if updated {
@@ -107,6 +141,9 @@ func (a monolithApp) UpdatePatientCondition(
 					ErrNotFound,
 				)
 			}
+			if isVersionConflict(err) {
+				return api.Condition{}, ifMatch.versionConflict("UpdatePatientCondition")
+			}
 			return api.Condition{}, fmt.Errorf(
 				"UpdatePatientCondition update failed: %w",
 				err,
@@ -117,58 +154,33 @@ func (a monolithApp) UpdatePatientCondition(
 		finalConditionData = existingCondition
 	}
 
-	apptsData, err := a.db.AppointmentsByConditionId(
-		ctx,
-		finalConditionData.Id,
-	)
-	if err != nil {
-		return api.Condition{}, fmt.Errorf(
-			"UpdatePatientCondition fetch appointments failed: %w",
-			err,
-		)
-	}
-
-	patientData, err := a.db.PatientById(ctx, finalConditionData.PatientId)
+	condition, err := a.conditionDetail(ctx, finalConditionData)
 	if err != nil {
-		return api.Condition{}, fmt.Errorf(
-			"UpdatePatientCondition data inconsistency: patient %s not found: %w",
-			finalConditionData.PatientId,
-			err,
-		)
+		return api.Condition{}, fmt.Errorf("UpdatePatientCondition: %w", err)
 	}
-
-	apiAppointments := make([]api.AppointmentDisplay, len(apptsData))
-	for i, appt := range apptsData {
-		doctorData, err := a.db.DoctorById(ctx, appt.DoctorId)
-		if err != nil {
-			return api.Condition{}, fmt.Errorf(
-				"UpdatePatientCondition data inconsistency: doctor %s for appointment %s not found: %w",
-				appt.DoctorId,
-				appt.Id,
-				err,
-			)
-		}
-		apiAppointments[i] = dataApptToApptDisplay(
-			appt,
-			patientData,
-			doctorData,
-		)
-	}
-
-	apiResponse := dataCondToCond(finalConditionData, apiAppointments)
-	return apiResponse, nil
+	return condition, nil
 }
 
+// PatientConditionsOnDate returns the page of the patient's conditions lasting
+// on the date.
 func (a monolithApp) PatientConditionsOnDate(
 	ctx context.Context,
 	patientId uuid.UUID,
 	date time.Time,
-) ([]api.ConditionDisplay, error) {
-	dataConditions, err := a.db.FindConditionsByPatientIdAndDate(ctx, patientId, date)
+	list ListParams,
+) (api.Conditions, error) {
+	query, err := list.query(conditionSorts)
+	if err != nil {
+		return api.Conditions{}, fmt.Errorf("PatientConditionsOnDate: %w", err)
+	}
+
+	conditions, err := a.db.FindConditionsByPatientIdAndDate(ctx, patientId, date, query)
 	if err != nil {
-		return nil, fmt.Errorf("PatientConditionsOnDate failed: %w", err)
+		return api.Conditions{}, fmt.Errorf("PatientConditionsOnDate failed: %w", err)
 	}
 
-	apiConditions := Map(dataConditions, dataCondToCondDisplay)
-	return apiConditions, nil
+	return api.Conditions{
+		Conditions: Map(conditions.Items, dataCondToCondDisplay),
+		Pagination: cursorPagination(conditions),
+	}, nil
 }
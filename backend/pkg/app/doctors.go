@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,6 +13,11 @@ import (
 	"github.com/Nesquiko/wac/pkg/data"
 )
 
+const (
+	InvalidDoctorProfileCode = "doctor.invalid-profile"
+	DoctorNotAcceptingCode   = "doctor.not-accepting"
+)
+
 func (a monolithApp) CreateDoctor(
 	ctx context.Context,
 	d api.DoctorRegistration,
@@ -51,41 +57,35 @@ func (a monolithApp) DoctorByEmail(ctx context.Context, email string) (api.Docto
 	return dataDoctorToApiDoctor(doctor), nil
 }
 
+// DoctorsCalendar returns the page of the doctor's appointments matching the
+// params.
 func (a monolithApp) DoctorsCalendar(
 	ctx context.Context,
 	doctorId api.DoctorId,
-	from api.From,
-	to *api.To,
+	params api.DoctorsCalendarParams,
+	list ListParams,
 ) (api.DoctorCalendar, error) {
-	var toTime *time.Time = nil
-	if to != nil {
-		toTime = &to.Time
-	}
-
-	appts, err := a.db.AppointmentsByDoctorId(ctx, doctorId, from.Time, toTime)
+	query, err := list.query(appointmentSorts)
 	if err != nil {
 		return api.DoctorCalendar{}, fmt.Errorf("DoctorCalendar: %w", err)
 	}
 
-	doctor, err := a.db.DoctorById(ctx, doctorId)
+	filter := appointmentFilter(params.From, params.To, params.Status, params.Type)
+	filter.DoctorId = &doctorId
+	appts, err := a.db.ListAppointments(ctx, filter, query)
 	if err != nil {
-		return api.DoctorCalendar{}, fmt.Errorf("DoctorCalendar doc find: %w", err)
-	}
-
-	calendar := api.DoctorCalendar{
-		Appointments: asPtr(make([]api.AppointmentDisplay, len(appts))),
+		return api.DoctorCalendar{}, fmt.Errorf("DoctorCalendar: %w", err)
 	}
 
-	for i, appt := range appts {
-		patient, err := a.db.PatientById(ctx, appt.PatientId)
-		if err != nil {
-			return api.DoctorCalendar{}, fmt.Errorf("DoctorCalendar patient find: %w", err)
-		}
-		(*calendar.Appointments)[i] = dataApptToApptDisplay(appt, patient, doctor)
-
+	displays, err := a.appointmentDisplays(ctx, appts.Items)
+	if err != nil {
+		return api.DoctorCalendar{}, fmt.Errorf("DoctorCalendar: %w", err)
 	}
 
-	return calendar, nil
+	return api.DoctorCalendar{
+		Appointments: &displays,
+		Pagination:   cursorPagination(appts),
+	}, nil
 }
 
 func (a monolithApp) AvailableDoctors(
@@ -101,12 +101,129 @@ func (a monolithApp) AvailableDoctors(
 	return availableApiDoctors, nil
 }
 
-func (a monolithApp) GetAllDoctors(ctx context.Context) ([]api.Doctor, error) {
-	allDataDoctors, err := a.db.GetAllDoctors(ctx)
+// Doctors returns the page of the clinic's doctors matching the params.
+func (a monolithApp) Doctors(
+	ctx context.Context,
+	params api.GetDoctorsParams,
+	list ListParams,
+) (api.Doctors, error) {
+	query, err := list.query(doctorSorts)
+	if err != nil {
+		return api.Doctors{}, fmt.Errorf("Doctors: %w", err)
+	}
+
+	filter := data.DoctorFilter{Active: params.Active}
+	if params.Specialization != nil {
+		filter.Specialization = string(*params.Specialization)
+	}
+	if params.Language != nil {
+		filter.Language = *params.Language
+	}
+
+	doctors, err := a.db.Doctors(ctx, filter, query)
 	if err != nil {
-		return nil, fmt.Errorf("GetAllDoctors failed: %w", err)
+		return api.Doctors{}, fmt.Errorf("Doctors failed: %w", err)
 	}
 
-	allApiDoctors := Map(allDataDoctors, dataDoctorToApiDoctor)
-	return allApiDoctors, nil
+	return api.Doctors{
+		Doctors:    Map(doctors.Items, dataDoctorToApiDoctor),
+		Pagination: cursorPagination(doctors),
+	}, nil
+}
+
+// UpdateDoctorProfile replaces the names and the profile of the doctor.
+func (a monolithApp) UpdateDoctorProfile(
+	ctx context.Context,
+	doctorId uuid.UUID,
+	req api.DoctorProfileUpdate,
+	ifMatch Precondition,
+) (api.Doctor, error) {
+	if !isActingDoctor(ctx, doctorId) {
+		if err := a.requirePermission(ctx, permEditDoctorProfiles); err != nil {
+			return api.Doctor{}, fmt.Errorf("UpdateDoctorProfile: %w", err)
+		}
+	}
+
+	doctor, err := a.db.DoctorById(ctx, doctorId)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return api.Doctor{}, fmt.Errorf("UpdateDoctorProfile: %w", ErrNotFound)
+		}
+		return api.Doctor{}, fmt.Errorf("UpdateDoctorProfile fetch failed: %w", err)
+	}
+	if !ifMatch.Matches(doctor.Version) {
+		return api.Doctor{}, fmt.Errorf("UpdateDoctorProfile: %w", ErrPreconditionFailed)
+	}
+
+	doctor = doctorProfileToData(doctor, req)
+	if duplicate := firstDuplicate(doctor.Specializations); duplicate != "" {
+		return api.Doctor{}, fmt.Errorf(
+			"UpdateDoctorProfile: %w",
+			invalidDoctorProfile(fmt.Sprintf("Specialization %q is given twice", duplicate)),
+		)
+	}
+
+	doctor, err = a.db.UpdateDoctor(ctx, doctorId, doctor)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return api.Doctor{}, fmt.Errorf("UpdateDoctorProfile: %w", ErrNotFound)
+		}
+		if isVersionConflict(err) {
+			return api.Doctor{}, ifMatch.versionConflict("UpdateDoctorProfile")
+		}
+		return api.Doctor{}, fmt.Errorf("UpdateDoctorProfile update failed: %w", err)
+	}
+
+	return dataDoctorToApiDoctor(doctor), nil
+}
+
+// checkDoctor validates that the appointment's doctor is active and accepts
+// the appointment's type.
+func (a monolithApp) checkDoctor(ctx context.Context, appt data.Appointment) error {
+	doctor, err := a.db.DoctorById(ctx, appt.DoctorId)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("checkDoctor: %w", err)
+	}
+
+	if !doctor.Active {
+		return doctorNotAccepting("Doctor doesn't accept new appointments")
+	}
+	if !doctor.Accepts(appt.Type) {
+		return doctorNotAccepting(
+			fmt.Sprintf("Doctor doesn't accept appointments of type %q", appt.Type),
+		)
+	}
+	return nil
+}
+
+func firstDuplicate(values []string) string {
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		if seen[value] {
+			return value
+		}
+		seen[value] = true
+	}
+	return ""
+}
+
+func invalidDoctorProfile(detail string) *ValidationError {
+	return &ValidationError{ErrorDetail: api.ErrorDetail{
+		Code:   InvalidDoctorProfileCode,
+		Title:  "Invalid doctor profile",
+		Detail: detail,
+		Status: http.StatusBadRequest,
+	}}
+}
+
+func doctorNotAccepting(detail string) *ValidationError {
+	return &ValidationError{ErrorDetail: api.ErrorDetail{
+		Code:   DoctorNotAcceptingCode,
+		Title:  "Doctor doesn't accept the appointment",
+		Detail: detail,
+		Status: http.StatusBadRequest,
+	}}
 }
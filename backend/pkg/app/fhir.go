@@ -0,0 +1,213 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Nesquiko/wac/pkg/data"
+	"github.com/Nesquiko/wac/pkg/fhir"
+)
+
+func (a monolithApp) FhirPatient(ctx context.Context, id uuid.UUID) (fhir.Patient, error) {
+	patient, err := a.db.PatientById(ctx, id)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return fhir.Patient{}, fmt.Errorf("FhirPatient: %w", ErrNotFound)
+		}
+		return fhir.Patient{}, fmt.Errorf("FhirPatient: %w", err)
+	}
+	return fhir.PatientFromData(patient), nil
+}
+
+func (a monolithApp) FhirPatients(
+	ctx context.Context,
+	search fhir.PatientSearch,
+) ([]fhir.Patient, error) {
+	var patient data.Patient
+	var err error
+	switch {
+	case search.Id != nil:
+		patient, err = a.db.PatientById(ctx, *search.Id)
+	case search.Email != nil:
+		patient, err = a.db.PatientByEmail(ctx, *search.Email)
+	default:
+		return []fhir.Patient{}, nil
+	}
+
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return []fhir.Patient{}, nil
+		}
+		return nil, fmt.Errorf("FhirPatients: %w", err)
+	}
+	if search.Email != nil && patient.Email != *search.Email {
+		return []fhir.Patient{}, nil
+	}
+
+	return []fhir.Patient{fhir.PatientFromData(patient)}, nil
+}
+
+func (a monolithApp) FhirPractitioner(
+	ctx context.Context,
+	id uuid.UUID,
+) (fhir.Practitioner, error) {
+	doctor, err := a.db.DoctorById(ctx, id)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return fhir.Practitioner{}, fmt.Errorf("FhirPractitioner: %w", ErrNotFound)
+		}
+		return fhir.Practitioner{}, fmt.Errorf("FhirPractitioner: %w", err)
+	}
+	return fhir.PractitionerFromData(doctor), nil
+}
+
+func (a monolithApp) FhirPractitioners(
+	ctx context.Context,
+	search fhir.PractitionerSearch,
+) ([]fhir.Practitioner, error) {
+	doctors, err := a.db.Doctors(ctx, data.DoctorFilter{}, data.ListQuery{})
+	if err != nil {
+		return nil, fmt.Errorf("FhirPractitioners: %w", err)
+	}
+
+	practitioners := make([]fhir.Practitioner, 0, len(doctors.Items))
+	for _, doctor := range doctors.Items {
+		if search.Id != nil && doctor.Id != *search.Id {
+			continue
+		}
+		if search.Email != nil && doctor.Email != *search.Email {
+			continue
+		}
+		practitioners = append(practitioners, fhir.PractitionerFromData(doctor))
+	}
+	return practitioners, nil
+}
+
+func (a monolithApp) FhirAppointment(ctx context.Context, id uuid.UUID) (fhir.Appointment, error) {
+	appt, err := a.db.AppointmentById(ctx, id)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return fhir.Appointment{}, fmt.Errorf("FhirAppointment: %w", ErrNotFound)
+		}
+		return fhir.Appointment{}, fmt.Errorf("FhirAppointment: %w", err)
+	}
+	return fhir.AppointmentFromData(appt), nil
+}
+
+// FhirAppointments searches appointments of a patient or a practitioner,
+// at least one of them must be set.
+func (a monolithApp) FhirAppointments(
+	ctx context.Context,
+	search fhir.AppointmentSearch,
+) ([]fhir.Appointment, error) {
+	from := time.Time{}
+	if search.Date.From != nil {
+		from = *search.Date.From
+	}
+
+	var appts []data.Appointment
+	var err error
+	switch {
+	case search.Patient != nil:
+		appts, err = a.db.AppointmentsByPatientId(ctx, *search.Patient, from, search.Date.To)
+	case search.Practitioner != nil:
+		appts, err = a.db.AppointmentsByDoctorId(ctx, *search.Practitioner, from, search.Date.To)
+	default:
+		return nil, fmt.Errorf("FhirAppointments: %w", fhir.ErrInvalidSearchParam)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("FhirAppointments: %w", err)
+	}
+
+	result := make([]fhir.Appointment, 0, len(appts))
+	for _, appt := range appts {
+		if search.Practitioner != nil && appt.DoctorId != *search.Practitioner {
+			continue
+		}
+		if !search.Date.Contains(appt.AppointmentDateTime) {
+			continue
+		}
+		resource := fhir.AppointmentFromData(appt)
+		if search.Status != nil && resource.Status != *search.Status {
+			continue
+		}
+		result = append(result, resource)
+	}
+	return result, nil
+}
+
+func (a monolithApp) FhirCondition(ctx context.Context, id uuid.UUID) (fhir.Condition, error) {
+	cond, err := a.db.ConditionById(ctx, id)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return fhir.Condition{}, fmt.Errorf("FhirCondition: %w", ErrNotFound)
+		}
+		return fhir.Condition{}, fmt.Errorf("FhirCondition: %w", err)
+	}
+	return fhir.ConditionFromData(cond), nil
+}
+
+func (a monolithApp) FhirConditions(
+	ctx context.Context,
+	search fhir.ConditionSearch,
+) ([]fhir.Condition, error) {
+	conds, err := a.db.FindConditionsByPatientId(ctx, search.Patient, time.Time{}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("FhirConditions: %w", err)
+	}
+
+	result := make([]fhir.Condition, 0, len(conds))
+	for _, cond := range conds {
+		if !search.OnsetDate.Contains(cond.Start) {
+			continue
+		}
+		resource := fhir.ConditionFromData(cond)
+		if search.ClinicalStatus != nil &&
+			fhir.ClinicalStatusOf(resource) != *search.ClinicalStatus {
+			continue
+		}
+		result = append(result, resource)
+	}
+	return result, nil
+}
+
+func (a monolithApp) FhirMedicationRequest(
+	ctx context.Context,
+	id uuid.UUID,
+) (fhir.MedicationRequest, error) {
+	prescription, err := a.db.PrescriptionById(ctx, id)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return fhir.MedicationRequest{}, fmt.Errorf("FhirMedicationRequest: %w", ErrNotFound)
+		}
+		return fhir.MedicationRequest{}, fmt.Errorf("FhirMedicationRequest: %w", err)
+	}
+	return fhir.MedicationRequestFromData(prescription), nil
+}
+
+func (a monolithApp) FhirMedicationRequests(
+	ctx context.Context,
+	search fhir.MedicationRequestSearch,
+) ([]fhir.MedicationRequest, error) {
+	prescriptions, err := a.db.FindPrescriptionsByPatientId(ctx, search.Patient, time.Time{}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("FhirMedicationRequests: %w", err)
+	}
+
+	result := make([]fhir.MedicationRequest, 0, len(prescriptions))
+	for _, prescription := range prescriptions {
+		if !search.AuthoredOn.Contains(prescription.Start) {
+			continue
+		}
+		resource := fhir.MedicationRequestFromData(prescription)
+		if search.Status != nil && resource.Status != *search.Status {
+			continue
+		}
+		result = append(result, resource)
+	}
+	return result, nil
+}
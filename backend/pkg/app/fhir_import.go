@@ -0,0 +1,769 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oapi-codegen/runtime/types"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+	"github.com/Nesquiko/wac/pkg/fhir"
+	"github.com/Nesquiko/wac/pkg/terminology"
+)
+
+var (
+	ErrInvalidImport = errors.New("import bundle is invalid")
+	ErrImportFailed  = errors.New("import failed, created records were rolled back")
+)
+
+// importOrder lists imported resource types so that every resource is created
+// after the resources it references.
+var importOrder = []string{
+	fhir.ResourceTypePatient,
+	fhir.ResourceTypeCondition,
+	fhir.ResourceTypeAppointment,
+	fhir.ResourceTypeMedicationRequest,
+}
+
+// ImportFhirBundle imports patients, conditions, appointments and medication
+// requests of a Bundle. The whole bundle is validated first and nothing is
+// written unless every entry is valid. Records created before a failure are
+// deleted again. Resources are identified by their first identifier, those
+// imported before are not created again. Only administrators import.
+func (a monolithApp) ImportFhirBundle(
+	ctx context.Context,
+	entries []fhir.Entry,
+	dryRun bool,
+) (fhir.ImportReport, error) {
+	if err := a.requirePermission(ctx, permImportFhir); err != nil {
+		return fhir.ImportReport{}, fmt.Errorf("ImportFhirBundle: %w", err)
+	}
+	imp := newBundleImport(a, entries, dryRun)
+
+	if err := imp.validate(ctx); err != nil {
+		return imp.report, fmt.Errorf("ImportFhirBundle validate: %w", err)
+	}
+	if !imp.report.Valid {
+		return imp.report, fmt.Errorf("ImportFhirBundle: %w", ErrInvalidImport)
+	}
+	if dryRun {
+		return imp.report, nil
+	}
+
+	if err := imp.run(ctx); err != nil {
+		// the records are rolled back even if the request was canceled
+		imp.rollback(context.WithoutCancel(ctx))
+		return imp.report, fmt.Errorf("ImportFhirBundle: %w", err)
+	}
+
+	imp.report.Imported = true
+	return imp.report, nil
+}
+
+// importRef is a resolved reference, either to another entry of the bundle
+// or to an existing record.
+type importRef struct {
+	entry   int
+	localId uuid.UUID
+}
+
+const existingRecord = -1
+
+var importableStatuses = []api.AppointmentStatus{
+	api.Requested,
+	api.Scheduled,
+	api.Completed,
+	api.Cancelled,
+	api.Denied,
+}
+
+type entryRefs struct {
+	patient      *importRef
+	practitioner *importRef
+	condition    *importRef
+	appointment  *importRef
+}
+
+type createdRecord struct {
+	resourceType string
+	id           uuid.UUID
+}
+
+type bundleImport struct {
+	app     monolithApp
+	entries []fhir.Entry
+	report  fhir.ImportReport
+
+	byRef    map[string]int
+	refs     []entryRefs
+	localIds []uuid.UUID
+
+	created  []createdRecord
+	mappings []data.ImportedResource
+}
+
+func newBundleImport(a monolithApp, entries []fhir.Entry, dryRun bool) *bundleImport {
+	imp := &bundleImport{
+		app:     a,
+		entries: entries,
+		report: fhir.ImportReport{
+			DryRun:  dryRun,
+			Valid:   true,
+			Entries: make([]fhir.ImportEntryReport, len(entries)),
+		},
+		byRef:    make(map[string]int),
+		refs:     make([]entryRefs, len(entries)),
+		localIds: make([]uuid.UUID, len(entries)),
+	}
+
+	for i, entry := range entries {
+		imp.report.Entries[i] = fhir.ImportEntryReport{
+			Index:        i,
+			ResourceType: entry.ResourceType,
+			Outcome:      fhir.ImportOutcomeIgnored,
+		}
+		if ids := entry.Identifiers(); len(ids) > 0 {
+			imp.report.Entries[i].Identifier = &ids[0]
+		}
+		if entry.FullUrl != "" {
+			imp.byRef[entry.FullUrl] = i
+		}
+		if id := entry.Id(); id != "" {
+			imp.byRef[entry.ResourceType+"/"+id] = i
+		}
+	}
+
+	return imp
+}
+
+func (imp *bundleImport) validate(ctx context.Context) error {
+	validators := []struct {
+		resourceType string
+		validate     func(ctx context.Context, i int) error
+	}{
+		{fhir.ResourceTypePatient, imp.validatePatient},
+		{fhir.ResourceTypePractitioner, imp.validatePractitioner},
+		{fhir.ResourceTypeCondition, imp.validateCondition},
+		{fhir.ResourceTypeAppointment, imp.validateAppointment},
+		{fhir.ResourceTypeMedicationRequest, imp.validateMedicationRequest},
+	}
+
+	for _, v := range validators {
+		for i, entry := range imp.entries {
+			if entry.ResourceType != v.resourceType {
+				continue
+			}
+			if err := v.validate(ctx, i); err != nil {
+				return fmt.Errorf("%s entry %d: %w", entry.ResourceType, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (imp *bundleImport) validatePatient(ctx context.Context, i int) error {
+	patient := imp.entries[i].Resource.(fhir.Patient)
+
+	found, err := imp.previouslyImported(ctx, i)
+	if err != nil || found {
+		return err
+	}
+
+	converted, err := fhir.PatientToData(fhir.Patient{Name: patient.Name, Telecom: patient.Telecom})
+	if err != nil {
+		imp.invalid(i, err.Error())
+		return nil
+	}
+	if converted.Email == "" {
+		imp.invalid(i, "telecom with an email is required")
+	}
+	if converted.FirstName == "" || converted.LastName == "" {
+		imp.invalid(i, "name with a given and a family name is required")
+	}
+	if imp.isInvalid(i) {
+		return nil
+	}
+
+	existing, err := imp.app.db.PatientByEmail(ctx, converted.Email)
+	if errors.Is(err, data.ErrNotFound) {
+		imp.outcome(i, fhir.ImportOutcomeCreated)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("find patient by email: %w", err)
+	}
+
+	imp.matched(i, existing.Id)
+	return nil
+}
+
+// validatePractitioner matches the practitioner to an existing doctor, by
+// email or by id. Doctors aren't created by imports.
+func (imp *bundleImport) validatePractitioner(ctx context.Context, i int) error {
+	practitioner := imp.entries[i].Resource.(fhir.Practitioner)
+	converted, _ := fhir.PractitionerToData(fhir.Practitioner{Telecom: practitioner.Telecom})
+
+	if converted.Email != "" {
+		doctor, err := imp.app.db.DoctorByEmail(ctx, converted.Email)
+		if err == nil {
+			imp.matched(i, doctor.Id)
+			return nil
+		} else if !errors.Is(err, data.ErrNotFound) {
+			return fmt.Errorf("find doctor by email: %w", err)
+		}
+	}
+
+	if id, err := uuid.Parse(practitioner.Id); err == nil {
+		doctor, err := imp.app.db.DoctorById(ctx, id)
+		if err == nil {
+			imp.matched(i, doctor.Id)
+			return nil
+		} else if !errors.Is(err, data.ErrNotFound) {
+			return fmt.Errorf("find doctor by id: %w", err)
+		}
+	}
+
+	imp.invalid(i, "practitioner doesn't match any doctor of this clinic")
+	return nil
+}
+
+func (imp *bundleImport) validateCondition(ctx context.Context, i int) error {
+	condition := imp.entries[i].Resource.(fhir.Condition)
+
+	found, err := imp.previouslyImported(ctx, i)
+	if err != nil || found {
+		return err
+	}
+
+	if conceptText(condition.Code) == "" {
+		imp.invalid(i, "code with a text or a display is required")
+	}
+	if code, ok := fhir.ICD10CodeOf(condition); ok {
+		if _, found := terminology.ICD10().Lookup(code); !found {
+			imp.invalid(i, fmt.Sprintf("code %q isn't an ICD-10 code", code))
+		}
+	}
+	if condition.OnsetDateTime == nil {
+		imp.invalid(i, "onsetDateTime is required")
+	} else if condition.AbatementDateTime != nil &&
+		condition.AbatementDateTime.Before(*condition.OnsetDateTime) {
+		imp.invalid(i, "abatementDateTime is before onsetDateTime")
+	}
+
+	imp.refs[i].patient, err = imp.resolve(ctx, i, condition.Subject.Reference, fhir.ResourceTypePatient)
+	if err != nil {
+		return err
+	}
+
+	if !imp.isInvalid(i) {
+		imp.outcome(i, fhir.ImportOutcomeCreated)
+	}
+	return nil
+}
+
+func (imp *bundleImport) validateAppointment(ctx context.Context, i int) error {
+	appt := imp.entries[i].Resource.(fhir.Appointment)
+
+	found, err := imp.previouslyImported(ctx, i)
+	if err != nil || found {
+		return err
+	}
+
+	if appt.Start == nil {
+		imp.invalid(i, "start is required")
+	}
+	status, err := fhir.AppointmentStatusToData(appt)
+	if err != nil {
+		imp.invalid(i, err.Error())
+	} else if !slices.Contains(importableStatuses, api.AppointmentStatus(status)) {
+		imp.invalid(i, fmt.Sprintf("unsupported appointment status %q", status))
+	}
+	if appt.AppointmentType != nil {
+		for _, coding := range appt.AppointmentType.Coding {
+			if coding.System == fhir.AppointmentTypeSystem &&
+				coding.Code != string(api.RegularCheck) {
+				imp.invalid(i, fmt.Sprintf("unsupported appointment type %q", coding.Code))
+			}
+		}
+	}
+
+	refs := &imp.refs[i]
+	for _, participant := range appt.Participant {
+		ref := participant.Actor.Reference
+		typ, err := imp.referenceType(ref)
+		if err != nil {
+			imp.invalid(i, err.Error())
+			continue
+		}
+
+		switch typ {
+		case fhir.ResourceTypePatient:
+			refs.patient, err = imp.resolve(ctx, i, ref, fhir.ResourceTypePatient)
+		case fhir.ResourceTypePractitioner:
+			refs.practitioner, err = imp.resolve(ctx, i, ref, fhir.ResourceTypePractitioner)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if !imp.isInvalid(i) && (refs.patient == nil || refs.practitioner == nil) {
+		imp.invalid(i, "participants must include a patient and a practitioner")
+	}
+
+	if len(appt.ReasonReference) > 0 {
+		refs.condition, err = imp.resolve(
+			ctx,
+			i,
+			appt.ReasonReference[0].Reference,
+			fhir.ResourceTypeCondition,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !imp.isInvalid(i) {
+		imp.outcome(i, fhir.ImportOutcomeCreated)
+	}
+	return nil
+}
+
+func (imp *bundleImport) validateMedicationRequest(ctx context.Context, i int) error {
+	req := imp.entries[i].Resource.(fhir.MedicationRequest)
+
+	found, err := imp.previouslyImported(ctx, i)
+	if err != nil || found {
+		return err
+	}
+
+	if conceptText(req.MedicationCodeableConcept) == "" {
+		imp.invalid(i, "medicationCodeableConcept with a text or a display is required")
+	}
+	start, end := medicationPeriod(req)
+	switch {
+	case start == nil || end == nil:
+		imp.invalid(i, "dispenseRequest.validityPeriod with a start and an end is required")
+	case end.Before(*start):
+		imp.invalid(i, "validity period ends before it starts")
+	}
+
+	imp.refs[i].patient, err = imp.resolve(ctx, i, req.Subject.Reference, fhir.ResourceTypePatient)
+	if err != nil {
+		return err
+	}
+	for _, info := range req.SupportingInformation {
+		if typ, _ := imp.referenceType(info.Reference); typ != fhir.ResourceTypeAppointment {
+			continue
+		}
+		imp.refs[i].appointment, err = imp.resolve(
+			ctx,
+			i,
+			info.Reference,
+			fhir.ResourceTypeAppointment,
+		)
+		if err != nil {
+			return err
+		}
+		break
+	}
+
+	if !imp.isInvalid(i) {
+		imp.outcome(i, fhir.ImportOutcomeCreated)
+	}
+	return nil
+}
+
+// previouslyImported checks whether the entry's source identifier was already
+// imported, entries without an identifier are invalid.
+func (imp *bundleImport) previouslyImported(ctx context.Context, i int) (bool, error) {
+	identifier := imp.report.Entries[i].Identifier
+	if identifier == nil || identifier.System == "" || identifier.Value == "" {
+		imp.invalid(i, "identifier with a system and a value is required")
+		return false, nil
+	}
+
+	imported, err := imp.app.db.ImportedResourceBySource(
+		ctx,
+		imp.entries[i].ResourceType,
+		identifier.System,
+		identifier.Value,
+	)
+	if errors.Is(err, data.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("find imported resource: %w", err)
+	}
+
+	imp.localIds[i] = imported.LocalId
+	imp.report.Entries[i].LocalId = asPtr(imported.LocalId)
+	imp.outcome(i, fhir.ImportOutcomeExisting)
+	return true, nil
+}
+
+// resolve resolves the reference to an entry of the bundle or an existing
+// record. Unresolvable references make entry i invalid and return nil.
+func (imp *bundleImport) resolve(
+	ctx context.Context,
+	i int,
+	ref string,
+	resourceType string,
+) (*importRef, error) {
+	if target, ok := imp.byRef[ref]; ok {
+		if imp.entries[target].ResourceType != resourceType {
+			imp.invalid(i, fmt.Sprintf("reference %q isn't a %s", ref, resourceType))
+			return nil, nil
+		}
+		if imp.isInvalid(target) {
+			imp.invalid(i, fmt.Sprintf("references invalid entry %d", target))
+			return nil, nil
+		}
+		return &importRef{entry: target, localId: imp.localIds[target]}, nil
+	}
+
+	typ, id, err := fhir.ParseReference(ref)
+	if err != nil || typ != resourceType {
+		imp.invalid(i, fmt.Sprintf("unresolvable %s reference %q", resourceType, ref))
+		return nil, nil
+	}
+
+	switch resourceType {
+	case fhir.ResourceTypePatient:
+		_, err = imp.app.db.PatientById(ctx, id)
+	case fhir.ResourceTypePractitioner:
+		_, err = imp.app.db.DoctorById(ctx, id)
+	case fhir.ResourceTypeCondition:
+		_, err = imp.app.db.ConditionById(ctx, id)
+	case fhir.ResourceTypeAppointment:
+		_, err = imp.app.db.AppointmentById(ctx, id)
+	}
+	if errors.Is(err, data.ErrNotFound) {
+		imp.invalid(i, fmt.Sprintf("referenced %s %q doesn't exist", resourceType, ref))
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("resolve %q: %w", ref, err)
+	}
+
+	return &importRef{entry: existingRecord, localId: id}, nil
+}
+
+func (imp *bundleImport) run(ctx context.Context) error {
+	for _, resourceType := range importOrder {
+		for i, entry := range imp.entries {
+			if entry.ResourceType != resourceType {
+				continue
+			}
+
+			var err error
+			switch imp.report.Entries[i].Outcome {
+			case fhir.ImportOutcomeCreated:
+				err = imp.create(ctx, i)
+			case fhir.ImportOutcomeMatched:
+				imp.addMapping(i)
+			}
+			if err != nil {
+				imp.report.Entries[i].Outcome = fhir.ImportOutcomeFailed
+				imp.report.Entries[i].Issues = append(
+					imp.report.Entries[i].Issues,
+					importFailureIssue(err),
+				)
+				return importFailure(fmt.Errorf("%s entry %d: %w", resourceType, i, err))
+			}
+		}
+	}
+
+	if err := imp.app.db.CreateImportedResources(ctx, imp.mappings); err != nil {
+		return importFailure(fmt.Errorf("store source identifiers: %w", err))
+	}
+	return nil
+}
+
+func (imp *bundleImport) create(ctx context.Context, i int) error {
+	var id uuid.UUID
+	var err error
+	switch res := imp.entries[i].Resource.(type) {
+	case fhir.Patient:
+		id, err = imp.createPatient(ctx, res)
+	case fhir.Condition:
+		id, err = imp.createCondition(ctx, i, res)
+	case fhir.Appointment:
+		id, err = imp.createAppointment(ctx, i, res)
+	case fhir.MedicationRequest:
+		id, err = imp.createPrescription(ctx, i, res)
+	}
+	if err != nil {
+		return err
+	}
+
+	imp.localIds[i] = id
+	imp.report.Entries[i].LocalId = asPtr(id)
+	imp.addMapping(i)
+	return nil
+}
+
+func (imp *bundleImport) createPatient(ctx context.Context, p fhir.Patient) (uuid.UUID, error) {
+	converted, _ := fhir.PatientToData(fhir.Patient{Name: p.Name, Telecom: p.Telecom})
+	patient, err := imp.app.CreatePatient(ctx, api.PatientRegistration{
+		Email:     types.Email(converted.Email),
+		FirstName: converted.FirstName,
+		LastName:  converted.LastName,
+		Role:      api.UserRolePatient,
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	imp.created = append(imp.created, createdRecord{fhir.ResourceTypePatient, patient.Id})
+	return patient.Id, nil
+}
+
+func (imp *bundleImport) createCondition(
+	ctx context.Context,
+	i int,
+	c fhir.Condition,
+) (uuid.UUID, error) {
+	newCond := api.NewCondition{
+		Name:      conceptText(c.Code),
+		PatientId: imp.localId(imp.refs[i].patient),
+		Start:     *c.OnsetDateTime,
+		End:       c.AbatementDateTime,
+	}
+	if code, ok := fhir.ICD10CodeOf(c); ok {
+		newCond.DiagnosisCode = &code
+	}
+
+	cond, err := imp.app.CreatePatientCondition(ctx, newCond)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	imp.created = append(imp.created, createdRecord{fhir.ResourceTypeCondition, *cond.Id})
+	return *cond.Id, nil
+}
+
+func (imp *bundleImport) createAppointment(
+	ctx context.Context,
+	i int,
+	a fhir.Appointment,
+) (uuid.UUID, error) {
+	refs := imp.refs[i]
+	req := api.NewAppointmentRequest{
+		PatientId:           imp.localId(refs.patient),
+		DoctorId:            imp.localId(refs.practitioner),
+		AppointmentDateTime: *a.Start,
+		Type:                asPtr(api.RegularCheck),
+	}
+	if a.Description != "" {
+		req.Reason = asPtr(a.Description)
+	}
+	if refs.condition != nil {
+		req.ConditionId = asPtr(imp.localId(refs.condition))
+	}
+
+	appt, err := imp.app.CreateAppointment(ctx, req)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	id := *appt.Id
+	imp.created = append(imp.created, createdRecord{fhir.ResourceTypeAppointment, id})
+
+	status, _ := fhir.AppointmentStatusToData(a)
+	by, reason := fhir.CancellationOf(a)
+	switch api.AppointmentStatus(status) {
+	case api.Scheduled:
+		_, err = imp.app.DecideAppointment(ctx, id, api.AppointmentDecision{Action: api.Accept})
+	case api.Completed:
+		_, err = imp.app.DecideAppointment(ctx, id, api.AppointmentDecision{Action: api.Accept})
+		if err == nil {
+			_, err = imp.app.db.CompleteAppointment(ctx, id)
+		}
+	case api.Denied:
+		_, err = imp.app.DecideAppointment(
+			ctx,
+			id,
+			api.AppointmentDecision{Action: api.Reject, Reason: reason},
+		)
+	case api.Cancelled:
+		// the other clinic may not say who cancelled, patients usually do
+		cancelledBy := api.UserRolePatient
+		if by != nil {
+			cancelledBy = api.UserRole(*by)
+		}
+		err = imp.app.CancelAppointment(
+			ctx,
+			id,
+			api.AppointmentCancellation{By: cancelledBy, Reason: reason},
+		)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("set appointment status %q: %w", status, err)
+	}
+
+	return id, nil
+}
+
+func (imp *bundleImport) createPrescription(
+	ctx context.Context,
+	i int,
+	m fhir.MedicationRequest,
+) (uuid.UUID, error) {
+	start, end := medicationPeriod(m)
+	req := api.NewPrescription{
+		Name:      conceptText(m.MedicationCodeableConcept),
+		PatientId: imp.localId(imp.refs[i].patient),
+		Start:     *start,
+		End:       *end,
+	}
+	if imp.refs[i].appointment != nil {
+		req.AppointmentId = asPtr(imp.localId(imp.refs[i].appointment))
+	}
+	if len(m.Note) > 0 {
+		req.DoctorsNote = asPtr(m.Note[0].Text)
+	}
+
+	prescription, err := imp.app.CreatePatientPrescription(ctx, req)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	imp.created = append(
+		imp.created,
+		createdRecord{fhir.ResourceTypeMedicationRequest, *prescription.Id},
+	)
+	return *prescription.Id, nil
+}
+
+// rollback deletes records created by the import, newest first.
+func (imp *bundleImport) rollback(ctx context.Context) {
+	for _, record := range slices.Backward(imp.created) {
+		var err error
+		switch record.resourceType {
+		case fhir.ResourceTypePatient:
+			err = imp.app.db.DeletePatient(ctx, record.id)
+		case fhir.ResourceTypeCondition:
+			err = imp.app.db.DeleteCondition(ctx, record.id)
+		case fhir.ResourceTypeAppointment:
+			err = imp.app.db.DeleteAppointment(ctx, record.id)
+		case fhir.ResourceTypeMedicationRequest:
+			err = imp.app.db.DeletePrescription(ctx, record.id, nil)
+		}
+		if err != nil {
+			slog.Error(
+				"failed to roll back imported record",
+				"error", err.Error(),
+				"resourceType", record.resourceType,
+				"id", record.id.String(),
+			)
+		}
+	}
+
+	for i := range imp.report.Entries {
+		if imp.report.Entries[i].Outcome == fhir.ImportOutcomeCreated {
+			imp.report.Entries[i].LocalId = nil
+		}
+	}
+}
+
+func (imp *bundleImport) addMapping(i int) {
+	identifier := imp.report.Entries[i].Identifier
+	imp.mappings = append(imp.mappings, data.ImportedResource{
+		ResourceType: imp.entries[i].ResourceType,
+		System:       identifier.System,
+		Value:        identifier.Value,
+		LocalId:      imp.localIds[i],
+		ImportedAt:   time.Now(),
+	})
+}
+
+func (imp *bundleImport) localId(ref *importRef) uuid.UUID {
+	if ref.entry == existingRecord {
+		return ref.localId
+	}
+	return imp.localIds[ref.entry]
+}
+
+func (imp *bundleImport) matched(i int, localId uuid.UUID) {
+	imp.localIds[i] = localId
+	imp.report.Entries[i].LocalId = asPtr(localId)
+	imp.outcome(i, fhir.ImportOutcomeMatched)
+}
+
+func (imp *bundleImport) outcome(i int, outcome string) {
+	imp.report.Entries[i].Outcome = outcome
+}
+
+func (imp *bundleImport) invalid(i int, issue string) {
+	imp.report.Valid = false
+	imp.report.Entries[i].Outcome = fhir.ImportOutcomeInvalid
+	imp.report.Entries[i].Issues = append(imp.report.Entries[i].Issues, issue)
+}
+
+func (imp *bundleImport) isInvalid(i int) bool {
+	return imp.report.Entries[i].Outcome == fhir.ImportOutcomeInvalid
+}
+
+// referenceType returns the resource type a reference points to, either an
+// entry of the bundle or a literal "<type>/<id>" reference.
+func (imp *bundleImport) referenceType(ref string) (string, error) {
+	if target, ok := imp.byRef[ref]; ok {
+		return imp.entries[target].ResourceType, nil
+	}
+	typ, _, err := fhir.ParseReference(ref)
+	return typ, err
+}
+
+func conceptText(concept fhir.CodeableConcept) string {
+	if concept.Text != "" {
+		return concept.Text
+	}
+	for _, coding := range concept.Coding {
+		if coding.Display != "" {
+			return coding.Display
+		}
+	}
+	return ""
+}
+
+func medicationPeriod(m fhir.MedicationRequest) (*time.Time, *time.Time) {
+	start := m.AuthoredOn
+	var end *time.Time
+	if m.DispenseRequest != nil && m.DispenseRequest.ValidityPeriod != nil {
+		if m.DispenseRequest.ValidityPeriod.Start != nil {
+			start = m.DispenseRequest.ValidityPeriod.Start
+		}
+		end = m.DispenseRequest.ValidityPeriod.End
+	}
+	return start, end
+}
+
+// importFailure marks failures caused by the imported data, not by the
+// application, as ErrImportFailed.
+func importFailure(err error) error {
+	if errors.Is(err, data.ErrDoctorUnavailable) ||
+		errors.Is(err, data.ErrResourceUnavailable) ||
+		errors.Is(err, data.ErrAlreadyImported) ||
+		errors.Is(err, ErrDuplicateEmail) ||
+		errors.Is(err, ErrTooManyRequested) {
+		return fmt.Errorf("%w: %w", ErrImportFailed, err)
+	}
+	return err
+}
+
+func importFailureIssue(err error) string {
+	switch {
+	case errors.Is(err, data.ErrDoctorUnavailable):
+		return data.ErrDoctorUnavailable.Error()
+	case errors.Is(err, data.ErrResourceUnavailable):
+		return data.ErrResourceUnavailable.Error()
+	case errors.Is(err, ErrDuplicateEmail):
+		return ErrDuplicateEmail.Error()
+	case errors.Is(err, ErrTooManyRequested):
+		return ErrTooManyRequested.Error()
+	default:
+		return "unexpected error"
+	}
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+func (a monolithApp) ConditionHistory(
+	ctx context.Context,
+	conditionId uuid.UUID,
+) ([]api.ConditionRevision, error) {
+	revisions, err := a.db.ConditionRevisions(ctx, conditionId)
+	if err != nil {
+		return nil, fmt.Errorf("ConditionHistory: %w", err)
+	}
+	if len(revisions) == 0 {
+		// Created before revisions were kept and not changed since.
+		if _, err := a.db.ConditionById(ctx, conditionId); err != nil {
+			if errors.Is(err, data.ErrNotFound) {
+				return nil, fmt.Errorf("ConditionHistory: %w", ErrNotFound)
+			}
+			return nil, fmt.Errorf("ConditionHistory find condition: %w", err)
+		}
+	}
+
+	return Map(revisions, dataCondRevisionToApi), nil
+}
+
+// ConditionAsOf returns the condition as it was at the given time, with its
+// current appointments.
+func (a monolithApp) ConditionAsOf(
+	ctx context.Context,
+	conditionId uuid.UUID,
+	at time.Time,
+) (api.Condition, error) {
+	revisions, err := a.db.ConditionRevisions(ctx, conditionId)
+	if err != nil {
+		return api.Condition{}, fmt.Errorf("ConditionAsOf: %w", err)
+	}
+	if len(revisions) == 0 {
+		return a.ConditionById(ctx, conditionId)
+	}
+
+	revision, ok := revisionAt(revisions, at)
+	if !ok {
+		return api.Condition{}, fmt.Errorf("ConditionAsOf: %w", ErrNotFound)
+	}
+
+	condition, err := a.conditionDetail(ctx, revision.Document)
+	if err != nil {
+		return api.Condition{}, fmt.Errorf("ConditionAsOf: %w", err)
+	}
+	return condition, nil
+}
+
+func (a monolithApp) PrescriptionHistory(
+	ctx context.Context,
+	prescriptionId uuid.UUID,
+) ([]api.PrescriptionRevision, error) {
+	revisions, err := a.db.PrescriptionRevisions(ctx, prescriptionId)
+	if err != nil {
+		return nil, fmt.Errorf("PrescriptionHistory: %w", err)
+	}
+	if len(revisions) == 0 {
+		// Created before revisions were kept and not changed since.
+		if _, err := a.db.PrescriptionById(ctx, prescriptionId); err != nil {
+			if errors.Is(err, data.ErrNotFound) {
+				return nil, fmt.Errorf("PrescriptionHistory: %w", ErrNotFound)
+			}
+			return nil, fmt.Errorf("PrescriptionHistory find prescription: %w", err)
+		}
+	}
+
+	return Map(revisions, dataPrescRevisionToApi), nil
+}
+
+// PrescriptionAsOf returns the prescription as it was at the given time, also
+// if it was deleted since.
+func (a monolithApp) PrescriptionAsOf(
+	ctx context.Context,
+	prescriptionId uuid.UUID,
+	at time.Time,
+) (api.Prescription, error) {
+	revisions, err := a.db.PrescriptionRevisions(ctx, prescriptionId)
+	if err != nil {
+		return api.Prescription{}, fmt.Errorf("PrescriptionAsOf: %w", err)
+	}
+	if len(revisions) == 0 {
+		return a.PrescriptionById(ctx, prescriptionId)
+	}
+
+	revision, ok := revisionAt(revisions, at)
+	if !ok {
+		return api.Prescription{}, fmt.Errorf("PrescriptionAsOf: %w", ErrNotFound)
+	}
+
+	prescription, err := a.prescriptionDetail(ctx, revision.Document)
+	if err != nil {
+		return api.Prescription{}, fmt.Errorf("PrescriptionAsOf: %w", err)
+	}
+	return prescription, nil
+}
+
+// revisionAt returns the revision in effect at the given time, there is none
+// before the document was created or after it was deleted. Revisions must be
+// ordered from the oldest.
+func revisionAt[T any](revisions []data.Revision[T], at time.Time) (data.Revision[T], bool) {
+	var revision data.Revision[T]
+	found := false
+	for _, r := range revisions {
+		if r.ChangedAt.After(at) {
+			break
+		}
+		revision, found = r, true
+	}
+
+	if !found || revision.Operation == data.RevisionDelete {
+		return data.Revision[T]{}, false
+	}
+	return revision, true
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+const (
+	InvalidListQueryCode = "list.invalid-query"
+
+	defaultPageLimit = 50
+)
+
+// ListParams are the params common to list endpoints.
+type ListParams struct {
+	// Sort is the name of the sort, descending if prefixed with "-". The
+	// list's default sort if empty.
+	Sort string
+	// Cursor of the page, the first page if empty.
+	Cursor string
+	// Limit of the items on the page, defaultPageLimit if 0.
+	Limit int
+}
+
+// listSorts are the sorts of a list, the fields it's sorted by under the name
+// of the sort, and the sort used if params don't name one.
+type listSorts struct {
+	fields      map[string][]string
+	defaultSort string
+}
+
+var (
+	doctorSorts = listSorts{
+		fields: map[string][]string{
+			string(api.GetDoctorsParamsSortLastName):  {"lastName", "firstName"},
+			string(api.GetDoctorsParamsSortFirstName): {"firstName", "lastName"},
+		},
+		defaultSort: string(api.GetDoctorsParamsSortLastName),
+	}
+	appointmentSorts = listSorts{
+		fields: map[string][]string{
+			string(api.DoctorsCalendarParamsSortAppointmentDateTime): {"appointmentDateTime"},
+		},
+		defaultSort: string(api.DoctorsCalendarParamsSortAppointmentDateTime),
+	}
+	conditionSorts = listSorts{
+		fields: map[string][]string{
+			string(api.ConditionsInDateParamsSortStart): {"start"},
+			string(api.ConditionsInDateParamsSortName):  {"name"},
+		},
+		defaultSort: string(api.ConditionsInDateParamsSortStart),
+	}
+)
+
+// query returns the data query of the params, the sort must be one of the
+// list's sorts.
+func (p ListParams) query(sorts listSorts) (data.ListQuery, error) {
+	q := data.ListQuery{Limit: p.Limit}
+	if q.Limit == 0 {
+		q.Limit = defaultPageLimit
+	}
+
+	sort := p.Sort
+	if sort == "" {
+		sort = sorts.defaultSort
+	}
+	name, descending := strings.CutPrefix(sort, "-")
+	fields, ok := sorts.fields[name]
+	if !ok {
+		return data.ListQuery{}, invalidListQuery("Unknown sort " + sort)
+	}
+	q.SortBy, q.Descending = fields, descending
+
+	if p.Cursor != "" {
+		cursor, err := data.ParseCursor(p.Cursor)
+		if err != nil || len(cursor) != len(fields)+1 {
+			return data.ListQuery{}, invalidListQuery("Cursor doesn't belong to the list")
+		}
+		q.After = cursor
+	}
+	return q, nil
+}
+
+func cursorPagination[T any](page data.Page[T]) api.CursorPagination {
+	pagination := api.CursorPagination{Total: int(page.Total)}
+	if page.Next != nil {
+		pagination.NextCursor = asPtr(page.Next.String())
+	}
+	return pagination
+}
+
+func invalidListQuery(detail string) *ValidationError {
+	return &ValidationError{ErrorDetail: api.ErrorDetail{
+		Code:   InvalidListQueryCode,
+		Title:  "Invalid list query",
+		Detail: detail,
+		Status: http.StatusBadRequest,
+	}}
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+func TestListParamsQuery(t *testing.T) {
+	q, err := ListParams{}.query(doctorSorts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"lastName", "firstName"}, q.SortBy)
+	assert.False(t, q.Descending)
+	assert.Equal(t, defaultPageLimit, q.Limit)
+	assert.Nil(t, q.After)
+
+	q, err = ListParams{Sort: "-appointmentDateTime", Limit: 10}.query(appointmentSorts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"appointmentDateTime"}, q.SortBy)
+	assert.True(t, q.Descending)
+	assert.Equal(t, 10, q.Limit)
+}
+
+func TestListParamsQuery_Cursor(t *testing.T) {
+	startType, start, err := bson.MarshalValue(time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	idType, id, err := bson.MarshalValue(uuid.NewString())
+	require.NoError(t, err)
+	cursor := data.Cursor{{Type: startType, Value: start}, {Type: idType, Value: id}}
+
+	q, err := ListParams{Cursor: cursor.String()}.query(conditionSorts)
+	require.NoError(t, err)
+	assert.Equal(t, cursor, q.After)
+
+	_, err = ListParams{Cursor: cursor.String()}.query(doctorSorts)
+	var valErr *ValidationError
+	require.True(t, errors.As(err, &valErr), "Cursor of a list sorted by other fields")
+	assert.Equal(t, InvalidListQueryCode, valErr.Code)
+
+	_, err = ListParams{Cursor: "not a cursor"}.query(conditionSorts)
+	assert.True(t, errors.As(err, &valErr))
+
+	_, err = ListParams{Sort: "email"}.query(doctorSorts)
+	assert.True(t, errors.As(err, &valErr))
+}
@@ -0,0 +1,362 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+const (
+	InvalidLocationCode = "location.invalid"
+	LocationClosedCode  = "location.closed"
+
+	openingHoursLayout = "15:04"
+)
+
+// parentKinds is the kind of location each kind of location is within.
+var parentKinds = map[data.LocationKind]data.LocationKind{
+	data.LocationBuilding: data.LocationSite,
+	data.LocationRoom:     data.LocationBuilding,
+}
+
+var apiWeekdays = map[api.Weekday]time.Weekday{
+	api.Monday:    time.Monday,
+	api.Tuesday:   time.Tuesday,
+	api.Wednesday: time.Wednesday,
+	api.Thursday:  time.Thursday,
+	api.Friday:    time.Friday,
+	api.Saturday:  time.Saturday,
+	api.Sunday:    time.Sunday,
+}
+
+func (a monolithApp) CreateLocation(
+	ctx context.Context,
+	req api.NewLocation,
+) (api.Location, error) {
+	if err := a.requirePermission(ctx, permManageResources); err != nil {
+		return api.Location{}, fmt.Errorf("CreateLocation: %w", err)
+	}
+
+	location := newLocationToData(req)
+	if err := validateOpeningHours(location.OpeningHours); err != nil {
+		return api.Location{}, fmt.Errorf("CreateLocation: %w", err)
+	}
+
+	parentKind, hasParent := parentKinds[location.Kind]
+	switch {
+	case !hasParent && location.ParentId != nil:
+		return api.Location{}, fmt.Errorf(
+			"CreateLocation: %w",
+			invalidLocation("Site can't be within another location"),
+		)
+	case hasParent && location.ParentId == nil:
+		return api.Location{}, fmt.Errorf(
+			"CreateLocation: %w",
+			invalidLocation(fmt.Sprintf("The %s must be within a %s", location.Kind, parentKind)),
+		)
+	case hasParent:
+		parent, err := a.db.LocationById(ctx, *location.ParentId)
+		if err != nil {
+			if errors.Is(err, data.ErrNotFound) {
+				return api.Location{}, fmt.Errorf(
+					"CreateLocation: %w",
+					invalidLocation("Parent location doesn't exist"),
+				)
+			}
+			return api.Location{}, fmt.Errorf("CreateLocation find parent: %w", err)
+		}
+		if parent.Kind != parentKind {
+			return api.Location{}, fmt.Errorf(
+				"CreateLocation: %w",
+				invalidLocation(fmt.Sprintf("The %s must be within a %s", location.Kind, parentKind)),
+			)
+		}
+	}
+
+	location, err := a.db.CreateLocation(ctx, location)
+	if err != nil {
+		return api.Location{}, fmt.Errorf("CreateLocation: %w", err)
+	}
+
+	return a.LocationById(ctx, location.Id)
+}
+
+func (a monolithApp) LocationById(ctx context.Context, id uuid.UUID) (api.Location, error) {
+	locations, err := a.locationTree(ctx)
+	if err != nil {
+		return api.Location{}, fmt.Errorf("LocationById: %w", err)
+	}
+
+	location := locations.display(&id)
+	if location == nil {
+		return api.Location{}, fmt.Errorf("LocationById: %w", ErrNotFound)
+	}
+	return *location, nil
+}
+
+func (a monolithApp) Locations(ctx context.Context) ([]api.Location, error) {
+	locations, err := a.db.Locations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Locations: %w", err)
+	}
+
+	tree := newLocationTree(locations)
+	result := make([]api.Location, len(locations))
+	for i, location := range locations {
+		result[i] = *tree.display(&location.Id)
+	}
+	return result, nil
+}
+
+// AssignResourceLocation moves the resource to the location, or unassigns it
+// if the request has no location.
+func (a monolithApp) AssignResourceLocation(
+	ctx context.Context,
+	resourceId uuid.UUID,
+	req api.ResourceLocation,
+) (api.NewResource, error) {
+	if err := a.requirePermission(ctx, permManageResources); err != nil {
+		return api.NewResource{}, fmt.Errorf("AssignResourceLocation: %w", err)
+	}
+
+	if req.LocationId != nil {
+		if _, err := a.db.LocationById(ctx, *req.LocationId); err != nil {
+			if errors.Is(err, data.ErrNotFound) {
+				return api.NewResource{}, fmt.Errorf(
+					"AssignResourceLocation: %w",
+					invalidLocation("Location doesn't exist"),
+				)
+			}
+			return api.NewResource{}, fmt.Errorf("AssignResourceLocation find location: %w", err)
+		}
+	}
+
+	resource, err := a.db.SetResourceLocation(ctx, resourceId, req.LocationId)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return api.NewResource{}, fmt.Errorf("AssignResourceLocation: %w", ErrNotFound)
+		}
+		return api.NewResource{}, fmt.Errorf("AssignResourceLocation: %w", err)
+	}
+
+	return dataResourceToApi(resource), nil
+}
+
+// checkLocation validates that the appointment's location exists and is open
+// for the whole appointment.
+func (a monolithApp) checkLocation(ctx context.Context, appt data.Appointment) error {
+	if appt.LocationId == nil {
+		return nil
+	}
+
+	locations, err := a.locationTree(ctx)
+	if err != nil {
+		return fmt.Errorf("checkLocation: %w", err)
+	}
+	if _, ok := locations[*appt.LocationId]; !ok {
+		return invalidLocation("Location doesn't exist")
+	}
+	if !locations.isOpen(*appt.LocationId, appt.AppointmentDateTime, appt.EndTime) {
+		return &ValidationError{ErrorDetail: api.ErrorDetail{
+			Code:   LocationClosedCode,
+			Title:  "Location is closed",
+			Detail: "Location isn't open for the whole appointment",
+			Status: http.StatusBadRequest,
+		}}
+	}
+	return nil
+}
+
+// checkFacilities validates that the facilities are at the appointment's
+// location, any facilities are allowed if it has none.
+func (a monolithApp) checkFacilities(
+	ctx context.Context,
+	appt data.Appointment,
+	facilityIds []uuid.UUID,
+) error {
+	if appt.LocationId == nil || len(facilityIds) == 0 {
+		return nil
+	}
+
+	locations, err := a.locationTree(ctx)
+	if err != nil {
+		return fmt.Errorf("checkFacilities: %w", err)
+	}
+	within := locations.within(*appt.LocationId)
+
+	for _, facilityId := range facilityIds {
+		facility, err := a.db.ResourceById(ctx, facilityId)
+		if err != nil {
+			if errors.Is(err, data.ErrNotFound) {
+				return fmt.Errorf("checkFacilities: facility %s %w", facilityId, ErrNotFound)
+			}
+			return fmt.Errorf("checkFacilities find facility: %w", err)
+		}
+		if facility.LocationId == nil || !slices.Contains(within, *facility.LocationId) {
+			return invalidLocation(
+				fmt.Sprintf("Facility %q isn't at the appointment's location", facility.Name),
+			)
+		}
+	}
+	return nil
+}
+
+// appointmentLocation returns the location of the appointment, nil if it has
+// none.
+func (a monolithApp) appointmentLocation(
+	ctx context.Context,
+	appt data.Appointment,
+) (*api.Location, error) {
+	if appt.LocationId == nil {
+		return nil, nil
+	}
+	locations, err := a.locationTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return locations.display(appt.LocationId), nil
+}
+
+func (a monolithApp) locationTree(ctx context.Context) (locationTree, error) {
+	locations, err := a.db.Locations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newLocationTree(locations), nil
+}
+
+// locationTree holds the locations of a clinic by their id.
+type locationTree map[uuid.UUID]data.Location
+
+func newLocationTree(locations []data.Location) locationTree {
+	tree := make(locationTree, len(locations))
+	for _, location := range locations {
+		tree[location.Id] = location
+	}
+	return tree
+}
+
+// within returns the location and all locations within it.
+func (t locationTree) within(id uuid.UUID) []uuid.UUID {
+	ids := []uuid.UUID{id}
+	for i := 0; i < len(ids); i++ {
+		for _, location := range t {
+			if location.ParentId != nil && *location.ParentId == ids[i] {
+				ids = append(ids, location.Id)
+			}
+		}
+	}
+	return ids
+}
+
+// ancestry returns the location followed by the locations it is within, from
+// the nearest one.
+func (t locationTree) ancestry(id uuid.UUID) []data.Location {
+	var ancestry []data.Location
+	for next := &id; next != nil; {
+		location, ok := t[*next]
+		if !ok {
+			break
+		}
+		ancestry = append(ancestry, location)
+		next = location.ParentId
+	}
+	return ancestry
+}
+
+// openingHours returns the opening hours of the location, inherited from the
+// nearest ancestor which has them. Nil means the location is always open.
+func (t locationTree) openingHours(id uuid.UUID) []data.OpeningHours {
+	for _, location := range t.ancestry(id) {
+		if len(location.OpeningHours) > 0 {
+			return location.OpeningHours
+		}
+	}
+	return nil
+}
+
+// isOpen reports whether the location is open from start to end, on the same
+// day in UTC.
+func (t locationTree) isOpen(id uuid.UUID, start time.Time, end time.Time) bool {
+	hours := t.openingHours(id)
+	if hours == nil {
+		return true
+	}
+
+	start, end = start.UTC(), end.UTC()
+	day := dayStart(start)
+	for _, h := range hours {
+		if h.Weekday != start.Weekday() {
+			continue
+		}
+		opensAt := day.Add(sinceMidnight(h.Opens))
+		closesAt := day.Add(sinceMidnight(h.Closes))
+		if !start.Before(opensAt) && !end.After(closesAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// display returns the location with its path, nil if there is no such
+// location.
+func (t locationTree) display(id *uuid.UUID) *api.Location {
+	if id == nil {
+		return nil
+	}
+	ancestry := t.ancestry(*id)
+	if len(ancestry) == 0 {
+		return nil
+	}
+
+	path := make([]string, len(ancestry))
+	for i, location := range ancestry {
+		path[len(ancestry)-1-i] = location.Name
+	}
+	return asPtr(dataLocationToApi(ancestry[0], path))
+}
+
+// validateOpeningHours checks that every day's hours close after they open and
+// that no day has hours twice.
+func validateOpeningHours(hours []data.OpeningHours) error {
+	seen := make(map[time.Weekday]bool, len(hours))
+	for _, h := range hours {
+		opens, opensErr := time.Parse(openingHoursLayout, h.Opens)
+		closes, closesErr := time.Parse(openingHoursLayout, h.Closes)
+		if opensErr != nil || closesErr != nil {
+			return invalidLocation("Opening hours must be formatted as HH:MM")
+		}
+		if !closes.After(opens) {
+			return invalidLocation(fmt.Sprintf("Location must close after it opens on %s", h.Weekday))
+		}
+		if seen[h.Weekday] {
+			return invalidLocation(fmt.Sprintf("Opening hours on %s are given twice", h.Weekday))
+		}
+		seen[h.Weekday] = true
+	}
+	return nil
+}
+
+// sinceMidnight returns the time of day, formatted as openingHoursLayout, as
+// duration since midnight. The clock must be valid, see validateOpeningHours.
+func sinceMidnight(clock string) time.Duration {
+	t, _ := time.Parse(openingHoursLayout, clock)
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
+
+func invalidLocation(detail string) *ValidationError {
+	return &ValidationError{ErrorDetail: api.ErrorDetail{
+		Code:   InvalidLocationCode,
+		Title:  "Invalid location",
+		Detail: detail,
+		Status: http.StatusBadRequest,
+	}}
+}
@@ -2,6 +2,8 @@ package app
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,42 +14,198 @@ import (
 )
 
 func patientRegToDataPatient(p api.PatientRegistration) data.Patient {
-	return data.Patient{
+	patient := data.Patient{
 		Email:     string(p.Email),
 		FirstName: p.FirstName,
 		LastName:  p.LastName,
 	}
+	return patientProfileToData(patient, api.PatientProfile{
+		DateOfBirth:      p.DateOfBirth,
+		Sex:              p.Sex,
+		Phone:            p.Phone,
+		NationalId:       p.NationalId,
+		Address:          p.Address,
+		Insurance:        p.Insurance,
+		EmergencyContact: p.EmergencyContact,
+	})
+}
+
+// patientProfileToData replaces the profile of the patient, phone numbers and
+// the national id are normalized.
+func patientProfileToData(patient data.Patient, p api.PatientProfile) data.Patient {
+	patient.DateOfBirth = nil
+	if p.DateOfBirth != nil {
+		patient.DateOfBirth = asPtr(p.DateOfBirth.Time)
+	}
+	patient.Sex = nil
+	if p.Sex != nil {
+		patient.Sex = asPtr(string(*p.Sex))
+	}
+	patient.Phone = nil
+	if p.Phone != nil {
+		patient.Phone = asPtr(normalizePhone(*p.Phone))
+	}
+	patient.NationalId = nil
+	if p.NationalId != nil {
+		patient.NationalId = asPtr(normalizeNationalId(*p.NationalId))
+	}
+	patient.Address = nil
+	if p.Address != nil {
+		patient.Address = &data.Address{
+			Street:     p.Address.Street,
+			City:       p.Address.City,
+			PostalCode: p.Address.PostalCode,
+			Country:    defaultCountry,
+		}
+		if p.Address.Country != nil {
+			patient.Address.Country = strings.ToUpper(*p.Address.Country)
+		}
+	}
+	patient.Insurance = nil
+	if p.Insurance != nil {
+		patient.Insurance = &data.Insurance{
+			Company:      p.Insurance.Company,
+			PolicyNumber: p.Insurance.PolicyNumber,
+		}
+	}
+	patient.EmergencyContact = nil
+	if p.EmergencyContact != nil {
+		patient.EmergencyContact = &data.EmergencyContact{
+			Name:         p.EmergencyContact.Name,
+			Relationship: p.EmergencyContact.Relationship,
+			Phone:        normalizePhone(p.EmergencyContact.Phone),
+		}
+	}
+	return patient
 }
 
 func dataPatientToApiPatient(p data.Patient) api.Patient {
-	return api.Patient{
-		Id:        p.Id,
-		Email:     types.Email(p.Email),
-		FirstName: p.FirstName,
-		LastName:  p.LastName,
-		Role:      api.UserRolePatient,
+	patient := api.Patient{
+		Id:         p.Id,
+		Email:      types.Email(p.Email),
+		FirstName:  p.FirstName,
+		LastName:   p.LastName,
+		Role:       api.UserRolePatient,
+		Phone:      p.Phone,
+		NationalId: p.NationalId,
+		Version:    asPtr(p.Version),
+	}
+	if p.DateOfBirth != nil {
+		patient.DateOfBirth = &types.Date{Time: *p.DateOfBirth}
+	}
+	if p.Sex != nil {
+		patient.Sex = asPtr(api.Sex(*p.Sex))
 	}
+	if p.Address != nil {
+		patient.Address = &api.Address{
+			Street:     p.Address.Street,
+			City:       p.Address.City,
+			PostalCode: p.Address.PostalCode,
+			Country:    asPtr(p.Address.Country),
+		}
+	}
+	if p.Insurance != nil {
+		patient.Insurance = &api.Insurance{
+			Company:      p.Insurance.Company,
+			PolicyNumber: p.Insurance.PolicyNumber,
+		}
+	}
+	if p.EmergencyContact != nil {
+		patient.EmergencyContact = &api.EmergencyContact{
+			Name:         p.EmergencyContact.Name,
+			Relationship: p.EmergencyContact.Relationship,
+			Phone:        p.EmergencyContact.Phone,
+		}
+	}
+	if len(p.ClinicIds) > 0 {
+		patient.ClinicIds = asPtr(slices.Clone(p.ClinicIds))
+	}
+	return patient
+}
+
+func dataClinicToApi(c data.Clinic) api.Clinic {
+	return api.Clinic{Id: c.Id, Name: c.Name}
 }
 
 func doctorRegToDataDoctor(d api.DoctorRegistration) data.Doctor {
 	doctor := data.Doctor{
-		Email:          string(d.Email),
-		FirstName:      d.FirstName,
-		LastName:       d.LastName,
-		Specialization: string(d.Specialization),
+		Email:           string(d.Email),
+		FirstName:       d.FirstName,
+		LastName:        d.LastName,
+		Specializations: []string{string(d.Specialization)},
 	}
 
 	return doctor
 }
 
+// doctorProfileToData replaces the names and the profile of the doctor.
+func doctorProfileToData(doctor data.Doctor, p api.DoctorProfileUpdate) data.Doctor {
+	doctor.FirstName = p.FirstName
+	doctor.LastName = p.LastName
+	doctor.Specializations = Map(p.Specializations, func(s api.SpecializationEnum) string {
+		return string(s)
+	})
+	doctor.Languages = nil
+	if p.Languages != nil {
+		doctor.Languages = slices.Clone(*p.Languages)
+	}
+	doctor.Bio = p.Bio
+	doctor.PhotoUrl = p.PhotoUrl
+	doctor.AppointmentTypes = nil
+	if p.AppointmentTypes != nil {
+		doctor.AppointmentTypes = Map(*p.AppointmentTypes, func(t api.AppointmentType) string {
+			return string(t)
+		})
+	}
+	doctor.Active = p.Active
+	return doctor
+}
+
 func dataDoctorToApiDoctor(d data.Doctor) api.Doctor {
-	return api.Doctor{
-		Id:             d.Id,
-		Email:          types.Email(d.Email),
-		FirstName:      d.FirstName,
-		LastName:       d.LastName,
-		Specialization: api.SpecializationEnum(d.Specialization),
-		Role:           api.UserRoleDoctor,
+	doctor := api.Doctor{
+		Id:        d.Id,
+		Email:     types.Email(d.Email),
+		FirstName: d.FirstName,
+		LastName:  d.LastName,
+		Specializations: Map(d.Specializations, func(s string) api.SpecializationEnum {
+			return api.SpecializationEnum(s)
+		}),
+		Bio:      d.Bio,
+		PhotoUrl: d.PhotoUrl,
+		Active:   d.Active,
+		Role:     api.UserRoleDoctor,
+		Version:  asPtr(d.Version),
+	}
+	if len(doctor.Specializations) > 0 {
+		doctor.Specialization = doctor.Specializations[0]
+	}
+	if len(d.Languages) > 0 {
+		doctor.Languages = asPtr(slices.Clone(d.Languages))
+	}
+	if len(d.AppointmentTypes) > 0 {
+		doctor.AppointmentTypes = asPtr(Map(d.AppointmentTypes, func(t string) api.AppointmentType {
+			return api.AppointmentType(t)
+		}))
+	}
+	return doctor
+}
+
+func newStaffToData(s api.NewStaff) data.Staff {
+	return data.Staff{
+		Email:     string(s.Email),
+		FirstName: s.FirstName,
+		LastName:  s.LastName,
+		Role:      data.StaffRole(s.Role),
+	}
+}
+
+func dataStaffToApi(s data.Staff) api.Staff {
+	return api.Staff{
+		Id:        s.Id,
+		Email:     types.Email(s.Email),
+		FirstName: s.FirstName,
+		LastName:  s.LastName,
+		Role:      api.StaffRole(s.Role),
 	}
 }
 
@@ -62,11 +220,19 @@ func newCondToDataCond(c api.NewCondition) data.Condition {
 
 func dataCondToCondDisplay(c data.Condition) api.ConditionDisplay {
 	return api.ConditionDisplay{
-		Id:    &c.Id,
-		Name:  c.Name,
-		Start: c.Start,
-		End:   c.End,
+		Id:        &c.Id,
+		Name:      c.Name,
+		Start:     c.Start,
+		End:       c.End,
+		Diagnosis: dataDiagnosisToApi(c.Diagnosis),
+	}
+}
+
+func dataDiagnosisToApi(d *data.Diagnosis) *api.Icd10Code {
+	if d == nil {
+		return nil
 	}
+	return &api.Icd10Code{Code: d.Code, Display: d.Display}
 }
 
 func dataCondToCond(c data.Condition, appts []api.AppointmentDisplay) api.Condition {
@@ -75,7 +241,9 @@ func dataCondToCond(c data.Condition, appts []api.AppointmentDisplay) api.Condit
 		Name:         c.Name,
 		Start:        c.Start,
 		End:          c.End,
+		Diagnosis:    dataDiagnosisToApi(c.Diagnosis),
 		Appointments: appts,
+		Version:      &c.Version,
 		AppointmentsIds: asPtr(
 			Map(appts, func(appt api.AppointmentDisplay) uuid.UUID { return appt.Id }),
 		),
@@ -106,6 +274,7 @@ func dataPrescToPresc(
 		End:           p.End,
 		DoctorsNote:   p.DoctorsNote,
 		AppointmentId: p.AppointmentId,
+		Version:       &p.Version,
 	}
 	if appt != nil {
 		presc.AppointmentId = &appt.Id
@@ -136,15 +305,77 @@ func resourceToMedicine(r data.Resource) api.Medicine {
 	return api.Medicine{Id: r.Id, Name: r.Name}
 }
 
+func dataResourceToApi(r data.Resource) api.NewResource {
+	return api.NewResource{
+		Id:         &r.Id,
+		Name:       r.Name,
+		Type:       api.ResourceType(r.Type),
+		LocationId: r.LocationId,
+	}
+}
+
+func newLocationToData(l api.NewLocation) data.Location {
+	location := data.Location{
+		Name:     l.Name,
+		Kind:     data.LocationKind(l.Kind),
+		ParentId: l.ParentId,
+	}
+	if l.OpeningHours != nil {
+		location.OpeningHours = Map(*l.OpeningHours, func(h api.OpeningHours) data.OpeningHours {
+			return data.OpeningHours{Weekday: apiWeekdays[h.Weekday], Opens: h.Opens, Closes: h.Closes}
+		})
+	}
+	return location
+}
+
+func dataLocationToApi(l data.Location, path []string) api.Location {
+	location := api.Location{
+		Id:       l.Id,
+		Name:     l.Name,
+		Kind:     api.LocationKind(l.Kind),
+		ParentId: l.ParentId,
+		Path:     path,
+	}
+	if len(l.OpeningHours) > 0 {
+		location.OpeningHours = asPtr(Map(l.OpeningHours, func(h data.OpeningHours) api.OpeningHours {
+			return api.OpeningHours{
+				Weekday: api.Weekday(strings.ToLower(h.Weekday.String())),
+				Opens:   h.Opens,
+				Closes:  h.Closes,
+			}
+		}))
+	}
+	return location
+}
+
+func staffBookingToDataAppt(b api.StaffBooking, patientId uuid.UUID) data.Appointment {
+	appt := newApptToDataAppt(api.NewAppointmentRequest{
+		PatientId:           patientId,
+		DoctorId:            b.DoctorId,
+		AppointmentDateTime: b.AppointmentDateTime,
+		Type:                b.Type,
+		ConditionId:         b.ConditionId,
+		ReferralId:          b.ReferralId,
+		LocationId:          b.LocationId,
+		Reason:              b.Reason,
+	})
+	if b.Schedule != nil && *b.Schedule {
+		appt.Status = string(api.Scheduled)
+	}
+	return appt
+}
+
 func newApptToDataAppt(a api.NewAppointmentRequest) data.Appointment {
 	appt := data.Appointment{
 		PatientId:           a.PatientId,
 		DoctorId:            a.DoctorId,
 		AppointmentDateTime: a.AppointmentDateTime,
-		EndTime:             a.AppointmentDateTime.Add(time.Hour),
+		EndTime:             a.AppointmentDateTime.Add(appointmentDuration),
 		Status:              string(api.Requested),
 		Reason:              a.Reason,
 		ConditionId:         a.ConditionId,
+		ReferralId:          a.ReferralId,
+		LocationId:          a.LocationId,
 	}
 
 	if a.Type != nil {
@@ -204,6 +435,7 @@ func dataApptToDoctorAppt(
 	equipment []data.Resource,
 	medicine []data.Resource,
 	prescriptions []data.Prescription,
+	note *data.VisitNote,
 ) api.DoctorAppointment {
 	doctorAppt := api.DoctorAppointment{
 		Id:                  &appt.Id,
@@ -233,9 +465,57 @@ func dataApptToDoctorAppt(
 
 	doctorAppt.Prescriptions = asPtr(Map(prescriptions, dataPrescToPrescDisplay))
 
+	if note != nil {
+		doctorAppt.VisitNote = asPtr(dataVisitNoteToApi(*note))
+	}
+
 	return doctorAppt
 }
 
+func newReferralToData(r api.NewReferral) data.Referral {
+	referral := data.Referral{
+		PatientId:    r.PatientId,
+		FromDoctorId: r.FromDoctorId,
+		ToDoctorId:   r.ToDoctorId,
+		ConditionId:  r.ConditionId,
+		Urgency:      string(r.Urgency),
+		Note:         r.Note,
+	}
+	if r.ToSpecialization != nil {
+		referral.ToSpecialization = asPtr(string(*r.ToSpecialization))
+	}
+	if r.ExpiresAt != nil {
+		referral.ExpiresAt = *r.ExpiresAt
+	}
+	return referral
+}
+
+func dataReferralToApi(
+	r data.Referral,
+	fromDoctor data.Doctor,
+	cond *data.Condition,
+) api.Referral {
+	referral := api.Referral{
+		Id:            r.Id,
+		PatientId:     r.PatientId,
+		FromDoctor:    dataDoctorToApiDoctor(fromDoctor),
+		ToDoctorId:    r.ToDoctorId,
+		Urgency:       api.ReferralUrgency(r.Urgency),
+		Note:          r.Note,
+		ExpiresAt:     r.ExpiresAt,
+		Status:        referralStatus(r),
+		AppointmentId: r.AppointmentId,
+		CreatedAt:     r.CreatedAt,
+	}
+	if r.ToSpecialization != nil {
+		referral.ToSpecialization = asPtr(api.SpecializationEnum(*r.ToSpecialization))
+	}
+	if cond != nil {
+		referral.Condition = asPtr(dataCondToCondDisplay(*cond))
+	}
+	return referral
+}
+
 func asPtr[T any](v T) *T {
 	return &v
 }
@@ -247,3 +527,152 @@ func Map[T, V any](ts []T, fn func(T) V) []V {
 	}
 	return result
 }
+
+// Index returns the values by their keys, a later value replaces an earlier
+// one with the same key.
+func Index[K comparable, V any](vs []V, key func(V) K) map[K]V {
+	result := make(map[K]V, len(vs))
+	for _, v := range vs {
+		result[key(v)] = v
+	}
+	return result
+}
+
+func dataCondRevisionToApi(r data.Revision[data.Condition]) api.ConditionRevision {
+	changedAt, changedBy := revisionChange(r.ChangedAt, r.ChangedBy)
+	return api.ConditionRevision{
+		Version:   r.Version,
+		Operation: api.RevisionOperation(r.Operation),
+		ChangedAt: changedAt,
+		ChangedBy: changedBy,
+		Condition: dataCondToCondDisplay(r.Document),
+	}
+}
+
+func dataPrescRevisionToApi(r data.Revision[data.Prescription]) api.PrescriptionRevision {
+	changedAt, changedBy := revisionChange(r.ChangedAt, r.ChangedBy)
+	return api.PrescriptionRevision{
+		Version:   r.Version,
+		Operation: api.RevisionOperation(r.Operation),
+		ChangedAt: changedAt,
+		ChangedBy: changedBy,
+		Prescription: api.PrescriptionSnapshot{
+			Id:            &r.Document.Id,
+			AppointmentId: r.Document.AppointmentId,
+			Name:          r.Document.Name,
+			Start:         r.Document.Start,
+			End:           r.Document.End,
+			DoctorsNote:   r.Document.DoctorsNote,
+		},
+	}
+}
+
+func revisionChange(at time.Time, by *data.Actor) (*time.Time, *api.Actor) {
+	var changedAt *time.Time
+	if !at.IsZero() {
+		changedAt = &at
+	}
+
+	var changedBy *api.Actor
+	if by != nil {
+		changedBy = &api.Actor{Id: by.Id, Role: api.UserRole(by.Role)}
+	}
+	return changedAt, changedBy
+}
+
+func updateVisitNoteToData(n api.UpdateVisitNote) data.VisitNote {
+	note := data.VisitNote{Text: n.Text}
+	if n.Sections != nil {
+		note.Sections = data.VisitNoteSections{
+			Anamnesis:     n.Sections.Anamnesis,
+			Findings:      n.Sections.Findings,
+			Diagnosis:     n.Sections.Diagnosis,
+			DiagnosisCode: n.Sections.DiagnosisCode,
+			Plan:          n.Sections.Plan,
+		}
+	}
+	if n.Vitals == nil {
+		return note
+	}
+
+	v := n.Vitals
+	if v.BloodPressure != nil {
+		note.Vitals.BloodPressure = &data.BloodPressure{
+			Systolic:  v.BloodPressure.Systolic,
+			Diastolic: v.BloodPressure.Diastolic,
+			Unit:      string(v.BloodPressure.Unit),
+		}
+	}
+	if v.HeartRate != nil {
+		note.Vitals.HeartRate = &data.Measurement{
+			Value: v.HeartRate.Value,
+			Unit:  string(v.HeartRate.Unit),
+		}
+	}
+	if v.Temperature != nil {
+		note.Vitals.Temperature = &data.Measurement{
+			Value: v.Temperature.Value,
+			Unit:  string(v.Temperature.Unit),
+		}
+	}
+	if v.Weight != nil {
+		note.Vitals.Weight = &data.Measurement{Value: v.Weight.Value, Unit: string(v.Weight.Unit)}
+	}
+	if v.Height != nil {
+		note.Vitals.Height = &data.Measurement{Value: v.Height.Value, Unit: string(v.Height.Unit)}
+	}
+	return note
+}
+
+func dataVisitNoteToApi(n data.VisitNote) api.VisitNote {
+	note := api.VisitNote{
+		Id:            &n.Id,
+		AppointmentId: &n.AppointmentId,
+		DoctorId:      &n.DoctorId,
+		Sections: api.VisitNoteSections{
+			Anamnesis:     n.Sections.Anamnesis,
+			Findings:      n.Sections.Findings,
+			Diagnosis:     n.Sections.Diagnosis,
+			DiagnosisCode: n.Sections.DiagnosisCode,
+			Plan:          n.Sections.Plan,
+		},
+		Text:      n.Text,
+		UpdatedAt: &n.UpdatedAt,
+		SignedAt:  n.SignedAt,
+		SignedBy:  n.SignedBy,
+	}
+
+	v := n.Vitals
+	if v.BloodPressure != nil {
+		note.Vitals.BloodPressure = &api.BloodPressure{
+			Systolic:  v.BloodPressure.Systolic,
+			Diastolic: v.BloodPressure.Diastolic,
+			Unit:      api.BloodPressureUnit(v.BloodPressure.Unit),
+		}
+	}
+	if v.HeartRate != nil {
+		note.Vitals.HeartRate = &api.HeartRate{
+			Value: v.HeartRate.Value,
+			Unit:  api.HeartRateUnit(v.HeartRate.Unit),
+		}
+	}
+	if v.Temperature != nil {
+		note.Vitals.Temperature = &api.Temperature{
+			Value: v.Temperature.Value,
+			Unit:  api.TemperatureUnit(v.Temperature.Unit),
+		}
+	}
+	if v.Weight != nil {
+		note.Vitals.Weight = &api.Weight{
+			Value: v.Weight.Value,
+			Unit:  api.WeightUnit(v.Weight.Unit),
+		}
+	}
+	if v.Height != nil {
+		note.Vitals.Height = &api.Height{
+			Value: v.Height.Value,
+			Unit:  api.HeightUnit(v.Height.Unit),
+		}
+	}
+	return note
+}
@@ -4,8 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"math/rand"
-	"time"
+	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
 
@@ -18,6 +18,9 @@ func (a monolithApp) CreatePatient(
 	p api.PatientRegistration,
 ) (api.Patient, error) {
 	patient := patientRegToDataPatient(p)
+	if err := validatePatientProfile(patient); err != nil {
+		return api.Patient{}, fmt.Errorf("CreatePatient: %w", err)
+	}
 
 	patient, err := a.db.CreatePatient(ctx, patient)
 	if errors.Is(err, data.ErrDuplicateEmail) {
@@ -53,65 +56,143 @@ func (a monolithApp) PatientByEmail(ctx context.Context, email string) (api.Pati
 	return dataPatientToApiPatient(patient), nil
 }
 
-func (a monolithApp) PatientsCalendar(
+const SearchCriteriaMissingCode = "patient.search-criteria-missing"
+
+// SearchPatients returns a page of the clinic's patients matching the search.
+// Doctors find only the patients they care for, staff with the permission
+// find all of them.
+func (a monolithApp) SearchPatients(
 	ctx context.Context,
-	patientId uuid.UUID,
-	from api.From,
-	to *api.To,
-) (api.PatientsCalendar, error) {
-	var toTime *time.Time = nil
-	if to != nil {
-		toTime = &to.Time
+	params api.SearchPatientsParams,
+) (api.PatientList, error) {
+	filter := data.PatientFilter{}
+	if params.Name != nil {
+		filter.Name = *params.Name
+	}
+	if params.Email != nil {
+		filter.Email = string(*params.Email)
+	}
+	if params.DateOfBirth != nil {
+		filter.DateOfBirth = asPtr(params.DateOfBirth.Time)
+	}
+	if params.NationalId != nil {
+		filter.NationalId = normalizeNationalId(*params.NationalId)
+	}
+	if strings.TrimSpace(filter.Name) == "" && filter.Email == "" &&
+		filter.DateOfBirth == nil && filter.NationalId == "" {
+		return api.PatientList{}, fmt.Errorf("SearchPatients: %w", &ValidationError{
+			ErrorDetail: api.ErrorDetail{
+				Code:   SearchCriteriaMissingCode,
+				Title:  "Search criteria missing",
+				Detail: "Search by name, email, date of birth or national id",
+				Status: http.StatusBadRequest,
+			},
+		})
+	}
+
+	actor, ok := data.ActorFromContext(ctx)
+	if ok && actor.Role == string(api.UserRoleDoctor) {
+		ids, err := a.doctorsPatientIds(ctx, actor.Id)
+		if err != nil {
+			return api.PatientList{}, fmt.Errorf("SearchPatients: %w", err)
+		}
+		filter.Ids = ids
+	} else if err := a.requirePermission(ctx, permSearchPatients); err != nil {
+		return api.PatientList{}, fmt.Errorf("SearchPatients: %w", err)
 	}
 
-	appts, err := a.db.AppointmentsByPatientId(ctx, patientId, from.Time, toTime)
+	patients, pagination, err := a.db.SearchPatients(ctx, filter, params.Page, params.PageSize)
 	if err != nil {
-		return api.PatientsCalendar{}, fmt.Errorf("PatientsCalendar appointments: %w", err)
+		return api.PatientList{}, fmt.Errorf("SearchPatients: %w", err)
+	}
+
+	result := api.PatientList{
+		Patients: make([]api.Patient, len(patients)),
+		Pagination: api.Pagination{
+			Page:     pagination.Page,
+			PageSize: pagination.PageSize,
+			Total:    int(pagination.Total),
+		},
+	}
+	for i, patient := range patients {
+		result.Patients[i] = dataPatientToApiPatient(patient)
+	}
+	return result, nil
+}
+
+// doctorsPatientIds returns the patients the doctor, who must be a doctor of
+// the context's clinic, cares for.
+func (a monolithApp) doctorsPatientIds(
+	ctx context.Context,
+	doctorId uuid.UUID,
+) ([]uuid.UUID, error) {
+	if _, err := a.db.DoctorById(ctx, doctorId); err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return nil, ErrForbidden
+		}
+		return nil, fmt.Errorf("doctorsPatientIds find doctor: %w", err)
 	}
 
-	conds, err := a.db.FindConditionsByPatientId(ctx, patientId, from.Time, toTime)
+	ids, err := a.db.DoctorsPatientIds(ctx, doctorId)
 	if err != nil {
-		return api.PatientsCalendar{}, fmt.Errorf("PatientsCalendar conditions: %w", err)
+		return nil, fmt.Errorf("doctorsPatientIds: %w", err)
 	}
+	return ids, nil
+}
 
-	prescriptions, err := a.db.FindPrescriptionsByPatientId(ctx, patientId, from.Time, toTime)
+// PatientsCalendar returns the page of the patient's appointments matching
+// the params. The first page also has all the patient's conditions and
+// prescriptions in the period.
+func (a monolithApp) PatientsCalendar(
+	ctx context.Context,
+	patientId uuid.UUID,
+	params api.PatientsCalendarParams,
+	list ListParams,
+) (api.PatientsCalendar, error) {
+	query, err := list.query(appointmentSorts)
 	if err != nil {
-		return api.PatientsCalendar{}, fmt.Errorf("PatientsCalendar prescriptions: %w", err)
+		return api.PatientsCalendar{}, fmt.Errorf("PatientsCalendar: %w", err)
 	}
 
-	calendar := api.PatientsCalendar{}
-	var doctor *data.Doctor = nil
-	if len(appts) != 0 {
-		calendar.Appointments = asPtr(make([]api.AppointmentDisplay, len(appts)))
+	filter := appointmentFilter(params.From, params.To, params.Status, params.Type)
+	filter.PatientId = &patientId
+	page, err := a.db.ListAppointments(ctx, filter, query)
+	if err != nil {
+		return api.PatientsCalendar{}, fmt.Errorf("PatientsCalendar appointments: %w", err)
+	}
 
-		d, err := a.db.DoctorById(ctx, appts[0].DoctorId)
+	conds := make([]data.Condition, 0)
+	prescriptions := make([]data.Prescription, 0)
+	if query.After == nil {
+		conds, err = a.db.FindConditionsByPatientId(ctx, patientId, filter.From, filter.To)
 		if err != nil {
-			return api.PatientsCalendar{}, fmt.Errorf("PatientsCalendar doc find: %w", err)
+			return api.PatientsCalendar{}, fmt.Errorf("PatientsCalendar conditions: %w", err)
 		}
-		doctor = &d
-	}
 
-	if len(conds) != 0 {
-		calendar.Conditions = asPtr(make([]api.ConditionDisplay, len(conds)))
-	}
-	if len(prescriptions) != 0 {
-		calendar.Prescriptions = asPtr(make([]api.PrescriptionDisplay, len(prescriptions)))
+		prescriptions, err = a.db.FindPrescriptionsByPatientId(
+			ctx,
+			patientId,
+			filter.From,
+			filter.To,
+		)
+		if err != nil {
+			return api.PatientsCalendar{}, fmt.Errorf("PatientsCalendar prescriptions: %w", err)
+		}
 	}
 
-	for i, appt := range appts {
-		patient, err := a.db.PatientById(ctx, appt.PatientId)
+	calendar := api.PatientsCalendar{Pagination: cursorPagination(page)}
+	if len(page.Items) != 0 {
+		displays, err := a.appointmentDisplays(ctx, page.Items)
 		if err != nil {
-			return api.PatientsCalendar{}, fmt.Errorf("PatientsCalendar patient find: %w", err)
+			return api.PatientsCalendar{}, fmt.Errorf("PatientsCalendar: %w", err)
 		}
-		(*calendar.Appointments)[i] = dataApptToApptDisplay(appt, patient, *doctor)
+		calendar.Appointments = &displays
 	}
-
-	for i, cond := range conds {
-		(*calendar.Conditions)[i] = dataCondToCondDisplay(cond)
+	if len(conds) != 0 {
+		calendar.Conditions = asPtr(Map(conds, dataCondToCondDisplay))
 	}
-
-	for i, presc := range prescriptions {
-		(*calendar.Prescriptions)[i] = dataPrescToPrescDisplay(presc)
+	if len(prescriptions) != 0 {
+		calendar.Prescriptions = asPtr(Map(prescriptions, dataPrescToPrescDisplay))
 	}
 
 	return calendar, nil
@@ -123,49 +204,17 @@ func (a monolithApp) PatientMedicalHistoryFiles(
 	page int,
 	pageSize int,
 ) (api.MedicalHistoryFileList, error) {
-	files := make([]string, 0)
-	for i := range pageSize {
-		fileType := dummyFileTypes[rand.Intn(len(dummyFileTypes))]
-		date := time.Now().AddDate(0, 0, -rand.Intn(365)).Format("2006-01-02")
-		files = append(files, fmt.Sprintf("%s_%s_%d.pdf", fileType, date, i))
-	}
-
-	pagination := api.Pagination{
-		Page:     page,
-		PageSize: pageSize,
-		Total:    38,
+	files, pagination, err := a.db.MedicalHistoryFilesByPatientId(ctx, patientId, page, pageSize)
+	if err != nil {
+		return api.MedicalHistoryFileList{}, fmt.Errorf("PatientMedicalHistoryFiles: %w", err)
 	}
 
 	return api.MedicalHistoryFileList{
-		Files:      files,
-		Pagination: pagination,
+		Files: Map(files, func(file data.MedicalHistoryFile) string { return file.Name }),
+		Pagination: api.Pagination{
+			Page:     pagination.Page,
+			PageSize: pagination.PageSize,
+			Total:    int(pagination.Total),
+		},
 	}, nil
 }
-
-var dummyFileTypes = []string{
-	"lab_result",
-	"medical_report",
-	"prescription",
-	"discharge_summary",
-	"consultation_note",
-	"radiology_report",
-	"pathology_report",
-	"medical_certificate",
-	"referral_letter",
-	"follow_up_note",
-	"progress_note",
-	"medication_list",
-	"allergy_report",
-	"immunization_record",
-	"vital_signs_chart",
-	"medical_history",
-	"family_medical_history",
-	"surgical_report",
-	"anesthesia_report",
-	"recovery_room_note",
-	"physical_examination_report",
-	"diagnostic_test_result",
-	"treatment_plan",
-	"care_plan",
-	"discharge_plan",
-}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+type permission string
+
+const (
+	// permBookForPatients allows booking and rescheduling appointments of any
+	// patient, patients can always book their own.
+	permBookForPatients permission = "appointments.book-for-patients"
+	// permRecordVitals allows recording vitals in visit notes, the assigned
+	// doctor can always write the whole note.
+	permRecordVitals    permission = "visit-notes.record-vitals"
+	permManageResources permission = "resources.manage"
+	permManageStaff     permission = "staff.manage"
+	permManageClinics   permission = "clinics.manage"
+	// permImportFhir allows importing FHIR Bundles into the clinic.
+	permImportFhir permission = "fhir.import"
+	// permSearchPatients allows searching all patients of the clinic, doctors
+	// can search the patients they care for.
+	permSearchPatients permission = "patients.search"
+	// permEditPatientProfiles allows updating profiles of any patient, patients
+	// can always update their own.
+	permEditPatientProfiles permission = "patients.edit-profiles"
+	// permEditDoctorProfiles allows updating profiles of any doctor, doctors
+	// can always update their own.
+	permEditDoctorProfiles permission = "doctors.edit-profiles"
+	// permManagePatientData allows exporting and erasing data of any patient,
+	// patients can always export and erase their own.
+	permManagePatientData permission = "patients.manage-data"
+)
+
+// rolePermissions is what each role of the acting user is allowed to do,
+// beyond what patients and doctors can do with their own data.
+var rolePermissions = map[api.UserRole][]permission{
+	api.UserRoleReceptionist: {permBookForPatients, permSearchPatients, permEditPatientProfiles},
+	api.UserRoleNurse:        {permRecordVitals, permSearchPatients},
+	api.UserRoleAdmin: {
+		permBookForPatients,
+		permManageResources,
+		permManageStaff,
+		permManageClinics,
+		permImportFhir,
+		permSearchPatients,
+		permEditPatientProfiles,
+		permEditDoctorProfiles,
+		permManagePatientData,
+	},
+}
+
+// requirePermission checks that the acting user has the permission, requests
+// without an acting user aren't allowed. Staff actors must be staff of the
+// context's clinic with the claimed role.
+func (a monolithApp) requirePermission(ctx context.Context, perm permission) error {
+	actor, ok := data.ActorFromContext(ctx)
+	if !ok {
+		return ErrForbidden
+	}
+	return a.authorizeActor(ctx, actor, perm)
+}
+
+// authorizeActor checks that the actor has the permission.
+func (a monolithApp) authorizeActor(ctx context.Context, actor data.Actor, perm permission) error {
+	if !slices.Contains(rolePermissions[api.UserRole(actor.Role)], perm) {
+		return ErrForbidden
+	}
+
+	staff, err := a.db.StaffById(ctx, actor.Id)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return ErrForbidden
+		}
+		return fmt.Errorf("authorize find staff: %w", err)
+	}
+	if string(staff.Role) != actor.Role {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// authorizeBooking checks that the acting user can book or reschedule an
+// appointment of the patient.
+func (a monolithApp) authorizeBooking(ctx context.Context, appt data.Appointment) error {
+	actor, ok := data.ActorFromContext(ctx)
+	if !ok || isActingPatient(ctx, appt.PatientId) {
+		return nil
+	}
+	return a.authorizeActor(ctx, actor, permBookForPatients)
+}
+
+// authorizeCancellation checks that the acting user can cancel the
+// appointment, the patient and the assigned doctor can always cancel it.
+func (a monolithApp) authorizeCancellation(ctx context.Context, appt data.Appointment) error {
+	actor, ok := data.ActorFromContext(ctx)
+	if !ok || isActingPatient(ctx, appt.PatientId) || isAssignedDoctor(ctx, appt) {
+		return nil
+	}
+	return a.authorizeActor(ctx, actor, permBookForPatients)
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+var (
+	// ErrPreconditionFailed is returned when a resource isn't in any of the
+	// versions a conditional write expects.
+	ErrPreconditionFailed = errors.New("resource was modified since the expected version")
+	// ErrModifiedConcurrently is returned when a resource was modified between
+	// reading and writing it back without a precondition.
+	ErrModifiedConcurrently = errors.New("resource was modified concurrently")
+)
+
+// Precondition lists the versions a conditional write expects the resource to
+// be in. Nil matches any version.
+type Precondition []int64
+
+func (p Precondition) Matches(version int64) bool {
+	return p == nil || slices.Contains(p, version)
+}
+
+// versionConflict translates a data.ErrVersionConflict of a write done under
+// the precondition.
+func (p Precondition) versionConflict(where string) error {
+	if p != nil {
+		return fmt.Errorf("%s: %w", where, ErrPreconditionFailed)
+	}
+	return fmt.Errorf("%s: %w", where, ErrModifiedConcurrently)
+}
+
+func isVersionConflict(err error) bool {
+	return errors.Is(err, data.ErrVersionConflict)
+}
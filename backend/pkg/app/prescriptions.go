@@ -49,6 +49,7 @@ func (a monolithApp) UpdatePatientPrescription(
 	ctx context.Context,
 	prescriptionId uuid.UUID,
 	updateData api.UpdatePrescription,
+	ifMatch Precondition,
 ) (api.Prescription, error) {
 	existingPrescription, err := a.db.PrescriptionById(ctx, prescriptionId)
 	if err != nil {
@@ -57,6 +58,12 @@ func (a monolithApp) UpdatePatientPrescription(
 		}
 		return api.Prescription{}, fmt.Errorf("UpdatePatientPrescription fetch failed: %w", err)
 	}
+	if !ifMatch.Matches(existingPrescription.Version) {
+		return api.Prescription{}, fmt.Errorf(
+			"UpdatePatientPrescription: %w",
+			ErrPreconditionFailed,
+		)
+	}
 
 	updated := false
 	if updateData.AppointmentId != nil {
@@ -112,6 +119,9 @@ func (a monolithApp) UpdatePatientPrescription(
 					ErrNotFound,
 				)
 			}
+			if isVersionConflict(err) {
+				return api.Prescription{}, ifMatch.versionConflict("UpdatePatientPrescription")
+			}
 			return api.Prescription{}, fmt.Errorf(
 				"UpdatePatientPrescription update failed: %w",
 				err,
@@ -170,17 +180,30 @@ func (a monolithApp) PrescriptionById(
 		return api.Prescription{}, fmt.Errorf("PrescriptionById fetch prescription failed: %w", err)
 	}
 
+	presc, err := a.prescriptionDetail(ctx, prescription)
+	if err != nil {
+		return api.Prescription{}, fmt.Errorf("PrescriptionById: %w", err)
+	}
+	return presc, nil
+}
+
+// prescriptionDetail adds the appointment of the prescription.
+func (a monolithApp) prescriptionDetail(
+	ctx context.Context,
+	prescription data.Prescription,
+) (api.Prescription, error) {
+	prescriptionId := prescription.Id
 	patient, err := a.db.PatientById(ctx, prescription.PatientId)
 	if err != nil {
 		if errors.Is(err, data.ErrNotFound) {
 			return api.Prescription{}, fmt.Errorf(
-				"PrescriptionById data inconsistency patient %s not found for prescription %s: %w",
+				"prescriptionDetail data inconsistency patient %s not found for prescription %s: %w",
 				prescription.PatientId,
 				prescriptionId,
 				err,
 			)
 		}
-		return api.Prescription{}, fmt.Errorf("PrescriptionById fetch patient failed: %w", err)
+		return api.Prescription{}, fmt.Errorf("prescriptionDetail fetch patient failed: %w", err)
 	}
 
 	var apptData *data.Appointment = nil
@@ -191,14 +214,14 @@ func (a monolithApp) PrescriptionById(
 		if err != nil {
 			if errors.Is(err, data.ErrNotFound) {
 				return api.Prescription{}, fmt.Errorf(
-					"PrescriptionById data inconsistency appointment %s not found for prescription %s: %w",
+					"prescriptionDetail data inconsistency appointment %s not found for prescription %s: %w",
 					*prescription.AppointmentId,
 					prescriptionId,
 					err,
 				)
 			}
 			return api.Prescription{}, fmt.Errorf(
-				"PrescriptionById fetch appointment failed: %w",
+				"prescriptionDetail fetch appointment failed: %w",
 				err,
 			)
 		}
@@ -208,14 +231,14 @@ func (a monolithApp) PrescriptionById(
 		if err != nil {
 			if errors.Is(err, data.ErrNotFound) {
 				return api.Prescription{}, fmt.Errorf(
-					"PrescriptionById data inconsistency: doctor %s not found for appointment %s: %w",
+					"prescriptionDetail data inconsistency: doctor %s not found for appointment %s: %w",
 					appointment.DoctorId,
 					appointment.Id,
 					err,
 				)
 			}
 			return api.Prescription{}, fmt.Errorf(
-				"PrescriptionById fetch doctor failed: %w",
+				"prescriptionDetail fetch doctor failed: %w",
 				err,
 			)
 		}
@@ -225,9 +248,35 @@ func (a monolithApp) PrescriptionById(
 	return dataPrescToPresc(prescription, apptData, &patient, doctorData), nil
 }
 
-func (a monolithApp) DeletePrescription(ctx context.Context, id uuid.UUID) error {
-	err := a.db.DeletePrescription(ctx, id)
+func (a monolithApp) DeletePrescription(
+	ctx context.Context,
+	id uuid.UUID,
+	ifMatch Precondition,
+) error {
+	var version *int64
+	if ifMatch != nil {
+		existing, err := a.db.PrescriptionById(ctx, id)
+		if err != nil {
+			if errors.Is(err, data.ErrNotFound) {
+				return fmt.Errorf(
+					"DeletePrescription prescription with id %s not found: %w",
+					id,
+					ErrNotFound,
+				)
+			}
+			return fmt.Errorf("DeletePrescription fetch failed: %w", err)
+		}
+		if !ifMatch.Matches(existing.Version) {
+			return fmt.Errorf("DeletePrescription: %w", ErrPreconditionFailed)
+		}
+		version = &existing.Version
+	}
+
+	err := a.db.DeletePrescription(ctx, id, version)
 	if err != nil {
+		if isVersionConflict(err) {
+			return ifMatch.versionConflict("DeletePrescription")
+		}
 		if errors.Is(err, data.ErrNotFound) {
 			return fmt.Errorf(
 				"DeletePrescription prescription with id %s not found: %w",
@@ -0,0 +1,267 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+// PatientDataExport holds every record tied to a patient, as stored.
+type PatientDataExport struct {
+	Patient             data.Patient
+	Appointments        []data.Appointment
+	Conditions          []data.Condition
+	Prescriptions       []data.Prescription
+	Reservations        []data.Reservation
+	VisitNotes          []data.VisitNote
+	Referrals           []data.Referral
+	MedicalHistoryFiles []data.MedicalHistoryFile
+}
+
+const (
+	erasedFirstName = "Erased"
+	erasedLastName  = "Patient"
+	erasedEmailFmt  = "erased+%s@erased.invalid"
+
+	erasureCancellationReason = "Patient's personal data were erased."
+
+	// exportFilesPageSize is the number of medical history files read at once
+	// while exporting.
+	exportFilesPageSize = 100
+)
+
+var pseudonymizedPatientFields = []string{
+	"email",
+	"firstName",
+	"lastName",
+	"dateOfBirth",
+	"sex",
+	"phone",
+	"nationalId",
+	"address",
+	"insurance",
+	"emergencyContact",
+}
+
+func (a monolithApp) ExportPatientData(
+	ctx context.Context,
+	patientId uuid.UUID,
+) (PatientDataExport, error) {
+	if err := a.authorizePatientData(ctx, patientId); err != nil {
+		return PatientDataExport{}, fmt.Errorf("ExportPatientData: %w", err)
+	}
+
+	patient, err := a.db.PatientById(ctx, patientId)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return PatientDataExport{}, fmt.Errorf("ExportPatientData: %w", ErrNotFound)
+		}
+		return PatientDataExport{}, fmt.Errorf("ExportPatientData find patient: %w", err)
+	}
+
+	records, err := a.patientRecords(ctx, patientId)
+	if err != nil {
+		return PatientDataExport{}, fmt.Errorf("ExportPatientData: %w", err)
+	}
+
+	files, err := a.patientMedicalHistoryFiles(ctx, patientId)
+	if err != nil {
+		return PatientDataExport{}, fmt.Errorf("ExportPatientData: %w", err)
+	}
+
+	return PatientDataExport{
+		Patient:             patient,
+		Appointments:        records.appointments,
+		Conditions:          records.conditions,
+		Prescriptions:       records.prescriptions,
+		Reservations:        records.reservations,
+		VisitNotes:          records.visitNotes,
+		Referrals:           records.referrals,
+		MedicalHistoryFiles: files,
+	}, nil
+}
+
+// authorizePatientData checks that the acting user can export and erase the
+// patient's data, the patient can always handle their own. Requests without an
+// acting user aren't allowed.
+func (a monolithApp) authorizePatientData(ctx context.Context, patientId uuid.UUID) error {
+	if isActingPatient(ctx, patientId) {
+		return nil
+	}
+	return a.requirePermission(ctx, permManagePatientData)
+}
+
+func (a monolithApp) ErasePatientData(
+	ctx context.Context,
+	patientId uuid.UUID,
+	req api.PatientErasureRequest,
+) (api.PatientErasureReport, error) {
+	if err := a.authorizePatientData(ctx, patientId); err != nil {
+		return api.PatientErasureReport{}, fmt.Errorf("ErasePatientData: %w", err)
+	}
+
+	patient, err := a.db.PatientById(ctx, patientId)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return api.PatientErasureReport{}, fmt.Errorf("ErasePatientData: %w", ErrNotFound)
+		}
+		return api.PatientErasureReport{}, fmt.Errorf("ErasePatientData find patient: %w", err)
+	}
+
+	records, err := a.patientRecords(ctx, patientId)
+	if err != nil {
+		return api.PatientErasureReport{}, fmt.Errorf("ErasePatientData: %w", err)
+	}
+
+	dryRun := req.DryRun != nil && *req.DryRun
+	report := api.PatientErasureReport{
+		PatientId:             patientId,
+		DryRun:                dryRun,
+		PseudonymizedFields:   pseudonymizedPatientFields,
+		CancelledAppointments: make([]uuid.UUID, 0),
+	}
+
+	now := time.Now()
+	cancelled := make(map[uuid.UUID]bool)
+	for _, appt := range records.appointments {
+		upcoming := appt.AppointmentDateTime.After(now)
+		open := appt.Status == string(api.Requested) || appt.Status == string(api.Scheduled)
+		if upcoming && open {
+			report.CancelledAppointments = append(report.CancelledAppointments, appt.Id)
+			cancelled[appt.Id] = true
+		}
+	}
+
+	for _, reservation := range records.reservations {
+		if cancelled[reservation.AppointmentId] {
+			report.ReleasedReservations++
+		} else {
+			report.Retained.Reservations++
+		}
+	}
+	report.Retained.Appointments = len(records.appointments)
+	report.Retained.Conditions = len(records.conditions)
+	report.Retained.Prescriptions = len(records.prescriptions)
+
+	if dryRun {
+		return report, nil
+	}
+
+	reason := erasureCancellationReason
+	if req.Reason != nil {
+		reason = *req.Reason
+	}
+	for _, apptId := range report.CancelledAppointments {
+		err := a.db.CancelAppointment(ctx, apptId, string(api.UserRolePatient), &reason)
+		if err != nil {
+			return api.PatientErasureReport{}, fmt.Errorf(
+				"ErasePatientData cancel appointment %s: %w",
+				apptId,
+				err,
+			)
+		}
+	}
+
+	patient.Email = fmt.Sprintf(erasedEmailFmt, patientId)
+	patient.FirstName = erasedFirstName
+	patient.LastName = erasedLastName
+	patient.DateOfBirth = nil
+	patient.Sex = nil
+	patient.Phone = nil
+	patient.NationalId = nil
+	patient.Address = nil
+	patient.Insurance = nil
+	patient.EmergencyContact = nil
+	patient.ErasedAt = asPtr(now)
+	if _, err := a.db.UpdatePatient(ctx, patientId, patient); err != nil {
+		return api.PatientErasureReport{}, fmt.Errorf("ErasePatientData pseudonymize: %w", err)
+	}
+	report.ErasedAt = patient.ErasedAt
+
+	return report, nil
+}
+
+type patientRecords struct {
+	appointments  []data.Appointment
+	conditions    []data.Condition
+	prescriptions []data.Prescription
+	reservations  []data.Reservation
+	visitNotes    []data.VisitNote
+	referrals     []data.Referral
+}
+
+// patientRecords loads all clinical records of a patient, regardless of their date.
+func (a monolithApp) patientRecords(
+	ctx context.Context,
+	patientId uuid.UUID,
+) (patientRecords, error) {
+	appts, err := a.db.AppointmentsByPatientId(ctx, patientId, time.Time{}, nil)
+	if err != nil {
+		return patientRecords{}, fmt.Errorf("patientRecords appointments: %w", err)
+	}
+
+	conds, err := a.db.FindConditionsByPatientId(ctx, patientId, time.Time{}, nil)
+	if err != nil {
+		return patientRecords{}, fmt.Errorf("patientRecords conditions: %w", err)
+	}
+
+	prescriptions, err := a.db.FindPrescriptionsByPatientId(ctx, patientId, time.Time{}, nil)
+	if err != nil {
+		return patientRecords{}, fmt.Errorf("patientRecords prescriptions: %w", err)
+	}
+
+	apptIds := Map(appts, func(appt data.Appointment) uuid.UUID { return appt.Id })
+	reservations, err := a.db.ReservationsByAppointmentIds(ctx, apptIds)
+	if err != nil {
+		return patientRecords{}, fmt.Errorf("patientRecords reservations: %w", err)
+	}
+
+	visitNotes, err := a.db.VisitNotesByAppointmentIds(ctx, apptIds)
+	if err != nil {
+		return patientRecords{}, fmt.Errorf("patientRecords visit notes: %w", err)
+	}
+
+	referrals, err := a.db.ReferralsByPatientId(ctx, patientId)
+	if err != nil {
+		return patientRecords{}, fmt.Errorf("patientRecords referrals: %w", err)
+	}
+
+	return patientRecords{
+		appointments:  appts,
+		conditions:    conds,
+		prescriptions: prescriptions,
+		reservations:  reservations,
+		visitNotes:    visitNotes,
+		referrals:     referrals,
+	}, nil
+}
+
+// patientMedicalHistoryFiles pages through all medical history files of the
+// patient, the newest first.
+func (a monolithApp) patientMedicalHistoryFiles(
+	ctx context.Context,
+	patientId uuid.UUID,
+) ([]data.MedicalHistoryFile, error) {
+	files := make([]data.MedicalHistoryFile, 0)
+	for page := 0; ; page++ {
+		items, pagination, err := a.db.MedicalHistoryFilesByPatientId(
+			ctx,
+			patientId,
+			page,
+			exportFilesPageSize,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("patientMedicalHistoryFiles page %d: %w", page, err)
+		}
+		files = append(files, items...)
+		if len(items) < exportFilesPageSize || int64(len(files)) >= pagination.Total {
+			return files, nil
+		}
+	}
+}
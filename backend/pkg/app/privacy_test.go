@@ -0,0 +1,145 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+func TestExportPatientData_AllMedicalHistoryFiles(t *testing.T) {
+	db := newExportDb()
+	patient := db.addPatient("Jana", "Nováková")
+	other := db.addPatient("Peter", "Novák")
+	uploadedAt := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	for i := range 2*exportFilesPageSize + 1 {
+		db.addFile(patient, fmt.Sprintf("lab_result_%d.pdf", i), uploadedAt)
+	}
+	db.addFile(other, "discharge_summary.pdf", uploadedAt)
+
+	ctx := asPatient(patient)
+	export, err := New(db, 0).ExportPatientData(ctx, patient)
+	require.NoError(t, err)
+	require.Len(t, export.MedicalHistoryFiles, 2*exportFilesPageSize+1)
+	for i, file := range export.MedicalHistoryFiles {
+		assert.Equal(t, fmt.Sprintf("lab_result_%d.pdf", i), file.Name)
+	}
+	assert.Equal(t, 3, db.pages, "Files are read page by page")
+
+	again, err := New(db, 0).ExportPatientData(ctx, patient)
+	require.NoError(t, err)
+	assert.Equal(t, export.MedicalHistoryFiles, again.MedicalHistoryFiles,
+		"Exports of unchanged data are the same")
+}
+
+func TestExportPatientData_FullLastPage(t *testing.T) {
+	db := newExportDb()
+	patient := db.addPatient("Jana", "Nováková")
+	for i := range exportFilesPageSize {
+		db.addFile(patient, fmt.Sprintf("lab_result_%d.pdf", i), time.Now())
+	}
+
+	export, err := New(db, 0).ExportPatientData(asPatient(patient), patient)
+	require.NoError(t, err)
+	assert.Len(t, export.MedicalHistoryFiles, exportFilesPageSize)
+	assert.Equal(t, 1, db.pages, "No empty page is read after the last one")
+}
+
+func TestExportPatientData_Forbidden(t *testing.T) {
+	db := newExportDb()
+	patient := db.addPatient("Jana", "Nováková")
+	other := db.addPatient("Peter", "Novák")
+
+	_, err := New(db, 0).ExportPatientData(context.Background(), patient)
+	assert.ErrorIs(t, err, ErrForbidden, "Requests without an acting user aren't allowed")
+	_, err = New(db, 0).ExportPatientData(asPatient(other), patient)
+	assert.ErrorIs(t, err, ErrForbidden, "Patients can't export data of others")
+}
+
+func asPatient(patientId uuid.UUID) context.Context {
+	return data.WithActor(
+		context.Background(),
+		data.Actor{Id: patientId, Role: string(api.UserRolePatient)},
+	)
+}
+
+// exportDb is an in-memory data.Db holding the records of exported patients,
+// files are paged in the order they were added.
+type exportDb struct {
+	*countingDb
+	files []data.MedicalHistoryFile
+	pages int
+}
+
+func newExportDb() *exportDb {
+	return &exportDb{countingDb: newCountingDb(0)}
+}
+
+func (db *exportDb) addFile(patientId uuid.UUID, name string, uploadedAt time.Time) {
+	db.files = append(db.files, data.MedicalHistoryFile{
+		Id:         uuid.New(),
+		PatientId:  patientId,
+		Name:       name,
+		UploadedAt: uploadedAt,
+	})
+}
+
+func (db *exportDb) MedicalHistoryFilesByPatientId(
+	_ context.Context,
+	patientId uuid.UUID,
+	page int,
+	pageSize int,
+) ([]data.MedicalHistoryFile, data.PaginationResult, error) {
+	db.pages++
+	files := make([]data.MedicalHistoryFile, 0)
+	for _, file := range db.files {
+		if file.PatientId == patientId {
+			files = append(files, file)
+		}
+	}
+
+	result := data.PaginationResult{Total: int64(len(files)), Page: page, PageSize: pageSize}
+	start := min(page*pageSize, len(files))
+	end := min(start+pageSize, len(files))
+	return files[start:end], result, nil
+}
+
+func (db *exportDb) AppointmentsByPatientId(
+	_ context.Context,
+	patientId uuid.UUID,
+	_ time.Time,
+	_ *time.Time,
+) ([]data.Appointment, error) {
+	appts := make([]data.Appointment, 0)
+	for _, appt := range db.appointments {
+		if appt.PatientId == patientId {
+			appts = append(appts, appt)
+		}
+	}
+	return appts, nil
+}
+
+func (db *exportDb) ReservationsByAppointmentIds(
+	context.Context,
+	[]uuid.UUID,
+) ([]data.Reservation, error) {
+	return nil, nil
+}
+
+func (db *exportDb) VisitNotesByAppointmentIds(
+	context.Context,
+	[]uuid.UUID,
+) ([]data.VisitNote, error) {
+	return nil, nil
+}
+
+func (db *exportDb) ReferralsByPatientId(context.Context, uuid.UUID) ([]data.Referral, error) {
+	return nil, nil
+}
@@ -0,0 +1,206 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+const (
+	InvalidProfileCode = "patient.invalid-profile"
+
+	// defaultCountry of addresses which don't name one.
+	defaultCountry = "SK"
+	// slovakCallingCode replaces the leading zero of Slovak phone numbers.
+	slovakCallingCode = "+421"
+)
+
+var (
+	internationalPhone = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	slovakPhone        = regexp.MustCompile(`^0[1-9][0-9]{8}$`)
+	phoneSeparators    = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "", "/", "")
+	countryCode        = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// UpdatePatientProfile replaces the names and the profile of the patient.
+func (a monolithApp) UpdatePatientProfile(
+	ctx context.Context,
+	patientId uuid.UUID,
+	req api.PatientProfileUpdate,
+	ifMatch Precondition,
+) (api.Patient, error) {
+	if err := a.authorizeProfileUpdate(ctx, patientId); err != nil {
+		return api.Patient{}, fmt.Errorf("UpdatePatientProfile: %w", err)
+	}
+
+	patient, err := a.db.PatientById(ctx, patientId)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return api.Patient{}, fmt.Errorf("UpdatePatientProfile: %w", ErrNotFound)
+		}
+		return api.Patient{}, fmt.Errorf("UpdatePatientProfile fetch failed: %w", err)
+	}
+	if !ifMatch.Matches(patient.Version) {
+		return api.Patient{}, fmt.Errorf("UpdatePatientProfile: %w", ErrPreconditionFailed)
+	}
+
+	patient.FirstName = req.FirstName
+	patient.LastName = req.LastName
+	patient = patientProfileToData(patient, api.PatientProfile{
+		DateOfBirth:      req.DateOfBirth,
+		Sex:              req.Sex,
+		Phone:            req.Phone,
+		NationalId:       req.NationalId,
+		Address:          req.Address,
+		Insurance:        req.Insurance,
+		EmergencyContact: req.EmergencyContact,
+	})
+	if err := validatePatientProfile(patient); err != nil {
+		return api.Patient{}, fmt.Errorf("UpdatePatientProfile: %w", err)
+	}
+
+	patient, err = a.db.UpdatePatient(ctx, patientId, patient)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return api.Patient{}, fmt.Errorf("UpdatePatientProfile: %w", ErrNotFound)
+		}
+		if isVersionConflict(err) {
+			return api.Patient{}, ifMatch.versionConflict("UpdatePatientProfile")
+		}
+		return api.Patient{}, fmt.Errorf("UpdatePatientProfile update failed: %w", err)
+	}
+
+	return dataPatientToApiPatient(patient), nil
+}
+
+// authorizeProfileUpdate checks that the acting user can update the patient's
+// profile, the patient can always update their own. Requests without an
+// acting user aren't allowed.
+func (a monolithApp) authorizeProfileUpdate(ctx context.Context, patientId uuid.UUID) error {
+	if isActingPatient(ctx, patientId) {
+		return nil
+	}
+	return a.requirePermission(ctx, permEditPatientProfiles)
+}
+
+// validatePatientProfile checks the normalized profile of the patient, see
+// patientProfileToData.
+func validatePatientProfile(p data.Patient) error {
+	if p.DateOfBirth != nil && p.DateOfBirth.After(time.Now()) {
+		return invalidProfile("Date of birth can't be in the future")
+	}
+	if p.Phone != nil && !internationalPhone.MatchString(*p.Phone) {
+		return invalidProfile(fmt.Sprintf("Phone number %q isn't valid", *p.Phone))
+	}
+	if p.EmergencyContact != nil && !internationalPhone.MatchString(p.EmergencyContact.Phone) {
+		return invalidProfile(
+			fmt.Sprintf("Emergency contact's phone number %q isn't valid", p.EmergencyContact.Phone),
+		)
+	}
+	if p.Address != nil && !countryCode.MatchString(p.Address.Country) {
+		return invalidProfile(fmt.Sprintf("Country %q isn't a two letter code", p.Address.Country))
+	}
+	if p.NationalId != nil {
+		born, ok := birthNumberDate(*p.NationalId)
+		if !ok {
+			return invalidProfile(fmt.Sprintf("Birth number %q isn't valid", *p.NationalId))
+		}
+		if p.DateOfBirth != nil && !sameDay(born, *p.DateOfBirth) {
+			return invalidProfile("Birth number doesn't match the date of birth")
+		}
+	}
+	return nil
+}
+
+// birthNumberDate returns the date of birth encoded in the Slovak birth
+// number, YYMMDD followed by three digits before 1954 and four since. Women
+// have 50 added to the month, and since 2004 20 may be added too. Ten digit
+// numbers are divisible by 11, or the first nine digits give remainder 10 and
+// the last one is 0.
+func birthNumberDate(id string) (time.Time, bool) {
+	if len(id) != 9 && len(id) != 10 {
+		return time.Time{}, false
+	}
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return time.Time{}, false
+	}
+
+	year, _ := strconv.Atoi(id[0:2])
+	month, _ := strconv.Atoi(id[2:4])
+	day, _ := strconv.Atoi(id[4:6])
+	switch {
+	case month > 70:
+		month -= 70
+	case month > 50:
+		month -= 50
+	case month > 20:
+		month -= 20
+	}
+
+	if len(id) == 9 {
+		if year >= 54 {
+			return time.Time{}, false
+		}
+		year += 1900
+	} else {
+		number, _ := strconv.ParseUint(id, 10, 64)
+		if number%11 != 0 && !(number/10%11 == 10 && number%10 == 0) {
+			return time.Time{}, false
+		}
+		if year < 54 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+	}
+
+	born := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if month < 1 || month > 12 || born.Day() != day {
+		return time.Time{}, false
+	}
+	return born, true
+}
+
+// normalizeNationalId removes the slash and spaces from the birth number,
+// "805412/1240" is stored as "8054121240".
+func normalizeNationalId(id string) string {
+	return strings.NewReplacer("/", "", " ", "").Replace(id)
+}
+
+// normalizePhone removes separators from the phone number and makes Slovak
+// numbers international, "0905 123 456" is stored as "+421905123456".
+func normalizePhone(phone string) string {
+	phone = phoneSeparators.Replace(strings.TrimSpace(phone))
+	switch {
+	case strings.HasPrefix(phone, "00"):
+		return "+" + phone[2:]
+	case slovakPhone.MatchString(phone):
+		return slovakCallingCode + phone[1:]
+	}
+	return phone
+}
+
+func sameDay(a time.Time, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+func invalidProfile(detail string) *ValidationError {
+	return &ValidationError{ErrorDetail: api.ErrorDetail{
+		Code:   InvalidProfileCode,
+		Title:  "Invalid patient profile",
+		Detail: detail,
+		Status: http.StatusBadRequest,
+	}}
+}
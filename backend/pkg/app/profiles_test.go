@@ -0,0 +1,69 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+)
+
+func TestBirthNumberDate(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		born time.Time
+	}{
+		{name: "man", id: "7503080002", born: time.Date(1975, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{name: "woman", id: "8054121240", born: time.Date(1980, 4, 12, 0, 0, 0, 0, time.UTC)},
+		{
+			name: "woman since 2004",
+			id:   "0572150007",
+			born: time.Date(2005, 2, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "remainder 10",
+			id:   "8054120020",
+			born: time.Date(1980, 4, 12, 0, 0, 0, 0, time.UTC),
+		},
+		{name: "before 1954", id: "385120123", born: time.Date(1938, 1, 20, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			born, ok := birthNumberDate(tc.id)
+			require.True(t, ok)
+			assert.Equal(t, tc.born, born)
+		})
+	}
+}
+
+func TestBirthNumberDate_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"checksum":            "8054121234",
+		"no such day":         "8002300009",
+		"nine digits in 1954": "540101123",
+		"too short":           "80541212",
+		"not a number":        "80541212a4",
+	}
+
+	for name, id := range tests {
+		_, ok := birthNumberDate(id)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := map[string]string{
+		"0905 123 456":       "+421905123456",
+		"02/123 456 78":      "+421212345678",
+		"+421 (905) 123-456": "+421905123456",
+		"00420 601 123 456":  "+420601123456",
+		"+44 20 7946 0958":   "+442079460958",
+	}
+
+	for phone, normalized := range tests {
+		assert.Equal(t, normalized, normalizePhone(phone), phone)
+		assert.Regexp(t, internationalPhone, normalizePhone(phone), phone)
+	}
+}
@@ -0,0 +1,250 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+const (
+	InvalidReferralCode = "referral.invalid"
+
+	defaultReferralValidity = 90 * 24 * time.Hour
+)
+
+// CreateReferral refers the patient to a doctor or a specialization, the
+// acting user must be the referring doctor.
+func (a monolithApp) CreateReferral(ctx context.Context, req api.NewReferral) (api.Referral, error) {
+	if !isActingDoctor(ctx, req.FromDoctorId) {
+		return api.Referral{}, fmt.Errorf("CreateReferral: %w", ErrForbidden)
+	}
+
+	referral := newReferralToData(req)
+	if referral.ExpiresAt.IsZero() {
+		referral.ExpiresAt = time.Now().Add(defaultReferralValidity)
+	}
+
+	switch {
+	case (referral.ToDoctorId == nil) == (referral.ToSpecialization == nil):
+		return api.Referral{}, fmt.Errorf(
+			"CreateReferral: %w",
+			invalidReferral("Exactly one of toDoctorId and toSpecialization is required"),
+		)
+	case referral.ToDoctorId != nil && *referral.ToDoctorId == referral.FromDoctorId:
+		return api.Referral{}, fmt.Errorf(
+			"CreateReferral: %w",
+			invalidReferral("Doctor can't refer a patient to themselves"),
+		)
+	case !referral.ExpiresAt.After(time.Now()):
+		return api.Referral{}, fmt.Errorf(
+			"CreateReferral: %w",
+			invalidReferral("Referral must expire in the future"),
+		)
+	}
+
+	if referral.ConditionId != nil {
+		cond, err := a.db.ConditionById(ctx, *referral.ConditionId)
+		if err != nil && !errors.Is(err, data.ErrNotFound) {
+			return api.Referral{}, fmt.Errorf("CreateReferral find condition: %w", err)
+		}
+		if err != nil || cond.PatientId != referral.PatientId {
+			return api.Referral{}, fmt.Errorf(
+				"CreateReferral: %w",
+				invalidReferral("Condition isn't a condition of the patient"),
+			)
+		}
+	}
+
+	referral, err := a.db.CreateReferral(ctx, referral)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return api.Referral{}, fmt.Errorf(
+				"CreateReferral: %w",
+				invalidReferral("Patient or doctor doesn't exist"),
+			)
+		}
+		return api.Referral{}, fmt.Errorf("CreateReferral: %w", err)
+	}
+
+	return a.referralDetail(ctx, referral)
+}
+
+func (a monolithApp) ReferralById(ctx context.Context, id uuid.UUID) (api.Referral, error) {
+	referral, err := a.db.ReferralById(ctx, id)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return api.Referral{}, fmt.Errorf("ReferralById: %w", ErrNotFound)
+		}
+		return api.Referral{}, fmt.Errorf("ReferralById: %w", err)
+	}
+	return a.referralDetail(ctx, referral)
+}
+
+func (a monolithApp) PatientReferrals(
+	ctx context.Context,
+	patientId uuid.UUID,
+) ([]api.Referral, error) {
+	referrals, err := a.db.ReferralsByPatientId(ctx, patientId)
+	if err != nil {
+		return nil, fmt.Errorf("PatientReferrals: %w", err)
+	}
+
+	result := make([]api.Referral, len(referrals))
+	for i, referral := range referrals {
+		result[i], err = a.referralDetail(ctx, referral)
+		if err != nil {
+			return nil, fmt.Errorf("PatientReferrals: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// CancelReferral cancels an active referral, only the referring doctor can
+// cancel it.
+func (a monolithApp) CancelReferral(ctx context.Context, id uuid.UUID) (api.Referral, error) {
+	referral, err := a.db.ReferralById(ctx, id)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return api.Referral{}, fmt.Errorf("CancelReferral: %w", ErrNotFound)
+		}
+		return api.Referral{}, fmt.Errorf("CancelReferral: %w", err)
+	}
+	if !isActingDoctor(ctx, referral.FromDoctorId) {
+		return api.Referral{}, fmt.Errorf("CancelReferral: %w", ErrForbidden)
+	}
+
+	referral, err = a.db.CancelReferral(ctx, id)
+	if err != nil {
+		return api.Referral{}, fmt.Errorf("CancelReferral: %w", referralError(err))
+	}
+	return a.referralDetail(ctx, referral)
+}
+
+// checkReferral validates that the requested appointment can be booked
+// against its referral, and fills in the referral's condition if the request
+// has none.
+func (a monolithApp) checkReferral(
+	ctx context.Context,
+	appt *data.Appointment,
+) error {
+	referral, err := a.db.ReferralById(ctx, *appt.ReferralId)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return invalidReferral("Referral doesn't exist")
+		}
+		return fmt.Errorf("checkReferral: %w", err)
+	}
+
+	if referral.PatientId != appt.PatientId {
+		return invalidReferral("Referral is for another patient")
+	}
+	if referralStatus(referral) != api.ReferralActive {
+		return ErrReferralNotActive
+	}
+
+	if referral.ToDoctorId != nil && *referral.ToDoctorId != appt.DoctorId {
+		return invalidReferral("Referral is for another doctor")
+	}
+	if referral.ToSpecialization != nil {
+		doctor, err := a.db.DoctorById(ctx, appt.DoctorId)
+		if err != nil {
+			if errors.Is(err, data.ErrNotFound) {
+				return invalidReferral("Doctor doesn't exist")
+			}
+			return fmt.Errorf("checkReferral find doctor: %w", err)
+		}
+		if !slices.Contains(doctor.Specializations, *referral.ToSpecialization) {
+			return invalidReferral("Referral is for another specialization")
+		}
+	}
+
+	if appt.ConditionId == nil {
+		appt.ConditionId = referral.ConditionId
+	}
+	return nil
+}
+
+// referral returns the referral the appointment was booked against, nil if
+// there is none.
+func (a monolithApp) referral(
+	ctx context.Context,
+	appointment data.Appointment,
+) (*api.Referral, error) {
+	if appointment.ReferralId == nil {
+		return nil, nil
+	}
+
+	referral, err := a.db.ReferralById(ctx, *appointment.ReferralId)
+	if errors.Is(err, data.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	detail, err := a.referralDetail(ctx, referral)
+	if err != nil {
+		return nil, err
+	}
+	return &detail, nil
+}
+
+func (a monolithApp) referralDetail(ctx context.Context, referral data.Referral) (api.Referral, error) {
+	fromDoctor, err := a.db.DoctorById(ctx, referral.FromDoctorId)
+	if err != nil {
+		return api.Referral{}, fmt.Errorf("referralDetail find doctor: %w", err)
+	}
+
+	var cond *data.Condition
+	if referral.ConditionId != nil {
+		c, err := a.db.ConditionById(ctx, *referral.ConditionId)
+		if err != nil && !errors.Is(err, data.ErrNotFound) {
+			return api.Referral{}, fmt.Errorf("referralDetail find condition: %w", err)
+		}
+		if err == nil {
+			cond = &c
+		}
+	}
+
+	return dataReferralToApi(referral, fromDoctor, cond), nil
+}
+
+// referralStatus is the status of the referral, an active referral past its
+// expiry is expired.
+func referralStatus(referral data.Referral) api.ReferralStatus {
+	if referral.Status == data.ReferralActive && !referral.ExpiresAt.After(time.Now()) {
+		return api.ReferralExpired
+	}
+	return api.ReferralStatus(referral.Status)
+}
+
+func isActingDoctor(ctx context.Context, doctorId uuid.UUID) bool {
+	actor, ok := data.ActorFromContext(ctx)
+	return ok && actor.Role == string(api.UserRoleDoctor) && actor.Id == doctorId
+}
+
+func invalidReferral(detail string) *ValidationError {
+	return &ValidationError{ErrorDetail: api.ErrorDetail{
+		Code:   InvalidReferralCode,
+		Title:  "Invalid referral",
+		Detail: detail,
+		Status: http.StatusBadRequest,
+	}}
+}
+
+func referralError(err error) error {
+	switch {
+	case errors.Is(err, data.ErrReferralNotActive):
+		return ErrReferralNotActive
+	case errors.Is(err, data.ErrNotFound):
+		return ErrNotFound
+	}
+	return err
+}
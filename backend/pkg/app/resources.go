@@ -16,16 +16,27 @@ func (a monolithApp) CreateResource(
 	ctx context.Context,
 	resource api.NewResource,
 ) (api.NewResource, error) {
-	res, err := a.db.CreateResource(ctx, resource.Name, data.ResourceType(resource.Type))
+	if err := a.requirePermission(ctx, permManageResources); err != nil {
+		return api.NewResource{}, fmt.Errorf("CreateResource: %w", err)
+	}
+
+	res, err := a.db.CreateResource(
+		ctx,
+		resource.Name,
+		data.ResourceType(resource.Type),
+		resource.LocationId,
+	)
 	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return api.NewResource{}, fmt.Errorf(
+				"CreateResource: %w",
+				invalidLocation("Location doesn't exist"),
+			)
+		}
 		return api.NewResource{}, fmt.Errorf("CreateResource: %w", err)
 	}
 
-	return api.NewResource{
-		Id:   &res.Id,
-		Name: res.Name,
-		Type: api.ResourceType(res.Type),
-	}, nil
+	return dataResourceToApi(res), nil
 }
 
 func (a monolithApp) ReserveResource(
@@ -52,11 +63,29 @@ func (a monolithApp) ReserveResource(
 	return nil
 }
 
+// AvailableResources returns resources free at the time, if locationId is
+// given only facilities at the location are offered.
 func (a monolithApp) AvailableResources(
 	ctx context.Context,
 	dateTime time.Time,
+	locationId *uuid.UUID,
 ) (api.AvailableResources, error) {
-	resources, err := a.db.FindAvailableResourcesAtTime(ctx, dateTime)
+	var facilityLocationIds []uuid.UUID
+	if locationId != nil {
+		locations, err := a.locationTree(ctx)
+		if err != nil {
+			return api.AvailableResources{}, fmt.Errorf("AvailableResources: %w", err)
+		}
+		if _, ok := locations[*locationId]; !ok {
+			return api.AvailableResources{}, fmt.Errorf(
+				"AvailableResources: %w",
+				invalidLocation("Location doesn't exist"),
+			)
+		}
+		facilityLocationIds = locations.within(*locationId)
+	}
+
+	resources, err := a.db.FindAvailableResourcesAtTime(ctx, dateTime, facilityLocationIds)
 	if err != nil {
 		return api.AvailableResources{}, fmt.Errorf("AvailableResources: %w", err)
 	}
@@ -175,6 +204,9 @@ func (a monolithApp) ReserveAppointmentResources(
 				resourceId,
 			)
 		}
+		if err := a.checkFacilities(ctx, appointment, []uuid.UUID{resourceId}); err != nil {
+			return api.DoctorAppointment{}, fmt.Errorf("ReserveAppointmentResources: %w", err)
+		}
 		_, err = a.db.CreateReservation(
 			ctx,
 			appointmentId,
@@ -301,6 +333,21 @@ func (a monolithApp) ReserveAppointmentResources(
 		)
 	}
 
+	note, err := a.visitNote(ctx, appointmentId)
+	if err != nil {
+		return api.DoctorAppointment{}, fmt.Errorf("ReserveAppointmentResources fetch visit note: %w", err)
+	}
+
+	location, err := a.appointmentLocation(ctx, appointment)
+	if err != nil {
+		return api.DoctorAppointment{}, fmt.Errorf("ReserveAppointmentResources fetch location: %w", err)
+	}
+
+	bookedBy, err := a.actingUser(ctx, appointment.BookedBy)
+	if err != nil {
+		return api.DoctorAppointment{}, fmt.Errorf("ReserveAppointmentResources fetch booked by: %w", err)
+	}
+
 	doctorAppointment := dataApptToDoctorAppt(
 		appointment,
 		patient,
@@ -309,7 +356,10 @@ func (a monolithApp) ReserveAppointmentResources(
 		equipment,
 		medicine,
 		prescriptions,
+		note,
 	)
+	doctorAppointment.Location = location
+	doctorAppointment.BookedBy = bookedBy
 
 	return doctorAppointment, nil
 }
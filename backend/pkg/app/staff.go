@@ -0,0 +1,88 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+// CreateStaff creates a staff member of the clinic, the acting user must be
+// an administrator. The first administrator is created with wacctl.
+func (a monolithApp) CreateStaff(ctx context.Context, req api.NewStaff) (api.Staff, error) {
+	if err := a.authorizeAdmin(ctx); err != nil {
+		return api.Staff{}, fmt.Errorf("CreateStaff: %w", err)
+	}
+
+	staff, err := a.db.CreateStaff(ctx, newStaffToData(req))
+	if err != nil {
+		if errors.Is(err, data.ErrDuplicateEmail) {
+			return api.Staff{}, fmt.Errorf("CreateStaff: %w", ErrDuplicateEmail)
+		}
+		return api.Staff{}, fmt.Errorf("CreateStaff: %w", err)
+	}
+
+	return dataStaffToApi(staff), nil
+}
+
+// StaffById returns the staff member, the acting user must be an
+// administrator or the staff member.
+func (a monolithApp) StaffById(ctx context.Context, id uuid.UUID) (api.Staff, error) {
+	actor, ok := data.ActorFromContext(ctx)
+	if !ok || actor.Id != id {
+		if err := a.authorizeAdmin(ctx); err != nil {
+			return api.Staff{}, fmt.Errorf("StaffById: %w", err)
+		}
+	}
+
+	staff, err := a.db.StaffById(ctx, id)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return api.Staff{}, fmt.Errorf("StaffById: %w", ErrNotFound)
+		}
+		return api.Staff{}, fmt.Errorf("StaffById: %w", err)
+	}
+
+	return dataStaffToApi(staff), nil
+}
+
+func (a monolithApp) StaffByEmail(ctx context.Context, email string) (api.Staff, error) {
+	staff, err := a.db.StaffByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return api.Staff{}, fmt.Errorf("StaffByEmail: %w", ErrNotFound)
+		}
+		return api.Staff{}, fmt.Errorf("StaffByEmail: %w", err)
+	}
+
+	return dataStaffToApi(staff), nil
+}
+
+// ClinicStaff returns the staff of the clinic, the acting user must be an
+// administrator.
+func (a monolithApp) ClinicStaff(ctx context.Context) ([]api.Staff, error) {
+	if err := a.authorizeAdmin(ctx); err != nil {
+		return nil, fmt.Errorf("ClinicStaff: %w", err)
+	}
+
+	staff, err := a.db.ClinicStaff(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ClinicStaff: %w", err)
+	}
+
+	result := make([]api.Staff, len(staff))
+	for i, s := range staff {
+		result[i] = dataStaffToApi(s)
+	}
+	return result, nil
+}
+
+// authorizeAdmin checks that the acting user manages staff, anyone could make
+// themselves staff if requests without an acting user were allowed.
+func (a monolithApp) authorizeAdmin(ctx context.Context) error {
+	return a.requirePermission(ctx, permManageStaff)
+}
@@ -0,0 +1,183 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+const (
+	InvalidSuggestionSearchCode = "suggestions.invalid-search"
+
+	defaultSuggestionRange = 14 * 24 * time.Hour
+	maxSuggestionRange     = 31 * 24 * time.Hour
+	defaultSuggestionLimit = 20
+	// afternoonHour is the hour at which the afternoon starts.
+	afternoonHour = 12
+)
+
+// interval is a half open time range [start, end).
+type interval struct {
+	start time.Time
+	end   time.Time
+}
+
+func (i interval) overlaps(start, end time.Time) bool {
+	return i.start.Before(end) && i.end.After(start)
+}
+
+// SuggestAppointments returns the earliest free slots of the doctors of the
+// specialization. Slots at the same time are ordered by the doctor's name,
+// with the preferred doctor first.
+func (a monolithApp) SuggestAppointments(
+	ctx context.Context,
+	params api.SuggestAppointmentsParams,
+) ([]api.AppointmentSuggestion, error) {
+	from := dayStart(params.From.Time)
+	to := from.Add(defaultSuggestionRange)
+	if params.To != nil {
+		to = dayStart(params.To.Time).AddDate(0, 0, 1)
+	}
+	switch {
+	case !to.After(from):
+		return nil, fmt.Errorf(
+			"SuggestAppointments: %w",
+			invalidSuggestionSearch("To can't be before from"),
+		)
+	case to.Sub(from) > maxSuggestionRange:
+		return nil, fmt.Errorf(
+			"SuggestAppointments: %w",
+			invalidSuggestionSearch("Range can span at most 31 days"),
+		)
+	}
+
+	limit := defaultSuggestionLimit
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+
+	var facilityIds []uuid.UUID
+	if params.FacilityIds != nil {
+		facilityIds = *params.FacilityIds
+	}
+	for _, id := range facilityIds {
+		resource, err := a.db.ResourceById(ctx, id)
+		if err != nil && !errors.Is(err, data.ErrNotFound) {
+			return nil, fmt.Errorf("SuggestAppointments find facility: %w", err)
+		}
+		if err != nil || resource.Type != data.ResourceTypeFacility {
+			return nil, fmt.Errorf(
+				"SuggestAppointments: %w",
+				invalidSuggestionSearch(fmt.Sprintf("Facility %s doesn't exist", id)),
+			)
+		}
+	}
+
+	doctors, err := a.db.DoctorsBySpecialization(ctx, string(params.Specialization))
+	if err != nil {
+		return nil, fmt.Errorf("SuggestAppointments: %w", err)
+	}
+	if params.PreferredDoctorId != nil {
+		i := slices.IndexFunc(doctors, func(d data.Doctor) bool {
+			return d.Id == *params.PreferredDoctorId
+		})
+		if i > 0 {
+			preferred := doctors[i]
+			doctors = slices.Insert(slices.Delete(doctors, i, i+1), 0, preferred)
+		}
+	}
+
+	doctorIds := make([]uuid.UUID, len(doctors))
+	for i, doctor := range doctors {
+		doctorIds[i] = doctor.Id
+	}
+	appointments, err := a.db.ActiveAppointmentsByDoctorIds(ctx, doctorIds, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("SuggestAppointments: %w", err)
+	}
+	busy := make(map[uuid.UUID][]interval, len(doctors))
+	for _, appt := range appointments {
+		busy[appt.DoctorId] = append(
+			busy[appt.DoctorId],
+			interval{appt.AppointmentDateTime, appt.EndTime},
+		)
+	}
+
+	reservations, err := a.db.ReservationsByResourceIds(ctx, facilityIds, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("SuggestAppointments: %w", err)
+	}
+	reserved := make([]interval, len(reservations))
+	for i, reservation := range reservations {
+		reserved[i] = interval{reservation.StartTime, reservation.EndTime}
+	}
+
+	suggestions := make([]api.AppointmentSuggestion, 0, limit)
+	now := time.Now()
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		for hour := firstSlotHour; hour <= lastSlotHour; hour++ {
+			start := day.Add(time.Duration(hour) * time.Hour)
+			end := start.Add(appointmentDuration)
+			if start.Before(now) || !inTimesOfDay(hour, params.TimesOfDay) {
+				continue
+			}
+			if slices.ContainsFunc(reserved, func(i interval) bool { return i.overlaps(start, end) }) {
+				continue
+			}
+
+			for _, doctor := range doctors {
+				if slices.ContainsFunc(
+					busy[doctor.Id],
+					func(i interval) bool { return i.overlaps(start, end) },
+				) {
+					continue
+				}
+
+				suggestions = append(suggestions, api.AppointmentSuggestion{
+					AppointmentDateTime: start,
+					Doctor:              dataDoctorToApiDoctor(doctor),
+				})
+				if len(suggestions) == limit {
+					return suggestions, nil
+				}
+			}
+		}
+	}
+
+	return suggestions, nil
+}
+
+// dayStart is the midnight of the t's day in UTC.
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// inTimesOfDay reports whether a slot starting at the hour falls within one of
+// the times of day, any hour does if none are given.
+func inTimesOfDay(hour int, timesOfDay *api.TimesOfDay) bool {
+	if timesOfDay == nil || len(*timesOfDay) == 0 {
+		return true
+	}
+	timeOfDay := api.Morning
+	if hour >= afternoonHour {
+		timeOfDay = api.Afternoon
+	}
+	return slices.Contains(*timesOfDay, timeOfDay)
+}
+
+func invalidSuggestionSearch(detail string) *ValidationError {
+	return &ValidationError{ErrorDetail: api.ErrorDetail{
+		Code:   InvalidSuggestionSearchCode,
+		Title:  "Invalid suggestion search",
+		Detail: detail,
+		Status: http.StatusBadRequest,
+	}}
+}
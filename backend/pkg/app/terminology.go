@@ -0,0 +1,33 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+	"github.com/Nesquiko/wac/pkg/terminology"
+)
+
+const UnknownDiagnosisCode = "condition.unknown-diagnosis"
+
+func (a monolithApp) SearchIcd10(query string, limit int) []api.Icd10Code {
+	return Map(terminology.ICD10().Search(query, limit), func(c terminology.Code) api.Icd10Code {
+		return api.Icd10Code{Code: c.Code, Display: c.Display}
+	})
+}
+
+// diagnosisOf returns the diagnosis of the ICD-10 code, or a ValidationError
+// if there is no such code.
+func diagnosisOf(code string) (*data.Diagnosis, error) {
+	found, ok := terminology.ICD10().Lookup(code)
+	if !ok {
+		return nil, &ValidationError{ErrorDetail: api.ErrorDetail{
+			Code:   UnknownDiagnosisCode,
+			Title:  "Unknown diagnosis code",
+			Detail: fmt.Sprintf("%q is not an ICD-10 code", code),
+			Status: http.StatusBadRequest,
+		}}
+	}
+	return &data.Diagnosis{Code: found.Code, Display: found.Display}, nil
+}
@@ -0,0 +1,660 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/fhir"
+)
+
+// tracer follows the global tracer provider, set by the server on start.
+var tracer = otel.Tracer("github.com/Nesquiko/wac/pkg/app")
+
+// Traced returns the app recording a span of every call of its methods that
+// takes a context.
+func Traced(a App) App {
+	return tracedApp{a}
+}
+
+type tracedApp struct {
+	App
+}
+
+func startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "app."+method)
+}
+
+// endSpan ends the span, marked as failed if err isn't nil, and returns err.
+func endSpan(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	return err
+}
+
+func (t tracedApp) CreateAppointment(
+	ctx context.Context,
+	appt api.NewAppointmentRequest,
+) (api.PatientAppointment, error) {
+	ctx, span := startSpan(ctx, "CreateAppointment")
+	res, err := t.App.CreateAppointment(ctx, appt)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) BookAppointment(
+	ctx context.Context,
+	req api.StaffBooking,
+) (api.DoctorAppointment, error) {
+	ctx, span := startSpan(ctx, "BookAppointment")
+	res, err := t.App.BookAppointment(ctx, req)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) CancelAppointment(
+	ctx context.Context,
+	appointmentId uuid.UUID,
+	req api.AppointmentCancellation,
+) error {
+	ctx, span := startSpan(ctx, "CancelAppointment")
+	return endSpan(span, t.App.CancelAppointment(ctx, appointmentId, req))
+}
+
+func (t tracedApp) PatientsAppointmentById(
+	ctx context.Context,
+	patientId uuid.UUID,
+	appointmentId uuid.UUID,
+) (api.PatientAppointment, error) {
+	ctx, span := startSpan(ctx, "PatientsAppointmentById")
+	res, err := t.App.PatientsAppointmentById(ctx, patientId, appointmentId)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) DoctorsAppointmentById(
+	ctx context.Context,
+	doctorId uuid.UUID,
+	appointmentId uuid.UUID,
+) (api.DoctorAppointment, error) {
+	ctx, span := startSpan(ctx, "DoctorsAppointmentById")
+	res, err := t.App.DoctorsAppointmentById(ctx, doctorId, appointmentId)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) DecideAppointment(
+	ctx context.Context,
+	appointmentId uuid.UUID,
+	decision api.AppointmentDecision,
+) (api.DoctorAppointment, error) {
+	ctx, span := startSpan(ctx, "DecideAppointment")
+	res, err := t.App.DecideAppointment(ctx, appointmentId, decision)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) RescheduleAppointment(
+	ctx context.Context,
+	appointmentId api.AppointmentId,
+	newDateTime time.Time,
+) (api.PatientAppointment, error) {
+	ctx, span := startSpan(ctx, "RescheduleAppointment")
+	res, err := t.App.RescheduleAppointment(ctx, appointmentId, newDateTime)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) ReassignAppointment(
+	ctx context.Context,
+	appointmentId uuid.UUID,
+	doctorId uuid.UUID,
+) (api.DoctorAppointment, error) {
+	ctx, span := startSpan(ctx, "ReassignAppointment")
+	res, err := t.App.ReassignAppointment(ctx, appointmentId, doctorId)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) CompleteAppointment(
+	ctx context.Context,
+	appointmentId uuid.UUID,
+) (api.DoctorAppointment, error) {
+	ctx, span := startSpan(ctx, "CompleteAppointment")
+	res, err := t.App.CompleteAppointment(ctx, appointmentId)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) VisitNote(ctx context.Context, appointmentId uuid.UUID) (api.VisitNote, error) {
+	ctx, span := startSpan(ctx, "VisitNote")
+	res, err := t.App.VisitNote(ctx, appointmentId)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) SaveVisitNote(
+	ctx context.Context,
+	appointmentId uuid.UUID,
+	note api.UpdateVisitNote,
+) (api.VisitNote, error) {
+	ctx, span := startSpan(ctx, "SaveVisitNote")
+	res, err := t.App.SaveVisitNote(ctx, appointmentId, note)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) CreatePatient(
+	ctx context.Context,
+	p api.PatientRegistration,
+) (api.Patient, error) {
+	ctx, span := startSpan(ctx, "CreatePatient")
+	res, err := t.App.CreatePatient(ctx, p)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) PatientById(ctx context.Context, id uuid.UUID) (api.Patient, error) {
+	ctx, span := startSpan(ctx, "PatientById")
+	res, err := t.App.PatientById(ctx, id)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) PatientByEmail(ctx context.Context, email string) (api.Patient, error) {
+	ctx, span := startSpan(ctx, "PatientByEmail")
+	res, err := t.App.PatientByEmail(ctx, email)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) SearchPatients(
+	ctx context.Context,
+	params api.SearchPatientsParams,
+) (api.PatientList, error) {
+	ctx, span := startSpan(ctx, "SearchPatients")
+	res, err := t.App.SearchPatients(ctx, params)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) UpdatePatientProfile(
+	ctx context.Context,
+	patientId uuid.UUID,
+	req api.PatientProfileUpdate,
+	ifMatch Precondition,
+) (api.Patient, error) {
+	ctx, span := startSpan(ctx, "UpdatePatientProfile")
+	res, err := t.App.UpdatePatientProfile(ctx, patientId, req, ifMatch)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) PatientsCalendar(
+	ctx context.Context,
+	patientId uuid.UUID,
+	params api.PatientsCalendarParams,
+	list ListParams,
+) (api.PatientsCalendar, error) {
+	ctx, span := startSpan(ctx, "PatientsCalendar")
+	res, err := t.App.PatientsCalendar(ctx, patientId, params, list)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) PatientMedicalHistoryFiles(
+	ctx context.Context,
+	patientId uuid.UUID,
+	page int,
+	pageSize int,
+) (api.MedicalHistoryFileList, error) {
+	ctx, span := startSpan(ctx, "PatientMedicalHistoryFiles")
+	res, err := t.App.PatientMedicalHistoryFiles(ctx, patientId, page, pageSize)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) SharePatient(
+	ctx context.Context,
+	patientId uuid.UUID,
+	clinicId uuid.UUID,
+) (api.Patient, error) {
+	ctx, span := startSpan(ctx, "SharePatient")
+	res, err := t.App.SharePatient(ctx, patientId, clinicId)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) UnsharePatient(
+	ctx context.Context,
+	patientId uuid.UUID,
+	clinicId uuid.UUID,
+) (api.Patient, error) {
+	ctx, span := startSpan(ctx, "UnsharePatient")
+	res, err := t.App.UnsharePatient(ctx, patientId, clinicId)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) ExportPatientData(
+	ctx context.Context,
+	patientId uuid.UUID,
+) (PatientDataExport, error) {
+	ctx, span := startSpan(ctx, "ExportPatientData")
+	res, err := t.App.ExportPatientData(ctx, patientId)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) ErasePatientData(
+	ctx context.Context,
+	patientId uuid.UUID,
+	req api.PatientErasureRequest,
+) (api.PatientErasureReport, error) {
+	ctx, span := startSpan(ctx, "ErasePatientData")
+	res, err := t.App.ErasePatientData(ctx, patientId, req)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) CreateClinic(ctx context.Context, req api.NewClinic) (api.Clinic, error) {
+	ctx, span := startSpan(ctx, "CreateClinic")
+	res, err := t.App.CreateClinic(ctx, req)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) ClinicById(ctx context.Context, id uuid.UUID) (api.Clinic, error) {
+	ctx, span := startSpan(ctx, "ClinicById")
+	res, err := t.App.ClinicById(ctx, id)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) AuthorizeClinic(ctx context.Context) error {
+	ctx, span := startSpan(ctx, "AuthorizeClinic")
+	return endSpan(span, t.App.AuthorizeClinic(ctx))
+}
+
+func (t tracedApp) CreateLocation(ctx context.Context, req api.NewLocation) (api.Location, error) {
+	ctx, span := startSpan(ctx, "CreateLocation")
+	res, err := t.App.CreateLocation(ctx, req)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) LocationById(ctx context.Context, id uuid.UUID) (api.Location, error) {
+	ctx, span := startSpan(ctx, "LocationById")
+	res, err := t.App.LocationById(ctx, id)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) Locations(ctx context.Context) ([]api.Location, error) {
+	ctx, span := startSpan(ctx, "Locations")
+	res, err := t.App.Locations(ctx)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) CreateStaff(ctx context.Context, req api.NewStaff) (api.Staff, error) {
+	ctx, span := startSpan(ctx, "CreateStaff")
+	res, err := t.App.CreateStaff(ctx, req)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) StaffById(ctx context.Context, id uuid.UUID) (api.Staff, error) {
+	ctx, span := startSpan(ctx, "StaffById")
+	res, err := t.App.StaffById(ctx, id)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) StaffByEmail(ctx context.Context, email string) (api.Staff, error) {
+	ctx, span := startSpan(ctx, "StaffByEmail")
+	res, err := t.App.StaffByEmail(ctx, email)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) ClinicStaff(ctx context.Context) ([]api.Staff, error) {
+	ctx, span := startSpan(ctx, "ClinicStaff")
+	res, err := t.App.ClinicStaff(ctx)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) CreateDoctor(ctx context.Context, d api.DoctorRegistration) (api.Doctor, error) {
+	ctx, span := startSpan(ctx, "CreateDoctor")
+	res, err := t.App.CreateDoctor(ctx, d)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) DoctorById(ctx context.Context, id uuid.UUID) (api.Doctor, error) {
+	ctx, span := startSpan(ctx, "DoctorById")
+	res, err := t.App.DoctorById(ctx, id)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) DoctorByEmail(ctx context.Context, email string) (api.Doctor, error) {
+	ctx, span := startSpan(ctx, "DoctorByEmail")
+	res, err := t.App.DoctorByEmail(ctx, email)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) DoctorsCalendar(
+	ctx context.Context,
+	doctorId uuid.UUID,
+	params api.DoctorsCalendarParams,
+	list ListParams,
+) (api.DoctorCalendar, error) {
+	ctx, span := startSpan(ctx, "DoctorsCalendar")
+	res, err := t.App.DoctorsCalendar(ctx, doctorId, params, list)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) DoctorTimeSlots(
+	ctx context.Context,
+	doctorId uuid.UUID,
+	date time.Time,
+) (api.DoctorTimeslots, error) {
+	ctx, span := startSpan(ctx, "DoctorTimeSlots")
+	res, err := t.App.DoctorTimeSlots(ctx, doctorId, date)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) AvailableDoctors(ctx context.Context, dateTime time.Time) ([]api.Doctor, error) {
+	ctx, span := startSpan(ctx, "AvailableDoctors")
+	res, err := t.App.AvailableDoctors(ctx, dateTime)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) SuggestAppointments(
+	ctx context.Context,
+	params api.SuggestAppointmentsParams,
+) ([]api.AppointmentSuggestion, error) {
+	ctx, span := startSpan(ctx, "SuggestAppointments")
+	res, err := t.App.SuggestAppointments(ctx, params)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) Doctors(
+	ctx context.Context,
+	params api.GetDoctorsParams,
+	list ListParams,
+) (api.Doctors, error) {
+	ctx, span := startSpan(ctx, "Doctors")
+	res, err := t.App.Doctors(ctx, params, list)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) UpdateDoctorProfile(
+	ctx context.Context,
+	doctorId uuid.UUID,
+	req api.DoctorProfileUpdate,
+	ifMatch Precondition,
+) (api.Doctor, error) {
+	ctx, span := startSpan(ctx, "UpdateDoctorProfile")
+	res, err := t.App.UpdateDoctorProfile(ctx, doctorId, req, ifMatch)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) CreatePatientCondition(
+	ctx context.Context,
+	cond api.NewCondition,
+) (api.ConditionDisplay, error) {
+	ctx, span := startSpan(ctx, "CreatePatientCondition")
+	res, err := t.App.CreatePatientCondition(ctx, cond)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) ConditionById(ctx context.Context, id uuid.UUID) (api.Condition, error) {
+	ctx, span := startSpan(ctx, "ConditionById")
+	res, err := t.App.ConditionById(ctx, id)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) UpdatePatientCondition(
+	ctx context.Context,
+	conditionId uuid.UUID,
+	updateData api.UpdateCondition,
+	ifMatch Precondition,
+) (api.Condition, error) {
+	ctx, span := startSpan(ctx, "UpdatePatientCondition")
+	res, err := t.App.UpdatePatientCondition(ctx, conditionId, updateData, ifMatch)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) PatientConditionsOnDate(
+	ctx context.Context,
+	patientId uuid.UUID,
+	date time.Time,
+	list ListParams,
+) (api.Conditions, error) {
+	ctx, span := startSpan(ctx, "PatientConditionsOnDate")
+	res, err := t.App.PatientConditionsOnDate(ctx, patientId, date, list)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) ConditionHistory(
+	ctx context.Context,
+	conditionId uuid.UUID,
+) ([]api.ConditionRevision, error) {
+	ctx, span := startSpan(ctx, "ConditionHistory")
+	res, err := t.App.ConditionHistory(ctx, conditionId)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) ConditionAsOf(
+	ctx context.Context,
+	conditionId uuid.UUID,
+	at time.Time,
+) (api.Condition, error) {
+	ctx, span := startSpan(ctx, "ConditionAsOf")
+	res, err := t.App.ConditionAsOf(ctx, conditionId, at)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) CreateReferral(ctx context.Context, req api.NewReferral) (api.Referral, error) {
+	ctx, span := startSpan(ctx, "CreateReferral")
+	res, err := t.App.CreateReferral(ctx, req)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) ReferralById(ctx context.Context, id uuid.UUID) (api.Referral, error) {
+	ctx, span := startSpan(ctx, "ReferralById")
+	res, err := t.App.ReferralById(ctx, id)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) PatientReferrals(
+	ctx context.Context,
+	patientId uuid.UUID,
+) ([]api.Referral, error) {
+	ctx, span := startSpan(ctx, "PatientReferrals")
+	res, err := t.App.PatientReferrals(ctx, patientId)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) CancelReferral(ctx context.Context, id uuid.UUID) (api.Referral, error) {
+	ctx, span := startSpan(ctx, "CancelReferral")
+	res, err := t.App.CancelReferral(ctx, id)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) CreatePatientPrescription(
+	ctx context.Context,
+	pres api.NewPrescription,
+) (api.Prescription, error) {
+	ctx, span := startSpan(ctx, "CreatePatientPrescription")
+	res, err := t.App.CreatePatientPrescription(ctx, pres)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) UpdatePatientPrescription(
+	ctx context.Context,
+	prescriptionId uuid.UUID,
+	updateData api.UpdatePrescription,
+	ifMatch Precondition,
+) (api.Prescription, error) {
+	ctx, span := startSpan(ctx, "UpdatePatientPrescription")
+	res, err := t.App.UpdatePatientPrescription(ctx, prescriptionId, updateData, ifMatch)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) PrescriptionById(
+	ctx context.Context,
+	prescriptionId uuid.UUID,
+) (api.Prescription, error) {
+	ctx, span := startSpan(ctx, "PrescriptionById")
+	res, err := t.App.PrescriptionById(ctx, prescriptionId)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) DeletePrescription(
+	ctx context.Context,
+	id uuid.UUID,
+	ifMatch Precondition,
+) error {
+	ctx, span := startSpan(ctx, "DeletePrescription")
+	return endSpan(span, t.App.DeletePrescription(ctx, id, ifMatch))
+}
+
+func (t tracedApp) PrescriptionHistory(
+	ctx context.Context,
+	prescriptionId uuid.UUID,
+) ([]api.PrescriptionRevision, error) {
+	ctx, span := startSpan(ctx, "PrescriptionHistory")
+	res, err := t.App.PrescriptionHistory(ctx, prescriptionId)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) PrescriptionAsOf(
+	ctx context.Context,
+	prescriptionId uuid.UUID,
+	at time.Time,
+) (api.Prescription, error) {
+	ctx, span := startSpan(ctx, "PrescriptionAsOf")
+	res, err := t.App.PrescriptionAsOf(ctx, prescriptionId, at)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) CreateResource(
+	ctx context.Context,
+	resource api.NewResource,
+) (api.NewResource, error) {
+	ctx, span := startSpan(ctx, "CreateResource")
+	res, err := t.App.CreateResource(ctx, resource)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) ReserveResource(
+	ctx context.Context,
+	resourceId uuid.UUID,
+	reservation api.ResourceReservation,
+) error {
+	ctx, span := startSpan(ctx, "ReserveResource")
+	return endSpan(span, t.App.ReserveResource(ctx, resourceId, reservation))
+}
+
+func (t tracedApp) AssignResourceLocation(
+	ctx context.Context,
+	resourceId uuid.UUID,
+	req api.ResourceLocation,
+) (api.NewResource, error) {
+	ctx, span := startSpan(ctx, "AssignResourceLocation")
+	res, err := t.App.AssignResourceLocation(ctx, resourceId, req)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) AvailableResources(
+	ctx context.Context,
+	dateTime time.Time,
+	locationId *uuid.UUID,
+) (api.AvailableResources, error) {
+	ctx, span := startSpan(ctx, "AvailableResources")
+	res, err := t.App.AvailableResources(ctx, dateTime, locationId)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) ReserveAppointmentResources(
+	ctx context.Context,
+	appointmentId uuid.UUID,
+	payload api.ReserveAppointmentResourcesJSONBody,
+) (api.DoctorAppointment, error) {
+	ctx, span := startSpan(ctx, "ReserveAppointmentResources")
+	res, err := t.App.ReserveAppointmentResources(ctx, appointmentId, payload)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) FhirPatient(ctx context.Context, id uuid.UUID) (fhir.Patient, error) {
+	ctx, span := startSpan(ctx, "FhirPatient")
+	res, err := t.App.FhirPatient(ctx, id)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) FhirPatients(
+	ctx context.Context,
+	search fhir.PatientSearch,
+) ([]fhir.Patient, error) {
+	ctx, span := startSpan(ctx, "FhirPatients")
+	res, err := t.App.FhirPatients(ctx, search)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) FhirPractitioner(ctx context.Context, id uuid.UUID) (fhir.Practitioner, error) {
+	ctx, span := startSpan(ctx, "FhirPractitioner")
+	res, err := t.App.FhirPractitioner(ctx, id)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) FhirPractitioners(
+	ctx context.Context,
+	search fhir.PractitionerSearch,
+) ([]fhir.Practitioner, error) {
+	ctx, span := startSpan(ctx, "FhirPractitioners")
+	res, err := t.App.FhirPractitioners(ctx, search)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) FhirAppointment(ctx context.Context, id uuid.UUID) (fhir.Appointment, error) {
+	ctx, span := startSpan(ctx, "FhirAppointment")
+	res, err := t.App.FhirAppointment(ctx, id)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) FhirAppointments(
+	ctx context.Context,
+	search fhir.AppointmentSearch,
+) ([]fhir.Appointment, error) {
+	ctx, span := startSpan(ctx, "FhirAppointments")
+	res, err := t.App.FhirAppointments(ctx, search)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) FhirCondition(ctx context.Context, id uuid.UUID) (fhir.Condition, error) {
+	ctx, span := startSpan(ctx, "FhirCondition")
+	res, err := t.App.FhirCondition(ctx, id)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) FhirConditions(
+	ctx context.Context,
+	search fhir.ConditionSearch,
+) ([]fhir.Condition, error) {
+	ctx, span := startSpan(ctx, "FhirConditions")
+	res, err := t.App.FhirConditions(ctx, search)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) FhirMedicationRequest(
+	ctx context.Context,
+	id uuid.UUID,
+) (fhir.MedicationRequest, error) {
+	ctx, span := startSpan(ctx, "FhirMedicationRequest")
+	res, err := t.App.FhirMedicationRequest(ctx, id)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) FhirMedicationRequests(
+	ctx context.Context,
+	search fhir.MedicationRequestSearch,
+) ([]fhir.MedicationRequest, error) {
+	ctx, span := startSpan(ctx, "FhirMedicationRequests")
+	res, err := t.App.FhirMedicationRequests(ctx, search)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) ImportFhirBundle(
+	ctx context.Context,
+	entries []fhir.Entry,
+	dryRun bool,
+) (fhir.ImportReport, error) {
+	ctx, span := startSpan(ctx, "ImportFhirBundle")
+	res, err := t.App.ImportFhirBundle(ctx, entries, dryRun)
+	return res, endSpan(span, err)
+}
+
+func (t tracedApp) Ready(ctx context.Context) error {
+	ctx, span := startSpan(ctx, "Ready")
+	return endSpan(span, t.App.Ready(ctx))
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+func (a monolithApp) VisitNote(ctx context.Context, appointmentId uuid.UUID) (api.VisitNote, error) {
+	note, err := a.db.VisitNoteByAppointmentId(ctx, appointmentId)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return api.VisitNote{}, fmt.Errorf("VisitNote: %w", ErrNotFound)
+		}
+		return api.VisitNote{}, fmt.Errorf("VisitNote: %w", err)
+	}
+	return dataVisitNoteToApi(note), nil
+}
+
+// SaveVisitNote writes the visit note of a scheduled appointment, only the
+// doctor assigned to the appointment can write it. Nurses record its vitals,
+// the rest of the note is kept as it is.
+func (a monolithApp) SaveVisitNote(
+	ctx context.Context,
+	appointmentId uuid.UUID,
+	note api.UpdateVisitNote,
+) (api.VisitNote, error) {
+	appointment, err := a.db.AppointmentById(ctx, appointmentId)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return api.VisitNote{}, fmt.Errorf("SaveVisitNote: %w", ErrNotFound)
+		}
+		return api.VisitNote{}, fmt.Errorf("SaveVisitNote find appointment: %w", err)
+	}
+
+	dataNote := updateVisitNoteToData(note)
+	if !isAssignedDoctor(ctx, appointment) {
+		actor, ok := data.ActorFromContext(ctx)
+		if !ok {
+			return api.VisitNote{}, fmt.Errorf("SaveVisitNote: %w", ErrForbidden)
+		}
+		if err := a.authorizeActor(ctx, actor, permRecordVitals); err != nil {
+			return api.VisitNote{}, fmt.Errorf("SaveVisitNote: %w", err)
+		}
+
+		current, err := a.visitNote(ctx, appointment.Id)
+		if err != nil {
+			return api.VisitNote{}, fmt.Errorf("SaveVisitNote find visit note: %w", err)
+		}
+		vitals := dataNote.Vitals
+		dataNote = data.VisitNote{}
+		if current != nil {
+			dataNote = *current
+		}
+		dataNote.Vitals = vitals
+	}
+	dataNote.AppointmentId = appointment.Id
+	dataNote.DoctorId = appointment.DoctorId
+
+	saved, err := a.db.SaveVisitNote(ctx, dataNote)
+	if err != nil {
+		return api.VisitNote{}, fmt.Errorf("SaveVisitNote: %w", visitNoteError(err))
+	}
+	return dataVisitNoteToApi(saved), nil
+}
+
+// CompleteAppointment completes a scheduled appointment and signs its visit
+// note, only the doctor assigned to the appointment can complete it.
+func (a monolithApp) CompleteAppointment(
+	ctx context.Context,
+	appointmentId uuid.UUID,
+) (api.DoctorAppointment, error) {
+	appointment, err := a.db.AppointmentById(ctx, appointmentId)
+	if err != nil {
+		if errors.Is(err, data.ErrNotFound) {
+			return api.DoctorAppointment{}, fmt.Errorf("CompleteAppointment: %w", ErrNotFound)
+		}
+		return api.DoctorAppointment{}, fmt.Errorf("CompleteAppointment find appointment: %w", err)
+	}
+	if !isAssignedDoctor(ctx, appointment) {
+		return api.DoctorAppointment{}, fmt.Errorf("CompleteAppointment: %w", ErrForbidden)
+	}
+
+	if _, err := a.db.CompleteAppointment(ctx, appointmentId); err != nil {
+		return api.DoctorAppointment{}, fmt.Errorf("CompleteAppointment: %w", visitNoteError(err))
+	}
+
+	return a.DoctorsAppointmentById(ctx, appointment.DoctorId, appointmentId)
+}
+
+// visitNote returns the visit note of the appointment, nil if it has none.
+func (a monolithApp) visitNote(
+	ctx context.Context,
+	appointmentId uuid.UUID,
+) (*data.VisitNote, error) {
+	note, err := a.db.VisitNoteByAppointmentId(ctx, appointmentId)
+	if errors.Is(err, data.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+func isAssignedDoctor(ctx context.Context, appointment data.Appointment) bool {
+	return isActingDoctor(ctx, appointment.DoctorId)
+}
+
+func visitNoteError(err error) error {
+	switch {
+	case errors.Is(err, data.ErrVisitNoteLocked):
+		return ErrVisitNoteLocked
+	case errors.Is(err, data.ErrAppointmentNotScheduled):
+		return ErrNotScheduled
+	case errors.Is(err, data.ErrNotFound):
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,35 @@
+package data
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Actor is the user on whose behalf a write is made.
+type Actor struct {
+	Id   uuid.UUID `bson:"id"   json:"id"`
+	Role string    `bson:"role" json:"role"`
+}
+
+type actorKey struct{}
+
+// WithActor returns a context carrying the actor, writes done with it are
+// attributed to the actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor of the context, if there is one.
+func ActorFromContext(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	return actor, ok
+}
+
+// actorOf returns the actor of the context, nil if there is none.
+func actorOf(ctx context.Context) *Actor {
+	if actor, ok := ActorFromContext(ctx); ok {
+		return &actor
+	}
+	return nil
+}
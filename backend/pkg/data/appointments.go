@@ -17,6 +17,7 @@ type Appointment struct {
 	Id                  uuid.UUID `bson:"_id"                 json:"id"`
 	PatientId           uuid.UUID `bson:"patientId"           json:"patientId"` // Reference to Patient._id
 	DoctorId            uuid.UUID `bson:"doctorId"            json:"doctorId"`  // Reference to Doctor._id
+	ClinicId            uuid.UUID `bson:"clinicId"            json:"clinicId"`  // Reference to Clinic._id
 	AppointmentDateTime time.Time `bson:"appointmentDateTime" json:"appointmentDateTime"`
 	EndTime             time.Time `bson:"endTime"             json:"endTime"`
 
@@ -24,14 +25,38 @@ type Appointment struct {
 	Status      string     `bson:"status"                json:"status"`
 	Reason      *string    `bson:"reason,omitempty"      json:"reason,omitempty"`
 	ConditionId *uuid.UUID `bson:"conditionId,omitempty" json:"conditionId,omitempty"`
+	ReferralId  *uuid.UUID `bson:"referralId,omitempty"  json:"referralId,omitempty"` // Reference to Referral._id
+	LocationId  *uuid.UUID `bson:"locationId,omitempty"  json:"locationId,omitempty"` // Reference to Location._id
 
 	CancellationReason *string `bson:"cancellationReason,omitempty" json:"cancellationReason,omitempty"`
 	CancelledBy        *string `bson:"cancelledBy,omitempty"        json:"cancelledBy,omitempty"`
 	DenialReason       *string `bson:"denialReason,omitempty"       json:"denialReason,omitempty"`
 
+	// BookedBy, RescheduledBy and CancelledByActor are the users who acted on
+	// the appointment, set from the context's actor. They differ from the
+	// patient when staff act on the patient's behalf.
+	BookedBy         *Actor `bson:"bookedBy,omitempty"         json:"bookedBy,omitempty"`
+	RescheduledBy    *Actor `bson:"rescheduledBy,omitempty"    json:"rescheduledBy,omitempty"`
+	CancelledByActor *Actor `bson:"cancelledByActor,omitempty" json:"cancelledByActor,omitempty"`
+
 	Medicines  []Resource `bson:"medicines,omitempty"  json:"medicines,omitempty"`
 	Facilities []Resource `bson:"facilities,omitempty" json:"facilities,omitempty"`
 	Equipment  []Resource `bson:"equipment,omitempty"  json:"equipment,omitempty"`
+
+	// Version is incremented on every write, see ErrVersionConflict.
+	Version int64 `bson:"version" json:"version"`
+}
+
+// AppointmentFilter selects appointments starting in a time period, empty
+// fields don't restrict the search.
+type AppointmentFilter struct {
+	DoctorId  *uuid.UUID
+	PatientId *uuid.UUID
+	From      time.Time
+	// To is inclusive, the period is open ended if nil.
+	To     *time.Time
+	Status []string
+	Type   []string
 }
 
 func (m *MongoDb) CreateAppointment(
@@ -52,12 +77,18 @@ func (m *MongoDb) CreateAppointment(
 		}
 	}
 
+	if appointment.LocationId != nil {
+		if err := m.locationExists(ctx, *appointment.LocationId); err != nil {
+			return Appointment{}, fmt.Errorf("CreateAppointment location check: %w", err)
+		}
+	}
+
 	appointmentsColl := m.Database.Collection(appointmentsCollection)
-	availabilityFilter := bson.M{
+	availabilityFilter := inClinic(ctx, bson.M{
 		"doctorId":            appointment.DoctorId,
 		"appointmentDateTime": appointment.AppointmentDateTime,
 		"status":              bson.M{"$nin": []string{"cancelled", "denied"}},
-	}
+	})
 
 	count, err := appointmentsColl.CountDocuments(ctx, availabilityFilter)
 	if err != nil {
@@ -87,8 +118,23 @@ func (m *MongoDb) CreateAppointment(
 	}
 
 	appointment.Id = uuid.New()
+	appointment.ClinicId = ClinicFromContext(ctx)
+	appointment.Version = initialVersion
+	appointment.BookedBy = actorOf(ctx)
+	if appointment.ReferralId != nil {
+		if err := m.bookReferral(ctx, *appointment.ReferralId, appointment.Id); err != nil {
+			return Appointment{}, fmt.Errorf("CreateAppointment: %w", err)
+		}
+	}
+
 	_, err = appointmentsColl.InsertOne(ctx, appointment)
 	if err != nil {
+		if appointment.ReferralId != nil {
+			if rerr := m.releaseReferral(ctx, appointment.Id); rerr != nil {
+				slog.Error("Failed to release referral of unsaved appointment",
+					"referralId", appointment.ReferralId, "error", rerr.Error())
+			}
+		}
 		return Appointment{}, fmt.Errorf("CreateAppointment: failed to insert document: %w", err)
 	}
 
@@ -97,7 +143,7 @@ func (m *MongoDb) CreateAppointment(
 
 func (m *MongoDb) AppointmentById(ctx context.Context, id uuid.UUID) (Appointment, error) {
 	appointmentsColl := m.Database.Collection(appointmentsCollection)
-	filter := bson.M{"_id": id}
+	filter := inClinic(ctx, bson.M{"_id": id})
 
 	var appt Appointment
 
@@ -123,14 +169,15 @@ func (m *MongoDb) CancelAppointment(
 	}
 
 	appointmentsColl := m.Database.Collection(appointmentsCollection)
-	update := bson.M{
+	update := incVersion(bson.M{
 		"$set": bson.M{
 			"status":             "cancelled",
 			"cancellationReason": cancellationReason,
 			"cancelledBy":        by,
+			"cancelledByActor":   actorOf(ctx),
 		},
-	}
-	filter := bson.M{"_id": appointmentId}
+	})
+	filter := inClinic(ctx, bson.M{"_id": appointmentId})
 
 	_, err := appointmentsColl.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -141,6 +188,10 @@ func (m *MongoDb) CancelAppointment(
 		return fmt.Errorf("CancelAppointment failed to delete reservations: %w", err)
 	}
 
+	if err := m.releaseReferral(ctx, appointmentId); err != nil {
+		return fmt.Errorf("CancelAppointment: %w", err)
+	}
+
 	return nil
 }
 
@@ -197,6 +248,9 @@ func (m *MongoDb) DecideAppointment(
 		if err != nil {
 			return Appointment{}, fmt.Errorf("DecideAppointment: %w", err)
 		}
+		if err := m.releaseReferral(ctx, appointmentId); err != nil {
+			return Appointment{}, fmt.Errorf("DecideAppointment: %w", err)
+		}
 	} else {
 		return Appointment{}, fmt.Errorf("DecideAppointment invalid decision %s for appointment %s", decision, appointmentId)
 	}
@@ -232,6 +286,79 @@ func (m *MongoDb) AppointmentsByPatientId(
 	return appts, nil
 }
 
+// ListAppointments returns the page of appointments of the context's clinic
+// matching the filter.
+func (m *MongoDb) ListAppointments(
+	ctx context.Context,
+	f AppointmentFilter,
+	q ListQuery,
+) (Page[Appointment], error) {
+	collection := m.Database.Collection(appointmentsCollection)
+
+	period := bson.M{"$gte": f.From}
+	if f.To != nil {
+		period["$lte"] = *f.To
+	}
+	filter := inClinic(ctx, bson.M{"appointmentDateTime": period})
+	if f.DoctorId != nil {
+		filter["doctorId"] = *f.DoctorId
+	}
+	if f.PatientId != nil {
+		filter["patientId"] = *f.PatientId
+	}
+	if len(f.Status) != 0 {
+		filter["status"] = bson.M{"$in": f.Status}
+	}
+	if len(f.Type) != 0 {
+		filter["type"] = bson.M{"$in": f.Type}
+	}
+
+	page, err := findPage[Appointment](ctx, collection, filter, q)
+	if err != nil {
+		return Page[Appointment]{}, fmt.Errorf("ListAppointments: %w", err)
+	}
+	return page, nil
+}
+
+// ActiveAppointmentsByDoctorIds returns the appointments of the doctors
+// overlapping the time range, which aren't cancelled or denied.
+func (m *MongoDb) ActiveAppointmentsByDoctorIds(
+	ctx context.Context,
+	doctorIds []uuid.UUID,
+	from time.Time,
+	to time.Time,
+) ([]Appointment, error) {
+	appointments := make([]Appointment, 0)
+	if len(doctorIds) == 0 {
+		return appointments, nil
+	}
+
+	collection := m.Database.Collection(appointmentsCollection)
+	filter := inClinic(ctx, bson.M{
+		"doctorId":            bson.M{"$in": doctorIds},
+		"appointmentDateTime": bson.M{"$lt": to},
+		"endTime":             bson.M{"$gt": from},
+		"status":              bson.M{"$nin": []string{"cancelled", "denied"}},
+	})
+	opts := options.Find().SetSort(bson.D{{Key: "appointmentDateTime", Value: 1}})
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("ActiveAppointmentsByDoctorIds: %w", err)
+	}
+	defer func() {
+		if cerr := cursor.Close(ctx); cerr != nil {
+			slog.Warn("Failed to close appointments cursor", "error", cerr.Error())
+		}
+	}()
+
+	if err = cursor.All(ctx, &appointments); err != nil {
+		return nil, fmt.Errorf("ActiveAppointmentsByDoctorIds decode failed: %w", err)
+	}
+
+	return appointments, nil
+}
+
 func (m *MongoDb) AppointmentsByDoctorIdAndDate(
 	ctx context.Context,
 	doctorId uuid.UUID,
@@ -271,11 +398,11 @@ func (m *MongoDb) RescheduleAppointment(
 		)
 	}
 
-	availabilityFilter := bson.M{
+	availabilityFilter := inClinic(ctx, bson.M{
 		"doctorId":            appointment.DoctorId,
 		"appointmentDateTime": newDateTime,
 		"status":              bson.M{"$nin": []string{"cancelled", "denied"}},
-	}
+	})
 
 	appointmentsColl := m.Database.Collection(appointmentsCollection)
 	count, err := appointmentsColl.CountDocuments(ctx, availabilityFilter)
@@ -294,13 +421,14 @@ func (m *MongoDb) RescheduleAppointment(
 		)
 	}
 
-	update := bson.M{
+	update := incVersion(bson.M{
 		"$set": bson.M{
 			"appointmentDateTime": newDateTime,
 			"status":              "requested",
+			"rescheduledBy":       actorOf(ctx),
 		},
-	}
-	filter := bson.M{"_id": appointmentId}
+	})
+	filter := inClinic(ctx, bson.M{"_id": appointmentId})
 
 	_, err = appointmentsColl.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -326,7 +454,7 @@ func (m *MongoDb) AppointmentsByConditionId(
 ) ([]Appointment, error) {
 	appointmentsColl := m.Database.Collection(appointmentsCollection)
 	appointments := make([]Appointment, 0)
-	filter := bson.M{"conditionId": conditionId}
+	filter := inClinic(ctx, bson.M{"conditionId": conditionId})
 
 	opts := options.Find().SetSort(bson.D{{Key: "appointmentDateTime", Value: -1}})
 
@@ -362,13 +490,13 @@ func (m *MongoDb) appointmentsByIdFieldAndDateRange(
 	appointmentsColl := m.Database.Collection(appointmentsCollection)
 	appointments := make([]Appointment, 0)
 
-	filter := bson.M{
+	filter := inClinic(ctx, bson.M{
 		idField: id,
 		"appointmentDateTime": bson.M{
 			"$gte": start,
 			"$lte": end,
 		},
-	}
+	})
 	opts := options.Find().SetSort(bson.D{{Key: "appointmentDateTime", Value: 1}})
 
 	cursor, err := appointmentsColl.Find(ctx, filter, opts)
@@ -406,10 +534,10 @@ func (m *MongoDb) appointmentsByIdField(
 	appointmentsColl := m.Database.Collection(appointmentsCollection)
 	appointments := make([]Appointment, 0)
 
-	filter := bson.M{
+	filter := inClinic(ctx, bson.M{
 		idField:               id,
 		"appointmentDateTime": bson.M{"$gte": from},
-	}
+	})
 	if to != nil {
 		filter["appointmentDateTime"] = bson.M{
 			"$gte": from,
@@ -443,13 +571,137 @@ func (m *MongoDb) appointmentsByIdField(
 	return appointments, nil
 }
 
+// CompleteAppointment marks a scheduled appointment as completed and signs
+// its visit note.
+func (m *MongoDb) CompleteAppointment(
+	ctx context.Context,
+	appointmentId uuid.UUID,
+) (Appointment, error) {
+	appointment, err := m.AppointmentById(ctx, appointmentId)
+	if err != nil {
+		return Appointment{}, fmt.Errorf("CompleteAppointment: %w", err)
+	}
+
+	if appointment.Status != "scheduled" {
+		return Appointment{}, fmt.Errorf(
+			"CompleteAppointment appointment %s: %w",
+			appointmentId,
+			ErrAppointmentNotScheduled,
+		)
+	}
+
+	appointmentsColl := m.Database.Collection(appointmentsCollection)
+	update := incVersion(bson.M{"$set": bson.M{"status": "completed"}})
+	filter := inClinic(ctx, bson.M{"_id": appointmentId})
+
+	_, err = appointmentsColl.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return Appointment{}, fmt.Errorf("CompleteAppointment: %w", err)
+	}
+
+	if err := m.signVisitNote(ctx, appointment); err != nil {
+		return Appointment{}, fmt.Errorf("CompleteAppointment: %w", err)
+	}
+	if err := m.completeReferral(ctx, appointmentId); err != nil {
+		return Appointment{}, fmt.Errorf("CompleteAppointment: %w", err)
+	}
+
+	return m.AppointmentById(ctx, appointmentId)
+}
+
+// ReassignAppointment moves a requested or scheduled appointment to another
+// doctor, who must be free at the appointment's time. Reservations are kept,
+// the time of the appointment doesn't change.
+func (m *MongoDb) ReassignAppointment(
+	ctx context.Context,
+	appointmentId uuid.UUID,
+	doctorId uuid.UUID,
+) (Appointment, error) {
+	appointment, err := m.AppointmentById(ctx, appointmentId)
+	if err != nil {
+		return Appointment{}, fmt.Errorf("ReassignAppointment: %w", err)
+	}
+
+	if appointment.Status != "scheduled" && appointment.Status != "requested" {
+		return Appointment{}, fmt.Errorf(
+			"ReassignAppointment appointment %s is not in a reassignable state",
+			appointmentId,
+		)
+	}
+
+	if err := m.doctorExists(ctx, doctorId); err != nil {
+		return Appointment{}, fmt.Errorf("ReassignAppointment doctor check: %w", err)
+	}
+
+	appointmentsColl := m.Database.Collection(appointmentsCollection)
+	availabilityFilter := inClinic(ctx, bson.M{
+		"_id":                 bson.M{"$ne": appointmentId},
+		"doctorId":            doctorId,
+		"appointmentDateTime": appointment.AppointmentDateTime,
+		"status":              bson.M{"$nin": []string{"cancelled", "denied"}},
+	})
+
+	count, err := appointmentsColl.CountDocuments(ctx, availabilityFilter)
+	if err != nil {
+		return Appointment{}, fmt.Errorf(
+			"ReassignAppointment doctor availability check failed: %w",
+			err,
+		)
+	}
+
+	if count > 0 {
+		return Appointment{}, fmt.Errorf(
+			"%w at %s",
+			ErrDoctorUnavailable,
+			appointment.AppointmentDateTime.Format(time.RFC3339),
+		)
+	}
+
+	update := incVersion(bson.M{"$set": bson.M{"doctorId": doctorId}})
+	filter := inClinic(ctx, bson.M{"_id": appointmentId})
+
+	_, err = appointmentsColl.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return Appointment{}, fmt.Errorf("ReassignAppointment failed to update appointment: %w", err)
+	}
+
+	return m.AppointmentById(ctx, appointmentId)
+}
+
+// DeleteAppointment deletes the appointment together with its reservations.
+func (m *MongoDb) DeleteAppointment(ctx context.Context, id uuid.UUID) error {
+	if err := m.DeleteReservationsByAppointmentId(ctx, id); err != nil {
+		return fmt.Errorf("DeleteAppointment: %w", err)
+	}
+	if err := m.deleteVisitNote(ctx, id); err != nil {
+		return fmt.Errorf("DeleteAppointment: %w", err)
+	}
+	if err := m.releaseReferral(ctx, id); err != nil {
+		return fmt.Errorf("DeleteAppointment: %w", err)
+	}
+
+	collection := m.Database.Collection(appointmentsCollection)
+	filter := inClinic(ctx, bson.M{"_id": id})
+
+	result, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("DeleteAppointment failed: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
 func (m *MongoDb) scheduleAppointment(
 	ctx context.Context,
 	appointmentId uuid.UUID,
 ) (Appointment, error) {
 	appointmentsColl := m.Database.Collection(appointmentsCollection)
-	update := bson.M{"$set": bson.M{"status": "scheduled"}}
-	filter := bson.M{"_id": appointmentId}
+	update := incVersion(bson.M{"$set": bson.M{"status": "scheduled"}})
+	filter := inClinic(ctx, bson.M{"_id": appointmentId})
 
 	_, err := appointmentsColl.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -465,8 +717,8 @@ func (m *MongoDb) denyAppointment(
 	reason *string,
 ) (Appointment, error) {
 	appointmentsColl := m.Database.Collection(appointmentsCollection)
-	update := bson.M{"$set": bson.M{"status": "denied", "denialReason": reason}}
-	filter := bson.M{"_id": appointmentId}
+	update := incVersion(bson.M{"$set": bson.M{"status": "denied", "denialReason": reason}})
+	filter := inClinic(ctx, bson.M{"_id": appointmentId})
 
 	_, err := appointmentsColl.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -495,14 +747,14 @@ func (m *MongoDb) updateAppointmentResources(
 		}
 	}
 
-	update := bson.M{
+	update := incVersion(bson.M{
 		"$set": bson.M{
 			"facilities": facilities,
 			"equipment":  equipment,
 			"medicines":  medicine,
 		},
-	}
-	filter := bson.M{"_id": appointmentId}
+	})
+	filter := inClinic(ctx, bson.M{"_id": appointmentId})
 
 	_, err := appointmentsColl.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -514,7 +766,7 @@ func (m *MongoDb) updateAppointmentResources(
 
 func (m *MongoDb) appointmentExists(ctx context.Context, id uuid.UUID) error {
 	appointmentsColl := m.Database.Collection(appointmentsCollection)
-	filter := bson.M{"_id": id}
+	filter := inClinic(ctx, bson.M{"_id": id})
 
 	count, err := appointmentsColl.CountDocuments(ctx, filter)
 	if err != nil {
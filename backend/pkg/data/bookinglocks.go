@@ -0,0 +1,57 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// BookingLock is held while a patient's appointment request is checked and
+// created, so concurrent requests of the patient are handled one by one.
+// Locks are deleted by a TTL index once their lease passes.
+type BookingLock struct {
+	PatientId uuid.UUID `bson:"_id"` // Reference to Patient._id
+	// Lease identifies the holder, only it releases the lock.
+	Lease       uuid.UUID `bson:"lease"`
+	LockedUntil time.Time `bson:"lockedUntil"`
+}
+
+// LockPatientBookings takes the patient's booking lock until the lease passes.
+// If another holder has it, false is returned. Locks whose lease passed
+// without being released, e.g. because the server stopped, are taken over.
+func (m *MongoDb) LockPatientBookings(
+	ctx context.Context,
+	patientId uuid.UUID,
+	lease time.Duration,
+) (BookingLock, bool, error) {
+	collection := m.Database.Collection(bookingLocksCollection)
+	now := time.Now()
+	lock := BookingLock{PatientId: patientId, Lease: uuid.New(), LockedUntil: now.Add(lease)}
+	filter := bson.M{"_id": patientId, "lockedUntil": bson.M{"$lte": now}}
+	update := bson.M{"$set": bson.M{"lease": lock.Lease, "lockedUntil": lock.LockedUntil}}
+
+	_, err := collection.UpdateOne(ctx, filter, update, options.UpdateOne().SetUpsert(true))
+	if err == nil {
+		return lock, true, nil
+	}
+	// the upsert inserts a lock with the _id of a held one
+	if mongo.IsDuplicateKeyError(err) {
+		return BookingLock{}, false, nil
+	}
+	return BookingLock{}, false, fmt.Errorf("LockPatientBookings: %w", err)
+}
+
+// UnlockPatientBookings releases the lock, unless it was taken over.
+func (m *MongoDb) UnlockPatientBookings(ctx context.Context, lock BookingLock) error {
+	collection := m.Database.Collection(bookingLocksCollection)
+	_, err := collection.DeleteOne(ctx, bson.M{"_id": lock.PatientId, "lease": lock.Lease})
+	if err != nil {
+		return fmt.Errorf("UnlockPatientBookings: %w", err)
+	}
+	return nil
+}
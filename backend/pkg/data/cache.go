@@ -0,0 +1,368 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Cache stores encoded values under string keys for a limited time. The
+// in-process LRUCache implements it, a shared cache like Redis can be used to
+// keep replicas consistent.
+type Cache interface {
+	// Get returns the value of the key, false if it's missing or expired.
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+	// DeletePrefix deletes the values of all keys starting with the prefix.
+	DeletePrefix(ctx context.Context, prefix string)
+}
+
+var (
+	cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "wac",
+		Subsystem: "cache",
+		Name:      "lookups_total",
+		Help:      "Cache lookups by region and result, hit or miss.",
+	}, []string{"region", "result"})
+	cacheEvictions = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "wac",
+		Subsystem: "cache",
+		Name:      "evictions_total",
+		Help:      "Values evicted from the in-process cache to make room for new ones.",
+	})
+)
+
+// Regions of cached values, values of a region are invalidated together.
+const (
+	doctorsRegion      = "doctors"
+	resourcesRegion    = "resources"
+	appointmentsRegion = "appointments"
+)
+
+// CachedDb is a Db caching doctors, available resources and doctors'
+// appointments on a date, which calendar screens read on every request.
+// Writes through CachedDb invalidate the regions of the clinic they change,
+// writes bypassing it, e.g. of wacctl or other replicas with their own
+// in-process cache, are seen once the values expire.
+type CachedDb struct {
+	Db
+	cache Cache
+	ttl   time.Duration
+}
+
+func NewCachedDb(db Db, cache Cache, ttl time.Duration) *CachedDb {
+	return &CachedDb{Db: db, cache: cache, ttl: ttl}
+}
+
+func (c *CachedDb) DoctorById(ctx context.Context, id uuid.UUID) (Doctor, error) {
+	return cached(ctx, c, doctorsRegion, "id:"+id.String(), func() (Doctor, error) {
+		return c.Db.DoctorById(ctx, id)
+	})
+}
+
+// DoctorsByIds returns the cached doctors and loads only the missing ones.
+func (c *CachedDb) DoctorsByIds(ctx context.Context, ids []uuid.UUID) ([]Doctor, error) {
+	doctors := make([]Doctor, 0, len(ids))
+	missing := make([]uuid.UUID, 0)
+	for _, id := range ids {
+		var doctor Doctor
+		if c.get(ctx, doctorsRegion, "id:"+id.String(), &doctor) {
+			doctors = append(doctors, doctor)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return doctors, nil
+	}
+
+	loaded, err := c.Db.DoctorsByIds(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, doctor := range loaded {
+		c.set(ctx, doctorsRegion, "id:"+doctor.Id.String(), doctor)
+	}
+	return append(doctors, loaded...), nil
+}
+
+// cachedPage is a Page with an encoded cursor, cursors don't survive JSON.
+type cachedPage[T any] struct {
+	Items []T
+	Next  string
+	Total int64
+}
+
+func (c *CachedDb) Doctors(
+	ctx context.Context,
+	filter DoctorFilter,
+	query ListQuery,
+) (Page[Doctor], error) {
+	active := "any"
+	if filter.Active != nil {
+		active = fmt.Sprint(*filter.Active)
+	}
+	after := ""
+	if query.After != nil {
+		after = query.After.String()
+	}
+	key := fmt.Sprintf(
+		"list:%q:%q:%s:%q:%t:%s:%d",
+		filter.Specialization,
+		filter.Language,
+		active,
+		query.SortBy,
+		query.Descending,
+		after,
+		query.Limit,
+	)
+
+	page, err := cached(ctx, c, doctorsRegion, key, func() (cachedPage[Doctor], error) {
+		page, err := c.Db.Doctors(ctx, filter, query)
+		if err != nil {
+			return cachedPage[Doctor]{}, err
+		}
+		cp := cachedPage[Doctor]{Items: page.Items, Total: page.Total}
+		if page.Next != nil {
+			cp.Next = page.Next.String()
+		}
+		return cp, nil
+	})
+	if err != nil {
+		return Page[Doctor]{}, err
+	}
+
+	result := Page[Doctor]{Items: page.Items, Total: page.Total}
+	if page.Next != "" {
+		if result.Next, err = ParseCursor(page.Next); err != nil {
+			return Page[Doctor]{}, fmt.Errorf("CachedDb.Doctors: %w", err)
+		}
+	}
+	return result, nil
+}
+
+func (c *CachedDb) CreateDoctor(ctx context.Context, doctor Doctor) (Doctor, error) {
+	defer c.invalidate(ctx, doctorsRegion)
+	return c.Db.CreateDoctor(ctx, doctor)
+}
+
+func (c *CachedDb) UpdateDoctor(ctx context.Context, id uuid.UUID, doctor Doctor) (Doctor, error) {
+	defer c.invalidate(ctx, doctorsRegion)
+	return c.Db.UpdateDoctor(ctx, id, doctor)
+}
+
+type availableResources = struct {
+	Medicines  []Resource
+	Facilities []Resource
+	Equipment  []Resource
+}
+
+func (c *CachedDb) FindAvailableResourcesAtTime(
+	ctx context.Context,
+	appointmentDate time.Time,
+	facilityLocationIds []uuid.UUID,
+) (availableResources, error) {
+	locations := make([]string, len(facilityLocationIds))
+	for i, id := range facilityLocationIds {
+		locations[i] = id.String()
+	}
+	slices.Sort(locations)
+	key := fmt.Sprintf(
+		"available:%s:%s",
+		appointmentDate.UTC().Format(time.RFC3339Nano),
+		strings.Join(locations, ","),
+	)
+
+	return cached(ctx, c, resourcesRegion, key, func() (availableResources, error) {
+		return c.Db.FindAvailableResourcesAtTime(ctx, appointmentDate, facilityLocationIds)
+	})
+}
+
+func (c *CachedDb) CreateResource(
+	ctx context.Context,
+	name string,
+	typ ResourceType,
+	locationId *uuid.UUID,
+) (Resource, error) {
+	defer c.invalidate(ctx, resourcesRegion)
+	return c.Db.CreateResource(ctx, name, typ, locationId)
+}
+
+func (c *CachedDb) SetResourceLocation(
+	ctx context.Context,
+	resourceId uuid.UUID,
+	locationId *uuid.UUID,
+) (Resource, error) {
+	defer c.invalidate(ctx, resourcesRegion)
+	return c.Db.SetResourceLocation(ctx, resourceId, locationId)
+}
+
+func (c *CachedDb) CreateReservation(
+	ctx context.Context,
+	appointmentId uuid.UUID,
+	resourceId uuid.UUID,
+	resourceName string,
+	resourceType ResourceType,
+	startTime time.Time,
+	endTime time.Time,
+) (Reservation, error) {
+	defer c.invalidate(ctx, resourcesRegion)
+	return c.Db.CreateReservation(
+		ctx,
+		appointmentId,
+		resourceId,
+		resourceName,
+		resourceType,
+		startTime,
+		endTime,
+	)
+}
+
+// AppointmentsByDoctorIdAndDate is cached for the doctor's timeslots.
+func (c *CachedDb) AppointmentsByDoctorIdAndDate(
+	ctx context.Context,
+	doctorId uuid.UUID,
+	date time.Time,
+) ([]Appointment, error) {
+	key := doctorId.String() + ":" + date.UTC().Format(time.RFC3339Nano)
+	return cached(ctx, c, appointmentsRegion, key, func() ([]Appointment, error) {
+		return c.Db.AppointmentsByDoctorIdAndDate(ctx, doctorId, date)
+	})
+}
+
+// Appointment writes change doctors' timeslots and free or reserve resources.
+
+func (c *CachedDb) CreateAppointment(
+	ctx context.Context,
+	appointment Appointment,
+) (Appointment, error) {
+	defer c.invalidate(ctx, appointmentsRegion, resourcesRegion)
+	return c.Db.CreateAppointment(ctx, appointment)
+}
+
+func (c *CachedDb) CancelAppointment(
+	ctx context.Context,
+	appointmentId uuid.UUID,
+	by string,
+	cancellationReason *string,
+) error {
+	defer c.invalidate(ctx, appointmentsRegion, resourcesRegion)
+	return c.Db.CancelAppointment(ctx, appointmentId, by, cancellationReason)
+}
+
+func (c *CachedDb) DecideAppointment(
+	ctx context.Context,
+	appointmentId uuid.UUID,
+	decision string,
+	denyReason *string,
+	resources []Resource,
+) (Appointment, error) {
+	defer c.invalidate(ctx, appointmentsRegion, resourcesRegion)
+	return c.Db.DecideAppointment(ctx, appointmentId, decision, denyReason, resources)
+}
+
+func (c *CachedDb) RescheduleAppointment(
+	ctx context.Context,
+	appointmentId uuid.UUID,
+	newDateTime time.Time,
+) (Appointment, error) {
+	defer c.invalidate(ctx, appointmentsRegion, resourcesRegion)
+	return c.Db.RescheduleAppointment(ctx, appointmentId, newDateTime)
+}
+
+func (c *CachedDb) CompleteAppointment(
+	ctx context.Context,
+	appointmentId uuid.UUID,
+) (Appointment, error) {
+	defer c.invalidate(ctx, appointmentsRegion, resourcesRegion)
+	return c.Db.CompleteAppointment(ctx, appointmentId)
+}
+
+func (c *CachedDb) ReassignAppointment(
+	ctx context.Context,
+	appointmentId uuid.UUID,
+	doctorId uuid.UUID,
+) (Appointment, error) {
+	defer c.invalidate(ctx, appointmentsRegion, resourcesRegion)
+	return c.Db.ReassignAppointment(ctx, appointmentId, doctorId)
+}
+
+func (c *CachedDb) DeleteAppointment(ctx context.Context, id uuid.UUID) error {
+	defer c.invalidate(ctx, appointmentsRegion, resourcesRegion)
+	return c.Db.DeleteAppointment(ctx, id)
+}
+
+// cached returns the cached value of the key in the region, or loads and
+// caches it. Errors aren't cached.
+func cached[T any](
+	ctx context.Context,
+	c *CachedDb,
+	region string,
+	key string,
+	load func() (T, error),
+) (T, error) {
+	var value T
+	if c.get(ctx, region, key, &value) {
+		return value, nil
+	}
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+	c.set(ctx, region, key, value)
+	return value, nil
+}
+
+func (c *CachedDb) get(ctx context.Context, region string, key string, value any) bool {
+	raw, ok := c.cache.Get(ctx, regionPrefix(ctx, region)+key)
+	if ok {
+		if err := json.Unmarshal(raw, value); err != nil {
+			slog.Warn("Failed to decode cached value", "region", region, "error", err.Error())
+			ok = false
+		}
+	}
+	if ok {
+		cacheLookups.WithLabelValues(region, "hit").Inc()
+	} else {
+		cacheLookups.WithLabelValues(region, "miss").Inc()
+	}
+	return ok
+}
+
+func (c *CachedDb) set(ctx context.Context, region string, key string, value any) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		slog.Warn("Failed to encode cached value", "region", region, "error", err.Error())
+		return
+	}
+	c.cache.Set(ctx, regionPrefix(ctx, region)+key, raw, c.ttl)
+}
+
+// invalidate deletes the cached values of the regions in the context's
+// clinic. It's deferred by writes, so values loaded during a write don't
+// outlive it.
+func (c *CachedDb) invalidate(ctx context.Context, regions ...string) {
+	for _, region := range regions {
+		c.cache.DeletePrefix(ctx, regionPrefix(ctx, region))
+	}
+}
+
+// Flush deletes the cached values of all regions in every clinic, after
+// writes which bypassed CachedDb, like integrity repairs.
+func (c *CachedDb) Flush(ctx context.Context) {
+	for _, region := range []string{doctorsRegion, resourcesRegion, appointmentsRegion} {
+		c.cache.DeletePrefix(ctx, region+":")
+	}
+}
+
+func regionPrefix(ctx context.Context, region string) string {
+	return region + ":" + ClinicFromContext(ctx).String() + ":"
+}
@@ -0,0 +1,257 @@
+package data
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestLRUCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUCache(2)
+	cache.Set(ctx, "a", []byte("1"), time.Minute)
+	cache.Set(ctx, "b", []byte("2"), time.Minute)
+	_, _ = cache.Get(ctx, "a")
+	cache.Set(ctx, "c", []byte("3"), time.Minute)
+
+	_, ok := cache.Get(ctx, "b")
+	assert.False(t, ok, "b was the least recently used")
+	value, ok := cache.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestLRUCache_Expires(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	cache := NewLRUCache(10)
+	cache.now = func() time.Time { return now }
+	cache.Set(ctx, "a", []byte("1"), time.Minute)
+
+	now = now.Add(59 * time.Second)
+	_, ok := cache.Get(ctx, "a")
+	assert.True(t, ok)
+
+	now = now.Add(time.Second)
+	_, ok = cache.Get(ctx, "a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestLRUCache_DeletePrefix(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUCache(10)
+	cache.Set(ctx, "doctors:1:a", []byte("1"), time.Minute)
+	cache.Set(ctx, "doctors:2:a", []byte("2"), time.Minute)
+
+	cache.DeletePrefix(ctx, "doctors:1:")
+
+	_, ok := cache.Get(ctx, "doctors:1:a")
+	assert.False(t, ok)
+	_, ok = cache.Get(ctx, "doctors:2:a")
+	assert.True(t, ok)
+}
+
+func TestCachedDb_DoctorInvalidatedOnUpdate(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeDb()
+	house := db.addDoctor("House")
+	cached := NewCachedDb(db, NewLRUCache(10), time.Minute)
+
+	for range 3 {
+		doctor, err := cached.DoctorById(ctx, house)
+		require.NoError(t, err)
+		assert.Equal(t, "House", doctor.LastName)
+	}
+	assert.Equal(t, 1, db.reads)
+
+	_, err := cached.UpdateDoctor(ctx, house, Doctor{Id: house, LastName: "Wilson"})
+	require.NoError(t, err)
+
+	doctor, err := cached.DoctorById(ctx, house)
+	require.NoError(t, err)
+	assert.Equal(t, "Wilson", doctor.LastName)
+	assert.Equal(t, 2, db.reads)
+}
+
+func TestCachedDb_ClinicsDontShareValues(t *testing.T) {
+	db := newFakeDb()
+	house := db.addDoctor("House")
+	cached := NewCachedDb(db, NewLRUCache(10), time.Minute)
+
+	_, err := cached.DoctorById(WithClinic(context.Background(), uuid.New()), house)
+	require.NoError(t, err)
+	_, err = cached.DoctorById(WithClinic(context.Background(), uuid.New()), house)
+	require.NoError(t, err)
+	assert.Equal(t, 2, db.reads)
+}
+
+func TestCachedDb_FlushAllClinics(t *testing.T) {
+	db := newFakeDb()
+	house := db.addDoctor("House")
+	cached := NewCachedDb(db, NewLRUCache(10), time.Minute)
+	clinics := []context.Context{
+		context.Background(),
+		WithClinic(context.Background(), uuid.New()),
+	}
+
+	for _, ctx := range clinics {
+		_, err := cached.DoctorById(ctx, house)
+		require.NoError(t, err)
+	}
+	cached.Flush(context.Background())
+	for _, ctx := range clinics {
+		_, err := cached.DoctorById(ctx, house)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 4, db.reads, "Values of every clinic are loaded again")
+}
+
+func TestCachedDb_DoctorsByIdsLoadsMissing(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeDb()
+	house := db.addDoctor("House")
+	wilson := db.addDoctor("Wilson")
+	cached := NewCachedDb(db, NewLRUCache(10), time.Minute)
+
+	_, err := cached.DoctorById(ctx, house)
+	require.NoError(t, err)
+
+	doctors, err := cached.DoctorsByIds(ctx, []uuid.UUID{house, wilson})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{house, wilson}, []uuid.UUID{doctors[0].Id, doctors[1].Id})
+	assert.Equal(t, []uuid.UUID{wilson}, db.loadedIds)
+
+	_, err = cached.DoctorsByIds(ctx, []uuid.UUID{house, wilson})
+	require.NoError(t, err)
+	assert.Equal(t, 2, db.reads, "Both doctors are cached")
+}
+
+func TestCachedDb_DoctorsPageKeepsCursor(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeDb()
+	db.addDoctor("House")
+	cached := NewCachedDb(db, NewLRUCache(10), time.Minute)
+	typ, lastName, err := bson.MarshalValue("House")
+	require.NoError(t, err)
+	db.next = Cursor{{Type: typ, Value: lastName}, {Type: typ, Value: lastName}}
+	query := ListQuery{SortBy: []string{"lastName"}, Limit: 1}
+
+	first, err := cached.Doctors(ctx, DoctorFilter{}, query)
+	require.NoError(t, err)
+	second, err := cached.Doctors(ctx, DoctorFilter{}, query)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, db.reads)
+	assert.Equal(t, first.Next.String(), second.Next.String())
+	assert.Equal(t, first.Items, second.Items)
+
+	_, err = cached.Doctors(ctx, DoctorFilter{}, ListQuery{SortBy: []string{"lastName"}, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 2, db.reads, "Other queries are cached apart")
+}
+
+func TestCachedDb_TimeslotsInvalidatedByAppointmentWrites(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeDb()
+	house := db.addDoctor("House")
+	cached := NewCachedDb(db, NewLRUCache(10), time.Minute)
+	date := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	_, err := cached.AppointmentsByDoctorIdAndDate(ctx, house, date)
+	require.NoError(t, err)
+	_, err = cached.AppointmentsByDoctorIdAndDate(ctx, house, date)
+	require.NoError(t, err)
+	assert.Equal(t, 1, db.reads)
+
+	_, err = cached.CreateAppointment(ctx, Appointment{DoctorId: house})
+	require.NoError(t, err)
+
+	appts, err := cached.AppointmentsByDoctorIdAndDate(ctx, house, date)
+	require.NoError(t, err)
+	assert.Len(t, appts, 1)
+	assert.Equal(t, 2, db.reads)
+}
+
+// fakeDb is an in-memory Db counting its reads, methods the tests don't use
+// panic.
+type fakeDb struct {
+	Db
+	reads        int
+	loadedIds    []uuid.UUID
+	next         Cursor
+	doctors      map[uuid.UUID]Doctor
+	appointments []Appointment
+}
+
+func newFakeDb() *fakeDb {
+	return &fakeDb{doctors: make(map[uuid.UUID]Doctor)}
+}
+
+func (db *fakeDb) addDoctor(lastName string) uuid.UUID {
+	id := uuid.New()
+	db.doctors[id] = Doctor{Id: id, LastName: lastName, Active: true}
+	return id
+}
+
+func (db *fakeDb) DoctorById(_ context.Context, id uuid.UUID) (Doctor, error) {
+	db.reads++
+	doctor, ok := db.doctors[id]
+	if !ok {
+		return Doctor{}, ErrNotFound
+	}
+	return doctor, nil
+}
+
+func (db *fakeDb) DoctorsByIds(_ context.Context, ids []uuid.UUID) ([]Doctor, error) {
+	db.reads++
+	db.loadedIds = ids
+	doctors := make([]Doctor, 0, len(ids))
+	for _, id := range ids {
+		if doctor, ok := db.doctors[id]; ok {
+			doctors = append(doctors, doctor)
+		}
+	}
+	return doctors, nil
+}
+
+func (db *fakeDb) Doctors(context.Context, DoctorFilter, ListQuery) (Page[Doctor], error) {
+	db.reads++
+	page := Page[Doctor]{Next: db.next, Total: int64(len(db.doctors))}
+	for _, doctor := range db.doctors {
+		page.Items = append(page.Items, doctor)
+	}
+	return page, nil
+}
+
+func (db *fakeDb) UpdateDoctor(_ context.Context, id uuid.UUID, doctor Doctor) (Doctor, error) {
+	db.doctors[id] = doctor
+	return doctor, nil
+}
+
+func (db *fakeDb) AppointmentsByDoctorIdAndDate(
+	_ context.Context,
+	doctorId uuid.UUID,
+	_ time.Time,
+) ([]Appointment, error) {
+	db.reads++
+	appts := make([]Appointment, 0)
+	for _, appt := range db.appointments {
+		if appt.DoctorId == doctorId {
+			appts = append(appts, appt)
+		}
+	}
+	return appts, nil
+}
+
+func (db *fakeDb) CreateAppointment(_ context.Context, appt Appointment) (Appointment, error) {
+	appt.Id = uuid.New()
+	db.appointments = append(db.appointments, appt)
+	return appt, nil
+}
@@ -0,0 +1,169 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// DefaultClinicId is the clinic of requests which don't name one. Data created
+// before there were clinics belongs to it.
+var DefaultClinicId = uuid.MustParse("7c2a5e0d-3b1f-4e8a-9d6c-0f4b2a1e5c3d")
+
+// clinicOwnedCollections hold documents belonging to a single clinic, in their
+// clinicId field. Patients are shared with clinics in their clinicIds field,
+// their conditions and prescriptions are visible where the patient is.
+var clinicOwnedCollections = []string{
+	doctorsCollection,
+	appointmentsCollection,
+	resourcesCollection,
+	reservationsCollection,
+	visitNotesCollection,
+	referralsCollection,
+	fhirImportsCollection,
+	locationsCollection,
+	staffCollection,
+}
+
+type Clinic struct {
+	Id        uuid.UUID `bson:"_id"       json:"id"`
+	Name      string    `bson:"name"      json:"name"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+type clinicKey struct{}
+
+// WithClinic returns a context acting in the clinic, all reads and writes done
+// with it are limited to the clinic's data.
+func WithClinic(ctx context.Context, clinicId uuid.UUID) context.Context {
+	return context.WithValue(ctx, clinicKey{}, clinicId)
+}
+
+// ClinicFromContext returns the clinic of the context, DefaultClinicId if
+// there is none.
+func ClinicFromContext(ctx context.Context) uuid.UUID {
+	if clinicId, ok := ctx.Value(clinicKey{}).(uuid.UUID); ok {
+		return clinicId
+	}
+	return DefaultClinicId
+}
+
+// inClinic returns the filter extended to match only documents of the
+// context's clinic.
+func inClinic(ctx context.Context, filter bson.M) bson.M {
+	filter["clinicId"] = ClinicFromContext(ctx)
+	return filter
+}
+
+// sharedWithClinic returns the patient filter extended to match only patients
+// shared with the context's clinic.
+func sharedWithClinic(ctx context.Context, filter bson.M) bson.M {
+	filter["clinicIds"] = ClinicFromContext(ctx)
+	return filter
+}
+
+// patientVisible reports whether the patient is shared with the context's
+// clinic. Conditions and prescriptions are visible only where their patient is.
+func (m *MongoDb) patientVisible(ctx context.Context, patientId uuid.UUID) (bool, error) {
+	err := m.patientExists(ctx, patientId)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (m *MongoDb) CreateClinic(ctx context.Context, clinic Clinic) (Clinic, error) {
+	collection := m.Database.Collection(clinicsCollection)
+	clinic.Id = uuid.New()
+	clinic.CreatedAt = time.Now()
+
+	if _, err := collection.InsertOne(ctx, clinic); err != nil {
+		return Clinic{}, fmt.Errorf("CreateClinic: failed to insert document: %w", err)
+	}
+
+	return clinic, nil
+}
+
+func (m *MongoDb) ClinicById(ctx context.Context, id uuid.UUID) (Clinic, error) {
+	collection := m.Database.Collection(clinicsCollection)
+
+	var clinic Clinic
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&clinic)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Clinic{}, ErrNotFound
+		}
+		return Clinic{}, fmt.Errorf("ClinicById: %w", err)
+	}
+
+	return clinic, nil
+}
+
+func (m *MongoDb) clinicExists(ctx context.Context, id uuid.UUID) error {
+	collection := m.Database.Collection(clinicsCollection)
+
+	count, err := collection.CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("clinicExists failed clinic count check: %w", err)
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// seedDefaultClinic creates the default clinic and assigns to it all data
+// created before there were clinics.
+func (m *MongoDb) seedDefaultClinic(ctx context.Context) error {
+	clinics := m.Database.Collection(clinicsCollection)
+	count, err := clinics.CountDocuments(ctx, bson.M{"_id": DefaultClinicId})
+	if err != nil {
+		return fmt.Errorf("seedDefaultClinic count check failed: %w", err)
+	}
+	if count == 0 {
+		clinic := Clinic{Id: DefaultClinicId, Name: "Default clinic", CreatedAt: time.Now()}
+		if _, err := clinics.InsertOne(ctx, clinic); err != nil {
+			return fmt.Errorf("seedDefaultClinic failed to insert clinic: %w", err)
+		}
+		slog.InfoContext(ctx, "Seeded default clinic", "id", DefaultClinicId)
+	}
+
+	for _, collName := range clinicOwnedCollections {
+		result, err := m.Database.Collection(collName).UpdateMany(
+			ctx,
+			bson.M{"clinicId": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"clinicId": DefaultClinicId}},
+		)
+		if err != nil {
+			return fmt.Errorf("seedDefaultClinic failed to assign %s: %w", collName, err)
+		}
+		if result.ModifiedCount > 0 {
+			slog.InfoContext(ctx, "Assigned documents to the default clinic",
+				"collection", collName, "count", result.ModifiedCount)
+		}
+	}
+
+	result, err := m.Database.Collection(patientsCollection).UpdateMany(
+		ctx,
+		bson.M{"clinicIds": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"clinicIds": []uuid.UUID{DefaultClinicId}}},
+	)
+	if err != nil {
+		return fmt.Errorf("seedDefaultClinic failed to share patients: %w", err)
+	}
+	if result.ModifiedCount > 0 {
+		slog.InfoContext(ctx, "Shared patients with the default clinic",
+			"count", result.ModifiedCount)
+	}
+
+	return nil
+}
@@ -14,11 +14,22 @@ import (
 )
 
 type Condition struct {
-	Id        uuid.UUID  `bson:"_id"           json:"id"`
-	PatientId uuid.UUID  `bson:"patientId"     json:"patientId"` // Reference to Patient._id
-	Name      string     `bson:"name"          json:"name"`
-	Start     time.Time  `bson:"start"         json:"start"`
-	End       *time.Time `bson:"end,omitempty" json:"end,omitempty"`
+	Id        uuid.UUID  `bson:"_id"                 json:"id"`
+	PatientId uuid.UUID  `bson:"patientId"           json:"patientId"` // Reference to Patient._id
+	Name      string     `bson:"name"                json:"name"`
+	Start     time.Time  `bson:"start"               json:"start"`
+	End       *time.Time `bson:"end,omitempty"       json:"end,omitempty"`
+	Diagnosis *Diagnosis `bson:"diagnosis,omitempty" json:"diagnosis,omitempty"`
+
+	// Version is incremented on every write, see ErrVersionConflict.
+	Version int64 `bson:"version" json:"version"`
+}
+
+// Diagnosis is an ICD-10 coded diagnosis, the display is kept as it was when
+// the code was assigned.
+type Diagnosis struct {
+	Code    string `bson:"code"    json:"code"`
+	Display string `bson:"display" json:"display"`
 }
 
 func (m *MongoDb) CreateCondition(ctx context.Context, condition Condition) (Condition, error) {
@@ -28,12 +39,18 @@ func (m *MongoDb) CreateCondition(ctx context.Context, condition Condition) (Con
 
 	collection := m.Database.Collection(conditionsCollection)
 	condition.Id = uuid.New()
+	condition.Version = initialVersion
 
 	_, err := collection.InsertOne(ctx, condition)
 	if err != nil {
 		return Condition{}, fmt.Errorf("CreateCondition: failed to insert document: %w", err)
 	}
 
+	err = m.recordCreate(ctx, conditionsCollection, condition.Id, condition.Version, condition)
+	if err != nil {
+		return Condition{}, fmt.Errorf("CreateCondition: %w", err)
+	}
+
 	return condition, nil
 }
 
@@ -49,6 +66,9 @@ func (m *MongoDb) ConditionById(ctx context.Context, id uuid.UUID) (Condition, e
 		}
 		return Condition{}, fmt.Errorf("ConditionById: failed to find document: %w", err)
 	}
+	if err := m.patientExists(ctx, condition.PatientId); err != nil {
+		return Condition{}, fmt.Errorf("ConditionById patient check: %w", err)
+	}
 
 	return condition, nil
 }
@@ -61,6 +81,11 @@ func (m *MongoDb) FindConditionsByPatientId(
 ) ([]Condition, error) {
 	collection := m.Database.Collection(conditionsCollection)
 	conditions := make([]Condition, 0)
+	if visible, err := m.patientVisible(ctx, patientId); err != nil {
+		return nil, fmt.Errorf("FindConditionsByPatientId: %w", err)
+	} else if !visible {
+		return conditions, nil
+	}
 
 	filter := bson.M{"patientId": patientId}
 	if to != nil {
@@ -94,6 +119,9 @@ func (m *MongoDb) FindConditionsByPatientId(
 	return conditions, nil
 }
 
+// UpdateCondition replaces the condition if its version is still the one of
+// the given condition, otherwise returns ErrVersionConflict. The new state is
+// recorded as a revision.
 func (m *MongoDb) UpdateCondition(
 	ctx context.Context,
 	id uuid.UUID,
@@ -105,32 +133,59 @@ func (m *MongoDb) UpdateCondition(
 			err,
 		)
 	}
+	previous, err := m.ConditionById(ctx, id)
+	if err != nil {
+		return Condition{}, fmt.Errorf("UpdateCondition: %w", err)
+	}
+	if previous.Version != condition.Version {
+		return Condition{}, ErrVersionConflict
+	}
 
-	collection := m.Database.Collection(conditionsCollection)
-	filter := bson.M{"_id": id}
-
-	opts := options.FindOneAndReplace().SetReturnDocument(options.After)
+	condition.Id = id
+	condition.Version++
+	revert, err := m.recordChange(
+		ctx,
+		conditionsCollection,
+		id,
+		RevisionUpdate,
+		previous,
+		previous.Version,
+		condition,
+		condition.Version,
+	)
+	if err != nil {
+		return Condition{}, fmt.Errorf("UpdateCondition: %w", err)
+	}
 
-	var updatedCondition Condition
-	err := collection.FindOneAndReplace(ctx, filter, condition, opts).
-		Decode(&updatedCondition)
+	collection := m.Database.Collection(conditionsCollection)
+	filter := withVersion(bson.M{"_id": id}, previous.Version)
+	res, err := collection.ReplaceOne(ctx, filter, condition)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return Condition{}, ErrNotFound
-		}
+		revert()
 		return Condition{}, fmt.Errorf("UpdateCondition failed: %w", err)
 	}
+	if res.MatchedCount == 0 {
+		revert()
+		return Condition{}, m.missingOrConflict(ctx, conditionsCollection, id)
+	}
 
-	return updatedCondition, nil
+	return condition, nil
 }
 
+// FindConditionsByPatientIdAndDate returns the page of the patient's
+// conditions lasting on the date.
 func (m *MongoDb) FindConditionsByPatientIdAndDate(
 	ctx context.Context,
 	patientId uuid.UUID,
 	date time.Time,
-) ([]Condition, error) {
+	q ListQuery,
+) (Page[Condition], error) {
 	collection := m.Database.Collection(conditionsCollection)
-	conditions := make([]Condition, 0)
+	if visible, err := m.patientVisible(ctx, patientId); err != nil {
+		return Page[Condition]{}, fmt.Errorf("FindConditionsByPatientIdAndDate: %w", err)
+	} else if !visible {
+		return Page[Condition]{Items: make([]Condition, 0)}, nil
+	}
 
 	year, month, day := date.Date()
 	startOfDay := time.Date(year, month, day, 0, 0, 0, 0, date.Location())
@@ -144,46 +199,65 @@ func (m *MongoDb) FindConditionsByPatientIdAndDate(
 		},
 	}
 
-	findOptions := options.Find().SetSort(bson.D{{Key: "start", Value: 1}})
-
-	cursor, err := collection.Find(ctx, filter, findOptions)
+	page, err := findPage[Condition](ctx, collection, filter, q)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return conditions, nil
-		}
-		return nil, fmt.Errorf("FindConditionsByPatientIdAndDate find failed: %w", err)
+		return Page[Condition]{}, fmt.Errorf("FindConditionsByPatientIdAndDate: %w", err)
 	}
+	return page, nil
+}
 
-	defer func() {
-		if cerr := cursor.Close(ctx); cerr != nil {
-			slog.Warn("Failed to close conditions cursor", "error", cerr.Error())
-		}
-	}()
+// DeleteCondition deletes the condition, its last state is kept as a delete
+// revision. Returns ErrVersionConflict if the condition is modified while
+// being deleted.
+func (m *MongoDb) DeleteCondition(ctx context.Context, id uuid.UUID) error {
+	deleted, err := m.ConditionById(ctx, id)
+	if err != nil {
+		return fmt.Errorf("DeleteCondition: %w", err)
+	}
 
-	if err = cursor.All(ctx, &conditions); err != nil {
-		slog.Error("Failed to decode condition documents from cursor", "error", err)
-		return nil, fmt.Errorf("FindConditionsByPatientIdAndDate decode failed: %w", err)
+	revert, err := m.recordChange(
+		ctx,
+		conditionsCollection,
+		id,
+		RevisionDelete,
+		deleted,
+		deleted.Version,
+		deleted,
+		deleted.Version+1,
+	)
+	if err != nil {
+		return fmt.Errorf("DeleteCondition: %w", err)
 	}
 
-	if err = cursor.Err(); err != nil {
-		slog.Error("Conditions cursor iteration error", "error", err)
-		return nil, fmt.Errorf("FindConditionsByPatientIdAndDate cursor error: %w", err)
+	// only the recorded state is deleted
+	collection := m.Database.Collection(conditionsCollection)
+	filter := withVersion(bson.M{"_id": id}, deleted.Version)
+	res, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		revert()
+		return fmt.Errorf("DeleteCondition failed: %w", err)
+	}
+	if res.DeletedCount == 0 {
+		revert()
+		return m.missingOrConflict(ctx, conditionsCollection, id)
 	}
 
-	return conditions, nil
+	return nil
 }
 
 func (m *MongoDb) conditionExists(ctx context.Context, id uuid.UUID) error {
 	conditionsColl := m.Database.Collection(conditionsCollection)
 	filter := bson.M{"_id": id}
+	opts := options.FindOne().SetProjection(bson.M{"patientId": 1})
 
-	count, err := conditionsColl.CountDocuments(ctx, filter)
+	var condition Condition
+	err := conditionsColl.FindOne(ctx, filter, opts).Decode(&condition)
 	if err != nil {
-		return fmt.Errorf("conditionExists failed condition count check: %w", err)
-	}
-	if count == 0 {
-		return ErrNotFound
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("conditionExists failed condition check: %w", err)
 	}
 
-	return nil
+	return m.patientExists(ctx, condition.PatientId)
 }
@@ -17,6 +17,8 @@ var (
 
 type Db interface {
 	Disconnect(ctx context.Context) error
+	// Ping returns an error if the database is unreachable.
+	Ping(ctx context.Context) error
 
 	CreateAppointment(ctx context.Context, appointment Appointment) (Appointment, error)
 	AppointmentById(ctx context.Context, id uuid.UUID) (Appointment, error)
@@ -32,6 +34,11 @@ type Db interface {
 		from time.Time,
 		to *time.Time,
 	) ([]Appointment, error)
+	ListAppointments(
+		ctx context.Context,
+		filter AppointmentFilter,
+		query ListQuery,
+	) (Page[Appointment], error)
 	CancelAppointment(
 		ctx context.Context,
 		appointmentId uuid.UUID,
@@ -56,16 +63,85 @@ type Db interface {
 		newDateTime time.Time,
 	) (Appointment, error)
 	AppointmentsByConditionId(ctx context.Context, conditionId uuid.UUID) ([]Appointment, error)
+	ActiveAppointmentsByDoctorIds(
+		ctx context.Context,
+		doctorIds []uuid.UUID,
+		from time.Time,
+		to time.Time,
+	) ([]Appointment, error)
+	CompleteAppointment(ctx context.Context, appointmentId uuid.UUID) (Appointment, error)
+	ReassignAppointment(
+		ctx context.Context,
+		appointmentId uuid.UUID,
+		doctorId uuid.UUID,
+	) (Appointment, error)
+	DeleteAppointment(ctx context.Context, id uuid.UUID) error
+	LockPatientBookings(
+		ctx context.Context,
+		patientId uuid.UUID,
+		lease time.Duration,
+	) (BookingLock, bool, error)
+	UnlockPatientBookings(ctx context.Context, lock BookingLock) error
+
+	VisitNoteByAppointmentId(ctx context.Context, appointmentId uuid.UUID) (VisitNote, error)
+	VisitNotesByAppointmentIds(
+		ctx context.Context,
+		appointmentIds []uuid.UUID,
+	) ([]VisitNote, error)
+	SaveVisitNote(ctx context.Context, note VisitNote) (VisitNote, error)
+
+	CreateReferral(ctx context.Context, referral Referral) (Referral, error)
+	ReferralById(ctx context.Context, id uuid.UUID) (Referral, error)
+	ReferralsByPatientId(ctx context.Context, patientId uuid.UUID) ([]Referral, error)
+	CancelReferral(ctx context.Context, id uuid.UUID) (Referral, error)
 
 	CreatePatient(ctx context.Context, patient Patient) (Patient, error)
 	PatientById(ctx context.Context, id uuid.UUID) (Patient, error)
+	PatientsByIds(ctx context.Context, ids []uuid.UUID) ([]Patient, error)
 	PatientByEmail(ctx context.Context, email string) (Patient, error)
+	UpdatePatient(ctx context.Context, id uuid.UUID, patient Patient) (Patient, error)
+	SearchPatients(
+		ctx context.Context,
+		filter PatientFilter,
+		page int,
+		pageSize int,
+	) ([]Patient, PaginationResult, error)
+	DoctorsPatientIds(ctx context.Context, doctorId uuid.UUID) ([]uuid.UUID, error)
+	DeletePatient(ctx context.Context, id uuid.UUID) error
+	SharePatient(ctx context.Context, patientId uuid.UUID, clinicId uuid.UUID) (Patient, error)
+	UnsharePatient(ctx context.Context, patientId uuid.UUID, clinicId uuid.UUID) (Patient, error)
+
+	CreateMedicalHistoryFile(
+		ctx context.Context,
+		file MedicalHistoryFile,
+	) (MedicalHistoryFile, error)
+	MedicalHistoryFilesByPatientId(
+		ctx context.Context,
+		patientId uuid.UUID,
+		page int,
+		pageSize int,
+	) ([]MedicalHistoryFile, PaginationResult, error)
+
+	CreateClinic(ctx context.Context, clinic Clinic) (Clinic, error)
+	ClinicById(ctx context.Context, id uuid.UUID) (Clinic, error)
+
+	CreateLocation(ctx context.Context, location Location) (Location, error)
+	LocationById(ctx context.Context, id uuid.UUID) (Location, error)
+	Locations(ctx context.Context) ([]Location, error)
+
+	CreateStaff(ctx context.Context, staff Staff) (Staff, error)
+	StaffById(ctx context.Context, id uuid.UUID) (Staff, error)
+	StaffByEmail(ctx context.Context, email string) (Staff, error)
+	ClinicStaff(ctx context.Context) ([]Staff, error)
 
 	CreateDoctor(ctx context.Context, doctor Doctor) (Doctor, error)
 	DoctorById(ctx context.Context, id uuid.UUID) (Doctor, error)
+	DoctorsByIds(ctx context.Context, ids []uuid.UUID) ([]Doctor, error)
 	DoctorByEmail(ctx context.Context, email string) (Doctor, error)
 	AvailableDoctors(ctx context.Context, dateTime time.Time) ([]Doctor, error)
-	GetAllDoctors(ctx context.Context) ([]Doctor, error)
+	Doctors(ctx context.Context, filter DoctorFilter, query ListQuery) (Page[Doctor], error)
+	UpdateDoctor(ctx context.Context, id uuid.UUID, doctor Doctor) (Doctor, error)
+	DoctorsBySpecialization(ctx context.Context, specialization string) ([]Doctor, error)
 
 	CreateCondition(ctx context.Context, condition Condition) (Condition, error)
 	ConditionById(ctx context.Context, id uuid.UUID) (Condition, error)
@@ -80,7 +156,10 @@ type Db interface {
 		ctx context.Context,
 		patientId uuid.UUID,
 		date time.Time,
-	) ([]Condition, error)
+		query ListQuery,
+	) (Page[Condition], error)
+	DeleteCondition(ctx context.Context, id uuid.UUID) error
+	ConditionRevisions(ctx context.Context, conditionId uuid.UUID) ([]Revision[Condition], error)
 
 	CreatePrescription(ctx context.Context, prescription Prescription) (Prescription, error)
 	PrescriptionById(ctx context.Context, id uuid.UUID) (Prescription, error)
@@ -99,13 +178,28 @@ type Db interface {
 		ctx context.Context,
 		appointmentId uuid.UUID,
 	) ([]Prescription, error)
-	DeletePrescription(ctx context.Context, id uuid.UUID) error
+	DeletePrescription(ctx context.Context, id uuid.UUID, version *int64) error
+	PrescriptionRevisions(
+		ctx context.Context,
+		prescriptionId uuid.UUID,
+	) ([]Revision[Prescription], error)
 
-	CreateResource(ctx context.Context, name string, typ ResourceType) (Resource, error)
+	CreateResource(
+		ctx context.Context,
+		name string,
+		typ ResourceType,
+		locationId *uuid.UUID,
+	) (Resource, error)
 	ResourceById(ctx context.Context, id uuid.UUID) (Resource, error)
+	SetResourceLocation(
+		ctx context.Context,
+		resourceId uuid.UUID,
+		locationId *uuid.UUID,
+	) (Resource, error)
 	FindAvailableResourcesAtTime(
 		ctx context.Context,
 		appointmentDate time.Time,
+		facilityLocationIds []uuid.UUID,
 	) (struct {
 		Medicines  []Resource
 		Facilities []Resource
@@ -121,6 +215,24 @@ type Db interface {
 		endTime time.Time,
 	) (Reservation, error)
 	ResourcesByAppointmentId(ctx context.Context, appointmentId uuid.UUID) ([]Resource, error)
+	ReservationsByAppointmentIds(
+		ctx context.Context,
+		appointmentIds []uuid.UUID,
+	) ([]Reservation, error)
+	ReservationsByResourceIds(
+		ctx context.Context,
+		resourceIds []uuid.UUID,
+		from time.Time,
+		to time.Time,
+	) ([]Reservation, error)
+
+	ImportedResourceBySource(
+		ctx context.Context,
+		resourceType string,
+		system string,
+		value string,
+	) (ImportedResource, error)
+	CreateImportedResources(ctx context.Context, resources []ImportedResource) error
 }
 
 type PaginationResult struct {
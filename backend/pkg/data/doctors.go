@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,16 +15,45 @@ import (
 )
 
 type Doctor struct {
-	Id             uuid.UUID `bson:"_id"            json:"id"`
-	Email          string    `bson:"email"          json:"email"`
-	FirstName      string    `bson:"firstName"      json:"firstName"`
-	LastName       string    `bson:"lastName"       json:"lastName"`
-	Specialization string    `bson:"specialization" json:"specialization"`
+	Id        uuid.UUID `bson:"_id"       json:"id"`
+	Email     string    `bson:"email"     json:"email"`
+	FirstName string    `bson:"firstName" json:"firstName"`
+	LastName  string    `bson:"lastName"  json:"lastName"`
+	// Specializations of the doctor, the first one is the primary.
+	Specializations []string `bson:"specializations" json:"specializations"`
+	// Languages the doctor speaks, ISO 639-1 codes.
+	Languages []string `bson:"languages,omitempty" json:"languages,omitempty"`
+	Bio       *string  `bson:"bio,omitempty"       json:"bio,omitempty"`
+	PhotoUrl  *string  `bson:"photoUrl,omitempty"  json:"photoUrl,omitempty"`
+	// AppointmentTypes the doctor accepts, all types if empty.
+	AppointmentTypes []string `bson:"appointmentTypes,omitempty" json:"appointmentTypes,omitempty"`
+	// Active doctors can be booked, inactive ones keep their appointments.
+	Active   bool      `bson:"active"   json:"active"`
+	ClinicId uuid.UUID `bson:"clinicId" json:"clinicId"` // Reference to Clinic._id
+
+	// Version is incremented on every write, see ErrVersionConflict.
+	Version int64 `bson:"version" json:"version"`
+}
+
+// DoctorFilter selects doctors, empty fields don't restrict the search.
+type DoctorFilter struct {
+	// Specialization matches any of the doctor's specializations.
+	Specialization string
+	Language       string
+	Active         *bool
+}
+
+// Accepts reports whether the doctor accepts appointments of the type.
+func (d Doctor) Accepts(appointmentType string) bool {
+	return len(d.AppointmentTypes) == 0 || slices.Contains(d.AppointmentTypes, appointmentType)
 }
 
 func (m *MongoDb) CreateDoctor(ctx context.Context, doctor Doctor) (Doctor, error) {
 	collection := m.Database.Collection(doctorsCollection)
 	doctor.Id = uuid.New()
+	doctor.ClinicId = ClinicFromContext(ctx)
+	doctor.Active = true
+	doctor.Version = initialVersion
 
 	_, err := collection.InsertOne(ctx, doctor)
 	if err != nil {
@@ -43,7 +73,7 @@ func (m *MongoDb) CreateDoctor(ctx context.Context, doctor Doctor) (Doctor, erro
 
 func (m *MongoDb) DoctorById(ctx context.Context, id uuid.UUID) (Doctor, error) {
 	collection := m.Database.Collection(doctorsCollection)
-	filter := bson.M{"_id": id}
+	filter := inClinic(ctx, bson.M{"_id": id})
 	var doctor Doctor
 
 	err := collection.FindOne(ctx, filter).Decode(&doctor)
@@ -57,9 +87,34 @@ func (m *MongoDb) DoctorById(ctx context.Context, id uuid.UUID) (Doctor, error)
 	return doctor, nil
 }
 
+// DoctorsByIds returns the doctors of the context's clinic with any of the
+// ids, in no particular order. Ids of no such doctors are skipped.
+func (m *MongoDb) DoctorsByIds(ctx context.Context, ids []uuid.UUID) ([]Doctor, error) {
+	doctors := make([]Doctor, 0, len(ids))
+	if len(ids) == 0 {
+		return doctors, nil
+	}
+
+	collection := m.Database.Collection(doctorsCollection)
+	cursor, err := collection.Find(ctx, inClinic(ctx, bson.M{"_id": bson.M{"$in": ids}}))
+	if err != nil {
+		return nil, fmt.Errorf("DoctorsByIds find failed: %w", err)
+	}
+	defer func() {
+		if cerr := cursor.Close(ctx); cerr != nil {
+			slog.Warn("Failed to close doctors cursor", "error", cerr.Error())
+		}
+	}()
+
+	if err = cursor.All(ctx, &doctors); err != nil {
+		return nil, fmt.Errorf("DoctorsByIds decode failed: %w", err)
+	}
+	return doctors, nil
+}
+
 func (m *MongoDb) DoctorByEmail(ctx context.Context, email string) (Doctor, error) {
 	collection := m.Database.Collection(doctorsCollection)
-	filter := bson.M{"email": email}
+	filter := inClinic(ctx, bson.M{"email": email})
 	var doctor Doctor
 
 	err := collection.FindOne(ctx, filter).Decode(&doctor)
@@ -79,11 +134,11 @@ func (m *MongoDb) AvailableDoctors(ctx context.Context, dateTime time.Time) ([]D
 
 	busyStatuses := []string{"requested", "Scheduled"}
 
-	appointmentFilter := bson.M{
+	appointmentFilter := inClinic(ctx, bson.M{
 		"appointmentDateTime": bson.M{"$lte": dateTime},
 		"endTime":             bson.M{"$gt": dateTime},
 		"status":              bson.M{"$in": busyStatuses},
-	}
+	})
 
 	findOptions := options.Find().SetProjection(bson.M{"doctorId": 1, "_id": 0})
 
@@ -132,9 +187,10 @@ func (m *MongoDb) AvailableDoctors(ctx context.Context, dateTime time.Time) ([]D
 		busyDoctorIds = append(busyDoctorIds, id)
 	}
 
-	doctorFilter := bson.M{
-		"_id": bson.M{"$nin": busyDoctorIds},
-	}
+	doctorFilter := inClinic(ctx, bson.M{
+		"_id":    bson.M{"$nin": busyDoctorIds},
+		"active": true,
+	})
 
 	doctorCursor, err := doctorCollection.Find(
 		ctx,
@@ -174,7 +230,7 @@ func (m *MongoDb) AvailableDoctors(ctx context.Context, dateTime time.Time) ([]D
 
 func (m *MongoDb) doctorExists(ctx context.Context, id uuid.UUID) error {
 	doctorsColl := m.Database.Collection(doctorsCollection)
-	filter := bson.M{"_id": id}
+	filter := inClinic(ctx, bson.M{"_id": id})
 
 	count, err := doctorsColl.CountDocuments(ctx, filter)
 	if err != nil {
@@ -187,38 +243,100 @@ func (m *MongoDb) doctorExists(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-func (m *MongoDb) GetAllDoctors(ctx context.Context) ([]Doctor, error) {
+// Doctors returns the page of doctors matching the filter.
+func (m *MongoDb) Doctors(ctx context.Context, f DoctorFilter, q ListQuery) (Page[Doctor], error) {
 	collection := m.Database.Collection(doctorsCollection)
-	doctors := make([]Doctor, 0)
 
-	filter := bson.M{}
+	filter := inClinic(ctx, bson.M{})
+	if f.Specialization != "" {
+		filter["specializations"] = f.Specialization
+	}
+	if f.Language != "" {
+		filter["languages"] = f.Language
+	}
+	if f.Active != nil {
+		filter["active"] = *f.Active
+	}
 
-	findOptions := options.Find().SetSort(
-		bson.D{{Key: "lastName", Value: 1}, {Key: "firstName", Value: 1}},
-	)
+	page, err := findPage[Doctor](ctx, collection, filter, q)
+	if err != nil {
+		return Page[Doctor]{}, fmt.Errorf("Doctors: %w", err)
+	}
+	return page, nil
+}
+
+// UpdateDoctor replaces the doctor if its version is still the one of the
+// given doctor, otherwise returns ErrVersionConflict.
+func (m *MongoDb) UpdateDoctor(ctx context.Context, id uuid.UUID, doctor Doctor) (Doctor, error) {
+	collection := m.Database.Collection(doctorsCollection)
+	filter := withVersion(inClinic(ctx, bson.M{"_id": id}), doctor.Version)
+	doctor.Id = id
+	doctor.ClinicId = ClinicFromContext(ctx)
+	doctor.Version++
+
+	opts := options.FindOneAndReplace().SetReturnDocument(options.After)
 
-	cursor, err := collection.Find(ctx, filter, findOptions)
+	var updatedDoctor Doctor
+	err := collection.FindOneAndReplace(ctx, filter, doctor, opts).Decode(&updatedDoctor)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return doctors, nil
+			return Doctor{}, m.missingOrConflict(ctx, doctorsCollection, id)
 		}
-		return nil, fmt.Errorf("GetAllDoctors find failed: %w", err)
+		return Doctor{}, fmt.Errorf("UpdateDoctor failed: %w", err)
 	}
 
+	return updatedDoctor, nil
+}
+
+// seedDoctorProfiles moves the single specialization of doctors created
+// before doctors had profiles into their specializations and activates them.
+func (m *MongoDb) seedDoctorProfiles(ctx context.Context) error {
+	result, err := m.Database.Collection(doctorsCollection).UpdateMany(
+		ctx,
+		bson.M{"specializations": bson.M{"$exists": false}},
+		bson.A{
+			bson.M{"$set": bson.M{
+				"specializations": bson.A{"$specialization"},
+				"active":          true,
+			}},
+			bson.M{"$unset": "specialization"},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("seedDoctorProfiles: %w", err)
+	}
+	if result.ModifiedCount > 0 {
+		slog.InfoContext(ctx, "Migrated doctors to profiles", "count", result.ModifiedCount)
+	}
+
+	return nil
+}
+
+// DoctorsBySpecialization returns the active doctors with the specialization,
+// among any of theirs, ordered by their name.
+func (m *MongoDb) DoctorsBySpecialization(
+	ctx context.Context,
+	specialization string,
+) ([]Doctor, error) {
+	collection := m.Database.Collection(doctorsCollection)
+	filter := inClinic(ctx, bson.M{"specializations": specialization, "active": true})
+	opts := options.Find().SetSort(
+		bson.D{{Key: "lastName", Value: 1}, {Key: "firstName", Value: 1}},
+	)
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("DoctorsBySpecialization: %w", err)
+	}
 	defer func() {
 		if cerr := cursor.Close(ctx); cerr != nil {
 			slog.Warn("Failed to close doctors cursor", "error", cerr.Error())
 		}
 	}()
 
+	doctors := make([]Doctor, 0)
 	if err = cursor.All(ctx, &doctors); err != nil {
-		slog.Error("Failed to decode doctor documents from cursor", "error", err)
-		return nil, fmt.Errorf("GetAllDoctors decode failed: %w", err)
-	}
-
-	if err = cursor.Err(); err != nil {
-		slog.Error("Doctors cursor iteration error", "error", err)
-		return nil, fmt.Errorf("GetAllDoctors cursor error: %w", err)
+		return nil, fmt.Errorf("DoctorsBySpecialization decode failed: %w", err)
 	}
 
 	return doctors, nil
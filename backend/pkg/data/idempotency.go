@@ -0,0 +1,149 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// IdempotentRequest is a request made with an idempotency key. The key is
+// reserved for the request while it's handled, afterwards its response is
+// replayed to retries. Requests are deleted by a TTL index once they expire.
+type IdempotentRequest struct {
+	// Id is the key scoped to the clinic and the acting user, so clients
+	// can't replay responses of others.
+	Id          string    `bson:"_id"`
+	ClinicId    uuid.UUID `bson:"clinicId"` // Reference to Clinic._id
+	Key         string    `bson:"key"`
+	RequestHash string    `bson:"requestHash"`
+	// Lease identifies the reservation, only its holder completes or releases
+	// the key.
+	Lease       uuid.UUID           `bson:"lease"`
+	LockedUntil time.Time           `bson:"lockedUntil"`
+	Response    *IdempotentResponse `bson:"response,omitempty"`
+	ExpiresAt   time.Time           `bson:"expiresAt"`
+}
+
+type IdempotentResponse struct {
+	Status int                 `bson:"status"`
+	Header map[string][]string `bson:"header"`
+	Body   []byte              `bson:"body"`
+}
+
+// ReserveIdempotencyKey reserves the key for the request with the hash until
+// the lease passes, the request expires after ttl. If the key is already
+// reserved or answered, its request is returned with false. Reservations
+// whose lease passed without a response, e.g. because the server stopped,
+// and expired requests not yet deleted are taken over.
+func (m *MongoDb) ReserveIdempotencyKey(
+	ctx context.Context,
+	key string,
+	requestHash string,
+	lease time.Duration,
+	ttl time.Duration,
+) (IdempotentRequest, bool, error) {
+	collection := m.Database.Collection(idempotencyKeysCollection)
+	now := time.Now()
+	request := IdempotentRequest{
+		Id:          idempotencyId(ctx, key),
+		ClinicId:    ClinicFromContext(ctx),
+		Key:         key,
+		RequestHash: requestHash,
+		Lease:       uuid.New(),
+		LockedUntil: now.Add(lease),
+		ExpiresAt:   now.Add(ttl),
+	}
+	filter := bson.M{
+		"_id": request.Id,
+		"$or": bson.A{
+			bson.M{"response": bson.M{"$exists": false}, "lockedUntil": bson.M{"$lte": now}},
+			bson.M{"expiresAt": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"clinicId":    request.ClinicId,
+			"key":         request.Key,
+			"requestHash": request.RequestHash,
+			"lease":       request.Lease,
+			"lockedUntil": request.LockedUntil,
+			"expiresAt":   request.ExpiresAt,
+		},
+		"$unset": bson.M{"response": ""},
+	}
+
+	// the reserved request may expire and be deleted before it's read, then
+	// the key is free again
+	for range 2 {
+		_, err := collection.UpdateOne(ctx, filter, update, options.UpdateOne().SetUpsert(true))
+		if err == nil {
+			return request, true, nil
+		}
+		// the upsert inserts a request with the _id of one not matching the
+		// filter
+		if !mongo.IsDuplicateKeyError(err) {
+			return IdempotentRequest{}, false, fmt.Errorf("ReserveIdempotencyKey: %w", err)
+		}
+
+		var reserved IdempotentRequest
+		err = collection.FindOne(ctx, bson.M{"_id": request.Id}).Decode(&reserved)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		} else if err != nil {
+			return IdempotentRequest{}, false, fmt.Errorf("ReserveIdempotencyKey: %w", err)
+		}
+		return reserved, false, nil
+	}
+	return IdempotentRequest{}, false, fmt.Errorf(
+		"ReserveIdempotencyKey: key %q keeps expiring",
+		key,
+	)
+}
+
+// CompleteIdempotencyKey stores the response of the request, unless the
+// lease was taken over.
+func (m *MongoDb) CompleteIdempotencyKey(
+	ctx context.Context,
+	request IdempotentRequest,
+	response IdempotentResponse,
+) error {
+	collection := m.Database.Collection(idempotencyKeysCollection)
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{"_id": request.Id, "lease": request.Lease},
+		bson.M{"$set": bson.M{"response": response}},
+	)
+	if err != nil {
+		return fmt.Errorf("CompleteIdempotencyKey: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey frees the key of a request without a response, so
+// a retry is handled anew.
+func (m *MongoDb) ReleaseIdempotencyKey(ctx context.Context, request IdempotentRequest) error {
+	collection := m.Database.Collection(idempotencyKeysCollection)
+	_, err := collection.DeleteOne(ctx, bson.M{
+		"_id":      request.Id,
+		"lease":    request.Lease,
+		"response": bson.M{"$exists": false},
+	})
+	if err != nil {
+		return fmt.Errorf("ReleaseIdempotencyKey: %w", err)
+	}
+	return nil
+}
+
+func idempotencyId(ctx context.Context, key string) string {
+	user := "anonymous"
+	if actor, ok := ActorFromContext(ctx); ok {
+		user = actor.Id.String()
+	}
+	return ClinicFromContext(ctx).String() + ":" + user + ":" + key
+}
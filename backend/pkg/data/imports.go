@@ -0,0 +1,92 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var ErrAlreadyImported = errors.New("resource with the source identifier was already imported")
+
+// ImportedResource maps a resource imported from another system, identified
+// by its source identifier, to the record created from it.
+type ImportedResource struct {
+	Id           uuid.UUID `bson:"_id"          json:"id"`
+	ResourceType string    `bson:"resourceType" json:"resourceType"`
+	System       string    `bson:"system"       json:"system"`
+	Value        string    `bson:"value"        json:"value"`
+	LocalId      uuid.UUID `bson:"localId"      json:"localId"`
+	ClinicId     uuid.UUID `bson:"clinicId"     json:"clinicId"` // Reference to Clinic._id
+	ImportedAt   time.Time `bson:"importedAt"   json:"importedAt"`
+}
+
+func (m *MongoDb) ImportedResourceBySource(
+	ctx context.Context,
+	resourceType string,
+	system string,
+	value string,
+) (ImportedResource, error) {
+	collection := m.Database.Collection(fhirImportsCollection)
+	filter := inClinic(ctx, bson.M{"resourceType": resourceType, "system": system, "value": value})
+
+	var imported ImportedResource
+	err := collection.FindOne(ctx, filter).Decode(&imported)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ImportedResource{}, ErrNotFound
+		}
+		return ImportedResource{}, fmt.Errorf("ImportedResourceBySource: %w", err)
+	}
+
+	return imported, nil
+}
+
+// CreateImportedResources stores all mappings or none of them. If any source
+// identifier was already imported, ErrAlreadyImported is returned.
+func (m *MongoDb) CreateImportedResources(
+	ctx context.Context,
+	resources []ImportedResource,
+) error {
+	if len(resources) == 0 {
+		return nil
+	}
+
+	collection := m.Database.Collection(fhirImportsCollection)
+	docs := make([]any, len(resources))
+	ids := make([]uuid.UUID, len(resources))
+	for i := range resources {
+		resources[i].Id = uuid.New()
+		resources[i].ClinicId = ClinicFromContext(ctx)
+		docs[i] = resources[i]
+		ids[i] = resources[i].Id
+	}
+
+	_, err := collection.InsertMany(ctx, docs)
+	if err == nil {
+		return nil
+	}
+
+	// ids are fresh, so only documents inserted by this call are removed
+	if _, delErr := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); delErr != nil {
+		slog.Error(
+			"Failed to remove partially inserted import mappings",
+			"error", delErr.Error(),
+		)
+	}
+
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) {
+		for _, we := range bulkErr.WriteErrors {
+			if we.Code == 11000 {
+				return ErrAlreadyImported
+			}
+		}
+	}
+	return fmt.Errorf("CreateImportedResources: failed to insert documents: %w", err)
+}
@@ -0,0 +1,378 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Reference describes a field of a collection holding the _id of a document
+// in another collection.
+type Reference struct {
+	Collection string
+	Field      string
+	Target     string
+	// Optional references are removed on repair, documents with a broken
+	// required reference are deleted.
+	Optional bool
+	// Filter limits the documents holding the reference, used when the target
+	// depends on the document.
+	Filter bson.M
+}
+
+// References lists every reference between the collections.
+var References = []Reference{
+	{Collection: appointmentsCollection, Field: "patientId", Target: patientsCollection},
+	{Collection: appointmentsCollection, Field: "doctorId", Target: doctorsCollection},
+	{
+		Collection: appointmentsCollection,
+		Field:      "conditionId",
+		Target:     conditionsCollection,
+		Optional:   true,
+	},
+	{Collection: conditionsCollection, Field: "patientId", Target: patientsCollection},
+	{Collection: prescriptionsCollection, Field: "patientId", Target: patientsCollection},
+	{
+		Collection: prescriptionsCollection,
+		Field:      "appointmentId",
+		Target:     appointmentsCollection,
+		Optional:   true,
+	},
+	{Collection: reservationsCollection, Field: "appointmentId", Target: appointmentsCollection},
+	{Collection: reservationsCollection, Field: "resourceId", Target: resourcesCollection},
+	{Collection: visitNotesCollection, Field: "appointmentId", Target: appointmentsCollection},
+	{Collection: visitNotesCollection, Field: "doctorId", Target: doctorsCollection},
+	{
+		Collection: appointmentsCollection,
+		Field:      "referralId",
+		Target:     referralsCollection,
+		Optional:   true,
+	},
+	{Collection: referralsCollection, Field: "patientId", Target: patientsCollection},
+	{Collection: medicalHistoryFilesCollection, Field: "patientId", Target: patientsCollection},
+	{Collection: referralsCollection, Field: "fromDoctorId", Target: doctorsCollection},
+	{
+		Collection: referralsCollection,
+		Field:      "toDoctorId",
+		Target:     doctorsCollection,
+		Optional:   true,
+	},
+	{
+		Collection: referralsCollection,
+		Field:      "conditionId",
+		Target:     conditionsCollection,
+		Optional:   true,
+	},
+	{
+		Collection: referralsCollection,
+		Field:      "appointmentId",
+		Target:     appointmentsCollection,
+		Optional:   true,
+	},
+	{
+		Collection: fhirImportsCollection,
+		Field:      "localId",
+		Target:     patientsCollection,
+		Filter:     bson.M{"resourceType": "Patient"},
+	},
+	{
+		Collection: fhirImportsCollection,
+		Field:      "localId",
+		Target:     conditionsCollection,
+		Filter:     bson.M{"resourceType": "Condition"},
+	},
+	{
+		Collection: fhirImportsCollection,
+		Field:      "localId",
+		Target:     appointmentsCollection,
+		Filter:     bson.M{"resourceType": "Appointment"},
+	},
+	{
+		Collection: fhirImportsCollection,
+		Field:      "localId",
+		Target:     prescriptionsCollection,
+		Filter:     bson.M{"resourceType": "MedicationRequest"},
+	},
+	{Collection: doctorsCollection, Field: "clinicId", Target: clinicsCollection},
+	{Collection: appointmentsCollection, Field: "clinicId", Target: clinicsCollection},
+	{Collection: resourcesCollection, Field: "clinicId", Target: clinicsCollection},
+	{Collection: reservationsCollection, Field: "clinicId", Target: clinicsCollection},
+	{Collection: visitNotesCollection, Field: "clinicId", Target: clinicsCollection},
+	{Collection: referralsCollection, Field: "clinicId", Target: clinicsCollection},
+	{Collection: fhirImportsCollection, Field: "clinicId", Target: clinicsCollection},
+	{Collection: locationsCollection, Field: "clinicId", Target: clinicsCollection},
+	{Collection: staffCollection, Field: "clinicId", Target: clinicsCollection},
+	{Collection: medicalHistoryFilesCollection, Field: "clinicId", Target: clinicsCollection},
+	{
+		Collection: locationsCollection,
+		Field:      "parentId",
+		Target:     locationsCollection,
+		Optional:   true,
+	},
+	{
+		Collection: resourcesCollection,
+		Field:      "locationId",
+		Target:     locationsCollection,
+		Optional:   true,
+	},
+	{
+		Collection: appointmentsCollection,
+		Field:      "locationId",
+		Target:     locationsCollection,
+		Optional:   true,
+	},
+}
+
+type IntegrityIssueKind string
+
+const (
+	// IssueOrphanedReference is a document referencing a missing document.
+	IssueOrphanedReference IntegrityIssueKind = "orphaned-reference"
+	// IssueStaleReservation is a reservation of a cancelled or denied appointment.
+	IssueStaleReservation IntegrityIssueKind = "stale-reservation"
+	// IssueInvalidTimeRange is an appointment ending before it starts.
+	IssueInvalidTimeRange IntegrityIssueKind = "invalid-time-range"
+)
+
+type IntegrityIssue struct {
+	Kind        IntegrityIssueKind `json:"kind"`
+	Collection  string             `json:"collection"`
+	DocumentId  uuid.UUID          `json:"documentId"`
+	Description string             `json:"description"`
+
+	reference *Reference
+}
+
+func (i IntegrityIssue) String() string {
+	return fmt.Sprintf("%s %s/%s: %s", i.Kind, i.Collection, i.DocumentId, i.Description)
+}
+
+// maxRepairPasses bounds RepairIntegrity, a repair can create new issues, e.g.
+// deleting an orphaned condition orphans its appointments.
+const maxRepairPasses = 5
+
+// CheckIntegrity finds documents with broken references, reservations of
+// cancelled or denied appointments and appointments ending before they start.
+func (m *MongoDb) CheckIntegrity(ctx context.Context) ([]IntegrityIssue, error) {
+	issues := make([]IntegrityIssue, 0)
+	for _, ref := range References {
+		found, err := m.orphanedReferences(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("CheckIntegrity: %w", err)
+		}
+		issues = append(issues, found...)
+	}
+
+	found, err := m.staleReservations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("CheckIntegrity: %w", err)
+	}
+	issues = append(issues, found...)
+
+	found, err = m.invalidAppointmentTimeRanges(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("CheckIntegrity: %w", err)
+	}
+	issues = append(issues, found...)
+
+	return issues, nil
+}
+
+// RepairIntegrity checks the integrity and repairs found issues until there
+// are none left. Broken optional references are removed, documents with a
+// broken required reference and stale reservations are deleted, appointments
+// ending before they start are given the default one hour duration.
+// Returns the repaired issues.
+func (m *MongoDb) RepairIntegrity(ctx context.Context) ([]IntegrityIssue, error) {
+	repaired := make([]IntegrityIssue, 0)
+	for range maxRepairPasses {
+		issues, err := m.CheckIntegrity(ctx)
+		if err != nil {
+			return repaired, fmt.Errorf("RepairIntegrity: %w", err)
+		}
+		if len(issues) == 0 {
+			return repaired, nil
+		}
+
+		for _, issue := range issues {
+			if err := m.repairIssue(ctx, issue); err != nil {
+				return repaired, fmt.Errorf("RepairIntegrity %s: %w", issue, err)
+			}
+			repaired = append(repaired, issue)
+		}
+	}
+
+	return repaired, fmt.Errorf(
+		"RepairIntegrity: issues remain after %d passes",
+		maxRepairPasses,
+	)
+}
+
+func (m *MongoDb) repairIssue(ctx context.Context, issue IntegrityIssue) error {
+	coll := m.Database.Collection(issue.Collection)
+	filter := bson.M{"_id": issue.DocumentId}
+
+	switch issue.Kind {
+	case IssueOrphanedReference:
+		if issue.reference.Optional {
+			update := incVersion(bson.M{"$unset": bson.M{issue.reference.Field: ""}})
+			_, err := coll.UpdateOne(ctx, filter, update)
+			return err
+		}
+		if issue.Collection == appointmentsCollection {
+			return m.DeleteAppointment(ctx, issue.DocumentId)
+		}
+		_, err := coll.DeleteOne(ctx, filter)
+		return err
+	case IssueStaleReservation:
+		_, err := coll.DeleteOne(ctx, filter)
+		return err
+	case IssueInvalidTimeRange:
+		update := bson.A{bson.M{"$set": bson.M{
+			"endTime": bson.M{"$dateAdd": bson.M{
+				"startDate": "$appointmentDateTime",
+				"unit":      "millisecond",
+				"amount":    time.Hour.Milliseconds(),
+			}},
+			"version": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}},
+		}}}
+		_, err := coll.UpdateOne(ctx, filter, update)
+		return err
+	}
+
+	return fmt.Errorf("unknown integrity issue kind %q", issue.Kind)
+}
+
+func (m *MongoDb) orphanedReferences(ctx context.Context, ref Reference) ([]IntegrityIssue, error) {
+	match := bson.M{ref.Field: bson.M{"$type": "binData"}}
+	for k, v := range ref.Filter {
+		match[k] = v
+	}
+
+	pipeline := bson.A{
+		bson.M{"$match": match},
+		bson.M{"$lookup": bson.M{
+			"from":         ref.Target,
+			"localField":   ref.Field,
+			"foreignField": "_id",
+			"as":           "target",
+		}},
+		bson.M{"$match": bson.M{"target": bson.M{"$size": 0}}},
+		bson.M{"$project": bson.M{"_id": 1, "ref": "$" + ref.Field}},
+	}
+
+	var docs []struct {
+		Id  uuid.UUID `bson:"_id"`
+		Ref uuid.UUID `bson:"ref"`
+	}
+	err := m.aggregate(ctx, ref.Collection, pipeline, &docs)
+	if err != nil {
+		return nil, fmt.Errorf("orphanedReferences %s.%s: %w", ref.Collection, ref.Field, err)
+	}
+
+	issues := make([]IntegrityIssue, len(docs))
+	for i, doc := range docs {
+		issues[i] = IntegrityIssue{
+			Kind:        IssueOrphanedReference,
+			Collection:  ref.Collection,
+			DocumentId:  doc.Id,
+			Description: fmt.Sprintf("%s references missing %s/%s", ref.Field, ref.Target, doc.Ref),
+			reference:   &ref,
+		}
+	}
+	return issues, nil
+}
+
+func (m *MongoDb) staleReservations(ctx context.Context) ([]IntegrityIssue, error) {
+	pipeline := bson.A{
+		bson.M{"$lookup": bson.M{
+			"from":         appointmentsCollection,
+			"localField":   "appointmentId",
+			"foreignField": "_id",
+			"as":           "appointment",
+		}},
+		bson.M{"$unwind": "$appointment"},
+		bson.M{"$match": bson.M{
+			"appointment.status": bson.M{"$in": []string{"cancelled", "denied"}},
+		}},
+		bson.M{"$project": bson.M{
+			"_id":           1,
+			"appointmentId": 1,
+			"status":        "$appointment.status",
+		}},
+	}
+
+	var docs []struct {
+		Id            uuid.UUID `bson:"_id"`
+		AppointmentId uuid.UUID `bson:"appointmentId"`
+		Status        string    `bson:"status"`
+	}
+	if err := m.aggregate(ctx, reservationsCollection, pipeline, &docs); err != nil {
+		return nil, fmt.Errorf("staleReservations: %w", err)
+	}
+
+	issues := make([]IntegrityIssue, len(docs))
+	for i, doc := range docs {
+		issues[i] = IntegrityIssue{
+			Kind:       IssueStaleReservation,
+			Collection: reservationsCollection,
+			DocumentId: doc.Id,
+			Description: fmt.Sprintf(
+				"reserved for %s appointment %s",
+				doc.Status,
+				doc.AppointmentId,
+			),
+		}
+	}
+	return issues, nil
+}
+
+func (m *MongoDb) invalidAppointmentTimeRanges(ctx context.Context) ([]IntegrityIssue, error) {
+	filter := bson.M{"$expr": bson.M{"$lt": bson.A{"$endTime", "$appointmentDateTime"}}}
+
+	cursor, err := m.Database.Collection(appointmentsCollection).Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalidAppointmentTimeRanges find failed: %w", err)
+	}
+
+	var appts []Appointment
+	if err := cursor.All(ctx, &appts); err != nil {
+		return nil, fmt.Errorf("invalidAppointmentTimeRanges decode failed: %w", err)
+	}
+
+	issues := make([]IntegrityIssue, len(appts))
+	for i, appt := range appts {
+		issues[i] = IntegrityIssue{
+			Kind:       IssueInvalidTimeRange,
+			Collection: appointmentsCollection,
+			DocumentId: appt.Id,
+			Description: fmt.Sprintf(
+				"ends at %s before it starts at %s",
+				appt.EndTime.Format(time.RFC3339),
+				appt.AppointmentDateTime.Format(time.RFC3339),
+			),
+		}
+	}
+	return issues, nil
+}
+
+func (m *MongoDb) aggregate(ctx context.Context, collection string, pipeline bson.A, dst any) error {
+	cursor, err := m.Database.Collection(collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("aggregation failed: %w", err)
+	}
+	defer func() {
+		if cerr := cursor.Close(ctx); cerr != nil {
+			slog.Warn("Failed to close cursor", "error", cerr.Error())
+		}
+	}()
+
+	if err := cursor.All(ctx, dst); err != nil {
+		return fmt.Errorf("decode failed: %w", err)
+	}
+	return nil
+}
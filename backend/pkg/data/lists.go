@@ -0,0 +1,158 @@
+package data
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var ErrInvalidCursor = errors.New("cursor doesn't belong to the list")
+
+// ListQuery selects a page of a list sorted by its fields. Ties are broken by
+// _id, so the order is stable and pages don't skip or repeat documents.
+type ListQuery struct {
+	// SortBy are the fields to sort by, in order of precedence.
+	SortBy     []string
+	Descending bool
+	// After is the cursor of the previous page, the first page if nil.
+	After Cursor
+	// Limit of the documents on the page, all of them if 0.
+	Limit int
+}
+
+// Cursor is the position after the last document of a page, the values of
+// its sort fields followed by its _id.
+type Cursor []bson.RawValue
+
+// Page is a page of a list, Next is nil on the last page.
+type Page[T any] struct {
+	Items []T
+	Next  Cursor
+	Total int64
+}
+
+type encodedCursor struct {
+	Keys []bson.RawValue `bson:"k"`
+}
+
+// String encodes the cursor to an opaque string, see ParseCursor.
+func (c Cursor) String() string {
+	raw, err := bson.Marshal(encodedCursor{Keys: c})
+	if err != nil {
+		// values of a cursor come from decoded documents and are always valid
+		panic(fmt.Sprintf("Cursor.String: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// ParseCursor decodes a cursor encoded by Cursor.String.
+func ParseCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("ParseCursor: %w", ErrInvalidCursor)
+	}
+	var cursor encodedCursor
+	if err := bson.Unmarshal(raw, &cursor); err != nil || len(cursor.Keys) == 0 {
+		return nil, fmt.Errorf("ParseCursor: %w", ErrInvalidCursor)
+	}
+	return cursor.Keys, nil
+}
+
+// findPage returns the page of documents matching the filter selected by the
+// query, with the total count of matching documents.
+func findPage[T any](
+	ctx context.Context,
+	collection *mongo.Collection,
+	filter bson.M,
+	q ListQuery,
+) (Page[T], error) {
+	fields := append(append([]string{}, q.SortBy...), "_id")
+	if q.After != nil && len(q.After) != len(fields) {
+		return Page[T]{}, ErrInvalidCursor
+	}
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return Page[T]{}, fmt.Errorf("findPage count failed: %w", err)
+	}
+
+	direction := 1
+	if q.Descending {
+		direction = -1
+	}
+	sort := bson.D{}
+	for _, field := range fields {
+		sort = append(sort, bson.E{Key: field, Value: direction})
+	}
+	opts := options.Find().SetSort(sort)
+	if q.Limit > 0 {
+		// one more document tells whether there is a next page
+		opts.SetLimit(int64(q.Limit) + 1)
+	}
+	if q.After != nil {
+		filter = bson.M{"$and": bson.A{filter, afterCursor(fields, q.After, q.Descending)}}
+	}
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return Page[T]{}, fmt.Errorf("findPage find failed: %w", err)
+	}
+	defer func() {
+		if cerr := cursor.Close(ctx); cerr != nil {
+			slog.Warn("Failed to close cursor", "error", cerr.Error())
+		}
+	}()
+
+	page := Page[T]{Items: make([]T, 0), Total: total}
+	var last bson.Raw
+	for cursor.Next(ctx) {
+		if q.Limit > 0 && len(page.Items) == q.Limit {
+			page.Next = cursorOf(last, fields)
+			break
+		}
+		var item T
+		if err := cursor.Decode(&item); err != nil {
+			return Page[T]{}, fmt.Errorf("findPage decode failed: %w", err)
+		}
+		page.Items = append(page.Items, item)
+		last = append(last[:0], cursor.Current...)
+	}
+	if err := cursor.Err(); err != nil {
+		return Page[T]{}, fmt.Errorf("findPage cursor error: %w", err)
+	}
+
+	return page, nil
+}
+
+// afterCursor returns the filter matching documents sorted after the cursor.
+func afterCursor(fields []string, after Cursor, descending bool) bson.M {
+	op := "$gt"
+	if descending {
+		op = "$lt"
+	}
+
+	or := make(bson.A, len(fields))
+	for i, field := range fields {
+		clause := bson.M{field: bson.M{op: after[i]}}
+		for j := range i {
+			clause[fields[j]] = after[j]
+		}
+		or[i] = clause
+	}
+	return bson.M{"$or": or}
+}
+
+func cursorOf(doc bson.Raw, fields []string) Cursor {
+	cursor := make(Cursor, len(fields))
+	for i, field := range fields {
+		cursor[i] = doc.Lookup(strings.Split(field, ".")...)
+	}
+	return cursor
+}
@@ -0,0 +1,114 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type LocationKind string
+
+const (
+	LocationSite     LocationKind = "site"
+	LocationBuilding LocationKind = "building"
+	LocationRoom     LocationKind = "room"
+)
+
+// OpeningHours of a location on a day of the week, Opens and Closes are UTC
+// times formatted as "15:04".
+type OpeningHours struct {
+	Weekday time.Weekday `bson:"weekday" json:"weekday"`
+	Opens   string       `bson:"opens"   json:"opens"`
+	Closes  string       `bson:"closes"  json:"closes"`
+}
+
+// Location is a clinic site, a building of a site or a room of a building.
+// Resources are assigned to locations and appointments take place at them.
+type Location struct {
+	Id       uuid.UUID    `bson:"_id"                json:"id"`
+	Name     string       `bson:"name"               json:"name"`
+	Kind     LocationKind `bson:"kind"               json:"kind"`
+	ParentId *uuid.UUID   `bson:"parentId,omitempty" json:"parentId,omitempty"` // Reference to Location._id
+	// OpeningHours are empty if the location is open when its parent is.
+	OpeningHours []OpeningHours `bson:"openingHours,omitempty" json:"openingHours,omitempty"`
+	ClinicId     uuid.UUID      `bson:"clinicId"               json:"clinicId"` // Reference to Clinic._id
+	CreatedAt    time.Time      `bson:"createdAt"              json:"createdAt"`
+}
+
+func (m *MongoDb) CreateLocation(ctx context.Context, location Location) (Location, error) {
+	if location.ParentId != nil {
+		if err := m.locationExists(ctx, *location.ParentId); err != nil {
+			return Location{}, fmt.Errorf("CreateLocation parent check: %w", err)
+		}
+	}
+
+	location.Id = uuid.New()
+	location.ClinicId = ClinicFromContext(ctx)
+	location.CreatedAt = time.Now()
+
+	collection := m.Database.Collection(locationsCollection)
+	if _, err := collection.InsertOne(ctx, location); err != nil {
+		return Location{}, fmt.Errorf("CreateLocation: failed to insert document: %w", err)
+	}
+
+	return location, nil
+}
+
+func (m *MongoDb) LocationById(ctx context.Context, id uuid.UUID) (Location, error) {
+	collection := m.Database.Collection(locationsCollection)
+
+	var location Location
+	err := collection.FindOne(ctx, inClinic(ctx, bson.M{"_id": id})).Decode(&location)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Location{}, ErrNotFound
+		}
+		return Location{}, fmt.Errorf("LocationById: %w", err)
+	}
+
+	return location, nil
+}
+
+// Locations returns all locations of the clinic, sorted by name.
+func (m *MongoDb) Locations(ctx context.Context) ([]Location, error) {
+	collection := m.Database.Collection(locationsCollection)
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+
+	cursor, err := collection.Find(ctx, inClinic(ctx, bson.M{}), opts)
+	if err != nil {
+		return nil, fmt.Errorf("Locations: %w", err)
+	}
+	defer func() {
+		if cerr := cursor.Close(ctx); cerr != nil {
+			slog.Warn("Failed to close locations cursor", "error", cerr.Error())
+		}
+	}()
+
+	locations := make([]Location, 0)
+	if err = cursor.All(ctx, &locations); err != nil {
+		return nil, fmt.Errorf("Locations decode failed: %w", err)
+	}
+
+	return locations, nil
+}
+
+func (m *MongoDb) locationExists(ctx context.Context, id uuid.UUID) error {
+	collection := m.Database.Collection(locationsCollection)
+
+	count, err := collection.CountDocuments(ctx, inClinic(ctx, bson.M{"_id": id}))
+	if err != nil {
+		return fmt.Errorf("locationExists failed location count check: %w", err)
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	indexNotFoundCode = 27
+	// indexOptionsConflictCode is returned when an index with the name or the
+	// keys of a created one exists with other options.
+	indexOptionsConflictCode = 85
+	// indexKeySpecsConflictCode is returned when an index with the name of a
+	// created one exists with other keys.
+	indexKeySpecsConflictCode = 86
+)
+
+// Reindex creates the indexes of all collections and drops the ones no
+// longer defined. Indexes are rebuilt one by one, the existing ones are kept
+// until their replacement is created, only the changed ones are briefly
+// missing. Unlike on connect, failing to create an index is an error.
+func (m *MongoDb) Reindex(ctx context.Context) error {
+	for collName, models := range indexModels() {
+		indexes := m.Database.Collection(collName).Indexes()
+		defined := map[string]bool{"_id_": true}
+		for _, model := range models {
+			name, err := reindex(ctx, indexes, model)
+			if err != nil {
+				return fmt.Errorf("Reindex failed to create index of %s: %w", collName, err)
+			}
+			defined[name] = true
+		}
+
+		specs, err := indexes.ListSpecifications(ctx)
+		if err != nil {
+			return fmt.Errorf("Reindex failed to list indexes of %s: %w", collName, err)
+		}
+		for _, spec := range specs {
+			if defined[spec.Name] {
+				continue
+			}
+			if err := indexes.DropOne(ctx, spec.Name); err != nil {
+				return fmt.Errorf("Reindex failed to drop index %s: %w", spec.Name, err)
+			}
+			slog.InfoContext(ctx, "Dropped stale index", "collection", collName, "index", spec.Name)
+		}
+		slog.InfoContext(ctx, "Reindexed", "collection", collName)
+	}
+
+	return nil
+}
+
+// reindex creates the index, an index conflicting with it is dropped first.
+// Creating an index which already exists does nothing.
+func reindex(ctx context.Context, indexes mongo.IndexView, model mongo.IndexModel) (string, error) {
+	name, err := indexes.CreateOne(ctx, model)
+	if err == nil || !isIndexConflict(err) {
+		return name, err
+	}
+
+	name = indexName(model)
+	err = indexes.DropOne(ctx, name)
+	if isIndexNotFound(err) {
+		// the keys are indexed under another name
+		err = indexes.DropWithKey(ctx, model.Keys)
+	}
+	if err != nil {
+		return "", err
+	}
+	slog.InfoContext(ctx, "Dropped changed index", "index", name)
+	return indexes.CreateOne(ctx, model)
+}
+
+func indexName(model mongo.IndexModel) string {
+	var opts options.IndexOptions
+	if model.Options != nil {
+		for _, set := range model.Options.List() {
+			_ = set(&opts)
+		}
+	}
+	if opts.Name == nil {
+		return ""
+	}
+	return *opts.Name
+}
+
+func isIndexConflict(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) &&
+		(cmdErr.Code == indexOptionsConflictCode || cmdErr.Code == indexKeySpecsConflictCode)
+}
+
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == indexNotFoundCode
+}
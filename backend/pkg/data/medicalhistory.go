@@ -0,0 +1,92 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MedicalHistoryFile is a file in a patient's medical history, e.g. a lab
+// result or a discharge summary, kept by the clinic which added it.
+type MedicalHistoryFile struct {
+	Id         uuid.UUID `bson:"_id"        json:"id"`
+	PatientId  uuid.UUID `bson:"patientId"  json:"patientId"` // Reference to Patient._id
+	Name       string    `bson:"name"       json:"name"`
+	UploadedAt time.Time `bson:"uploadedAt" json:"uploadedAt"`
+	ClinicId   uuid.UUID `bson:"clinicId"   json:"clinicId"` // Reference to Clinic._id
+}
+
+func (m *MongoDb) CreateMedicalHistoryFile(
+	ctx context.Context,
+	file MedicalHistoryFile,
+) (MedicalHistoryFile, error) {
+	if err := m.patientExists(ctx, file.PatientId); err != nil {
+		return MedicalHistoryFile{}, fmt.Errorf("CreateMedicalHistoryFile patient check: %w", err)
+	}
+
+	file.Id = uuid.New()
+	if file.UploadedAt.IsZero() {
+		file.UploadedAt = time.Now()
+	}
+	file.ClinicId = ClinicFromContext(ctx)
+
+	collection := m.Database.Collection(medicalHistoryFilesCollection)
+	if _, err := collection.InsertOne(ctx, file); err != nil {
+		return MedicalHistoryFile{}, fmt.Errorf(
+			"CreateMedicalHistoryFile: failed to insert document: %w",
+			err,
+		)
+	}
+
+	return file, nil
+}
+
+// MedicalHistoryFilesByPatientId returns a page of the patient's files kept by
+// the context's clinic, the newest first, and the number of all of them. Files
+// uploaded at the same time are ordered by their ids, so pages don't overlap.
+func (m *MongoDb) MedicalHistoryFilesByPatientId(
+	ctx context.Context,
+	patientId uuid.UUID,
+	page int,
+	pageSize int,
+) ([]MedicalHistoryFile, PaginationResult, error) {
+	collection := m.Database.Collection(medicalHistoryFilesCollection)
+	filter := inClinic(ctx, bson.M{"patientId": patientId})
+	opts := options.Find().
+		SetSort(bson.D{{Key: "uploadedAt", Value: -1}, {Key: "_id", Value: 1}}).
+		SetSkip(int64(page * pageSize)).
+		SetLimit(int64(pageSize))
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf(
+			"MedicalHistoryFilesByPatientId count failed: %w",
+			err,
+		)
+	}
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("MedicalHistoryFilesByPatientId: %w", err)
+	}
+	defer func() {
+		if cerr := cursor.Close(ctx); cerr != nil {
+			slog.Warn("Failed to close medical history files cursor", "error", cerr.Error())
+		}
+	}()
+
+	files := make([]MedicalHistoryFile, 0)
+	if err = cursor.All(ctx, &files); err != nil {
+		return nil, PaginationResult{}, fmt.Errorf(
+			"MedicalHistoryFilesByPatientId decode failed: %w",
+			err,
+		)
+	}
+
+	return files, PaginationResult{Total: total, Page: page, PageSize: pageSize}, nil
+}
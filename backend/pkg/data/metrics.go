@@ -0,0 +1,147 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
+)
+
+const statsTimeout = 5 * time.Second
+
+var mongoCommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "wac",
+	Subsystem: "mongo",
+	Name:      "command_duration_seconds",
+	Help:      "Duration of MongoDB commands by command name and outcome.",
+	Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+}, []string{"command", "outcome"})
+
+// commandMonitor observes the duration of every command sent to MongoDB and
+// traces the commands of traced operations.
+func commandMonitor() *event.CommandMonitor {
+	spans := &commandSpans{}
+	return &event.CommandMonitor{
+		Started: spans.start,
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			mongoCommandDuration.WithLabelValues(e.CommandName, "success").
+				Observe(e.Duration.Seconds())
+			spans.end(e.CommandFinishedEvent, nil)
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			mongoCommandDuration.WithLabelValues(e.CommandName, "failure").
+				Observe(e.Duration.Seconds())
+			spans.end(e.CommandFinishedEvent, e.Failure)
+		},
+	}
+}
+
+// StatsCollector collects gauges of the clinics' operations, it queries the
+// database on every scrape.
+type StatsCollector struct {
+	db           *MongoDb
+	awaiting     *prometheus.Desc
+	reservations *prometheus.Desc
+}
+
+func NewStatsCollector(db *MongoDb) *StatsCollector {
+	return &StatsCollector{
+		db: db,
+		awaiting: prometheus.NewDesc(
+			"wac_appointments_awaiting_decision",
+			"Requested appointments a doctor hasn't accepted or denied yet.",
+			[]string{"clinic"},
+			nil,
+		),
+		reservations: prometheus.NewDesc(
+			"wac_reservations_today",
+			"Resource reservations starting today.",
+			[]string{"clinic"},
+			nil,
+		),
+	}
+}
+
+func (c *StatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.awaiting
+	ch <- c.reservations
+}
+
+// Collect skips gauges it fails to query, so the other metrics are still
+// scraped.
+func (c *StatsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), statsTimeout)
+	defer cancel()
+
+	awaiting, err := c.db.countByClinic(
+		ctx,
+		appointmentsCollection,
+		bson.M{"status": "requested"},
+	)
+	if err != nil {
+		slog.Warn("Failed to collect awaiting appointments", "error", err.Error())
+	}
+	for clinicId, count := range awaiting {
+		ch <- prometheus.MustNewConstMetric(
+			c.awaiting,
+			prometheus.GaugeValue,
+			float64(count),
+			clinicId.String(),
+		)
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	reservations, err := c.db.countByClinic(
+		ctx,
+		reservationsCollection,
+		bson.M{"startTime": bson.M{"$gte": today, "$lt": today.AddDate(0, 0, 1)}},
+	)
+	if err != nil {
+		slog.Warn("Failed to collect today's reservations", "error", err.Error())
+	}
+	for clinicId, count := range reservations {
+		ch <- prometheus.MustNewConstMetric(
+			c.reservations,
+			prometheus.GaugeValue,
+			float64(count),
+			clinicId.String(),
+		)
+	}
+}
+
+// countByClinic counts the documents of the collection matching the filter in
+// every clinic.
+func (m *MongoDb) countByClinic(
+	ctx context.Context,
+	collectionName string,
+	filter bson.M,
+) (map[uuid.UUID]int64, error) {
+	pipeline := bson.A{
+		bson.M{"$match": filter},
+		bson.M{"$group": bson.M{"_id": "$clinicId", "count": bson.M{"$sum": 1}}},
+	}
+	cursor, err := m.Database.Collection(collectionName).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("countByClinic aggregate failed: %w", err)
+	}
+
+	var groups []struct {
+		ClinicId uuid.UUID `bson:"_id"`
+		Count    int64     `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, fmt.Errorf("countByClinic decode failed: %w", err)
+	}
+	counts := make(map[uuid.UUID]int64, len(groups))
+	for _, group := range groups {
+		counts[group.ClinicId] = group.Count
+	}
+	return counts, nil
+}
@@ -27,6 +27,18 @@ const (
 	appointmentsCollection  = "appointments"
 	resourcesCollection     = "resources"
 	reservationsCollection  = "reservations"
+	fhirImportsCollection   = "fhirImports"
+	revisionsCollection     = "revisions"
+	visitNotesCollection    = "visitNotes"
+	referralsCollection     = "referrals"
+	clinicsCollection       = "clinics"
+	locationsCollection     = "locations"
+	staffCollection         = "staff"
+
+	medicalHistoryFilesCollection = "medicalHistoryFiles"
+
+	idempotencyKeysCollection = "idempotencyKeys"
+	bookingLocksCollection    = "bookingLocks"
 )
 
 var Collections = []string{
@@ -37,6 +49,16 @@ var Collections = []string{
 	appointmentsCollection,
 	resourcesCollection,
 	reservationsCollection,
+	fhirImportsCollection,
+	revisionsCollection,
+	visitNotesCollection,
+	referralsCollection,
+	clinicsCollection,
+	locationsCollection,
+	staffCollection,
+	medicalHistoryFilesCollection,
+	idempotencyKeysCollection,
+	bookingLocksCollection,
 }
 
 var (
@@ -49,7 +71,10 @@ func ConnectMongo(ctx context.Context, uri string, db string) (*MongoDb, error)
 	mongoRegistry.RegisterTypeEncoder(tUUID, bson.ValueEncoderFunc(uuidEncodeValue))
 	mongoRegistry.RegisterTypeDecoder(tUUID, bson.ValueDecoderFunc(uuidDecodeValue))
 
-	opts := options.Client().ApplyURI(uri).SetRegistry(mongoRegistry)
+	opts := options.Client().
+		ApplyURI(uri).
+		SetRegistry(mongoRegistry).
+		SetMonitor(commandMonitor())
 	client, err := mongo.Connect(opts)
 	if err != nil {
 		return nil, fmt.Errorf("ConnectMongo: %w", err)
@@ -75,6 +100,15 @@ func ConnectMongo(ctx context.Context, uri string, db string) (*MongoDb, error)
 	}
 
 	mongoDB := &MongoDb{Database: mongoDb}
+	if err = mongoDB.seedDefaultClinic(ctx); err != nil {
+		return nil, fmt.Errorf("ConnectMongo: failed to seed default clinic: %w", err)
+	}
+	if err = mongoDB.seedDoctorProfiles(ctx); err != nil {
+		return nil, fmt.Errorf("ConnectMongo: failed to seed doctor profiles: %w", err)
+	}
+	if err = mongoDB.seedPatientSearchKeys(ctx); err != nil {
+		return nil, fmt.Errorf("ConnectMongo: failed to seed patient search keys: %w", err)
+	}
 	if err = mongoDB.seedResources(ctx); err != nil {
 		return nil, fmt.Errorf("ConnectMongo: failed to seed resources: %w", err)
 	}
@@ -102,19 +136,55 @@ func initCollections(ctx context.Context, mongoDb *mongo.Database, cols []string
 	return nil
 }
 
-func initIndexes(ctx context.Context, mongoDb *mongo.Database) error {
-	indexes := map[string][]mongo.IndexModel{
+func indexModels() map[string][]mongo.IndexModel {
+	return map[string][]mongo.IndexModel{
 		patientsCollection: {
 			{
 				Keys:    bson.D{{Key: "email", Value: 1}},
 				Options: options.Index().SetUnique(true).SetName("idx_patient_email_unique"),
 			},
+			{
+				Keys:    bson.D{{Key: "clinicIds", Value: 1}},
+				Options: options.Index().SetName("idx_patient_clinicIds"),
+			},
+			{
+				Keys: bson.D{
+					{Key: "clinicIds", Value: 1},
+					{Key: "search.lastName", Value: 1},
+					{Key: "search.firstName", Value: 1},
+				},
+				Options: options.Index().SetName("idx_patient_clinicIds_search_name"),
+			},
+			{
+				Keys:    bson.D{{Key: "clinicIds", Value: 1}, {Key: "search.firstName", Value: 1}},
+				Options: options.Index().SetName("idx_patient_clinicIds_search_firstName"),
+			},
+			{
+				Keys:    bson.D{{Key: "dateOfBirth", Value: 1}},
+				Options: options.Index().SetName("idx_patient_dateOfBirth").SetSparse(true),
+			},
+			{
+				Keys:    bson.D{{Key: "nationalId", Value: 1}},
+				Options: options.Index().SetName("idx_patient_nationalId").SetSparse(true),
+			},
 		},
 		doctorsCollection: {
 			{
 				Keys:    bson.D{{Key: "email", Value: 1}},
 				Options: options.Index().SetUnique(true).SetName("idx_doctor_email_unique"),
 			},
+			{
+				Keys:    bson.D{{Key: "clinicId", Value: 1}, {Key: "specializations", Value: 1}},
+				Options: options.Index().SetName("idx_doctor_clinicId_specializations"),
+			},
+			{
+				Keys: bson.D{
+					{Key: "clinicId", Value: 1},
+					{Key: "lastName", Value: 1},
+					{Key: "firstName", Value: 1},
+				},
+				Options: options.Index().SetName("idx_doctor_clinicId_lastName_firstName"),
+			},
 		},
 		conditionsCollection: {
 			{
@@ -145,11 +215,27 @@ func initIndexes(ctx context.Context, mongoDb *mongo.Database) error {
 				Keys:    bson.D{{Key: "appointmentDateTime", Value: 1}},
 				Options: options.Index().SetName("idx_appointment_datetime"),
 			},
+			{
+				Keys: bson.D{
+					{Key: "clinicId", Value: 1},
+					{Key: "doctorId", Value: 1},
+					{Key: "appointmentDateTime", Value: 1},
+				},
+				Options: options.Index().SetName("idx_appointment_clinicId_doctorId_datetime"),
+			},
+			{
+				Keys: bson.D{
+					{Key: "clinicId", Value: 1},
+					{Key: "patientId", Value: 1},
+					{Key: "appointmentDateTime", Value: 1},
+				},
+				Options: options.Index().SetName("idx_appointment_clinicId_patientId_datetime"),
+			},
 		},
 		resourcesCollection: {
 			{
-				Keys:    bson.D{{Key: "type", Value: 1}},
-				Options: options.Index().SetName("idx_resource_type"),
+				Keys:    bson.D{{Key: "clinicId", Value: 1}, {Key: "type", Value: 1}},
+				Options: options.Index().SetName("idx_resource_clinicId_type"),
 			},
 		},
 		reservationsCollection: {
@@ -165,9 +251,91 @@ func initIndexes(ctx context.Context, mongoDb *mongo.Database) error {
 				Options: options.Index().SetName("idx_reservation_appointmentId"),
 			},
 		},
+		fhirImportsCollection: {
+			{
+				Keys: bson.D{
+					{Key: "clinicId", Value: 1},
+					{Key: "resourceType", Value: 1},
+					{Key: "system", Value: 1},
+					{Key: "value", Value: 1},
+				},
+				Options: options.Index().SetUnique(true).SetName("idx_fhir_import_clinicId_source_unique"),
+			},
+		},
+		revisionsCollection: {
+			{
+				Keys: bson.D{
+					{Key: "collection", Value: 1},
+					{Key: "documentId", Value: 1},
+					{Key: "version", Value: 1},
+				},
+				Options: options.Index().SetName("idx_revision_document_version"),
+			},
+		},
+		visitNotesCollection: {
+			{
+				Keys:    bson.D{{Key: "appointmentId", Value: 1}},
+				Options: options.Index().SetUnique(true).SetName("idx_visit_note_appointmentId_unique"),
+			},
+		},
+		referralsCollection: {
+			{
+				Keys:    bson.D{{Key: "patientId", Value: 1}, {Key: "createdAt", Value: -1}},
+				Options: options.Index().SetName("idx_referral_patientId_createdAt"),
+			},
+			{
+				Keys:    bson.D{{Key: "appointmentId", Value: 1}},
+				Options: options.Index().SetName("idx_referral_appointmentId"),
+			},
+		},
+		locationsCollection: {
+			{
+				Keys:    bson.D{{Key: "clinicId", Value: 1}, {Key: "name", Value: 1}},
+				Options: options.Index().SetName("idx_location_clinicId_name"),
+			},
+		},
+		staffCollection: {
+			{
+				Keys:    bson.D{{Key: "email", Value: 1}},
+				Options: options.Index().SetUnique(true).SetName("idx_staff_email_unique"),
+			},
+			{
+				Keys:    bson.D{{Key: "clinicId", Value: 1}, {Key: "lastName", Value: 1}},
+				Options: options.Index().SetName("idx_staff_clinicId_lastName"),
+			},
+		},
+		medicalHistoryFilesCollection: {
+			{
+				Keys: bson.D{
+					{Key: "clinicId", Value: 1},
+					{Key: "patientId", Value: 1},
+					{Key: "uploadedAt", Value: -1},
+					{Key: "_id", Value: 1},
+				},
+				Options: options.Index().SetName("idx_medical_history_file_patientId_uploadedAt"),
+			},
+		},
+		idempotencyKeysCollection: {
+			{
+				Keys: bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().
+					SetExpireAfterSeconds(0).
+					SetName("idx_idempotency_key_expiresAt_ttl"),
+			},
+		},
+		bookingLocksCollection: {
+			{
+				Keys: bson.D{{Key: "lockedUntil", Value: 1}},
+				Options: options.Index().
+					SetExpireAfterSeconds(0).
+					SetName("idx_booking_lock_lockedUntil_ttl"),
+			},
+		},
 	}
+}
 
-	for collName, indexModels := range indexes {
+func initIndexes(ctx context.Context, mongoDb *mongo.Database) error {
+	for collName, indexModels := range indexModels() {
 		coll := mongoDb.Collection(collName)
 		indexNames, err := coll.Indexes().CreateMany(ctx, indexModels)
 		if err != nil {
@@ -191,6 +359,13 @@ func (m *MongoDb) Disconnect(ctx context.Context) error {
 	return m.Database.Client().Disconnect(ctx)
 }
 
+func (m *MongoDb) Ping(ctx context.Context) error {
+	if err := m.Database.Client().Ping(ctx, nil); err != nil {
+		return fmt.Errorf("Ping: %w", err)
+	}
+	return nil
+}
+
 func uuidEncodeValue(ec bson.EncodeContext, vw bson.ValueWriter, val reflect.Value) error {
 	if !val.IsValid() || val.Type() != tUUID {
 		return bsoncodec.ValueEncoderError{
@@ -4,22 +4,118 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
 )
 
+// ErrLastClinic is returned when a patient would stop being shared with any
+// clinic.
+var ErrLastClinic = errors.New("patient must be shared with at least one clinic")
+
 type Patient struct {
 	Id        uuid.UUID `bson:"_id"       json:"id"`
 	Email     string    `bson:"email"     json:"email"`
 	FirstName string    `bson:"firstName" json:"firstName"`
 	LastName  string    `bson:"lastName"  json:"lastName"`
+
+	DateOfBirth *time.Time `bson:"dateOfBirth,omitempty" json:"dateOfBirth,omitempty"`
+	Sex         *string    `bson:"sex,omitempty"         json:"sex,omitempty"`
+	// Phone is an international number, like +421905123456.
+	Phone *string `bson:"phone,omitempty" json:"phone,omitempty"`
+	// NationalId is the Slovak birth number, without the slash.
+	NationalId       *string           `bson:"nationalId,omitempty"       json:"nationalId,omitempty"`
+	Address          *Address          `bson:"address,omitempty"          json:"address,omitempty"`
+	Insurance        *Insurance        `bson:"insurance,omitempty"        json:"insurance,omitempty"`
+	EmergencyContact *EmergencyContact `bson:"emergencyContact,omitempty" json:"emergencyContact,omitempty"`
+
+	// Search holds the patient's names folded for searching, see FoldName.
+	Search PatientSearchKeys `bson:"search" json:"-"`
+
+	// ClinicIds are the clinics the patient consented to share their records
+	// with, the patient is invisible to other clinics.
+	ClinicIds []uuid.UUID `bson:"clinicIds" json:"clinicIds"` // References to Clinic._id
+
+	// ErasedAt is set when the patient's personal data were pseudonymized.
+	ErasedAt *time.Time `bson:"erasedAt,omitempty" json:"erasedAt,omitempty"`
+
+	// Version is incremented on every write, see ErrVersionConflict.
+	Version int64 `bson:"version" json:"version"`
+}
+
+type Address struct {
+	Street     string `bson:"street"     json:"street"`
+	City       string `bson:"city"       json:"city"`
+	PostalCode string `bson:"postalCode" json:"postalCode"`
+	// Country is an ISO 3166-1 alpha-2 code.
+	Country string `bson:"country" json:"country"`
+}
+
+type Insurance struct {
+	Company      string `bson:"company"      json:"company"`
+	PolicyNumber string `bson:"policyNumber" json:"policyNumber"`
+}
+
+type EmergencyContact struct {
+	Name         string  `bson:"name"                   json:"name"`
+	Relationship *string `bson:"relationship,omitempty" json:"relationship,omitempty"`
+	// Phone is an international number, like +421905123456.
+	Phone string `bson:"phone" json:"phone"`
+}
+
+// PatientSearchKeys are the patient's names folded with FoldName.
+type PatientSearchKeys struct {
+	FirstName string `bson:"firstName"`
+	LastName  string `bson:"lastName"`
+}
+
+// PatientFilter selects patients by their details, names match by prefix of
+// any of their words. Empty fields don't restrict the search.
+type PatientFilter struct {
+	// Name is matched word by word, each word must prefix the first or the last
+	// name, regardless of case and diacritics.
+	Name        string
+	Email       string
+	DateOfBirth *time.Time
+	NationalId  string
+	// Ids restricts the search to the patients, if not nil.
+	Ids []uuid.UUID
+}
+
+// foldName removes diacritics, so "Nováková" folds to "novakova".
+var foldName = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// FoldName returns the name lowercased and without diacritics, the way names
+// are matched in SearchPatients.
+func FoldName(name string) string {
+	folded, _, err := transform.String(foldName, name)
+	if err != nil {
+		folded = name
+	}
+	return strings.ToLower(strings.TrimSpace(folded))
+}
+
+func patientSearchKeys(p Patient) PatientSearchKeys {
+	return PatientSearchKeys{FirstName: FoldName(p.FirstName), LastName: FoldName(p.LastName)}
 }
 
 func (m *MongoDb) CreatePatient(ctx context.Context, patient Patient) (Patient, error) {
 	collection := m.Database.Collection(patientsCollection)
 	patient.Id = uuid.New()
+	patient.ClinicIds = []uuid.UUID{ClinicFromContext(ctx)}
+	patient.Search = patientSearchKeys(patient)
+	patient.Version = initialVersion
 
 	_, err := collection.InsertOne(ctx, patient)
 	if err != nil {
@@ -39,7 +135,7 @@ func (m *MongoDb) CreatePatient(ctx context.Context, patient Patient) (Patient,
 
 func (m *MongoDb) PatientById(ctx context.Context, id uuid.UUID) (Patient, error) {
 	collection := m.Database.Collection(patientsCollection)
-	filter := bson.M{"_id": id}
+	filter := sharedWithClinic(ctx, bson.M{"_id": id})
 	var patient Patient
 
 	err := collection.FindOne(ctx, filter).Decode(&patient)
@@ -53,9 +149,34 @@ func (m *MongoDb) PatientById(ctx context.Context, id uuid.UUID) (Patient, error
 	return patient, nil
 }
 
+// PatientsByIds returns the patients shared with the context's clinic with any
+// of the ids, in no particular order. Ids of no such patients are skipped.
+func (m *MongoDb) PatientsByIds(ctx context.Context, ids []uuid.UUID) ([]Patient, error) {
+	patients := make([]Patient, 0, len(ids))
+	if len(ids) == 0 {
+		return patients, nil
+	}
+
+	collection := m.Database.Collection(patientsCollection)
+	cursor, err := collection.Find(ctx, sharedWithClinic(ctx, bson.M{"_id": bson.M{"$in": ids}}))
+	if err != nil {
+		return nil, fmt.Errorf("PatientsByIds find failed: %w", err)
+	}
+	defer func() {
+		if cerr := cursor.Close(ctx); cerr != nil {
+			slog.Warn("Failed to close patients cursor", "error", cerr.Error())
+		}
+	}()
+
+	if err = cursor.All(ctx, &patients); err != nil {
+		return nil, fmt.Errorf("PatientsByIds decode failed: %w", err)
+	}
+	return patients, nil
+}
+
 func (m *MongoDb) PatientByEmail(ctx context.Context, email string) (Patient, error) {
 	collection := m.Database.Collection(patientsCollection)
-	filter := bson.M{"email": email}
+	filter := sharedWithClinic(ctx, bson.M{"email": email})
 	var patient Patient
 
 	err := collection.FindOne(ctx, filter).Decode(&patient)
@@ -69,9 +190,282 @@ func (m *MongoDb) PatientByEmail(ctx context.Context, email string) (Patient, er
 	return patient, nil
 }
 
+// UpdatePatient replaces the patient if its version is still the one of the
+// given patient, otherwise returns ErrVersionConflict.
+func (m *MongoDb) UpdatePatient(
+	ctx context.Context,
+	id uuid.UUID,
+	patient Patient,
+) (Patient, error) {
+	collection := m.Database.Collection(patientsCollection)
+	filter := withVersion(sharedWithClinic(ctx, bson.M{"_id": id}), patient.Version)
+	patient.Id = id
+	patient.Search = patientSearchKeys(patient)
+	patient.Version++
+
+	opts := options.FindOneAndReplace().SetReturnDocument(options.After)
+
+	var updatedPatient Patient
+	err := collection.FindOneAndReplace(ctx, filter, patient, opts).Decode(&updatedPatient)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Patient{}, m.missingOrConflict(ctx, patientsCollection, id)
+		}
+		var writeErr mongo.WriteException
+		if errors.As(err, &writeErr) {
+			for _, we := range writeErr.WriteErrors {
+				if we.Code == 11000 {
+					return Patient{}, ErrDuplicateEmail
+				}
+			}
+		}
+		return Patient{}, fmt.Errorf("UpdatePatient failed: %w", err)
+	}
+
+	return updatedPatient, nil
+}
+
+// SearchPatients returns a page of the patients shared with the context's
+// clinic matching the filter, sorted by their names, and the number of all
+// matching patients.
+func (m *MongoDb) SearchPatients(
+	ctx context.Context,
+	f PatientFilter,
+	page int,
+	pageSize int,
+) ([]Patient, PaginationResult, error) {
+	collection := m.Database.Collection(patientsCollection)
+	filter := sharedWithClinic(ctx, patientSearchFilter(f))
+	sort := bson.D{
+		{Key: "search.lastName", Value: 1},
+		{Key: "search.firstName", Value: 1},
+		{Key: "_id", Value: 1},
+	}
+	opts := options.Find().
+		SetSort(sort).
+		SetSkip(int64(page * pageSize)).
+		SetLimit(int64(pageSize))
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("SearchPatients count failed: %w", err)
+	}
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("SearchPatients: %w", err)
+	}
+	defer func() {
+		if cerr := cursor.Close(ctx); cerr != nil {
+			slog.Warn("Failed to close patients cursor", "error", cerr.Error())
+		}
+	}()
+
+	patients := make([]Patient, 0)
+	if err = cursor.All(ctx, &patients); err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("SearchPatients decode failed: %w", err)
+	}
+
+	return patients, PaginationResult{Total: total, Page: page, PageSize: pageSize}, nil
+}
+
+func patientSearchFilter(f PatientFilter) bson.M {
+	filter := bson.M{}
+	var words bson.A
+	for _, word := range strings.Fields(FoldName(f.Name)) {
+		prefix := bson.Regex{Pattern: "^" + regexp.QuoteMeta(word)}
+		words = append(words, bson.M{"$or": bson.A{
+			bson.M{"search.firstName": prefix},
+			bson.M{"search.lastName": prefix},
+		}})
+	}
+	if len(words) > 0 {
+		filter["$and"] = words
+	}
+	if f.Email != "" {
+		filter["email"] = f.Email
+	}
+	if f.DateOfBirth != nil {
+		filter["dateOfBirth"] = *f.DateOfBirth
+	}
+	if f.NationalId != "" {
+		filter["nationalId"] = f.NationalId
+	}
+	if f.Ids != nil {
+		filter["_id"] = bson.M{"$in": f.Ids}
+	}
+	return filter
+}
+
+// DoctorsPatientIds returns the patients the doctor has a care relationship
+// with in the context's clinic, those with an appointment with the doctor or
+// a referral from or to the doctor.
+func (m *MongoDb) DoctorsPatientIds(ctx context.Context, doctorId uuid.UUID) ([]uuid.UUID, error) {
+	appointments := m.Database.Collection(appointmentsCollection)
+	var appointmentPatients []uuid.UUID
+	err := appointments.Distinct(ctx, "patientId", inClinic(ctx, bson.M{"doctorId": doctorId})).
+		Decode(&appointmentPatients)
+	if err != nil {
+		return nil, fmt.Errorf("DoctorsPatientIds appointments: %w", err)
+	}
+
+	referrals := m.Database.Collection(referralsCollection)
+	var referralPatients []uuid.UUID
+	err = referrals.Distinct(ctx, "patientId", inClinic(ctx, bson.M{"$or": bson.A{
+		bson.M{"fromDoctorId": doctorId},
+		bson.M{"toDoctorId": doctorId},
+	}})).Decode(&referralPatients)
+	if err != nil {
+		return nil, fmt.Errorf("DoctorsPatientIds referrals: %w", err)
+	}
+
+	ids := make([]uuid.UUID, 0, len(appointmentPatients)+len(referralPatients))
+	seen := make(map[uuid.UUID]bool, cap(ids))
+	for _, id := range append(appointmentPatients, referralPatients...) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// seedPatientSearchKeys folds the names of patients created before patients
+// could be searched.
+func (m *MongoDb) seedPatientSearchKeys(ctx context.Context) error {
+	collection := m.Database.Collection(patientsCollection)
+	cursor, err := collection.Find(ctx, bson.M{"search": bson.M{"$exists": false}})
+	if err != nil {
+		return fmt.Errorf("seedPatientSearchKeys: %w", err)
+	}
+	defer func() {
+		if cerr := cursor.Close(ctx); cerr != nil {
+			slog.Warn("Failed to close patients cursor", "error", cerr.Error())
+		}
+	}()
+
+	var count int
+	for cursor.Next(ctx) {
+		var patient Patient
+		if err := cursor.Decode(&patient); err != nil {
+			return fmt.Errorf("seedPatientSearchKeys decode failed: %w", err)
+		}
+		_, err := collection.UpdateOne(
+			ctx,
+			bson.M{"_id": patient.Id},
+			bson.M{"$set": bson.M{"search": patientSearchKeys(patient)}},
+		)
+		if err != nil {
+			return fmt.Errorf("seedPatientSearchKeys failed to update %s: %w", patient.Id, err)
+		}
+		count++
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("seedPatientSearchKeys: %w", err)
+	}
+	if count > 0 {
+		slog.InfoContext(ctx, "Folded names of patients for searching", "count", count)
+	}
+
+	return nil
+}
+
+func (m *MongoDb) DeletePatient(ctx context.Context, id uuid.UUID) error {
+	collection := m.Database.Collection(patientsCollection)
+	filter := sharedWithClinic(ctx, bson.M{"_id": id})
+
+	result, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("DeletePatient failed: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// SharePatient shares the patient, visible in the context's clinic, with
+// another clinic.
+func (m *MongoDb) SharePatient(
+	ctx context.Context,
+	patientId uuid.UUID,
+	clinicId uuid.UUID,
+) (Patient, error) {
+	if err := m.clinicExists(ctx, clinicId); err != nil {
+		return Patient{}, fmt.Errorf("SharePatient clinic check: %w", err)
+	}
+
+	collection := m.Database.Collection(patientsCollection)
+	filter := sharedWithClinic(ctx, bson.M{"_id": patientId})
+	update := incVersion(bson.M{"$addToSet": bson.M{"clinicIds": clinicId}})
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var patient Patient
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&patient)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Patient{}, fmt.Errorf("SharePatient: %w", ErrNotFound)
+		}
+		return Patient{}, fmt.Errorf("SharePatient: %w", err)
+	}
+
+	return patient, nil
+}
+
+// UnsharePatient stops sharing the patient, visible in the context's clinic,
+// with the clinic. Returns ErrNotFound if the patient isn't shared with the
+// clinic, and ErrLastClinic if it is the patient's only clinic.
+func (m *MongoDb) UnsharePatient(
+	ctx context.Context,
+	patientId uuid.UUID,
+	clinicId uuid.UUID,
+) (Patient, error) {
+	collection := m.Database.Collection(patientsCollection)
+	filter := bson.M{
+		"_id":       patientId,
+		"clinicIds": bson.M{"$all": bson.A{ClinicFromContext(ctx), clinicId}},
+		// the patient must be shared with another clinic than the removed one
+		"clinicIds.1": bson.M{"$exists": true},
+	}
+	update := incVersion(bson.M{"$pull": bson.M{"clinicIds": clinicId}})
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var patient Patient
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&patient)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			err = m.unshareFailure(ctx, patientId, clinicId)
+		}
+		return Patient{}, fmt.Errorf("UnsharePatient: %w", err)
+	}
+
+	return patient, nil
+}
+
+// unshareFailure explains why the patient couldn't stop being shared with the
+// clinic.
+func (m *MongoDb) unshareFailure(ctx context.Context, patientId, clinicId uuid.UUID) error {
+	collection := m.Database.Collection(patientsCollection)
+	filter := sharedWithClinic(ctx, bson.M{"_id": patientId})
+
+	var patient Patient
+	if err := collection.FindOne(ctx, filter).Decode(&patient); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("unshareFailure find patient: %w", err)
+	}
+	if !slices.Contains(patient.ClinicIds, clinicId) {
+		return ErrNotFound
+	}
+	return ErrLastClinic
+}
+
 func (m *MongoDb) patientExists(ctx context.Context, patientId uuid.UUID) error {
 	patientsColl := m.Database.Collection(patientsCollection)
-	filter := bson.M{"_id": patientId}
+	filter := sharedWithClinic(ctx, bson.M{"_id": patientId})
 
 	count, err := patientsColl.CountDocuments(ctx, filter)
 	if err != nil {
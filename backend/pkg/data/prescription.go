@@ -21,6 +21,9 @@ type Prescription struct {
 	Start         time.Time  `bson:"start"                   json:"start"`
 	End           time.Time  `bson:"end"                     json:"end"`
 	DoctorsNote   *string    `bson:"doctorsNote,omitempty"   json:"doctorsNote,omitempty"`
+
+	// Version is incremented on every write, see ErrVersionConflict.
+	Version int64 `bson:"version" json:"version"`
 }
 
 func (m *MongoDb) CreatePrescription(
@@ -33,12 +36,24 @@ func (m *MongoDb) CreatePrescription(
 
 	collection := m.Database.Collection(prescriptionsCollection)
 	prescription.Id = uuid.New()
+	prescription.Version = initialVersion
 
 	_, err := collection.InsertOne(ctx, prescription)
 	if err != nil {
 		return Prescription{}, fmt.Errorf("CreatePrescription failed to insert document: %w", err)
 	}
 
+	err = m.recordCreate(
+		ctx,
+		prescriptionsCollection,
+		prescription.Id,
+		prescription.Version,
+		prescription,
+	)
+	if err != nil {
+		return Prescription{}, fmt.Errorf("CreatePrescription: %w", err)
+	}
+
 	return prescription, nil
 }
 
@@ -54,6 +69,9 @@ func (m *MongoDb) PrescriptionById(ctx context.Context, id uuid.UUID) (Prescript
 		}
 		return Prescription{}, fmt.Errorf("PrescriptionById failed to find document: %w", err)
 	}
+	if err := m.patientExists(ctx, prescription.PatientId); err != nil {
+		return Prescription{}, fmt.Errorf("PrescriptionById patient check: %w", err)
+	}
 
 	return prescription, nil
 }
@@ -66,6 +84,11 @@ func (m *MongoDb) FindPrescriptionsByPatientId(
 ) ([]Prescription, error) {
 	collection := m.Database.Collection(prescriptionsCollection)
 	prescriptions := make([]Prescription, 0)
+	if visible, err := m.patientVisible(ctx, patientId); err != nil {
+		return nil, fmt.Errorf("FindPrescriptionsByPatientId: %w", err)
+	} else if !visible {
+		return prescriptions, nil
+	}
 
 	filter := bson.M{
 		"patientId": patientId,
@@ -103,6 +126,9 @@ func (m *MongoDb) FindPrescriptionsByPatientId(
 	return prescriptions, nil
 }
 
+// UpdatePrescription updates the prescription if its version is still the one
+// of the given prescription, otherwise returns ErrVersionConflict. The new
+// state is recorded as a revision.
 func (m *MongoDb) UpdatePrescription(
 	ctx context.Context,
 	id uuid.UUID,
@@ -114,10 +140,33 @@ func (m *MongoDb) UpdatePrescription(
 			err,
 		)
 	}
+	previous, err := m.PrescriptionById(ctx, id)
+	if err != nil {
+		return Prescription{}, fmt.Errorf("UpdatePrescription: %w", err)
+	}
+	if previous.Version != prescription.Version {
+		return Prescription{}, ErrVersionConflict
+	}
 
-	collection := m.Database.Collection(prescriptionsCollection)
-	filter := bson.M{"_id": id}
+	updatedPrescription := prescription
+	updatedPrescription.Id = id
+	updatedPrescription.Version = previous.Version + 1
+	revert, err := m.recordChange(
+		ctx,
+		prescriptionsCollection,
+		id,
+		RevisionUpdate,
+		previous,
+		previous.Version,
+		updatedPrescription,
+		updatedPrescription.Version,
+	)
+	if err != nil {
+		return Prescription{}, fmt.Errorf("UpdatePrescription: %w", err)
+	}
 
+	collection := m.Database.Collection(prescriptionsCollection)
+	filter := withVersion(bson.M{"_id": id}, previous.Version)
 	updatePayload := bson.M{
 		"patientId":     prescription.PatientId,
 		"appointmentId": prescription.AppointmentId,
@@ -126,19 +175,17 @@ func (m *MongoDb) UpdatePrescription(
 		"end":           prescription.End,
 		"doctorsNote":   prescription.DoctorsNote,
 	}
-	update := bson.M{"$set": updatePayload}
-
-	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	update := incVersion(bson.M{"$set": updatePayload})
 
-	var updatedPrescription Prescription
-	err := collection.FindOneAndUpdate(ctx, filter, update, opts).
-		Decode(&updatedPrescription)
+	res, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return Prescription{}, ErrNotFound
-		}
+		revert()
 		return Prescription{}, fmt.Errorf("UpdatePrescription failed: %w", err)
 	}
+	if res.MatchedCount == 0 {
+		revert()
+		return Prescription{}, m.missingOrConflict(ctx, prescriptionsCollection, id)
+	}
 
 	return updatedPrescription, nil
 }
@@ -149,6 +196,11 @@ func (m *MongoDb) PrescriptionByAppointmentId(
 ) ([]Prescription, error) {
 	collection := m.Database.Collection(prescriptionsCollection)
 	prescriptions := make([]Prescription, 0)
+	if err := m.appointmentExists(ctx, appointmentId); errors.Is(err, ErrNotFound) {
+		return prescriptions, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("PrescriptionByAppointmentId: %w", err)
+	}
 
 	filter := bson.M{"appointmentId": appointmentId}
 
@@ -181,17 +233,44 @@ func (m *MongoDb) PrescriptionByAppointmentId(
 	return prescriptions, nil
 }
 
-func (m *MongoDb) DeletePrescription(ctx context.Context, id uuid.UUID) error {
-	collection := m.Database.Collection(prescriptionsCollection)
-	filter := bson.M{"_id": id}
+// DeletePrescription deletes the prescription, if version isn't nil only when
+// the prescription has that version, otherwise returns ErrVersionConflict, as
+// it does if the prescription is modified while being deleted. Its last state
+// is kept as a delete revision.
+func (m *MongoDb) DeletePrescription(ctx context.Context, id uuid.UUID, version *int64) error {
+	deleted, err := m.PrescriptionById(ctx, id)
+	if err != nil {
+		return fmt.Errorf("DeletePrescription: %w", err)
+	}
+	if version != nil && deleted.Version != *version {
+		return ErrVersionConflict
+	}
 
-	result, err := collection.DeleteOne(ctx, filter)
+	revert, err := m.recordChange(
+		ctx,
+		prescriptionsCollection,
+		id,
+		RevisionDelete,
+		deleted,
+		deleted.Version,
+		deleted,
+		deleted.Version+1,
+	)
 	if err != nil {
-		return fmt.Errorf("DeletePrescription failed: %w", err)
+		return fmt.Errorf("DeletePrescription: %w", err)
 	}
 
-	if result.DeletedCount == 0 {
-		return ErrNotFound
+	// only the recorded state is deleted
+	collection := m.Database.Collection(prescriptionsCollection)
+	filter := withVersion(bson.M{"_id": id}, deleted.Version)
+	res, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		revert()
+		return fmt.Errorf("DeletePrescription failed: %w", err)
+	}
+	if res.DeletedCount == 0 {
+		revert()
+		return m.missingOrConflict(ctx, prescriptionsCollection, id)
 	}
 
 	return nil
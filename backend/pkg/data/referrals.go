@@ -0,0 +1,201 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var ErrReferralNotActive = errors.New("referral isn't active")
+
+type ReferralStatus string
+
+const (
+	// ReferralActive referrals can be booked until they expire.
+	ReferralActive ReferralStatus = "active"
+	// ReferralBooked referrals have an appointment, they become active again
+	// if the appointment is cancelled, denied or deleted.
+	ReferralBooked    ReferralStatus = "booked"
+	ReferralCompleted ReferralStatus = "completed"
+	ReferralCancelled ReferralStatus = "cancelled"
+)
+
+// Referral sends a patient from one doctor to another doctor, or to any doctor
+// of a specialization. The patient books an appointment against it.
+type Referral struct {
+	Id               uuid.UUID      `bson:"_id"                        json:"id"`
+	PatientId        uuid.UUID      `bson:"patientId"                  json:"patientId"`            // Reference to Patient._id
+	FromDoctorId     uuid.UUID      `bson:"fromDoctorId"               json:"fromDoctorId"`         // Reference to Doctor._id
+	ToDoctorId       *uuid.UUID     `bson:"toDoctorId,omitempty"       json:"toDoctorId,omitempty"` // Reference to Doctor._id
+	ToSpecialization *string        `bson:"toSpecialization,omitempty" json:"toSpecialization,omitempty"`
+	ConditionId      *uuid.UUID     `bson:"conditionId,omitempty"      json:"conditionId,omitempty"` // Reference to Condition._id
+	Urgency          string         `bson:"urgency"                    json:"urgency"`
+	Note             *string        `bson:"note,omitempty"             json:"note,omitempty"`
+	ExpiresAt        time.Time      `bson:"expiresAt"                  json:"expiresAt"`
+	Status           ReferralStatus `bson:"status"                     json:"status"`
+	AppointmentId    *uuid.UUID     `bson:"appointmentId,omitempty"    json:"appointmentId,omitempty"` // Reference to Appointment._id
+	CreatedAt        time.Time      `bson:"createdAt"                  json:"createdAt"`
+	ClinicId         uuid.UUID      `bson:"clinicId"                   json:"clinicId"` // Reference to Clinic._id
+
+	// Version is incremented on every write, see ErrVersionConflict.
+	Version int64 `bson:"version" json:"version"`
+}
+
+func (m *MongoDb) CreateReferral(ctx context.Context, referral Referral) (Referral, error) {
+	if err := m.patientExists(ctx, referral.PatientId); err != nil {
+		return Referral{}, fmt.Errorf("CreateReferral patient check: %w", err)
+	}
+	if err := m.doctorExists(ctx, referral.FromDoctorId); err != nil {
+		return Referral{}, fmt.Errorf("CreateReferral referring doctor check: %w", err)
+	}
+	if referral.ToDoctorId != nil {
+		if err := m.doctorExists(ctx, *referral.ToDoctorId); err != nil {
+			return Referral{}, fmt.Errorf("CreateReferral target doctor check: %w", err)
+		}
+	}
+	if referral.ConditionId != nil {
+		if err := m.conditionExists(ctx, *referral.ConditionId); err != nil {
+			return Referral{}, fmt.Errorf("CreateReferral condition check: %w", err)
+		}
+	}
+
+	referral.Id = uuid.New()
+	referral.Status = ReferralActive
+	referral.AppointmentId = nil
+	referral.CreatedAt = time.Now()
+	referral.ClinicId = ClinicFromContext(ctx)
+	referral.Version = initialVersion
+
+	collection := m.Database.Collection(referralsCollection)
+	if _, err := collection.InsertOne(ctx, referral); err != nil {
+		return Referral{}, fmt.Errorf("CreateReferral: failed to insert document: %w", err)
+	}
+
+	return referral, nil
+}
+
+func (m *MongoDb) ReferralById(ctx context.Context, id uuid.UUID) (Referral, error) {
+	collection := m.Database.Collection(referralsCollection)
+
+	var referral Referral
+	err := collection.FindOne(ctx, inClinic(ctx, bson.M{"_id": id})).Decode(&referral)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Referral{}, ErrNotFound
+		}
+		return Referral{}, fmt.Errorf("ReferralById: %w", err)
+	}
+
+	return referral, nil
+}
+
+// ReferralsByPatientId returns the referrals of the patient, the newest first.
+func (m *MongoDb) ReferralsByPatientId(
+	ctx context.Context,
+	patientId uuid.UUID,
+) ([]Referral, error) {
+	collection := m.Database.Collection(referralsCollection)
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	cursor, err := collection.Find(ctx, inClinic(ctx, bson.M{"patientId": patientId}), opts)
+	if err != nil {
+		return nil, fmt.Errorf("ReferralsByPatientId: %w", err)
+	}
+	defer func() {
+		if cerr := cursor.Close(ctx); cerr != nil {
+			slog.Warn("Failed to close referrals cursor", "error", cerr.Error())
+		}
+	}()
+
+	referrals := make([]Referral, 0)
+	if err = cursor.All(ctx, &referrals); err != nil {
+		return nil, fmt.Errorf("ReferralsByPatientId decode failed: %w", err)
+	}
+
+	return referrals, nil
+}
+
+// CancelReferral cancels an active referral, booked referrals can't be
+// cancelled until their appointment is.
+func (m *MongoDb) CancelReferral(ctx context.Context, id uuid.UUID) (Referral, error) {
+	collection := m.Database.Collection(referralsCollection)
+	filter := inClinic(ctx, bson.M{"_id": id, "status": ReferralActive})
+	update := incVersion(bson.M{"$set": bson.M{"status": ReferralCancelled}})
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var referral Referral
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&referral)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			if _, err := m.ReferralById(ctx, id); err != nil {
+				return Referral{}, fmt.Errorf("CancelReferral: %w", err)
+			}
+			return Referral{}, fmt.Errorf("CancelReferral: %w", ErrReferralNotActive)
+		}
+		return Referral{}, fmt.Errorf("CancelReferral: %w", err)
+	}
+
+	return referral, nil
+}
+
+// bookReferral claims an active, unexpired referral for the appointment.
+func (m *MongoDb) bookReferral(ctx context.Context, id uuid.UUID, appointmentId uuid.UUID) error {
+	collection := m.Database.Collection(referralsCollection)
+	filter := inClinic(ctx, bson.M{
+		"_id":       id,
+		"status":    ReferralActive,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	})
+	update := incVersion(bson.M{
+		"$set": bson.M{"status": ReferralBooked, "appointmentId": appointmentId},
+	})
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("bookReferral: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		if _, err := m.ReferralById(ctx, id); err != nil {
+			return fmt.Errorf("bookReferral: %w", err)
+		}
+		return fmt.Errorf("bookReferral: %w", ErrReferralNotActive)
+	}
+
+	return nil
+}
+
+// releaseReferral makes the referral booked by the appointment active again,
+// if there is one.
+func (m *MongoDb) releaseReferral(ctx context.Context, appointmentId uuid.UUID) error {
+	collection := m.Database.Collection(referralsCollection)
+	filter := inClinic(ctx, bson.M{"appointmentId": appointmentId, "status": ReferralBooked})
+	update := incVersion(bson.M{
+		"$set":   bson.M{"status": ReferralActive},
+		"$unset": bson.M{"appointmentId": ""},
+	})
+
+	if _, err := collection.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("releaseReferral: %w", err)
+	}
+	return nil
+}
+
+// completeReferral completes the referral booked by the appointment, if there
+// is one.
+func (m *MongoDb) completeReferral(ctx context.Context, appointmentId uuid.UUID) error {
+	collection := m.Database.Collection(referralsCollection)
+	filter := inClinic(ctx, bson.M{"appointmentId": appointmentId, "status": ReferralBooked})
+	update := incVersion(bson.M{"$set": bson.M{"status": ReferralCompleted}})
+
+	if _, err := collection.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("completeReferral: %w", err)
+	}
+	return nil
+}
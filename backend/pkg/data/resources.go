@@ -22,9 +22,11 @@ const (
 )
 
 type Resource struct {
-	Id   uuid.UUID    `bson:"_id"  json:"id"`
-	Name string       `bson:"name" json:"name"`
-	Type ResourceType `bson:"type" json:"type"`
+	Id         uuid.UUID    `bson:"_id"                  json:"id"`
+	Name       string       `bson:"name"                 json:"name"`
+	Type       ResourceType `bson:"type"                 json:"type"`
+	ClinicId   uuid.UUID    `bson:"clinicId"             json:"clinicId"`             // Reference to Clinic._id
+	LocationId *uuid.UUID   `bson:"locationId,omitempty" json:"locationId,omitempty"` // Reference to Location._id
 }
 
 type Reservation struct {
@@ -35,19 +37,29 @@ type Reservation struct {
 	ResourceType  ResourceType `bson:"resourceType"  json:"resourceType"`
 	StartTime     time.Time    `bson:"startTime"     json:"startTime"`
 	EndTime       time.Time    `bson:"endTime"       json:"endTime"`
+	ClinicId      uuid.UUID    `bson:"clinicId"      json:"clinicId"` // Reference to Clinic._id
 }
 
 func (m *MongoDb) CreateResource(
 	ctx context.Context,
 	name string,
 	typ ResourceType,
+	locationId *uuid.UUID,
 ) (Resource, error) {
+	if locationId != nil {
+		if err := m.locationExists(ctx, *locationId); err != nil {
+			return Resource{}, fmt.Errorf("CreateResource location check: %w", err)
+		}
+	}
+
 	collection := m.Database.Collection(resourcesCollection)
 
 	resource := Resource{
-		Id:   uuid.New(),
-		Name: name,
-		Type: typ,
+		Id:         uuid.New(),
+		Name:       name,
+		Type:       typ,
+		ClinicId:   ClinicFromContext(ctx),
+		LocationId: locationId,
 	}
 
 	_, err := collection.InsertOne(ctx, resource)
@@ -64,7 +76,7 @@ func (m *MongoDb) CreateResource(
 
 func (m *MongoDb) ResourceById(ctx context.Context, id uuid.UUID) (Resource, error) {
 	collection := m.Database.Collection(resourcesCollection)
-	filter := bson.M{"_id": id}
+	filter := inClinic(ctx, bson.M{"_id": id})
 
 	var resource Resource
 	err := collection.FindOne(ctx, filter).Decode(&resource)
@@ -78,6 +90,37 @@ func (m *MongoDb) ResourceById(ctx context.Context, id uuid.UUID) (Resource, err
 	return resource, nil
 }
 
+// SetResourceLocation assigns the resource to the location, or unassigns it if
+// locationId is nil.
+func (m *MongoDb) SetResourceLocation(
+	ctx context.Context,
+	resourceId uuid.UUID,
+	locationId *uuid.UUID,
+) (Resource, error) {
+	update := bson.M{"$unset": bson.M{"locationId": ""}}
+	if locationId != nil {
+		if err := m.locationExists(ctx, *locationId); err != nil {
+			return Resource{}, fmt.Errorf("SetResourceLocation location check: %w", err)
+		}
+		update = bson.M{"$set": bson.M{"locationId": *locationId}}
+	}
+
+	collection := m.Database.Collection(resourcesCollection)
+	filter := inClinic(ctx, bson.M{"_id": resourceId})
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var resource Resource
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&resource)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Resource{}, fmt.Errorf("SetResourceLocation %s: %w", resourceId, ErrNotFound)
+		}
+		return Resource{}, fmt.Errorf("SetResourceLocation: %w", err)
+	}
+
+	return resource, nil
+}
+
 func (m *MongoDb) CreateReservation(
 	ctx context.Context,
 	appointmentId uuid.UUID,
@@ -102,12 +145,12 @@ func (m *MongoDb) CreateReservation(
 	// --- Conflict Check (excluding self) ---
 	// This check MUST happen before the upsert to prevent overwriting a valid
 	// reservation from another appointment if the timing overlaps.
-	conflictFilter := bson.M{
+	conflictFilter := inClinic(ctx, bson.M{
 		"resourceId":    resourceId,
 		"appointmentId": bson.M{"$ne": appointmentId}, // Exclude the current appointment
 		"startTime":     bson.M{"$lt": endTime},
 		"endTime":       bson.M{"$gt": startTime},
-	}
+	})
 
 	count, err := collection.CountDocuments(ctx, conflictFilter)
 	if err != nil {
@@ -130,10 +173,10 @@ func (m *MongoDb) CreateReservation(
 	// the reservation for *this* specific appointment and resource.
 
 	// Filter to find the specific reservation for this appointment and resource
-	upsertFilter := bson.M{
+	upsertFilter := inClinic(ctx, bson.M{
 		"appointmentId": appointmentId,
 		"resourceId":    resourceId,
-	}
+	})
 
 	// Define the fields to set on update or initial insert
 	updateFields := bson.M{
@@ -171,9 +214,12 @@ func (m *MongoDb) CreateReservation(
 	return result, nil
 }
 
+// FindAvailableResourcesAtTime returns resources not reserved at the time.
+// Facilities are limited to facilityLocationIds, unless it is nil.
 func (m *MongoDb) FindAvailableResourcesAtTime(
 	ctx context.Context,
 	appointmentDate time.Time,
+	facilityLocationIds []uuid.UUID,
 ) (struct {
 	Medicines  []Resource
 	Facilities []Resource
@@ -201,7 +247,16 @@ func (m *MongoDb) FindAvailableResourcesAtTime(
 	// 2. $match: Keep only those resources where the lookup found *no*
 	//    conflicting reservations (i.e., the resulting array is empty).
 
+	match := inClinic(ctx, bson.M{})
+	if facilityLocationIds != nil {
+		match["$or"] = []bson.M{
+			{"type": bson.M{"$ne": ResourceTypeFacility}},
+			{"locationId": bson.M{"$in": facilityLocationIds}},
+		}
+	}
+
 	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: match}},
 		// Lookup conflicting reservations
 		bson.D{
 			{Key: "$lookup", Value: bson.M{
@@ -287,7 +342,7 @@ func (m *MongoDb) DeleteReservationsByAppointmentId(
 	appointmentId uuid.UUID,
 ) error {
 	collection := m.Database.Collection(reservationsCollection)
-	filter := bson.M{"appointmentId": appointmentId}
+	filter := inClinic(ctx, bson.M{"appointmentId": appointmentId})
 
 	_, err := collection.DeleteMany(ctx, filter)
 	if err != nil {
@@ -309,9 +364,10 @@ func (m *MongoDb) ResourcesByAppointmentId(
 	resourceMap := make(map[uuid.UUID]Resource)
 	for _, reservation := range reservations {
 		resource := Resource{
-			Id:   reservation.ResourceId,
-			Name: reservation.ResourceName,
-			Type: reservation.ResourceType,
+			Id:       reservation.ResourceId,
+			Name:     reservation.ResourceName,
+			Type:     reservation.ResourceType,
+			ClinicId: reservation.ClinicId,
 		}
 		resourceMap[reservation.ResourceId] = resource
 	}
@@ -329,7 +385,7 @@ func (m *MongoDb) ReservationsByAppointmentId(
 	appointmentId uuid.UUID,
 ) ([]Reservation, error) {
 	collection := m.Database.Collection(reservationsCollection)
-	filter := bson.M{"appointmentId": appointmentId}
+	filter := inClinic(ctx, bson.M{"appointmentId": appointmentId})
 
 	var reservations []Reservation
 	cursor, err := collection.Find(ctx, filter)
@@ -353,9 +409,81 @@ func (m *MongoDb) ReservationsByAppointmentId(
 	return reservations, nil
 }
 
+func (m *MongoDb) ReservationsByAppointmentIds(
+	ctx context.Context,
+	appointmentIds []uuid.UUID,
+) ([]Reservation, error) {
+	reservations := make([]Reservation, 0)
+	if len(appointmentIds) == 0 {
+		return reservations, nil
+	}
+
+	collection := m.Database.Collection(reservationsCollection)
+	filter := inClinic(ctx, bson.M{"appointmentId": bson.M{"$in": appointmentIds}})
+	opts := options.Find().SetSort(bson.D{{Key: "startTime", Value: 1}})
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("ReservationsByAppointmentIds: %w", err)
+	}
+	defer func() {
+		if cerr := cursor.Close(ctx); cerr != nil {
+			slog.Warn("Failed to close reservations cursor", "error", cerr.Error())
+		}
+	}()
+
+	if err = cursor.All(ctx, &reservations); err != nil {
+		return nil, fmt.Errorf("ReservationsByAppointmentIds decode failed: %w", err)
+	}
+
+	if err = cursor.Err(); err != nil {
+		return nil, fmt.Errorf("ReservationsByAppointmentIds cursor error: %w", err)
+	}
+
+	return reservations, nil
+}
+
+// ReservationsByResourceIds returns the reservations of the resources
+// overlapping the time range.
+func (m *MongoDb) ReservationsByResourceIds(
+	ctx context.Context,
+	resourceIds []uuid.UUID,
+	from time.Time,
+	to time.Time,
+) ([]Reservation, error) {
+	reservations := make([]Reservation, 0)
+	if len(resourceIds) == 0 {
+		return reservations, nil
+	}
+
+	collection := m.Database.Collection(reservationsCollection)
+	filter := inClinic(ctx, bson.M{
+		"resourceId": bson.M{"$in": resourceIds},
+		"startTime":  bson.M{"$lt": to},
+		"endTime":    bson.M{"$gt": from},
+	})
+	opts := options.Find().SetSort(bson.D{{Key: "startTime", Value: 1}})
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("ReservationsByResourceIds: %w", err)
+	}
+	defer func() {
+		if cerr := cursor.Close(ctx); cerr != nil {
+			slog.Warn("Failed to close reservations cursor", "error", cerr.Error())
+		}
+	}()
+
+	if err = cursor.All(ctx, &reservations); err != nil {
+		return nil, fmt.Errorf("ReservationsByResourceIds decode failed: %w", err)
+	}
+
+	return reservations, nil
+}
+
 func (m *MongoDb) resourceExists(ctx context.Context, id uuid.UUID) error {
 	resourcesColl := m.Database.Collection(resourcesCollection)
-	filter := bson.M{"_id": id}
+	filter := inClinic(ctx, bson.M{"_id": id})
 
 	count, err := resourcesColl.CountDocuments(ctx, filter)
 	if err != nil {
@@ -371,34 +499,40 @@ func (m *MongoDb) resourceExists(ctx context.Context, id uuid.UUID) error {
 func (m *MongoDb) seedResources(ctx context.Context) error {
 	initialResources := []Resource{
 		{
-			Id:   uuid.MustParse("399ae499-ac47-468a-9c76-0a58c028141a"),
-			Name: "Operating Room 1",
-			Type: ResourceTypeFacility,
+			Id:       uuid.MustParse("399ae499-ac47-468a-9c76-0a58c028141a"),
+			Name:     "Operating Room 1",
+			Type:     ResourceTypeFacility,
+			ClinicId: DefaultClinicId,
 		},
 		{
-			Id:   uuid.MustParse("76673eca-82e1-46dd-b54a-d80fc02c3eaf"),
-			Name: "Consultation Room A",
-			Type: ResourceTypeFacility,
+			Id:       uuid.MustParse("76673eca-82e1-46dd-b54a-d80fc02c3eaf"),
+			Name:     "Consultation Room A",
+			Type:     ResourceTypeFacility,
+			ClinicId: DefaultClinicId,
 		},
 		{
-			Id:   uuid.MustParse("660ee5f2-3ec2-4b71-a7b9-4cd2cc9c9a48"),
-			Name: "MRI Machine",
-			Type: ResourceTypeEquipment,
+			Id:       uuid.MustParse("660ee5f2-3ec2-4b71-a7b9-4cd2cc9c9a48"),
+			Name:     "MRI Machine",
+			Type:     ResourceTypeEquipment,
+			ClinicId: DefaultClinicId,
 		},
 		{
-			Id:   uuid.MustParse("32aeb6b4-100a-459e-bece-15a0d24af9ae"),
-			Name: "X-ray Machine",
-			Type: ResourceTypeEquipment,
+			Id:       uuid.MustParse("32aeb6b4-100a-459e-bece-15a0d24af9ae"),
+			Name:     "X-ray Machine",
+			Type:     ResourceTypeEquipment,
+			ClinicId: DefaultClinicId,
 		},
 		{
-			Id:   uuid.MustParse("6241705f-f56d-4ce9-aed4-03d3295a4159"),
-			Name: "Painkillers",
-			Type: ResourceTypeMedicine,
+			Id:       uuid.MustParse("6241705f-f56d-4ce9-aed4-03d3295a4159"),
+			Name:     "Painkillers",
+			Type:     ResourceTypeMedicine,
+			ClinicId: DefaultClinicId,
 		},
 		{
-			Id:   uuid.MustParse("24430efc-8308-4f1e-8cab-15f6d43216a5"),
-			Name: "Antibiotics",
-			Type: ResourceTypeMedicine,
+			Id:       uuid.MustParse("24430efc-8308-4f1e-8cab-15f6d43216a5"),
+			Name:     "Antibiotics",
+			Type:     ResourceTypeMedicine,
+			ClinicId: DefaultClinicId,
 		},
 	}
 
@@ -0,0 +1,222 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type RevisionOperation string
+
+const (
+	// RevisionBaseline is the state of a document created before revisions
+	// were kept, stored on its first write. When it was written is unknown.
+	RevisionBaseline RevisionOperation = "baseline"
+	RevisionCreate   RevisionOperation = "create"
+	RevisionUpdate   RevisionOperation = "update"
+	RevisionDelete   RevisionOperation = "delete"
+)
+
+// Revision is an immutable snapshot of a document right after a write to it.
+// A delete revision holds the last state of the deleted document.
+type Revision[T any] struct {
+	Id         uuid.UUID         `bson:"_id"                 json:"id"`
+	Collection string            `bson:"collection"          json:"collection"`
+	DocumentId uuid.UUID         `bson:"documentId"          json:"documentId"`
+	Version    int64             `bson:"version"             json:"version"`
+	Operation  RevisionOperation `bson:"operation"           json:"operation"`
+	ChangedAt  time.Time         `bson:"changedAt,omitempty" json:"changedAt,omitzero"`
+	ChangedBy  *Actor            `bson:"changedBy,omitempty" json:"changedBy,omitempty"`
+	Document   T                 `bson:"document"            json:"document"`
+}
+
+// ConditionRevisions returns the revisions of the condition ordered from the
+// oldest, also of an already deleted one.
+func (m *MongoDb) ConditionRevisions(
+	ctx context.Context,
+	conditionId uuid.UUID,
+) ([]Revision[Condition], error) {
+	revisions, err := revisionsOf[Condition](ctx, m, conditionsCollection, conditionId)
+	if err != nil {
+		return nil, fmt.Errorf("ConditionRevisions: %w", err)
+	}
+	if len(revisions) > 0 {
+		patientId := revisions[len(revisions)-1].Document.PatientId
+		if visible, err := m.patientVisible(ctx, patientId); err != nil {
+			return nil, fmt.Errorf("ConditionRevisions: %w", err)
+		} else if !visible {
+			return []Revision[Condition]{}, nil
+		}
+	}
+	return revisions, nil
+}
+
+// PrescriptionRevisions returns the revisions of the prescription ordered from
+// the oldest, also of an already deleted one.
+func (m *MongoDb) PrescriptionRevisions(
+	ctx context.Context,
+	prescriptionId uuid.UUID,
+) ([]Revision[Prescription], error) {
+	revisions, err := revisionsOf[Prescription](
+		ctx,
+		m,
+		prescriptionsCollection,
+		prescriptionId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("PrescriptionRevisions: %w", err)
+	}
+	if len(revisions) > 0 {
+		patientId := revisions[len(revisions)-1].Document.PatientId
+		if visible, err := m.patientVisible(ctx, patientId); err != nil {
+			return nil, fmt.Errorf("PrescriptionRevisions: %w", err)
+		} else if !visible {
+			return []Revision[Prescription]{}, nil
+		}
+	}
+	return revisions, nil
+}
+
+func revisionsOf[T any](
+	ctx context.Context,
+	m *MongoDb,
+	collection string,
+	documentId uuid.UUID,
+) ([]Revision[T], error) {
+	revisions := make([]Revision[T], 0)
+	filter := bson.M{"collection": collection, "documentId": documentId}
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: 1}})
+
+	cursor, err := m.Database.Collection(revisionsCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("revisionsOf find failed: %w", err)
+	}
+
+	defer func() {
+		if cerr := cursor.Close(ctx); cerr != nil {
+			slog.Warn("Failed to close revisions cursor", "error", cerr.Error())
+		}
+	}()
+
+	if err = cursor.All(ctx, &revisions); err != nil {
+		return nil, fmt.Errorf("revisionsOf decode failed: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// recordCreate stores the first revision of a newly created document.
+func (m *MongoDb) recordCreate(
+	ctx context.Context,
+	collection string,
+	id uuid.UUID,
+	version int64,
+	document any,
+) error {
+	_, err := m.insertRevision(ctx, collection, id, version, RevisionCreate, document, time.Now())
+	return err
+}
+
+// recordChange stores the revision of an update or delete of a document,
+// before is its state prior to the write. If the document has no revisions
+// yet, because it was created before they were kept, before is stored first
+// as its baseline. It is called before the write, so a write is never left
+// without its revision, and the returned revert removes the stored revisions
+// if the write then fails, as writes aren't done in transactions.
+func (m *MongoDb) recordChange(
+	ctx context.Context,
+	collection string,
+	id uuid.UUID,
+	operation RevisionOperation,
+	before any,
+	beforeVersion int64,
+	after any,
+	afterVersion int64,
+) (func(), error) {
+	filter := bson.M{"collection": collection, "documentId": id}
+	count, err := m.Database.Collection(revisionsCollection).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("recordChange count failed: %w", err)
+	}
+
+	recorded := make([]uuid.UUID, 0, 2)
+	revert := func() { m.removeRevisions(context.WithoutCancel(ctx), recorded) }
+	if count == 0 {
+		revisionId, err := m.insertRevision(
+			ctx,
+			collection,
+			id,
+			beforeVersion,
+			RevisionBaseline,
+			before,
+			time.Time{},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("recordChange baseline: %w", err)
+		}
+		recorded = append(recorded, revisionId)
+	}
+
+	revisionId, err := m.insertRevision(
+		ctx,
+		collection,
+		id,
+		afterVersion,
+		operation,
+		after,
+		time.Now(),
+	)
+	if err != nil {
+		revert()
+		return nil, fmt.Errorf("recordChange: %w", err)
+	}
+	recorded = append(recorded, revisionId)
+
+	return revert, nil
+}
+
+// removeRevisions deletes revisions of a write which failed.
+func (m *MongoDb) removeRevisions(ctx context.Context, ids []uuid.UUID) {
+	if len(ids) == 0 {
+		return
+	}
+	filter := bson.M{"_id": bson.M{"$in": ids}}
+	if _, err := m.Database.Collection(revisionsCollection).DeleteMany(ctx, filter); err != nil {
+		slog.Error("failed to remove revisions of a failed write", "error", err.Error())
+	}
+}
+
+func (m *MongoDb) insertRevision(
+	ctx context.Context,
+	collection string,
+	id uuid.UUID,
+	version int64,
+	operation RevisionOperation,
+	document any,
+	changedAt time.Time,
+) (uuid.UUID, error) {
+	revision := Revision[any]{
+		Id:         uuid.New(),
+		Collection: collection,
+		DocumentId: id,
+		Version:    version,
+		Operation:  operation,
+		ChangedAt:  changedAt,
+		Document:   document,
+	}
+	if actor, ok := ActorFromContext(ctx); ok {
+		revision.ChangedBy = &actor
+	}
+
+	_, err := m.Database.Collection(revisionsCollection).InsertOne(ctx, revision)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("insertRevision failed: %w", err)
+	}
+
+	return revision.Id, nil
+}
@@ -0,0 +1,109 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type StaffRole string
+
+const (
+	StaffNurse        StaffRole = "nurse"
+	StaffReceptionist StaffRole = "receptionist"
+	StaffAdmin        StaffRole = "admin"
+)
+
+// Staff is a nurse, receptionist or administrator of a clinic. Staff don't
+// register themselves, an administrator creates them.
+type Staff struct {
+	Id        uuid.UUID `bson:"_id"       json:"id"`
+	Email     string    `bson:"email"     json:"email"`
+	FirstName string    `bson:"firstName" json:"firstName"`
+	LastName  string    `bson:"lastName"  json:"lastName"`
+	Role      StaffRole `bson:"role"      json:"role"`
+	ClinicId  uuid.UUID `bson:"clinicId"  json:"clinicId"` // Reference to Clinic._id
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+func (m *MongoDb) CreateStaff(ctx context.Context, staff Staff) (Staff, error) {
+	collection := m.Database.Collection(staffCollection)
+	staff.Id = uuid.New()
+	staff.ClinicId = ClinicFromContext(ctx)
+	staff.CreatedAt = time.Now()
+
+	_, err := collection.InsertOne(ctx, staff)
+	if err != nil {
+		var writeErr mongo.WriteException
+		if errors.As(err, &writeErr) {
+			for _, we := range writeErr.WriteErrors {
+				if we.Code == 11000 {
+					return Staff{}, ErrDuplicateEmail
+				}
+			}
+		}
+		return Staff{}, fmt.Errorf("CreateStaff: failed to insert document: %w", err)
+	}
+
+	return staff, nil
+}
+
+func (m *MongoDb) StaffById(ctx context.Context, id uuid.UUID) (Staff, error) {
+	collection := m.Database.Collection(staffCollection)
+
+	var staff Staff
+	err := collection.FindOne(ctx, inClinic(ctx, bson.M{"_id": id})).Decode(&staff)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Staff{}, ErrNotFound
+		}
+		return Staff{}, fmt.Errorf("StaffById: %w", err)
+	}
+
+	return staff, nil
+}
+
+func (m *MongoDb) StaffByEmail(ctx context.Context, email string) (Staff, error) {
+	collection := m.Database.Collection(staffCollection)
+
+	var staff Staff
+	err := collection.FindOne(ctx, inClinic(ctx, bson.M{"email": email})).Decode(&staff)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Staff{}, ErrNotFound
+		}
+		return Staff{}, fmt.Errorf("StaffByEmail: %w", err)
+	}
+
+	return staff, nil
+}
+
+// ClinicStaff returns all staff of the clinic, sorted by last name.
+func (m *MongoDb) ClinicStaff(ctx context.Context) ([]Staff, error) {
+	collection := m.Database.Collection(staffCollection)
+	opts := options.Find().SetSort(bson.D{{Key: "lastName", Value: 1}, {Key: "firstName", Value: 1}})
+
+	cursor, err := collection.Find(ctx, inClinic(ctx, bson.M{}), opts)
+	if err != nil {
+		return nil, fmt.Errorf("ClinicStaff: %w", err)
+	}
+	defer func() {
+		if cerr := cursor.Close(ctx); cerr != nil {
+			slog.Warn("Failed to close staff cursor", "error", cerr.Error())
+		}
+	}()
+
+	staff := make([]Staff, 0)
+	if err = cursor.All(ctx, &staff); err != nil {
+		return nil, fmt.Errorf("ClinicStaff decode failed: %w", err)
+	}
+
+	return staff, nil
+}
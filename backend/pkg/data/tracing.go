@@ -0,0 +1,75 @@
+package data
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer follows the global tracer provider, set by the server on start.
+var tracer = otel.Tracer("github.com/Nesquiko/wac/pkg/data")
+
+// commandSpans are spans of the MongoDB commands in flight. Only commands
+// sent within a traced request, or other traced operation, get a span.
+type commandSpans struct {
+	spans sync.Map
+}
+
+type commandKey struct {
+	connectionId string
+	requestId    int64
+}
+
+func (s *commandSpans) start(ctx context.Context, e *event.CommandStartedEvent) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+
+	name := e.CommandName
+	collection := commandCollection(e)
+	if collection != "" {
+		name += " " + collection
+	}
+	_, span := tracer.Start(
+		ctx,
+		name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemMongoDB,
+			semconv.DBNamespace(e.DatabaseName),
+			semconv.DBOperationName(e.CommandName),
+			semconv.DBCollectionName(collection),
+		),
+	)
+	s.spans.Store(commandKey{e.ConnectionID, e.RequestID}, span)
+}
+
+// end ends the span of the command, marked as failed if failure isn't nil.
+func (s *commandSpans) end(e event.CommandFinishedEvent, failure error) {
+	value, ok := s.spans.LoadAndDelete(commandKey{e.ConnectionID, e.RequestID})
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	if failure != nil {
+		span.RecordError(failure)
+		span.SetStatus(codes.Error, failure.Error())
+	}
+	span.End()
+}
+
+// commandCollection returns the collection the command operates on, the
+// value of its first element for commands like find or insert.
+func commandCollection(e *event.CommandStartedEvent) string {
+	elem, err := e.Command.IndexErr(0)
+	if err != nil {
+		return ""
+	}
+	collection, _ := elem.Value().StringValueOK()
+	return collection
+}
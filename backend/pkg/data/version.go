@@ -0,0 +1,47 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ErrVersionConflict is returned by conditional writes when the document's
+// version isn't the expected one, i.e. it was modified in the meantime.
+var ErrVersionConflict = errors.New("document was modified concurrently")
+
+// initialVersion is the version of newly created documents. Documents created
+// before versioning have no version field, which is treated as version 0.
+const initialVersion = 1
+
+// withVersion returns the filter extended to match only documents with the
+// given version.
+func withVersion(filter bson.M, version int64) bson.M {
+	if version == 0 {
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	} else {
+		filter["version"] = version
+	}
+	return filter
+}
+
+// incVersion returns the update extended to increment the document's version.
+func incVersion(update bson.M) bson.M {
+	update["$inc"] = bson.M{"version": 1}
+	return update
+}
+
+// missingOrConflict tells apart why a conditional write matched no document.
+func (m *MongoDb) missingOrConflict(ctx context.Context, collection string, id uuid.UUID) error {
+	count, err := m.Database.Collection(collection).CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("missingOrConflict count failed: %w", err)
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	return ErrVersionConflict
+}
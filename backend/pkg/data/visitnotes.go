@@ -0,0 +1,186 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	ErrAppointmentNotScheduled = errors.New("appointment is not scheduled")
+	ErrVisitNoteLocked         = errors.New("visit note was signed and can't be changed")
+)
+
+// VisitNote documents what happened during an appointment, there is at most
+// one per appointment. It is signed, and can't be changed anymore, when the
+// appointment is completed.
+type VisitNote struct {
+	Id            uuid.UUID         `bson:"_id"                json:"id"`
+	AppointmentId uuid.UUID         `bson:"appointmentId"      json:"appointmentId"` // Reference to Appointment._id
+	DoctorId      uuid.UUID         `bson:"doctorId"           json:"doctorId"`      // Reference to Doctor._id
+	ClinicId      uuid.UUID         `bson:"clinicId"           json:"clinicId"`      // Reference to Clinic._id
+	Sections      VisitNoteSections `bson:"sections"           json:"sections"`
+	Text          *string           `bson:"text,omitempty"     json:"text,omitempty"`
+	Vitals        Vitals            `bson:"vitals"             json:"vitals"`
+	UpdatedAt     time.Time         `bson:"updatedAt"          json:"updatedAt"`
+	SignedAt      *time.Time        `bson:"signedAt,omitempty" json:"signedAt,omitempty"`
+	SignedBy      *uuid.UUID        `bson:"signedBy,omitempty" json:"signedBy,omitempty"` // Reference to Doctor._id
+
+	// Version is incremented on every write, see ErrVersionConflict.
+	Version int64 `bson:"version" json:"version"`
+}
+
+type VisitNoteSections struct {
+	Anamnesis     *string `bson:"anamnesis,omitempty"     json:"anamnesis,omitempty"`
+	Findings      *string `bson:"findings,omitempty"      json:"findings,omitempty"`
+	Diagnosis     *string `bson:"diagnosis,omitempty"     json:"diagnosis,omitempty"`
+	DiagnosisCode *string `bson:"diagnosisCode,omitempty" json:"diagnosisCode,omitempty"`
+	Plan          *string `bson:"plan,omitempty"          json:"plan,omitempty"`
+}
+
+type Vitals struct {
+	BloodPressure *BloodPressure `bson:"bloodPressure,omitempty" json:"bloodPressure,omitempty"`
+	HeartRate     *Measurement   `bson:"heartRate,omitempty"     json:"heartRate,omitempty"`
+	Temperature   *Measurement   `bson:"temperature,omitempty"   json:"temperature,omitempty"`
+	Weight        *Measurement   `bson:"weight,omitempty"        json:"weight,omitempty"`
+	Height        *Measurement   `bson:"height,omitempty"        json:"height,omitempty"`
+}
+
+type BloodPressure struct {
+	Systolic  int    `bson:"systolic"  json:"systolic"`
+	Diastolic int    `bson:"diastolic" json:"diastolic"`
+	Unit      string `bson:"unit"      json:"unit"`
+}
+
+type Measurement struct {
+	Value float64 `bson:"value" json:"value"`
+	Unit  string  `bson:"unit"  json:"unit"`
+}
+
+func (m *MongoDb) VisitNoteByAppointmentId(
+	ctx context.Context,
+	appointmentId uuid.UUID,
+) (VisitNote, error) {
+	collection := m.Database.Collection(visitNotesCollection)
+	filter := inClinic(ctx, bson.M{"appointmentId": appointmentId})
+
+	var note VisitNote
+	err := collection.FindOne(ctx, filter).Decode(&note)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return VisitNote{}, ErrNotFound
+		}
+		return VisitNote{}, fmt.Errorf("VisitNoteByAppointmentId: %w", err)
+	}
+
+	return note, nil
+}
+
+func (m *MongoDb) VisitNotesByAppointmentIds(
+	ctx context.Context,
+	appointmentIds []uuid.UUID,
+) ([]VisitNote, error) {
+	notes := make([]VisitNote, 0)
+	if len(appointmentIds) == 0 {
+		return notes, nil
+	}
+
+	collection := m.Database.Collection(visitNotesCollection)
+	filter := inClinic(ctx, bson.M{"appointmentId": bson.M{"$in": appointmentIds}})
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("VisitNotesByAppointmentIds: %w", err)
+	}
+	defer func() {
+		if cerr := cursor.Close(ctx); cerr != nil {
+			slog.Warn("Failed to close visit notes cursor", "error", cerr.Error())
+		}
+	}()
+
+	if err = cursor.All(ctx, &notes); err != nil {
+		return nil, fmt.Errorf("VisitNotesByAppointmentIds decode failed: %w", err)
+	}
+
+	return notes, nil
+}
+
+// SaveVisitNote creates or replaces the content of the visit note of the
+// note's appointment. The appointment must be scheduled, notes of completed
+// appointments are locked.
+func (m *MongoDb) SaveVisitNote(ctx context.Context, note VisitNote) (VisitNote, error) {
+	appointment, err := m.AppointmentById(ctx, note.AppointmentId)
+	if err != nil {
+		return VisitNote{}, fmt.Errorf("SaveVisitNote: %w", err)
+	}
+
+	switch appointment.Status {
+	case "scheduled":
+	case "completed":
+		return VisitNote{}, fmt.Errorf("SaveVisitNote: %w", ErrVisitNoteLocked)
+	default:
+		return VisitNote{}, fmt.Errorf("SaveVisitNote: %w", ErrAppointmentNotScheduled)
+	}
+
+	collection := m.Database.Collection(visitNotesCollection)
+	filter := inClinic(ctx, bson.M{
+		"appointmentId": note.AppointmentId,
+		"signedAt":      bson.M{"$exists": false},
+	})
+	update := incVersion(bson.M{
+		"$set": bson.M{
+			"doctorId":  note.DoctorId,
+			"sections":  note.Sections,
+			"text":      note.Text,
+			"vitals":    note.Vitals,
+			"updatedAt": time.Now(),
+		},
+		"$setOnInsert": bson.M{"_id": uuid.New()},
+	})
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var saved VisitNote
+	err = collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&saved)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			// The note was signed, the upsert clashed with it.
+			return VisitNote{}, fmt.Errorf("SaveVisitNote: %w", ErrVisitNoteLocked)
+		}
+		return VisitNote{}, fmt.Errorf("SaveVisitNote: %w", err)
+	}
+
+	return saved, nil
+}
+
+// signVisitNote locks the visit note of the appointment, if it has one.
+func (m *MongoDb) signVisitNote(ctx context.Context, appointment Appointment) error {
+	collection := m.Database.Collection(visitNotesCollection)
+	filter := inClinic(ctx, bson.M{
+		"appointmentId": appointment.Id,
+		"signedAt":      bson.M{"$exists": false},
+	})
+	update := incVersion(bson.M{
+		"$set": bson.M{"signedAt": time.Now(), "signedBy": appointment.DoctorId},
+	})
+
+	if _, err := collection.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("signVisitNote: %w", err)
+	}
+	return nil
+}
+
+func (m *MongoDb) deleteVisitNote(ctx context.Context, appointmentId uuid.UUID) error {
+	collection := m.Database.Collection(visitNotesCollection)
+	filter := inClinic(ctx, bson.M{"appointmentId": appointmentId})
+	if _, err := collection.DeleteOne(ctx, filter); err != nil {
+		return fmt.Errorf("deleteVisitNote: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+)
+
+// Entry is a Bundle entry decoded into its typed resource. Resource is one of
+// Patient, Practitioner, Condition, MedicationRequest or Appointment, and nil
+// for any other resource type.
+type Entry struct {
+	FullUrl      string
+	ResourceType string
+	Resource     any
+}
+
+// Identifiers returns identifiers of the entry's resource.
+func (e Entry) Identifiers() []Identifier {
+	switch res := e.Resource.(type) {
+	case Patient:
+		return res.Identifier
+	case Practitioner:
+		return res.Identifier
+	case Condition:
+		return res.Identifier
+	case MedicationRequest:
+		return res.Identifier
+	case Appointment:
+		return res.Identifier
+	default:
+		return nil
+	}
+}
+
+// Id returns the logical id of the entry's resource.
+func (e Entry) Id() string {
+	if res, ok := e.Resource.(Resource); ok {
+		return res.ResourceId()
+	}
+	return ""
+}
+
+// ParseBundle decodes a Bundle and its entries. Malformed JSON or a resource
+// not matching its declared type is reported as ErrInvalidResource.
+func ParseBundle(r io.Reader) ([]Entry, error) {
+	var bundle struct {
+		ResourceType string `json:"resourceType"`
+		Entry        []struct {
+			FullUrl  string          `json:"fullUrl"`
+			Resource json.RawMessage `json:"resource"`
+		} `json:"entry"`
+	}
+	if err := json.NewDecoder(r).Decode(&bundle); err != nil {
+		return nil, fmt.Errorf("%w: bundle: %w", ErrInvalidResource, err)
+	}
+	if bundle.ResourceType != ResourceTypeBundle {
+		return nil, fmt.Errorf("%w: expected a Bundle, got %q", ErrInvalidResource, bundle.ResourceType)
+	}
+
+	entries := make([]Entry, len(bundle.Entry))
+	for i, raw := range bundle.Entry {
+		var header struct {
+			ResourceType string `json:"resourceType"`
+		}
+		if err := json.Unmarshal(raw.Resource, &header); err != nil {
+			return nil, fmt.Errorf("%w: entry %d: %w", ErrInvalidResource, i, err)
+		}
+
+		var err error
+		entry := Entry{FullUrl: raw.FullUrl, ResourceType: header.ResourceType}
+		switch header.ResourceType {
+		case ResourceTypePatient:
+			entry.Resource, err = decodeResource[Patient](raw.Resource)
+		case ResourceTypePractitioner:
+			entry.Resource, err = decodeResource[Practitioner](raw.Resource)
+		case ResourceTypeCondition:
+			entry.Resource, err = decodeResource[Condition](raw.Resource)
+		case ResourceTypeMedicationRequest:
+			entry.Resource, err = decodeResource[MedicationRequest](raw.Resource)
+		case ResourceTypeAppointment:
+			entry.Resource, err = decodeResource[Appointment](raw.Resource)
+		}
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%w: entry %d %s: %w",
+				ErrInvalidResource,
+				i,
+				header.ResourceType,
+				err,
+			)
+		}
+		entries[i] = entry
+	}
+
+	return entries, nil
+}
+
+func decodeResource[T any](raw json.RawMessage) (T, error) {
+	var res T
+	err := json.Unmarshal(raw, &res)
+	return res, err
+}
+
+// Outcomes of a single entry of an import.
+const (
+	// ImportOutcomeCreated means a new record was (or on dry run would be) created.
+	ImportOutcomeCreated = "created"
+	// ImportOutcomeExisting means the source identifier was imported before.
+	ImportOutcomeExisting = "existing"
+	// ImportOutcomeMatched means the resource matched an existing record,
+	// e.g. a patient with the same email.
+	ImportOutcomeMatched = "matched"
+	// ImportOutcomeIgnored is used for unsupported resource types.
+	ImportOutcomeIgnored = "ignored"
+	ImportOutcomeInvalid = "invalid"
+	ImportOutcomeFailed  = "failed"
+)
+
+type ImportEntryReport struct {
+	Index        int         `json:"index"`
+	ResourceType string      `json:"resourceType"`
+	Identifier   *Identifier `json:"identifier,omitempty"`
+	Outcome      string      `json:"outcome"`
+	LocalId      *uuid.UUID  `json:"localId,omitempty"`
+	Issues       []string    `json:"issues,omitempty"`
+}
+
+// ImportReport describes what an import of a Bundle did, or on dry run
+// would do, with each of its entries.
+type ImportReport struct {
+	DryRun   bool                `json:"dryRun"`
+	Valid    bool                `json:"valid"`
+	Imported bool                `json:"imported"`
+	Entries  []ImportEntryReport `json:"entries"`
+}
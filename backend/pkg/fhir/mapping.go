@@ -0,0 +1,543 @@
+package fhir
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+const (
+	SpecializationSystem  = "urn:wac:specialization"
+	AppointmentTypeSystem = "urn:wac:appointment-type"
+
+	// AppointmentStatusExtension keeps the original appointment status when
+	// it has no exact FHIR counterpart (denied appointments are "cancelled").
+	AppointmentStatusExtension = "urn:wac:extension:appointment-status"
+
+	CancellationReasonSystem = "http://terminology.hl7.org/CodeSystem/appointment-cancellation-reason"
+	ConditionClinicalSystem  = "http://terminology.hl7.org/CodeSystem/condition-clinical"
+	ICD10System              = "http://hl7.org/fhir/sid/icd-10"
+)
+
+// FHIR Appointment.status codes.
+const (
+	AppointmentStatusProposed  = "proposed"
+	AppointmentStatusPending   = "pending"
+	AppointmentStatusBooked    = "booked"
+	AppointmentStatusFulfilled = "fulfilled"
+	AppointmentStatusCancelled = "cancelled"
+)
+
+// FHIR Condition.clinicalStatus codes.
+const (
+	ClinicalStatusActive   = "active"
+	ClinicalStatusResolved = "resolved"
+)
+
+// FHIR MedicationRequest.status codes.
+const (
+	MedicationRequestStatusActive    = "active"
+	MedicationRequestStatusCompleted = "completed"
+)
+
+const (
+	cancelledByPatient = "pat"
+	cancelledByDoctor  = "prov"
+)
+
+var (
+	ErrInvalidReference = errors.New("invalid reference")
+	ErrInvalidResource  = errors.New("invalid resource")
+)
+
+var appointmentStatuses = map[string]string{
+	"requested": AppointmentStatusPending,
+	"scheduled": AppointmentStatusBooked,
+	"completed": AppointmentStatusFulfilled,
+	"cancelled": AppointmentStatusCancelled,
+	"denied":    AppointmentStatusCancelled,
+}
+
+var dataAppointmentStatuses = map[string]string{
+	AppointmentStatusProposed:  "requested",
+	AppointmentStatusPending:   "requested",
+	AppointmentStatusBooked:    "scheduled",
+	AppointmentStatusFulfilled: "completed",
+	AppointmentStatusCancelled: "cancelled",
+}
+
+func PatientFromData(p data.Patient) Patient {
+	return Patient{
+		ResourceType: ResourceTypePatient,
+		Id:           p.Id.String(),
+		Active:       asPtr(p.ErasedAt == nil),
+		Name:         []HumanName{officialName(p.FirstName, p.LastName)},
+		Telecom:      []ContactPoint{email(p.Email)},
+	}
+}
+
+func PatientToData(p Patient) (data.Patient, error) {
+	id, err := parseId(p.Id)
+	if err != nil {
+		return data.Patient{}, fmt.Errorf("PatientToData: %w", err)
+	}
+	firstName, lastName := nameParts(p.Name)
+
+	return data.Patient{
+		Id:        id,
+		Email:     emailOf(p.Telecom),
+		FirstName: firstName,
+		LastName:  lastName,
+	}, nil
+}
+
+func PractitionerFromData(d data.Doctor) Practitioner {
+	qualifications := make([]Qualification, len(d.Specializations))
+	for i, specialization := range d.Specializations {
+		qualifications[i] = Qualification{Code: CodeableConcept{
+			Coding: []Coding{{System: SpecializationSystem, Code: specialization}},
+			Text:   specialization,
+		}}
+	}
+
+	return Practitioner{
+		ResourceType:  ResourceTypePractitioner,
+		Id:            d.Id.String(),
+		Active:        asPtr(d.Active),
+		Name:          []HumanName{officialName(d.FirstName, d.LastName)},
+		Telecom:       []ContactPoint{email(d.Email)},
+		Qualification: qualifications,
+	}
+}
+
+func PractitionerToData(p Practitioner) (data.Doctor, error) {
+	id, err := parseId(p.Id)
+	if err != nil {
+		return data.Doctor{}, fmt.Errorf("PractitionerToData: %w", err)
+	}
+	firstName, lastName := nameParts(p.Name)
+
+	specializations := make([]string, 0, len(p.Qualification))
+	for _, q := range p.Qualification {
+		if code, ok := codeOf(q.Code, SpecializationSystem); ok {
+			specializations = append(specializations, code)
+		}
+	}
+
+	return data.Doctor{
+		Id:              id,
+		Email:           emailOf(p.Telecom),
+		FirstName:       firstName,
+		LastName:        lastName,
+		Specializations: specializations,
+		Active:          p.Active == nil || *p.Active,
+	}, nil
+}
+
+// AppointmentFromData maps an appointment. Reserved resources are not part of
+// the FHIR Appointment and are omitted.
+func AppointmentFromData(a data.Appointment) Appointment {
+	appt := Appointment{
+		ResourceType: ResourceTypeAppointment,
+		Id:           a.Id.String(),
+		Status:       appointmentStatuses[a.Status],
+		AppointmentType: &CodeableConcept{
+			Coding: []Coding{{System: AppointmentTypeSystem, Code: a.Type}},
+		},
+		Start: asPtr(a.AppointmentDateTime),
+		End:   asPtr(a.EndTime),
+		Participant: []AppointmentParticipant{
+			{Actor: reference(ResourceTypePatient, a.PatientId), Status: "accepted"},
+			{Actor: reference(ResourceTypePractitioner, a.DoctorId), Status: doctorParticipation(a.Status)},
+		},
+	}
+
+	if a.Status == "denied" {
+		appt.Extension = []Extension{{Url: AppointmentStatusExtension, ValueCode: a.Status}}
+	}
+	if a.Reason != nil {
+		appt.Description = *a.Reason
+	}
+	if a.ConditionId != nil {
+		appt.ReasonReference = []Reference{reference(ResourceTypeCondition, *a.ConditionId)}
+	}
+
+	switch {
+	case a.Status == "denied":
+		appt.CancelationReason = cancelationReason(cancelledByDoctor, a.DenialReason)
+	case a.CancelledBy != nil || a.CancellationReason != nil:
+		by := ""
+		if a.CancelledBy != nil {
+			by = cancelledByCode(*a.CancelledBy)
+		}
+		appt.CancelationReason = cancelationReason(by, a.CancellationReason)
+	}
+
+	return appt
+}
+
+func AppointmentToData(a Appointment) (data.Appointment, error) {
+	id, err := parseId(a.Id)
+	if err != nil {
+		return data.Appointment{}, fmt.Errorf("AppointmentToData: %w", err)
+	}
+	if a.Start == nil || a.End == nil {
+		return data.Appointment{}, fmt.Errorf("AppointmentToData start and end: %w", ErrInvalidResource)
+	}
+
+	status, err := AppointmentStatusToData(a)
+	if err != nil {
+		return data.Appointment{}, fmt.Errorf("AppointmentToData: %w", err)
+	}
+
+	appt := data.Appointment{
+		Id:                  id,
+		AppointmentDateTime: *a.Start,
+		EndTime:             *a.End,
+		Status:              status,
+	}
+
+	for _, participant := range a.Participant {
+		typ, actorId, err := ParseReference(participant.Actor.Reference)
+		if err != nil {
+			return data.Appointment{}, fmt.Errorf("AppointmentToData participant: %w", err)
+		}
+		switch typ {
+		case ResourceTypePatient:
+			appt.PatientId = actorId
+		case ResourceTypePractitioner:
+			appt.DoctorId = actorId
+		}
+	}
+	if appt.PatientId == uuid.Nil || appt.DoctorId == uuid.Nil {
+		return data.Appointment{}, fmt.Errorf("AppointmentToData participants: %w", ErrInvalidResource)
+	}
+
+	if a.AppointmentType != nil {
+		appt.Type, _ = codeOf(*a.AppointmentType, AppointmentTypeSystem)
+	}
+	if a.Description != "" {
+		appt.Reason = asPtr(a.Description)
+	}
+	for _, ref := range a.ReasonReference {
+		conditionId, err := parseTypedReference(ref.Reference, ResourceTypeCondition)
+		if err != nil {
+			return data.Appointment{}, fmt.Errorf("AppointmentToData reason: %w", err)
+		}
+		appt.ConditionId = &conditionId
+	}
+
+	if a.CancelationReason != nil {
+		by, reason := CancellationOf(a)
+		if status == "denied" {
+			appt.DenialReason = reason
+		} else {
+			appt.CancellationReason = reason
+			appt.CancelledBy = by
+		}
+	}
+
+	return appt, nil
+}
+
+// CancellationOf returns who cancelled the appointment ("patient" or
+// "doctor") and why, both are nil when not stated.
+func CancellationOf(a Appointment) (*string, *string) {
+	if a.CancelationReason == nil {
+		return nil, nil
+	}
+
+	var by, reason *string
+	if code, ok := codeOf(*a.CancelationReason, CancellationReasonSystem); ok {
+		by = asPtr(cancelledByRole(code))
+	}
+	if a.CancelationReason.Text != "" {
+		reason = asPtr(a.CancelationReason.Text)
+	}
+	return by, reason
+}
+
+// AppointmentStatusToData returns the data status of the appointment,
+// preferring the status kept in AppointmentStatusExtension.
+func AppointmentStatusToData(a Appointment) (string, error) {
+	for _, ext := range a.Extension {
+		if ext.Url == AppointmentStatusExtension {
+			return ext.ValueCode, nil
+		}
+	}
+
+	status, ok := dataAppointmentStatuses[a.Status]
+	if !ok {
+		return "", fmt.Errorf("%w status %q", ErrInvalidResource, a.Status)
+	}
+	return status, nil
+}
+
+func ConditionFromData(c data.Condition) Condition {
+	code := CodeableConcept{Text: c.Name}
+	if c.Diagnosis != nil {
+		code.Coding = []Coding{{
+			System:  ICD10System,
+			Code:    c.Diagnosis.Code,
+			Display: c.Diagnosis.Display,
+		}}
+	}
+
+	return Condition{
+		ResourceType:      ResourceTypeCondition,
+		Id:                c.Id.String(),
+		ClinicalStatus:    clinicalStatus(c.End),
+		Code:              code,
+		Subject:           reference(ResourceTypePatient, c.PatientId),
+		OnsetDateTime:     asPtr(c.Start),
+		AbatementDateTime: c.End,
+	}
+}
+
+func ConditionToData(c Condition) (data.Condition, error) {
+	id, err := parseId(c.Id)
+	if err != nil {
+		return data.Condition{}, fmt.Errorf("ConditionToData: %w", err)
+	}
+	patientId, err := parseTypedReference(c.Subject.Reference, ResourceTypePatient)
+	if err != nil {
+		return data.Condition{}, fmt.Errorf("ConditionToData subject: %w", err)
+	}
+	if c.OnsetDateTime == nil {
+		return data.Condition{}, fmt.Errorf("ConditionToData onset: %w", ErrInvalidResource)
+	}
+
+	condition := data.Condition{
+		Id:        id,
+		PatientId: patientId,
+		Name:      c.Code.Text,
+		Start:     *c.OnsetDateTime,
+		End:       c.AbatementDateTime,
+	}
+	for _, coding := range c.Code.Coding {
+		if coding.System == ICD10System {
+			condition.Diagnosis = &data.Diagnosis{Code: coding.Code, Display: coding.Display}
+			break
+		}
+	}
+	return condition, nil
+}
+
+func MedicationRequestFromData(p data.Prescription) MedicationRequest {
+	status := MedicationRequestStatusActive
+	if p.End.Before(time.Now()) {
+		status = MedicationRequestStatusCompleted
+	}
+
+	req := MedicationRequest{
+		ResourceType:              ResourceTypeMedicationRequest,
+		Id:                        p.Id.String(),
+		Status:                    status,
+		Intent:                    "order",
+		MedicationCodeableConcept: CodeableConcept{Text: p.Name},
+		Subject:                   reference(ResourceTypePatient, p.PatientId),
+		AuthoredOn:                asPtr(p.Start),
+		DispenseRequest: &DispenseRequest{
+			ValidityPeriod: &Period{Start: asPtr(p.Start), End: asPtr(p.End)},
+		},
+	}
+	if p.AppointmentId != nil {
+		req.SupportingInformation = []Reference{reference(ResourceTypeAppointment, *p.AppointmentId)}
+	}
+	if p.DoctorsNote != nil {
+		req.Note = []Annotation{{Text: *p.DoctorsNote}}
+	}
+
+	return req
+}
+
+func MedicationRequestToData(m MedicationRequest) (data.Prescription, error) {
+	id, err := parseId(m.Id)
+	if err != nil {
+		return data.Prescription{}, fmt.Errorf("MedicationRequestToData: %w", err)
+	}
+	patientId, err := parseTypedReference(m.Subject.Reference, ResourceTypePatient)
+	if err != nil {
+		return data.Prescription{}, fmt.Errorf("MedicationRequestToData subject: %w", err)
+	}
+
+	if m.DispenseRequest == nil || m.DispenseRequest.ValidityPeriod == nil ||
+		m.DispenseRequest.ValidityPeriod.Start == nil ||
+		m.DispenseRequest.ValidityPeriod.End == nil {
+		return data.Prescription{}, fmt.Errorf(
+			"MedicationRequestToData validity period: %w",
+			ErrInvalidResource,
+		)
+	}
+
+	prescription := data.Prescription{
+		Id:        id,
+		PatientId: patientId,
+		Name:      m.MedicationCodeableConcept.Text,
+		Start:     *m.DispenseRequest.ValidityPeriod.Start,
+		End:       *m.DispenseRequest.ValidityPeriod.End,
+	}
+	for _, ref := range m.SupportingInformation {
+		typ, refId, err := ParseReference(ref.Reference)
+		if err != nil {
+			return data.Prescription{}, fmt.Errorf("MedicationRequestToData supporting info: %w", err)
+		}
+		if typ == ResourceTypeAppointment {
+			prescription.AppointmentId = &refId
+		}
+	}
+	if len(m.Note) > 0 {
+		prescription.DoctorsNote = asPtr(m.Note[0].Text)
+	}
+
+	return prescription, nil
+}
+
+// ParseReference splits a literal reference, relative ("Patient/<id>") or
+// absolute ("https://host/fhir/Patient/<id>"), into its type and id.
+func ParseReference(ref string) (string, uuid.UUID, error) {
+	parts := strings.Split(strings.TrimSuffix(ref, "/"), "/")
+	if len(parts) < 2 {
+		return "", uuid.Nil, fmt.Errorf("%w %q", ErrInvalidReference, ref)
+	}
+
+	id, err := uuid.Parse(parts[len(parts)-1])
+	if err != nil {
+		return "", uuid.Nil, fmt.Errorf("%w %q", ErrInvalidReference, ref)
+	}
+
+	return parts[len(parts)-2], id, nil
+}
+
+func parseTypedReference(ref, resourceType string) (uuid.UUID, error) {
+	typ, id, err := ParseReference(ref)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if typ != resourceType {
+		return uuid.Nil, fmt.Errorf("%w %q, expected %s", ErrInvalidReference, ref, resourceType)
+	}
+	return id, nil
+}
+
+func parseId(id string) (uuid.UUID, error) {
+	if id == "" {
+		return uuid.Nil, nil
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w id %q", ErrInvalidResource, id)
+	}
+	return parsed, nil
+}
+
+func reference(resourceType string, id uuid.UUID) Reference {
+	return Reference{Reference: resourceType + "/" + id.String()}
+}
+
+func officialName(firstName, lastName string) HumanName {
+	return HumanName{Use: "official", Family: lastName, Given: []string{firstName}}
+}
+
+func nameParts(names []HumanName) (string, string) {
+	if len(names) == 0 {
+		return "", ""
+	}
+	name := names[0]
+	for _, n := range names {
+		if n.Use == "official" {
+			name = n
+			break
+		}
+	}
+	return strings.Join(name.Given, " "), name.Family
+}
+
+func email(address string) ContactPoint {
+	return ContactPoint{System: "email", Value: address}
+}
+
+func emailOf(telecom []ContactPoint) string {
+	for _, cp := range telecom {
+		if cp.System == "email" {
+			return cp.Value
+		}
+	}
+	return ""
+}
+
+func codeOf(concept CodeableConcept, system string) (string, bool) {
+	for _, coding := range concept.Coding {
+		if coding.System == system {
+			return coding.Code, true
+		}
+	}
+	return "", false
+}
+
+func clinicalStatus(end *time.Time) *CodeableConcept {
+	status := ClinicalStatusActive
+	if end != nil && end.Before(time.Now()) {
+		status = ClinicalStatusResolved
+	}
+	return &CodeableConcept{Coding: []Coding{{System: ConditionClinicalSystem, Code: status}}}
+}
+
+// ICD10CodeOf returns the ICD-10 code of a condition, if it is coded.
+func ICD10CodeOf(c Condition) (string, bool) {
+	return codeOf(c.Code, ICD10System)
+}
+
+// ClinicalStatusOf returns the clinical status code of a condition.
+func ClinicalStatusOf(c Condition) string {
+	if c.ClinicalStatus == nil {
+		return ""
+	}
+	code, _ := codeOf(*c.ClinicalStatus, ConditionClinicalSystem)
+	return code
+}
+
+func doctorParticipation(status string) string {
+	switch status {
+	case "requested":
+		return "needs-action"
+	case "denied":
+		return "declined"
+	default:
+		return "accepted"
+	}
+}
+
+func cancelationReason(by string, text *string) *CodeableConcept {
+	reason := &CodeableConcept{}
+	if by != "" {
+		reason.Coding = []Coding{{System: CancellationReasonSystem, Code: by}}
+	}
+	if text != nil {
+		reason.Text = *text
+	}
+	return reason
+}
+
+func cancelledByCode(role string) string {
+	if role == "doctor" {
+		return cancelledByDoctor
+	}
+	return cancelledByPatient
+}
+
+func cancelledByRole(code string) string {
+	if code == cancelledByDoctor {
+		return "doctor"
+	}
+	return "patient"
+}
+
+func asPtr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,233 @@
+package fhir
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+// roundTrip marshals the resource to JSON and back, so the tests also cover
+// the wire format partners receive.
+func roundTrip[T any](t *testing.T, resource T) T {
+	t.Helper()
+
+	raw, err := json.Marshal(resource)
+	require.NoError(t, err, "Failed to marshal resource")
+
+	var decoded T
+	err = json.Unmarshal(raw, &decoded)
+	require.NoError(t, err, "Failed to unmarshal resource")
+
+	return decoded
+}
+
+var start = time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC)
+
+func TestPatientRoundTrip(t *testing.T) {
+	patient := data.Patient{
+		Id:        uuid.New(),
+		Email:     "jane.doe@patient.com",
+		FirstName: "Jane",
+		LastName:  "Doe",
+	}
+
+	resource := roundTrip(t, PatientFromData(patient))
+	assert.Equal(t, ResourceTypePatient, resource.ResourceType)
+
+	mapped, err := PatientToData(resource)
+	require.NoError(t, err)
+	assert.Equal(t, patient, mapped)
+}
+
+func TestPractitionerRoundTrip(t *testing.T) {
+	doctor := data.Doctor{
+		Id:              uuid.New(),
+		Email:           "john.smith@doctor.com",
+		FirstName:       "John",
+		LastName:        "Smith",
+		Specializations: []string{"cardiologist", "internist"},
+		Active:          true,
+	}
+
+	resource := roundTrip(t, PractitionerFromData(doctor))
+	assert.Equal(t, ResourceTypePractitioner, resource.ResourceType)
+
+	mapped, err := PractitionerToData(resource)
+	require.NoError(t, err)
+	assert.Equal(t, doctor, mapped)
+}
+
+func TestAppointmentRoundTrip(t *testing.T) {
+	base := data.Appointment{
+		Id:                  uuid.New(),
+		PatientId:           uuid.New(),
+		DoctorId:            uuid.New(),
+		AppointmentDateTime: start,
+		EndTime:             start.Add(time.Hour),
+		Type:                "regular_check",
+	}
+
+	tests := []struct {
+		name         string
+		modify       func(a *data.Appointment)
+		expectedFhir string
+	}{
+		{
+			name: "requested with reason and condition",
+			modify: func(a *data.Appointment) {
+				a.Status = "requested"
+				a.Reason = asPtr("Chest pain")
+				a.ConditionId = asPtr(uuid.New())
+			},
+			expectedFhir: AppointmentStatusPending,
+		},
+		{
+			name:         "scheduled",
+			modify:       func(a *data.Appointment) { a.Status = "scheduled" },
+			expectedFhir: AppointmentStatusBooked,
+		},
+		{
+			name:         "completed",
+			modify:       func(a *data.Appointment) { a.Status = "completed" },
+			expectedFhir: AppointmentStatusFulfilled,
+		},
+		{
+			name: "cancelled by doctor",
+			modify: func(a *data.Appointment) {
+				a.Status = "cancelled"
+				a.CancelledBy = asPtr("doctor")
+				a.CancellationReason = asPtr("Doctor is sick")
+			},
+			expectedFhir: AppointmentStatusCancelled,
+		},
+		{
+			name: "cancelled by patient",
+			modify: func(a *data.Appointment) {
+				a.Status = "cancelled"
+				a.CancelledBy = asPtr("patient")
+			},
+			expectedFhir: AppointmentStatusCancelled,
+		},
+		{
+			name: "denied",
+			modify: func(a *data.Appointment) {
+				a.Status = "denied"
+				a.DenialReason = asPtr("Not my specialization")
+			},
+			expectedFhir: AppointmentStatusCancelled,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			appt := base
+			tc.modify(&appt)
+
+			resource := roundTrip(t, AppointmentFromData(appt))
+			assert.Equal(t, tc.expectedFhir, resource.Status)
+
+			mapped, err := AppointmentToData(resource)
+			require.NoError(t, err)
+			assert.True(t, appt.AppointmentDateTime.Equal(mapped.AppointmentDateTime))
+			assert.True(t, appt.EndTime.Equal(mapped.EndTime))
+			mapped.AppointmentDateTime, mapped.EndTime = appt.AppointmentDateTime, appt.EndTime
+			assert.Equal(t, appt, mapped)
+		})
+	}
+}
+
+func TestConditionRoundTrip(t *testing.T) {
+	tests := []struct {
+		name           string
+		end            *time.Time
+		diagnosis      *data.Diagnosis
+		clinicalStatus string
+	}{
+		{name: "ongoing", end: nil, clinicalStatus: ClinicalStatusActive},
+		{name: "resolved", end: asPtr(start.AddDate(0, 1, 0)), clinicalStatus: ClinicalStatusResolved},
+		{
+			name:           "coded",
+			diagnosis:      &data.Diagnosis{Code: "I10", Display: "Essential (primary) hypertension"},
+			clinicalStatus: ClinicalStatusActive,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			condition := data.Condition{
+				Id:        uuid.New(),
+				PatientId: uuid.New(),
+				Name:      "Hypertension",
+				Start:     start,
+				End:       tc.end,
+				Diagnosis: tc.diagnosis,
+			}
+
+			resource := roundTrip(t, ConditionFromData(condition))
+			assert.Equal(t, tc.clinicalStatus, ClinicalStatusOf(resource))
+			code, coded := ICD10CodeOf(resource)
+			assert.Equal(t, tc.diagnosis != nil, coded)
+			if tc.diagnosis != nil {
+				assert.Equal(t, tc.diagnosis.Code, code)
+			}
+
+			mapped, err := ConditionToData(resource)
+			require.NoError(t, err)
+			assert.Equal(t, condition, mapped)
+		})
+	}
+}
+
+func TestMedicationRequestRoundTrip(t *testing.T) {
+	prescription := data.Prescription{
+		Id:            uuid.New(),
+		PatientId:     uuid.New(),
+		AppointmentId: asPtr(uuid.New()),
+		Name:          "Ibuprofen 400mg",
+		Start:         start,
+		End:           start.AddDate(0, 0, 7),
+		DoctorsNote:   asPtr("Twice a day after meal"),
+	}
+
+	resource := roundTrip(t, MedicationRequestFromData(prescription))
+	assert.Equal(t, MedicationRequestStatusCompleted, resource.Status)
+
+	mapped, err := MedicationRequestToData(resource)
+	require.NoError(t, err)
+	assert.Equal(t, prescription, mapped)
+}
+
+func TestParseReference(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name    string
+		ref     string
+		typ     string
+		wantErr bool
+	}{
+		{name: "relative", ref: "Patient/" + id.String(), typ: ResourceTypePatient},
+		{name: "absolute", ref: "https://other.clinic/fhir/Practitioner/" + id.String(), typ: ResourceTypePractitioner},
+		{name: "missing type", ref: id.String(), wantErr: true},
+		{name: "not an uuid", ref: "Patient/123", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			typ, parsed, err := ParseReference(tc.ref)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidReference)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.typ, typ)
+			assert.Equal(t, id, parsed)
+		})
+	}
+}
@@ -0,0 +1,206 @@
+// Package fhir contains the subset of HL7 FHIR R4 resources exchanged with
+// partner systems, and their mapping to and from the data layer entities.
+package fhir
+
+import "time"
+
+const (
+	ResourceTypePatient           = "Patient"
+	ResourceTypePractitioner      = "Practitioner"
+	ResourceTypeAppointment       = "Appointment"
+	ResourceTypeCondition         = "Condition"
+	ResourceTypeMedicationRequest = "MedicationRequest"
+	ResourceTypeBundle            = "Bundle"
+	ResourceTypeOperationOutcome  = "OperationOutcome"
+)
+
+type Identifier struct {
+	System string `json:"system,omitempty"`
+	Value  string `json:"value"`
+}
+
+type HumanName struct {
+	Use    string   `json:"use,omitempty"`
+	Family string   `json:"family,omitempty"`
+	Given  []string `json:"given,omitempty"`
+}
+
+type ContactPoint struct {
+	System string `json:"system"`
+	Value  string `json:"value"`
+	Use    string `json:"use,omitempty"`
+}
+
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code"`
+	Display string `json:"display,omitempty"`
+}
+
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+type Reference struct {
+	Reference string `json:"reference"`
+	Display   string `json:"display,omitempty"`
+}
+
+type Period struct {
+	Start *time.Time `json:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+type Annotation struct {
+	Text string `json:"text"`
+}
+
+type Extension struct {
+	Url       string `json:"url"`
+	ValueCode string `json:"valueCode,omitempty"`
+}
+
+type Patient struct {
+	ResourceType string         `json:"resourceType"`
+	Id           string         `json:"id,omitempty"`
+	Identifier   []Identifier   `json:"identifier,omitempty"`
+	Active       *bool          `json:"active,omitempty"`
+	Name         []HumanName    `json:"name,omitempty"`
+	Telecom      []ContactPoint `json:"telecom,omitempty"`
+}
+
+type Qualification struct {
+	Code CodeableConcept `json:"code"`
+}
+
+type Practitioner struct {
+	ResourceType  string          `json:"resourceType"`
+	Id            string          `json:"id,omitempty"`
+	Identifier    []Identifier    `json:"identifier,omitempty"`
+	Active        *bool           `json:"active,omitempty"`
+	Name          []HumanName     `json:"name,omitempty"`
+	Telecom       []ContactPoint  `json:"telecom,omitempty"`
+	Qualification []Qualification `json:"qualification,omitempty"`
+}
+
+type AppointmentParticipant struct {
+	Actor  Reference `json:"actor"`
+	Status string    `json:"status"`
+}
+
+type Appointment struct {
+	ResourceType      string                   `json:"resourceType"`
+	Id                string                   `json:"id,omitempty"`
+	Identifier        []Identifier             `json:"identifier,omitempty"`
+	Extension         []Extension              `json:"extension,omitempty"`
+	Status            string                   `json:"status"`
+	CancelationReason *CodeableConcept         `json:"cancelationReason,omitempty"`
+	AppointmentType   *CodeableConcept         `json:"appointmentType,omitempty"`
+	ReasonReference   []Reference              `json:"reasonReference,omitempty"`
+	Description       string                   `json:"description,omitempty"`
+	Start             *time.Time               `json:"start,omitempty"`
+	End               *time.Time               `json:"end,omitempty"`
+	Comment           string                   `json:"comment,omitempty"`
+	Participant       []AppointmentParticipant `json:"participant"`
+}
+
+type Condition struct {
+	ResourceType      string           `json:"resourceType"`
+	Id                string           `json:"id,omitempty"`
+	Identifier        []Identifier     `json:"identifier,omitempty"`
+	ClinicalStatus    *CodeableConcept `json:"clinicalStatus,omitempty"`
+	Code              CodeableConcept  `json:"code"`
+	Subject           Reference        `json:"subject"`
+	OnsetDateTime     *time.Time       `json:"onsetDateTime,omitempty"`
+	AbatementDateTime *time.Time       `json:"abatementDateTime,omitempty"`
+}
+
+type DispenseRequest struct {
+	ValidityPeriod *Period `json:"validityPeriod,omitempty"`
+}
+
+type MedicationRequest struct {
+	ResourceType              string           `json:"resourceType"`
+	Id                        string           `json:"id,omitempty"`
+	Identifier                []Identifier     `json:"identifier,omitempty"`
+	Status                    string           `json:"status"`
+	Intent                    string           `json:"intent"`
+	MedicationCodeableConcept CodeableConcept  `json:"medicationCodeableConcept"`
+	Subject                   Reference        `json:"subject"`
+	AuthoredOn                *time.Time       `json:"authoredOn,omitempty"`
+	SupportingInformation     []Reference      `json:"supportingInformation,omitempty"`
+	Note                      []Annotation     `json:"note,omitempty"`
+	DispenseRequest           *DispenseRequest `json:"dispenseRequest,omitempty"`
+}
+
+type BundleEntry struct {
+	FullUrl  string `json:"fullUrl,omitempty"`
+	Resource any    `json:"resource"`
+}
+
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Total        *int          `json:"total,omitempty"`
+	Entry        []BundleEntry `json:"entry"`
+}
+
+const BundleTypeSearchset = "searchset"
+
+// NewSearchset wraps resources into a searchset Bundle, fullUrl of each entry
+// is resolved against baseUrl.
+func NewSearchset[T Resource](baseUrl string, resources []T) Bundle {
+	total := len(resources)
+	bundle := Bundle{
+		ResourceType: ResourceTypeBundle,
+		Type:         BundleTypeSearchset,
+		Total:        &total,
+		Entry:        make([]BundleEntry, len(resources)),
+	}
+	for i, res := range resources {
+		bundle.Entry[i] = BundleEntry{
+			FullUrl:  baseUrl + "/" + res.Type() + "/" + res.ResourceId(),
+			Resource: res,
+		}
+	}
+	return bundle
+}
+
+// Resource is implemented by every FHIR resource which can be a Bundle entry.
+type Resource interface {
+	Type() string
+	ResourceId() string
+}
+
+func (p Patient) Type() string                 { return ResourceTypePatient }
+func (p Patient) ResourceId() string           { return p.Id }
+func (p Practitioner) Type() string            { return ResourceTypePractitioner }
+func (p Practitioner) ResourceId() string      { return p.Id }
+func (a Appointment) Type() string             { return ResourceTypeAppointment }
+func (a Appointment) ResourceId() string       { return a.Id }
+func (c Condition) Type() string               { return ResourceTypeCondition }
+func (c Condition) ResourceId() string         { return c.Id }
+func (m MedicationRequest) Type() string       { return ResourceTypeMedicationRequest }
+func (m MedicationRequest) ResourceId() string { return m.Id }
+
+type OperationOutcomeIssue struct {
+	Severity    string `json:"severity"`
+	Code        string `json:"code"`
+	Diagnostics string `json:"diagnostics,omitempty"`
+}
+
+type OperationOutcome struct {
+	ResourceType string                  `json:"resourceType"`
+	Issue        []OperationOutcomeIssue `json:"issue"`
+}
+
+// NewOperationOutcome creates an outcome with a single error issue.
+func NewOperationOutcome(code, diagnostics string) OperationOutcome {
+	return OperationOutcome{
+		ResourceType: ResourceTypeOperationOutcome,
+		Issue: []OperationOutcomeIssue{
+			{Severity: "error", Code: code, Diagnostics: diagnostics},
+		},
+	}
+}
@@ -0,0 +1,120 @@
+package fhir
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidSearchParam = errors.New("invalid search parameter")
+
+type PatientSearch struct {
+	Id    *uuid.UUID
+	Email *string
+}
+
+type PractitionerSearch struct {
+	Id    *uuid.UUID
+	Email *string
+}
+
+type AppointmentSearch struct {
+	Patient      *uuid.UUID
+	Practitioner *uuid.UUID
+	Status       *string
+	Date         DateRange
+}
+
+type ConditionSearch struct {
+	Patient        uuid.UUID
+	ClinicalStatus *string
+	OnsetDate      DateRange
+}
+
+type MedicationRequestSearch struct {
+	Patient    uuid.UUID
+	Status     *string
+	AuthoredOn DateRange
+}
+
+// DateRange is a half open interval [From, To), nil bounds are unbounded.
+type DateRange struct {
+	From *time.Time
+	To   *time.Time
+}
+
+func (r DateRange) Contains(t time.Time) bool {
+	if r.From != nil && t.Before(*r.From) {
+		return false
+	}
+	if r.To != nil && !t.Before(*r.To) {
+		return false
+	}
+	return true
+}
+
+const dateLayout = "2006-01-02"
+
+// ParseDateParams intersects FHIR date search parameters, e.g.
+// "date=ge2025-01-01&date=lt2025-02-01". Supported prefixes are eq (default),
+// gt, ge, lt and le, values are either a date or a RFC 3339 date time. Dates
+// are interpreted in loc.
+func ParseDateParams(values []string, loc *time.Location) (DateRange, error) {
+	var r DateRange
+	for _, value := range values {
+		prefix, raw := "eq", value
+		if len(value) > 2 && value[0] >= 'a' && value[0] <= 'z' {
+			prefix, raw = value[:2], value[2:]
+		}
+
+		start, end, err := parseDateValue(raw, loc)
+		if err != nil {
+			return DateRange{}, fmt.Errorf("%w %q: %w", ErrInvalidSearchParam, value, err)
+		}
+
+		switch prefix {
+		case "eq":
+			r.from(start)
+			r.to(end)
+		case "ge":
+			r.from(start)
+		case "gt":
+			r.from(end)
+		case "le":
+			r.to(end)
+		case "lt":
+			r.to(start)
+		default:
+			return DateRange{}, fmt.Errorf("%w %q: unsupported prefix", ErrInvalidSearchParam, value)
+		}
+	}
+	return r, nil
+}
+
+// parseDateValue returns the interval the value covers, a whole day for dates
+// and a single instant for date times.
+func parseDateValue(value string, loc *time.Location) (time.Time, time.Time, error) {
+	if day, err := time.ParseInLocation(dateLayout, value, loc); err == nil {
+		return day, day.AddDate(0, 0, 1), nil
+	}
+
+	instant, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return instant, instant.Add(time.Nanosecond), nil
+}
+
+func (r *DateRange) from(t time.Time) {
+	if r.From == nil || t.After(*r.From) {
+		r.From = &t
+	}
+}
+
+func (r *DateRange) to(t time.Time) {
+	if r.To == nil || t.Before(*r.To) {
+		r.To = &t
+	}
+}
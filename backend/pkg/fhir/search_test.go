@@ -0,0 +1,67 @@
+package fhir
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+)
+
+func TestParseDateParams(t *testing.T) {
+	day := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+	nextDay := day.AddDate(0, 0, 1)
+
+	tests := []struct {
+		name   string
+		values []string
+		from   *time.Time
+		to     *time.Time
+	}{
+		{name: "none", values: nil},
+		{name: "equal day", values: []string{"2025-03-14"}, from: &day, to: &nextDay},
+		{name: "explicit eq", values: []string{"eq2025-03-14"}, from: &day, to: &nextDay},
+		{name: "ge", values: []string{"ge2025-03-14"}, from: &day},
+		{name: "gt", values: []string{"gt2025-03-14"}, from: &nextDay},
+		{name: "le", values: []string{"le2025-03-14"}, to: &nextDay},
+		{name: "lt", values: []string{"lt2025-03-14"}, to: &day},
+		{
+			name:   "intersection",
+			values: []string{"ge2025-03-01", "ge2025-03-14", "lt2025-04-01", "le2025-03-14"},
+			from:   &day,
+			to:     &nextDay,
+		},
+		{
+			name:   "date time",
+			values: []string{"ge2025-03-14T00:00:00Z"},
+			from:   &day,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := ParseDateParams(tc.values, time.UTC)
+			require.NoError(t, err)
+			assert.Equal(t, tc.from, r.From)
+			assert.Equal(t, tc.to, r.To)
+		})
+	}
+}
+
+func TestParseDateParams_Invalid(t *testing.T) {
+	for _, value := range []string{"14.03.2025", "ne2025-03-14", "ge"} {
+		_, err := ParseDateParams([]string{value}, time.UTC)
+		assert.ErrorIs(t, err, ErrInvalidSearchParam, "value %q", value)
+	}
+}
+
+func TestDateRangeContains(t *testing.T) {
+	day := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+	r, err := ParseDateParams([]string{"2025-03-14"}, time.UTC)
+	require.NoError(t, err)
+
+	assert.True(t, r.Contains(day))
+	assert.True(t, r.Contains(day.Add(23*time.Hour)))
+	assert.False(t, r.Contains(day.AddDate(0, 0, 1)))
+	assert.False(t, r.Contains(day.Add(-time.Second)))
+}
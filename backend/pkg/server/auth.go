@@ -22,6 +22,17 @@ func (s Server) RegisterUser(w http.ResponseWriter, r *http.Request) {
 		encodeError(w, decodeErrToApiError(err))
 		return
 	}
+	if isStaffRole(api.UserRole(regType)) {
+		encodeError(w, &ApiError{
+			ErrorDetail: api.ErrorDetail{
+				Code:   "auth.staff-registration",
+				Title:  "Bad Request",
+				Detail: "Staff can't register themselves, an administrator creates them.",
+				Status: http.StatusBadRequest,
+			},
+		})
+		return
+	}
 	if regType == string(api.UserRoleDoctor) {
 		doctor, err := req.AsDoctorRegistration()
 		if err != nil {
@@ -55,6 +66,7 @@ func (s Server) RegisterUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	patient, err := s.app.CreatePatient(r.Context(), pat)
+	var valErr *app.ValidationError
 	if errors.Is(err, app.ErrDuplicateEmail) {
 		apiErr := &ApiError{
 			ErrorDetail: api.ErrorDetail{
@@ -66,6 +78,9 @@ func (s Server) RegisterUser(w http.ResponseWriter, r *http.Request) {
 		}
 		encodeError(w, apiErr)
 		return
+	} else if errors.As(err, &valErr) {
+		encodeError(w, fromValidationError(valErr))
+		return
 	} else if err != nil {
 		slog.Error(UnexpectedError, "error", err.Error(), "where", "RegisterUser", "role", "patient")
 		encodeError(w, internalServerError())
@@ -82,6 +97,37 @@ func (s Server) LoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ip := clientIP(r)
+	if until := s.limiter.lockedOut(r.Context(), ip, string(req.Email)); !until.IsZero() {
+		tooManyRequests(w, LoginLockedOutCode, "Too many failed logins, retry in %d seconds", until)
+		return
+	}
+
+	if isStaffRole(req.Role) {
+		staff, err := s.app.StaffByEmail(r.Context(), string(req.Email))
+		if errors.Is(err, app.ErrNotFound) || (err == nil && string(staff.Role) != string(req.Role)) {
+			apiErr := &ApiError{
+				ErrorDetail: api.ErrorDetail{
+					Code:   "staff.not-found",
+					Title:  "Not Found",
+					Detail: fmt.Sprintf("Staff member %s with email %q not found.", req.Role, req.Email),
+					Status: http.StatusNotFound,
+				},
+			}
+			s.limiter.loginFailed(r.Context(), ip, string(req.Email))
+			encodeError(w, apiErr)
+			return
+		} else if err != nil {
+			slog.Error(UnexpectedError, "error", err.Error(), "where", "LoginUser", "role", req.Role)
+			encodeError(w, internalServerError())
+			return
+		}
+
+		s.limiter.loginSucceeded(r.Context(), string(req.Email))
+		encode(w, http.StatusOK, staff)
+		return
+	}
+
 	if req.Role == api.UserRoleDoctor {
 		doc, err := s.app.DoctorByEmail(r.Context(), string(req.Email))
 		if errors.Is(err, app.ErrNotFound) {
@@ -93,6 +139,7 @@ func (s Server) LoginUser(w http.ResponseWriter, r *http.Request) {
 					Status: http.StatusNotFound,
 				},
 			}
+			s.limiter.loginFailed(r.Context(), ip, string(req.Email))
 			encodeError(w, apiErr)
 			return
 		} else if err != nil {
@@ -101,6 +148,7 @@ func (s Server) LoginUser(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		s.limiter.loginSucceeded(r.Context(), string(req.Email))
 		encode(w, http.StatusOK, doc)
 		return
 	}
@@ -115,6 +163,7 @@ func (s Server) LoginUser(w http.ResponseWriter, r *http.Request) {
 				Status: http.StatusNotFound,
 			},
 		}
+		s.limiter.loginFailed(r.Context(), ip, string(req.Email))
 		encodeError(w, apiErr)
 		return
 	} else if err != nil {
@@ -123,5 +172,14 @@ func (s Server) LoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.limiter.loginSucceeded(r.Context(), string(req.Email))
 	encode(w, http.StatusOK, patient)
 }
+
+func isStaffRole(role api.UserRole) bool {
+	switch role {
+	case api.UserRoleNurse, api.UserRoleReceptionist, api.UserRoleAdmin:
+		return true
+	}
+	return false
+}
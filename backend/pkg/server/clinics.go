@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/app"
+)
+
+const LastClinicCode = "patient.last-clinic"
+
+// CreateClinic implements api.ServerInterface.
+func (s Server) CreateClinic(w http.ResponseWriter, r *http.Request) {
+	req, decodeErr := Decode[api.NewClinic](w, r)
+	if decodeErr != nil {
+		encodeError(w, decodeErr)
+		return
+	}
+
+	clinic, err := s.app.CreateClinic(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, app.ErrForbidden) {
+			encodeError(w, forbidden(manageClinicsForbiddenDetail))
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "CreateClinic")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusCreated, clinic)
+}
+
+// Clinic implements api.ServerInterface.
+func (s Server) Clinic(w http.ResponseWriter, r *http.Request, clinicId api.ClinicId) {
+	clinic, err := s.app.ClinicById(r.Context(), clinicId)
+	if err != nil {
+		if errors.Is(err, app.ErrNotFound) {
+			encodeError(w, notFoundId("Clinic", clinicId))
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "Clinic")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusOK, clinic)
+}
+
+// SharePatient implements api.ServerInterface.
+func (s Server) SharePatient(
+	w http.ResponseWriter,
+	r *http.Request,
+	patientId api.PatientId,
+	clinicId api.ClinicId,
+) {
+	patient, err := s.app.SharePatient(r.Context(), patientId, clinicId)
+	if err != nil {
+		if apiErr := s.sharingApiError(r.Context(), err, patientId, clinicId); apiErr != nil {
+			encodeError(w, apiErr)
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "SharePatient")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusOK, patient)
+}
+
+// UnsharePatient implements api.ServerInterface.
+func (s Server) UnsharePatient(
+	w http.ResponseWriter,
+	r *http.Request,
+	patientId api.PatientId,
+	clinicId api.ClinicId,
+) {
+	patient, err := s.app.UnsharePatient(r.Context(), patientId, clinicId)
+	if err != nil {
+		if apiErr := s.sharingApiError(r.Context(), err, patientId, clinicId); apiErr != nil {
+			encodeError(w, apiErr)
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "UnsharePatient")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusOK, patient)
+}
+
+// sharingApiError translates errors of sharing the patient with the clinic,
+// nil if it's unexpected.
+func (s Server) sharingApiError(
+	ctx context.Context,
+	err error,
+	patientId api.PatientId,
+	clinicId api.ClinicId,
+) *ApiError {
+	switch {
+	case errors.Is(err, app.ErrNotFound):
+		if _, err := s.app.ClinicById(ctx, clinicId); errors.Is(err, app.ErrNotFound) {
+			return notFoundId("Clinic", clinicId)
+		}
+		return notFoundId("Patient", patientId)
+	case errors.Is(err, app.ErrForbidden):
+		return forbidden("Only the patient can consent to sharing their records")
+	case errors.Is(err, app.ErrLastClinic):
+		return &ApiError{
+			ErrorDetail: api.ErrorDetail{
+				Code:   LastClinicCode,
+				Title:  "Conflict",
+				Detail: "Patient must be shared with at least one clinic",
+				Status: http.StatusConflict,
+			},
+		}
+	}
+	return nil
+}
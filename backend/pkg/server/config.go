@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -13,6 +14,9 @@ type Config struct {
 		Host     string `mapstructure:"host"`
 		Port     string `mapstructure:"port"`
 		Timezone string `mapstructure:"timezone"`
+		// TrustedProxies are IPs or CIDR networks of reverse proxies allowed
+		// to forward client IPs in X-Forwarded-For and X-Real-IP.
+		TrustedProxies []string `mapstructure:"trusted_proxies"`
 	} `mapstructure:"app"`
 
 	Log struct {
@@ -26,6 +30,71 @@ type Config struct {
 		Password string `mapstructure:"password"`
 		Db       string `mapstructure:"db"`
 	} `mapstructure:"mongo"`
+
+	Integrity struct {
+		// Interval of the periodic integrity check, zero disables it.
+		Interval time.Duration `mapstructure:"interval"`
+		Repair   bool          `mapstructure:"repair"`
+	} `mapstructure:"integrity"`
+
+	Cache struct {
+		// Enabled caches doctors, available resources and timeslots in process.
+		Enabled bool `mapstructure:"enabled"`
+		// Size is the maximum number of cached values.
+		Size int `mapstructure:"size"`
+		// TTL bounds how long writes bypassing the cache stay unseen.
+		TTL time.Duration `mapstructure:"ttl"`
+	} `mapstructure:"cache"`
+
+	Tracing struct {
+		// Exporter of spans, none, stdout or otlp.
+		Exporter string `mapstructure:"exporter"`
+		// Endpoint of the OTLP/HTTP collector, host:port.
+		Endpoint string `mapstructure:"endpoint"`
+		// Insecure exports to the collector over plain HTTP.
+		Insecure bool `mapstructure:"insecure"`
+		// SampleRatio of traces started by the server, traces of sampled
+		// incoming requests are always recorded.
+		SampleRatio float64 `mapstructure:"sample_ratio"`
+	} `mapstructure:"tracing"`
+
+	RateLimit RateLimitConfig `mapstructure:"ratelimit"`
+
+	Idempotency IdempotencyConfig `mapstructure:"idempotency"`
+}
+
+// RateLimitConfig limits the number of requests in a window, a zero limit
+// doesn't limit requests.
+type RateLimitConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	Window  time.Duration `mapstructure:"window"`
+	// PerIP limits requests from a client IP.
+	PerIP int `mapstructure:"per_ip"`
+	// PerUser limits requests of the acting user from X-User-Id, on top of
+	// PerIP.
+	PerUser int `mapstructure:"per_user"`
+	// Auth limits logins and registrations from a client IP.
+	Auth int `mapstructure:"auth"`
+	// Booking limits appointment requests from a client IP.
+	Booking int `mapstructure:"booking"`
+	// MaxRequested caps the upcoming appointments a patient can have awaiting
+	// doctors' decisions, also when Enabled is false.
+	MaxRequested int `mapstructure:"max_requested"`
+	// LoginFailures of a client IP or an email within Lockout lock them out
+	// of logging in until Lockout passes since the first failure.
+	LoginFailures int           `mapstructure:"login_failures"`
+	Lockout       time.Duration `mapstructure:"lockout"`
+}
+
+// IdempotencyConfig configures how long responses to requests with an
+// Idempotency-Key are kept.
+type IdempotencyConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// TTL of kept responses, retries after it are handled as new requests.
+	TTL time.Duration `mapstructure:"ttl"`
+	// Lease of a key while its request is handled. Retries within it are
+	// rejected, after it the key is taken over, e.g. if the server stopped.
+	Lease time.Duration `mapstructure:"lease"`
 }
 
 func (c Config) MongoURI() string {
@@ -47,11 +116,28 @@ const (
 	MongoHostDefault = "localhost"
 	MongoPortDefault = 27017
 	MongoDbDefault   = "xcastven-xkilian-db"
+	CacheSizeDefault = 10_000
+	CacheTTLDefault  = 30 * time.Second
+
+	TracingEndpointDefault    = "localhost:4318"
+	TracingSampleRatioDefault = 1.0
+
+	RateLimitWindowDefault        = time.Minute
+	RateLimitPerIPDefault         = 300
+	RateLimitPerUserDefault       = 120
+	RateLimitAuthDefault          = 10
+	RateLimitBookingDefault       = 5
+	RateLimitMaxRequestedDefault  = 5
+	RateLimitLoginFailuresDefault = 5
+	RateLimitLockoutDefault       = 15 * time.Minute
+
+	IdempotencyTTLDefault   = 24 * time.Hour
+	IdempotencyLeaseDefault = time.Minute
 )
 
 const EnvPrefix = "wac"
 
-func loadConfig() (*Config, error) {
+func LoadConfig() (*Config, error) {
 	v := viper.New()
 
 	v.SetEnvPrefix(EnvPrefix)
@@ -61,17 +147,39 @@ func loadConfig() (*Config, error) {
 	v.SetDefault("app.host", AppHostDefault)
 	v.SetDefault("app.port", AppPortDefault)
 	v.SetDefault("app.timezone", TzDefault)
+	v.SetDefault("app.trusted_proxies", []string{})
 	v.SetDefault("log.level", LogLevelDefault)
 	v.SetDefault("mongo.host", MongoHostDefault)
 	v.SetDefault("mongo.port", MongoPortDefault)
 	v.SetDefault("mongo.db", MongoDbDefault)
 	v.SetDefault("mongo.user", "")
 	v.SetDefault("mongo.password", "")
+	v.SetDefault("integrity.interval", 0)
+	v.SetDefault("integrity.repair", false)
+	v.SetDefault("cache.enabled", true)
+	v.SetDefault("cache.size", CacheSizeDefault)
+	v.SetDefault("cache.ttl", CacheTTLDefault)
+	v.SetDefault("tracing.exporter", TracingExporterNone)
+	v.SetDefault("tracing.endpoint", TracingEndpointDefault)
+	v.SetDefault("tracing.insecure", false)
+	v.SetDefault("tracing.sample_ratio", TracingSampleRatioDefault)
+	v.SetDefault("ratelimit.enabled", true)
+	v.SetDefault("ratelimit.window", RateLimitWindowDefault)
+	v.SetDefault("ratelimit.per_ip", RateLimitPerIPDefault)
+	v.SetDefault("ratelimit.per_user", RateLimitPerUserDefault)
+	v.SetDefault("ratelimit.auth", RateLimitAuthDefault)
+	v.SetDefault("ratelimit.booking", RateLimitBookingDefault)
+	v.SetDefault("ratelimit.max_requested", RateLimitMaxRequestedDefault)
+	v.SetDefault("ratelimit.login_failures", RateLimitLoginFailuresDefault)
+	v.SetDefault("ratelimit.lockout", RateLimitLockoutDefault)
+	v.SetDefault("idempotency.enabled", true)
+	v.SetDefault("idempotency.ttl", IdempotencyTTLDefault)
+	v.SetDefault("idempotency.lease", IdempotencyLeaseDefault)
 
 	var cfg Config
 	err := v.Unmarshal(&cfg)
 	if err != nil {
-		return nil, fmt.Errorf("loadConfig failed to unmarshal config: %w", err)
+		return nil, fmt.Errorf("LoadConfig failed to unmarshal config: %w", err)
 	}
 
 	return &cfg, nil
@@ -17,6 +17,7 @@ func encode[T any](w http.ResponseWriter, status int, response T) {
 }
 
 func encodeError(w http.ResponseWriter, err *ApiError) {
+	countApiError(err)
 	encodeWithContentType(w, err.Status, err.ErrorDetail, ApplicationProblemJSON)
 }
 
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/app"
+)
+
+const (
+	ETag = "ETag"
+
+	IfMatch = "If-Match"
+
+	PreconditionFailedCode   = "precondition.failed"
+	ModifiedConcurrentlyCode = "conflict.modified-concurrently"
+)
+
+// etag formats a resource version as a strong entity tag.
+func etag(version *int64) string {
+	if version == nil {
+		return `"0"`
+	}
+	return strconv.Quote(strconv.FormatInt(*version, 10))
+}
+
+// ifMatchPrecondition parses an If-Match header. "*" matches any version, as
+// does a missing or empty header, the write is then unconditional. Weak or
+// malformed tags never match, as If-Match uses the strong comparison.
+func ifMatchPrecondition(ifMatch *api.IfMatch) app.Precondition {
+	if ifMatch == nil {
+		return nil
+	}
+	if tags := strings.TrimSpace(*ifMatch); tags == "" || tags == "*" {
+		return nil
+	}
+
+	precondition := app.Precondition{}
+	for tag := range strings.SplitSeq(*ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+		if err != nil {
+			continue
+		}
+		precondition = append(precondition, version)
+	}
+	return precondition
+}
+
+func preconditionFailed() *ApiError {
+	return &ApiError{
+		ErrorDetail: api.ErrorDetail{
+			Code:   PreconditionFailedCode,
+			Title:  "Precondition Failed",
+			Detail: "Resource was modified, fetch it again and retry",
+			Status: http.StatusPreconditionFailed,
+		},
+	}
+}
+
+func modifiedConcurrently() *ApiError {
+	return &ApiError{
+		ErrorDetail: api.ErrorDetail{
+			Code:   ModifiedConcurrentlyCode,
+			Title:  "Conflict",
+			Detail: "Resource was modified concurrently, retry the request",
+			Status: http.StatusConflict,
+		},
+	}
+}
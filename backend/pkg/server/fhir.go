@@ -0,0 +1,289 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chi_middleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/httplog/v2"
+	"github.com/google/uuid"
+
+	"github.com/Nesquiko/wac/pkg/app"
+	"github.com/Nesquiko/wac/pkg/fhir"
+)
+
+const (
+	ApplicationFhirJSON = "application/fhir+json"
+	FhirBasePath        = "/api/fhir"
+
+	// MaxFhirBundleBytes limits imported bundles, which carry a patient's
+	// whole history and so are larger than regular requests.
+	MaxFhirBundleBytes = 32 * MaxBytes
+)
+
+// FHIR OperationOutcome.issue.code values.
+const (
+	fhirIssueNotFound  = "not-found"
+	fhirIssueInvalid   = "invalid"
+	fhirIssueException = "exception"
+	fhirIssueForbidden = "forbidden"
+)
+
+// fhirRouter serves the FHIR R4 read API and Bundle imports. It isn't
+// described by the OpenAPI spec, so it has its own middlewares and errors are
+// OperationOutcomes.
+func fhirRouter(a app.App, logger *httplog.Logger, proxies TrustedProxies) http.Handler {
+	r := chi.NewRouter()
+	r.Use(chi_middleware.Recoverer, realIPMiddleware(proxies), httplog.RequestLogger(logger))
+	r.Use(actorMiddleware(encodeFhirApiError), clinicMiddleware(a, nil, encodeFhirApiError))
+
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		encodeFhirError(w, http.StatusNotFound, fhirIssueNotFound, "Unsupported resource type")
+	})
+
+	r.Get("/Patient/{id}", fhirRead(fhir.ResourceTypePatient, a.FhirPatient))
+	r.Get("/Patient", fhirSearch(func(ctx context.Context, q url.Values) ([]fhir.Patient, error) {
+		search := fhir.PatientSearch{Email: queryString(q, "email")}
+		id, err := queryId(q, "_id", fhir.ResourceTypePatient)
+		if err != nil {
+			return nil, err
+		}
+		search.Id = id
+		return a.FhirPatients(ctx, search)
+	}))
+
+	r.Get("/Practitioner/{id}", fhirRead(fhir.ResourceTypePractitioner, a.FhirPractitioner))
+	r.Get(
+		"/Practitioner",
+		fhirSearch(func(ctx context.Context, q url.Values) ([]fhir.Practitioner, error) {
+			search := fhir.PractitionerSearch{Email: queryString(q, "email")}
+			id, err := queryId(q, "_id", fhir.ResourceTypePractitioner)
+			if err != nil {
+				return nil, err
+			}
+			search.Id = id
+			return a.FhirPractitioners(ctx, search)
+		}),
+	)
+
+	r.Get("/Appointment/{id}", fhirRead(fhir.ResourceTypeAppointment, a.FhirAppointment))
+	r.Get(
+		"/Appointment",
+		fhirSearch(func(ctx context.Context, q url.Values) ([]fhir.Appointment, error) {
+			var search fhir.AppointmentSearch
+			var err error
+			if search.Patient, err = queryId(q, "patient", fhir.ResourceTypePatient); err != nil {
+				return nil, err
+			}
+			search.Practitioner, err = queryId(q, "practitioner", fhir.ResourceTypePractitioner)
+			if err != nil {
+				return nil, err
+			}
+			if search.Patient == nil && search.Practitioner == nil {
+				return nil, fmt.Errorf(
+					"%w: patient or practitioner is required",
+					fhir.ErrInvalidSearchParam,
+				)
+			}
+			if search.Date, err = fhir.ParseDateParams(q["date"], time.Local); err != nil {
+				return nil, err
+			}
+			search.Status = queryString(q, "status")
+			return a.FhirAppointments(ctx, search)
+		}),
+	)
+
+	r.Get("/Condition/{id}", fhirRead(fhir.ResourceTypeCondition, a.FhirCondition))
+	r.Get("/Condition", fhirSearch(func(ctx context.Context, q url.Values) ([]fhir.Condition, error) {
+		patient, err := requiredPatient(q)
+		if err != nil {
+			return nil, err
+		}
+		search := fhir.ConditionSearch{
+			Patient:        patient,
+			ClinicalStatus: queryString(q, "clinical-status"),
+		}
+		if search.OnsetDate, err = fhir.ParseDateParams(q["onset-date"], time.Local); err != nil {
+			return nil, err
+		}
+		return a.FhirConditions(ctx, search)
+	}))
+
+	r.Get(
+		"/MedicationRequest/{id}",
+		fhirRead(fhir.ResourceTypeMedicationRequest, a.FhirMedicationRequest),
+	)
+	r.Get(
+		"/MedicationRequest",
+		fhirSearch(func(ctx context.Context, q url.Values) ([]fhir.MedicationRequest, error) {
+			patient, err := requiredPatient(q)
+			if err != nil {
+				return nil, err
+			}
+			search := fhir.MedicationRequestSearch{
+				Patient: patient,
+				Status:  queryString(q, "status"),
+			}
+			if search.AuthoredOn, err = fhir.ParseDateParams(q["authoredon"], time.Local); err != nil {
+				return nil, err
+			}
+			return a.FhirMedicationRequests(ctx, search)
+		}),
+	)
+
+	r.Post("/$import", fhirImport(a))
+
+	return r
+}
+
+// fhirImport imports a Bundle, "?dryRun=true" only validates it. The import
+// report is returned on success as well as on failure.
+func fhirImport(a app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := http.MaxBytesReader(w, r.Body, MaxFhirBundleBytes)
+		entries, err := fhir.ParseBundle(body)
+		if err != nil {
+			encodeFhirError(w, http.StatusBadRequest, fhirIssueInvalid, err.Error())
+			return
+		}
+
+		dryRun := r.URL.Query().Get("dryRun") == "true"
+		report, err := a.ImportFhirBundle(r.Context(), entries, dryRun)
+		switch {
+		case err == nil:
+			encode(w, http.StatusOK, report)
+		case errors.Is(err, app.ErrInvalidImport):
+			encode(w, http.StatusUnprocessableEntity, report)
+		case errors.Is(err, app.ErrImportFailed):
+			encode(w, http.StatusConflict, report)
+		case errors.Is(err, app.ErrForbidden):
+			encodeFhirError(w, http.StatusForbidden, fhirIssueForbidden,
+				"Only administrators can import bundles")
+		default:
+			slog.Error(UnexpectedError, "error", err.Error(), "where", "fhirImport")
+			encodeFhirError(w, http.StatusInternalServerError, fhirIssueException, "Unexpected error")
+		}
+	}
+}
+
+func fhirRead[T any](
+	resourceType string,
+	read func(ctx context.Context, id uuid.UUID) (T, error),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rawId := chi.URLParam(r, "id")
+		notFoundMsg := fmt.Sprintf("%s/%s was not found", resourceType, rawId)
+
+		id, err := uuid.Parse(rawId)
+		if err != nil {
+			encodeFhirError(w, http.StatusNotFound, fhirIssueNotFound, notFoundMsg)
+			return
+		}
+
+		resource, err := read(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, app.ErrNotFound) {
+				encodeFhirError(w, http.StatusNotFound, fhirIssueNotFound, notFoundMsg)
+				return
+			}
+			slog.Error(UnexpectedError, "error", err.Error(), "where", "fhirRead "+resourceType)
+			encodeFhirError(w, http.StatusInternalServerError, fhirIssueException, "Unexpected error")
+			return
+		}
+
+		encodeWithContentType(w, http.StatusOK, resource, ApplicationFhirJSON)
+	}
+}
+
+func fhirSearch[T fhir.Resource](
+	search func(ctx context.Context, q url.Values) ([]T, error),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resources, err := search(r.Context(), r.URL.Query())
+		if err != nil {
+			if errors.Is(err, fhir.ErrInvalidSearchParam) {
+				encodeFhirError(w, http.StatusBadRequest, fhirIssueInvalid, err.Error())
+				return
+			}
+			slog.Error(UnexpectedError, "error", err.Error(), "where", "fhirSearch "+r.URL.Path)
+			encodeFhirError(w, http.StatusInternalServerError, fhirIssueException, "Unexpected error")
+			return
+		}
+
+		bundle := fhir.NewSearchset(fhirBaseUrl(r), resources)
+		encodeWithContentType(w, http.StatusOK, bundle, ApplicationFhirJSON)
+	}
+}
+
+// encodeFhirApiError encodes errors of the API middlewares as OperationOutcomes.
+func encodeFhirApiError(w http.ResponseWriter, err *ApiError) {
+	code := fhirIssueInvalid
+	switch {
+	case err.Status == http.StatusForbidden:
+		code = fhirIssueForbidden
+	case err.Status >= http.StatusInternalServerError:
+		code = fhirIssueException
+	}
+	encodeFhirError(w, err.Status, code, err.Detail)
+}
+
+func encodeFhirError(w http.ResponseWriter, status int, code, diagnostics string) {
+	encodeWithContentType(
+		w,
+		status,
+		fhir.NewOperationOutcome(code, diagnostics),
+		ApplicationFhirJSON,
+	)
+}
+
+func fhirBaseUrl(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + FhirBasePath
+}
+
+func queryString(q url.Values, name string) *string {
+	if !q.Has(name) {
+		return nil
+	}
+	value := q.Get(name)
+	return &value
+}
+
+// queryId parses a reference search parameter, which is either a plain id or
+// a "<resourceType>/<id>" reference.
+func queryId(q url.Values, name, resourceType string) (*uuid.UUID, error) {
+	if !q.Has(name) {
+		return nil, nil
+	}
+
+	value := strings.TrimPrefix(q.Get(name), resourceType+"/")
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s=%q", fhir.ErrInvalidSearchParam, name, q.Get(name))
+	}
+	return &id, nil
+}
+
+func requiredPatient(q url.Values) (uuid.UUID, error) {
+	patient, err := queryId(q, "patient", fhir.ResourceTypePatient)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if patient == nil {
+		return uuid.Nil, fmt.Errorf("%w: patient is required", fhir.ErrInvalidSearchParam)
+	}
+	return *patient, nil
+}
@@ -24,6 +24,10 @@ func (s Server) CancelAppointment(
 
 	err := s.app.CancelAppointment(r.Context(), appointmentId, req)
 	if err != nil {
+		if errors.Is(err, app.ErrForbidden) {
+			encodeError(w, forbidden(cancellationForbiddenDetail))
+			return
+		}
 		slog.Error(UnexpectedError, "error", err.Error(), "where", "CancelAppointment")
 		encodeError(w, internalServerError())
 		return
@@ -37,14 +41,29 @@ func (s Server) ConditionDetail(
 	w http.ResponseWriter,
 	r *http.Request,
 	conditionId api.ConditionId,
+	params api.ConditionDetailParams,
 ) {
-	cond, err := s.app.ConditionById(r.Context(), conditionId)
+	var cond api.Condition
+	var err error
+	if params.AsOf != nil {
+		cond, err = s.app.ConditionAsOf(r.Context(), conditionId, *params.AsOf)
+	} else {
+		cond, err = s.app.ConditionById(r.Context(), conditionId)
+	}
 	if err != nil {
-		slog.Error(UnexpectedError, "error", err.Error(), "where", "GetAvailableResources")
+		if errors.Is(err, app.ErrNotFound) {
+			encodeError(w, notFoundId("Condition", conditionId))
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "ConditionDetail")
 		encodeError(w, internalServerError())
 		return
 	}
 
+	// A past version can't be a precondition of a write.
+	if params.AsOf == nil {
+		w.Header().Set(ETag, etag(cond.Version))
+	}
 	encode(w, http.StatusOK, cond)
 }
 
@@ -62,7 +81,11 @@ func (s Server) DecideAppointment(
 
 	doctorAppt, err := s.app.DecideAppointment(r.Context(), appointmentId, req)
 	if err != nil {
-		if errors.Is(err, app.ErrResourceUnavailable) {
+		var valErr *app.ValidationError
+		if errors.As(err, &valErr) {
+			encodeError(w, fromValidationError(valErr))
+			return
+		} else if errors.Is(err, app.ErrResourceUnavailable) {
 			apiErr := &ApiError{
 				ErrorDetail: api.ErrorDetail{
 					Code:   "resources.unavailable",
@@ -89,8 +112,18 @@ func (s Server) DoctorsCalendar(
 	doctorId api.DoctorId,
 	params api.DoctorsCalendarParams,
 ) {
-	calendar, err := s.app.DoctorsCalendar(r.Context(), doctorId, params.From, params.To)
+	calendar, err := s.app.DoctorsCalendar(
+		r.Context(),
+		doctorId,
+		params,
+		listParams(params.Sort, params.Cursor, params.Limit),
+	)
 	if err != nil {
+		var valErr *app.ValidationError
+		if errors.As(err, &valErr) {
+			encodeError(w, fromValidationError(valErr))
+			return
+		}
 		slog.Error(UnexpectedError, "error", err.Error(), "where", "DoctorsCalendar")
 		encodeError(w, internalServerError())
 		return
@@ -122,8 +155,13 @@ func (s Server) GetAvailableResources(
 	r *http.Request,
 	params api.GetAvailableResourcesParams,
 ) {
-	resources, err := s.app.AvailableResources(r.Context(), params.DateTime)
+	resources, err := s.app.AvailableResources(r.Context(), params.DateTime, params.LocationId)
 	if err != nil {
+		var valErr *app.ValidationError
+		if errors.As(err, &valErr) {
+			encodeError(w, fromValidationError(valErr))
+			return
+		}
 		slog.Error(UnexpectedError, "error", err.Error(), "where", "GetAvailableResources")
 		encodeError(w, internalServerError())
 		return
@@ -153,6 +191,65 @@ func (s Server) GetDoctorById(w http.ResponseWriter, r *http.Request, doctorId a
 		return
 	}
 
+	w.Header().Set(ETag, etag(doctor.Version))
+	encode(w, http.StatusOK, doctor)
+}
+
+// UpdateDoctorProfile implements api.ServerInterface.
+func (s Server) UpdateDoctorProfile(
+	w http.ResponseWriter,
+	r *http.Request,
+	doctorId api.DoctorId,
+	params api.UpdateDoctorProfileParams,
+) {
+	req, decodeErr := Decode[api.DoctorProfileUpdate](w, r)
+	if decodeErr != nil {
+		encodeError(w, decodeErr)
+		return
+	}
+
+	doctor, err := s.app.UpdateDoctorProfile(
+		r.Context(),
+		doctorId,
+		req,
+		ifMatchPrecondition(params.IfMatch),
+	)
+	if err != nil {
+		if errors.Is(err, app.ErrNotFound) {
+			encodeError(w, notFoundId("Doctor", doctorId))
+			return
+		}
+		if errors.Is(err, app.ErrForbidden) {
+			encodeError(w, forbidden(editDoctorProfileForbiddenDetail))
+			return
+		}
+		if errors.Is(err, app.ErrPreconditionFailed) {
+			encodeError(w, preconditionFailed())
+			return
+		}
+		if errors.Is(err, app.ErrModifiedConcurrently) {
+			encodeError(w, modifiedConcurrently())
+			return
+		}
+		var valErr *app.ValidationError
+		if errors.As(err, &valErr) {
+			encodeError(w, fromValidationError(valErr))
+			return
+		}
+		slog.Error(
+			UnexpectedError,
+			"error",
+			err.Error(),
+			"where",
+			"UpdateDoctorProfile",
+			"doctorId",
+			doctorId.String(),
+		)
+		encodeError(w, internalServerError())
+		return
+	}
+
+	w.Header().Set(ETag, etag(doctor.Version))
 	encode(w, http.StatusOK, doctor)
 }
 
@@ -193,6 +290,65 @@ func (s Server) GetPatientById(w http.ResponseWriter, r *http.Request, patientId
 		return
 	}
 
+	w.Header().Set(ETag, etag(patient.Version))
+	encode(w, http.StatusOK, patient)
+}
+
+// UpdatePatientProfile implements api.ServerInterface.
+func (s Server) UpdatePatientProfile(
+	w http.ResponseWriter,
+	r *http.Request,
+	patientId api.PatientId,
+	params api.UpdatePatientProfileParams,
+) {
+	req, decodeErr := Decode[api.PatientProfileUpdate](w, r)
+	if decodeErr != nil {
+		encodeError(w, decodeErr)
+		return
+	}
+
+	patient, err := s.app.UpdatePatientProfile(
+		r.Context(),
+		patientId,
+		req,
+		ifMatchPrecondition(params.IfMatch),
+	)
+	if err != nil {
+		if errors.Is(err, app.ErrNotFound) {
+			encodeError(w, notFoundId("Patient", patientId))
+			return
+		}
+		if errors.Is(err, app.ErrForbidden) {
+			encodeError(w, forbidden(editProfileForbiddenDetail))
+			return
+		}
+		if errors.Is(err, app.ErrPreconditionFailed) {
+			encodeError(w, preconditionFailed())
+			return
+		}
+		if errors.Is(err, app.ErrModifiedConcurrently) {
+			encodeError(w, modifiedConcurrently())
+			return
+		}
+		var valErr *app.ValidationError
+		if errors.As(err, &valErr) {
+			encodeError(w, fromValidationError(valErr))
+			return
+		}
+		slog.Error(
+			UnexpectedError,
+			"error",
+			err.Error(),
+			"where",
+			"UpdatePatientProfile",
+			"patientId",
+			patientId.String(),
+		)
+		encodeError(w, internalServerError())
+		return
+	}
+
+	w.Header().Set(ETag, etag(patient.Version))
 	encode(w, http.StatusOK, patient)
 }
 
@@ -219,8 +375,18 @@ func (s Server) PatientsCalendar(
 	patientId api.PatientId,
 	params api.PatientsCalendarParams,
 ) {
-	calendar, err := s.app.PatientsCalendar(r.Context(), patientId, params.From, params.To)
+	calendar, err := s.app.PatientsCalendar(
+		r.Context(),
+		patientId,
+		params,
+		listParams(params.Sort, params.Cursor, params.Limit),
+	)
 	if err != nil {
+		var valErr *app.ValidationError
+		if errors.As(err, &valErr) {
+			encodeError(w, fromValidationError(valErr))
+			return
+		}
 		slog.Error(UnexpectedError, "error", err.Error(), "where", "PatientsCalendar")
 		encodeError(w, internalServerError())
 		return
@@ -251,6 +417,30 @@ func (s Server) PatientsMedicalHistoryFiles(
 	encode(w, http.StatusOK, files)
 }
 
+// SearchPatients implements api.ServerInterface.
+func (s Server) SearchPatients(
+	w http.ResponseWriter,
+	r *http.Request,
+	params api.SearchPatientsParams,
+) {
+	patients, err := s.app.SearchPatients(r.Context(), params)
+	if err != nil {
+		var valErr *app.ValidationError
+		if errors.As(err, &valErr) {
+			encodeError(w, fromValidationError(valErr))
+			return
+		} else if errors.Is(err, app.ErrForbidden) {
+			encodeError(w, forbidden(searchPatientsForbiddenDetail))
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "SearchPatients")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusOK, patients)
+}
+
 // RescheduleAppointment implements api.ServerInterface.
 func (s Server) RescheduleAppointment(
 	w http.ResponseWriter,
@@ -265,7 +455,14 @@ func (s Server) RescheduleAppointment(
 
 	appt, err := s.app.RescheduleAppointment(r.Context(), appointmentId, req.NewAppointmentDateTime)
 	if err != nil {
-		if errors.Is(err, app.ErrDoctorUnavailable) {
+		var valErr *app.ValidationError
+		if errors.As(err, &valErr) {
+			encodeError(w, fromValidationError(valErr))
+			return
+		} else if errors.Is(err, app.ErrForbidden) {
+			encodeError(w, forbidden(bookingForbiddenDetail))
+			return
+		} else if errors.Is(err, app.ErrDoctorUnavailable) {
 			apiErr := &ApiError{
 				ErrorDetail: api.ErrorDetail{
 					Code:   "doctor.unavailable",
@@ -295,6 +492,11 @@ func (s Server) CreatePatientCondition(w http.ResponseWriter, r *http.Request) {
 
 	cond, err := s.app.CreatePatientCondition(r.Context(), req)
 	if err != nil {
+		var valErr *app.ValidationError
+		if errors.As(err, &valErr) {
+			encodeError(w, fromValidationError(valErr))
+			return
+		}
 		slog.Error(UnexpectedError, "error", err.Error(), "where", "CreatePatientCondition")
 		encodeError(w, internalServerError())
 		return
@@ -331,6 +533,14 @@ func (s Server) RequestAppointment(w http.ResponseWriter, r *http.Request) {
 
 	appt, err := s.app.CreateAppointment(r.Context(), req)
 	if err != nil {
+		if errors.Is(err, app.ErrForbidden) {
+			encodeError(w, forbidden(bookingForbiddenDetail))
+			return
+		}
+		if apiErr := bookingApiError(err); apiErr != nil {
+			encodeError(w, apiErr)
+			return
+		}
 		slog.Error(UnexpectedError, "error", err.Error(), "where", "RequestAppointment")
 		encodeError(w, internalServerError())
 		return
@@ -339,6 +549,71 @@ func (s Server) RequestAppointment(w http.ResponseWriter, r *http.Request) {
 	encode(w, http.StatusCreated, appt)
 }
 
+// BookAppointment implements api.ServerInterface.
+func (s Server) BookAppointment(w http.ResponseWriter, r *http.Request) {
+	req, decodeErr := Decode[api.StaffBooking](w, r)
+	if decodeErr != nil {
+		encodeError(w, decodeErr)
+		return
+	}
+
+	appt, err := s.app.BookAppointment(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, app.ErrForbidden):
+			encodeError(w, forbidden(staffBookingForbiddenDetail))
+			return
+		case errors.Is(err, app.ErrDuplicateEmail):
+			encodeError(w, &ApiError{
+				ErrorDetail: api.ErrorDetail{
+					Code:  "patient.email-exists",
+					Title: "Conflict",
+					Detail: fmt.Sprintf(
+						"A patient with email %q already exists, but isn't shared with the clinic.",
+						*req.Patient.Email,
+					),
+					Status: http.StatusConflict,
+				},
+			})
+			return
+		}
+		if apiErr := bookingApiError(err); apiErr != nil {
+			encodeError(w, apiErr)
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "BookAppointment")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusCreated, appt)
+}
+
+// bookingApiError maps errors of creating an appointment.
+func bookingApiError(err error) *ApiError {
+	if errors.Is(err, app.ErrTooManyRequested) {
+		return &ApiError{
+			ErrorDetail: api.ErrorDetail{
+				Code:   TooManyRequestedCode,
+				Title:  "Conflict",
+				Detail: "Patient has too many appointments awaiting a decision",
+				Status: http.StatusConflict,
+			},
+		}
+	}
+	if errors.Is(err, app.ErrDoctorUnavailable) {
+		return &ApiError{
+			ErrorDetail: api.ErrorDetail{
+				Code:   "doctor.unavailable",
+				Title:  "Conflict",
+				Detail: "Doctor is unavailable at the requested time",
+				Status: http.StatusConflict,
+			},
+		}
+	}
+	return referralApiError(err, nil)
+}
+
 // CreateResource implements api.ServerInterface.
 func (s Server) CreateResource(w http.ResponseWriter, r *http.Request) {
 	req, decodeErr := Decode[api.NewResource](w, r)
@@ -349,6 +624,14 @@ func (s Server) CreateResource(w http.ResponseWriter, r *http.Request) {
 
 	resource, err := s.app.CreateResource(r.Context(), req)
 	if err != nil {
+		var valErr *app.ValidationError
+		if errors.As(err, &valErr) {
+			encodeError(w, fromValidationError(valErr))
+			return
+		} else if errors.Is(err, app.ErrForbidden) {
+			encodeError(w, forbidden(manageResourcesForbiddenDetail))
+			return
+		}
 		slog.Error(UnexpectedError, "error", err.Error(), "where", "CreateResource")
 		encodeError(w, internalServerError())
 		return
@@ -404,8 +687,15 @@ func (s Server) PrescriptionDetail(
 	w http.ResponseWriter,
 	r *http.Request,
 	prescriptionId api.PrescriptionId,
+	params api.PrescriptionDetailParams,
 ) {
-	prescription, err := s.app.PrescriptionById(r.Context(), prescriptionId)
+	var prescription api.Prescription
+	var err error
+	if params.AsOf != nil {
+		prescription, err = s.app.PrescriptionAsOf(r.Context(), prescriptionId, *params.AsOf)
+	} else {
+		prescription, err = s.app.PrescriptionById(r.Context(), prescriptionId)
+	}
 	if err != nil {
 		if errors.Is(err, app.ErrNotFound) {
 			encodeError(w, notFoundId("Prescription", prescriptionId))
@@ -424,6 +714,10 @@ func (s Server) PrescriptionDetail(
 		return
 	}
 
+	// A past version can't be a precondition of a write.
+	if params.AsOf == nil {
+		w.Header().Set(ETag, etag(prescription.Version))
+	}
 	encode(w, http.StatusOK, prescription)
 }
 
@@ -444,7 +738,11 @@ func (s Server) ReserveAppointmentResources(
 		req,
 	)
 	if err != nil {
-		if errors.Is(err, app.ErrNotFound) {
+		var valErr *app.ValidationError
+		if errors.As(err, &valErr) {
+			encodeError(w, fromValidationError(valErr))
+			return
+		} else if errors.Is(err, app.ErrNotFound) {
 			apiErr := &ApiError{
 				ErrorDetail: api.ErrorDetail{
 					Code:   "resource.or.appointment.not-found",
@@ -489,6 +787,7 @@ func (s Server) UpdateCondition(
 	w http.ResponseWriter,
 	r *http.Request,
 	conditionId api.ConditionId,
+	params api.UpdateConditionParams,
 ) {
 	req, decodeErr := Decode[api.UpdateCondition](w, r)
 	if decodeErr != nil {
@@ -500,12 +799,26 @@ func (s Server) UpdateCondition(
 		r.Context(),
 		conditionId,
 		req,
+		ifMatchPrecondition(params.IfMatch),
 	)
 	if err != nil {
 		if errors.Is(err, app.ErrNotFound) {
 			encodeError(w, notFoundId("Condition", conditionId))
 			return
 		}
+		if errors.Is(err, app.ErrPreconditionFailed) {
+			encodeError(w, preconditionFailed())
+			return
+		}
+		if errors.Is(err, app.ErrModifiedConcurrently) {
+			encodeError(w, modifiedConcurrently())
+			return
+		}
+		var valErr *app.ValidationError
+		if errors.As(err, &valErr) {
+			encodeError(w, fromValidationError(valErr))
+			return
+		}
 		slog.Error(
 			UnexpectedError,
 			"error",
@@ -519,6 +832,7 @@ func (s Server) UpdateCondition(
 		return
 	}
 
+	w.Header().Set(ETag, etag(updatedCondition.Version))
 	encode(w, http.StatusOK, updatedCondition)
 }
 
@@ -526,6 +840,7 @@ func (s Server) UpdatePrescription(
 	w http.ResponseWriter,
 	r *http.Request,
 	prescriptionId api.PrescriptionId,
+	params api.UpdatePrescriptionParams,
 ) {
 	req, decodeErr := Decode[api.UpdatePrescription](w, r)
 	if decodeErr != nil {
@@ -537,12 +852,21 @@ func (s Server) UpdatePrescription(
 		r.Context(),
 		prescriptionId,
 		req,
+		ifMatchPrecondition(params.IfMatch),
 	)
 	if err != nil {
 		if errors.Is(err, app.ErrNotFound) {
 			encodeError(w, notFoundId("Prescription", prescriptionId))
 			return
 		}
+		if errors.Is(err, app.ErrPreconditionFailed) {
+			encodeError(w, preconditionFailed())
+			return
+		}
+		if errors.Is(err, app.ErrModifiedConcurrently) {
+			encodeError(w, modifiedConcurrently())
+			return
+		}
 		slog.Error(
 			UnexpectedError,
 			"error",
@@ -556,25 +880,35 @@ func (s Server) UpdatePrescription(
 		return
 	}
 
+	w.Header().Set(ETag, etag(updatedPrescription.Version))
 	encode(w, http.StatusOK, updatedPrescription)
 }
 
 // GetDoctors implements api.ServerInterface.
-func (s Server) GetDoctors(w http.ResponseWriter, r *http.Request) {
-	doctors, err := s.app.GetAllDoctors(r.Context())
+func (s Server) GetDoctors(w http.ResponseWriter, r *http.Request, params api.GetDoctorsParams) {
+	doctors, err := s.app.Doctors(
+		r.Context(),
+		params,
+		listParams(params.Sort, params.Cursor, params.Limit),
+	)
 	if err != nil {
+		var valErr *app.ValidationError
+		if errors.As(err, &valErr) {
+			encodeError(w, fromValidationError(valErr))
+			return
+		}
 		slog.Error(
 			UnexpectedError,
 			"error",
 			err.Error(),
 			"where",
-			"GetAllDoctors",
+			"GetDoctors",
 		)
 		encodeError(w, internalServerError())
 		return
 	}
 
-	encode(w, http.StatusOK, api.Doctors{Doctors: doctors})
+	encode(w, http.StatusOK, doctors)
 }
 
 // ConditionsInDate implements api.ServerInterface.
@@ -588,8 +922,14 @@ func (s Server) ConditionsInDate(
 		r.Context(),
 		patientId,
 		params.Date.Time,
+		listParams(params.Sort, params.Cursor, params.Limit),
 	)
 	if err != nil {
+		var valErr *app.ValidationError
+		if errors.As(err, &valErr) {
+			encodeError(w, fromValidationError(valErr))
+			return
+		}
 		slog.Error(
 			UnexpectedError,
 			"error",
@@ -605,7 +945,7 @@ func (s Server) ConditionsInDate(
 		return
 	}
 
-	encode(w, http.StatusOK, api.Conditions{Conditions: conditions})
+	encode(w, http.StatusOK, conditions)
 }
 
 // DeletePrescription implements api.ServerInterface.
@@ -613,13 +953,26 @@ func (s Server) DeletePrescription(
 	w http.ResponseWriter,
 	r *http.Request,
 	prescriptionId api.PrescriptionId,
+	params api.DeletePrescriptionParams,
 ) {
-	err := s.app.DeletePrescription(r.Context(), prescriptionId)
+	err := s.app.DeletePrescription(
+		r.Context(),
+		prescriptionId,
+		ifMatchPrecondition(params.IfMatch),
+	)
 	if err != nil {
 		if errors.Is(err, app.ErrNotFound) {
 			encodeError(w, notFoundId("Prescription", prescriptionId))
 			return
 		}
+		if errors.Is(err, app.ErrPreconditionFailed) {
+			encodeError(w, preconditionFailed())
+			return
+		}
+		if errors.Is(err, app.ErrModifiedConcurrently) {
+			encodeError(w, modifiedConcurrently())
+			return
+		}
 
 		slog.Error(
 			UnexpectedError,
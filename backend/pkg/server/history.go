@@ -0,0 +1,66 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/app"
+)
+
+// ConditionHistory implements api.ServerInterface.
+func (s Server) ConditionHistory(
+	w http.ResponseWriter,
+	r *http.Request,
+	conditionId api.ConditionId,
+) {
+	revisions, err := s.app.ConditionHistory(r.Context(), conditionId)
+	if err != nil {
+		if errors.Is(err, app.ErrNotFound) {
+			encodeError(w, notFoundId("Condition", conditionId))
+			return
+		}
+		slog.Error(
+			UnexpectedError,
+			"error",
+			err.Error(),
+			"where",
+			"ConditionHistory",
+			"conditionId",
+			conditionId.String(),
+		)
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusOK, api.ConditionHistory{Revisions: revisions})
+}
+
+// PrescriptionHistory implements api.ServerInterface.
+func (s Server) PrescriptionHistory(
+	w http.ResponseWriter,
+	r *http.Request,
+	prescriptionId api.PrescriptionId,
+) {
+	revisions, err := s.app.PrescriptionHistory(r.Context(), prescriptionId)
+	if err != nil {
+		if errors.Is(err, app.ErrNotFound) {
+			encodeError(w, notFoundId("Prescription", prescriptionId))
+			return
+		}
+		slog.Error(
+			UnexpectedError,
+			"error",
+			err.Error(),
+			"where",
+			"PrescriptionHistory",
+			"prescriptionId",
+			prescriptionId.String(),
+		)
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusOK, api.PrescriptionHistory{Revisions: revisions})
+}
@@ -0,0 +1,245 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	chi_middleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	LocationHeader           = "Location"
+
+	InvalidIdempotencyKeyCode = "idempotency.invalid-key"
+	IdempotencyKeyReusedCode  = "idempotency.key-reused"
+	IdempotencyInFlightCode   = "idempotency.in-flight"
+
+	MaxIdempotencyKeyLength = 255
+)
+
+// replayedHeaders are the headers of kept responses set by handlers, others
+// are set by middlewares on every response.
+var replayedHeaders = []string{ContentType, ETag, LocationHeader}
+
+// IdempotencyStore reserves idempotency keys for requests and keeps their
+// responses, data.MongoDb implements it.
+type IdempotencyStore interface {
+	ReserveIdempotencyKey(
+		ctx context.Context,
+		key string,
+		requestHash string,
+		lease time.Duration,
+		ttl time.Duration,
+	) (data.IdempotentRequest, bool, error)
+	CompleteIdempotencyKey(
+		ctx context.Context,
+		request data.IdempotentRequest,
+		response data.IdempotentResponse,
+	) error
+	ReleaseIdempotencyKey(ctx context.Context, request data.IdempotentRequest) error
+}
+
+// Idempotency makes creating requests with an Idempotency-Key header safe to
+// retry. The first request with a key is handled, its retries get the same
+// response replayed instead of creating duplicates. Requests are handled as
+// usual if the store fails.
+type Idempotency struct {
+	cfg   IdempotencyConfig
+	store IdempotencyStore
+}
+
+func NewIdempotency(cfg IdempotencyConfig, store IdempotencyStore) *Idempotency {
+	return &Idempotency{cfg: cfg, store: store}
+}
+
+// middleware handles idempotency keys of creating operations, POSTs answering
+// 201 Created. It must run after actorMiddleware and clinicMiddleware, keys
+// are scoped to the acting user and the clinic.
+func (i *Idempotency) middleware(spec *openapi3.T) func(http.Handler) http.Handler {
+	creating := creatingRoutes(spec)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			route := r.Method + " " + chi.RouteContext(r.Context()).RoutePattern()
+			if key == "" || !creating[route] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > MaxIdempotencyKeyLength {
+				encodeError(w, invalidIdempotencyKey(fmt.Sprintf(
+					"%s must have at most %d characters",
+					IdempotencyKeyHeader,
+					MaxIdempotencyKeyLength,
+				)))
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBytes))
+			if err != nil {
+				encodeError(w, decodeErrToApiError(err))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			hash := requestHash(r, body)
+			request, reserved, err := i.store.ReserveIdempotencyKey(
+				r.Context(),
+				key,
+				hash,
+				i.cfg.Lease,
+				i.cfg.TTL,
+			)
+			if err != nil {
+				slog.Error(UnexpectedError, "error", err.Error(), "where", "Idempotency")
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !reserved {
+				switch {
+				case request.RequestHash != hash:
+					encodeError(w, idempotencyConflict(
+						IdempotencyKeyReusedCode,
+						fmt.Sprintf(
+							"%s %q was used for a different request",
+							IdempotencyKeyHeader,
+							key,
+						),
+					))
+				case request.Response == nil:
+					encodeError(w, idempotencyConflict(
+						IdempotencyInFlightCode,
+						fmt.Sprintf(
+							"Request with %s %q is still being handled, retry later",
+							IdempotencyKeyHeader,
+							key,
+						),
+					))
+				default:
+					replay(w, *request.Response)
+				}
+				return
+			}
+
+			i.handle(w, r, next, request)
+		})
+	}
+}
+
+// handle handles the request reserved for its key and keeps its response.
+// Server errors aren't kept, the key is released so a retry is handled anew.
+func (i *Idempotency) handle(
+	w http.ResponseWriter,
+	r *http.Request,
+	next http.Handler,
+	request data.IdempotentRequest,
+) {
+	// the response is kept even if the client gives up waiting for it
+	ctx := context.WithoutCancel(r.Context())
+	completed := false
+	defer func() {
+		if completed {
+			return
+		}
+		if err := i.store.ReleaseIdempotencyKey(ctx, request); err != nil {
+			slog.Error(UnexpectedError, "error", err.Error(), "where", "Idempotency.handle")
+		}
+	}()
+
+	var body bytes.Buffer
+	ww := chi_middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+	ww.Tee(&body)
+	next.ServeHTTP(ww, r)
+
+	status := ww.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status >= http.StatusInternalServerError {
+		return
+	}
+
+	header := make(map[string][]string)
+	for _, name := range replayedHeaders {
+		if values := w.Header().Values(name); len(values) > 0 {
+			header[name] = values
+		}
+	}
+	err := i.store.CompleteIdempotencyKey(ctx, request, data.IdempotentResponse{
+		Status: status,
+		Header: header,
+		Body:   body.Bytes(),
+	})
+	if err != nil {
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "Idempotency.handle")
+		return
+	}
+	completed = true
+}
+
+func replay(w http.ResponseWriter, response data.IdempotentResponse) {
+	for name, values := range response.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(response.Status)
+	if _, err := w.Write(response.Body); err != nil {
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "replay")
+	}
+}
+
+// requestHash identifies the request by its route and body, a key can't be
+// reused for another request.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s?%s\n", r.Method, r.URL.Path, r.URL.RawQuery)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// creatingRoutes returns the routes of the spec's operations answering 201
+// Created to POSTs, under their method and route pattern.
+func creatingRoutes(spec *openapi3.T) map[string]bool {
+	routes := make(map[string]bool)
+	for path, item := range spec.Paths.Map() {
+		if item.Post != nil && item.Post.Responses.Status(http.StatusCreated) != nil {
+			routes[http.MethodPost+" /api"+path] = true
+		}
+	}
+	return routes
+}
+
+func invalidIdempotencyKey(detail string) *ApiError {
+	return &ApiError{
+		ErrorDetail: api.ErrorDetail{
+			Code:   InvalidIdempotencyKeyCode,
+			Title:  "Invalid idempotency key",
+			Detail: detail,
+			Status: http.StatusBadRequest,
+		},
+	}
+}
+
+func idempotencyConflict(code string, detail string) *ApiError {
+	return &ApiError{
+		ErrorDetail: api.ErrorDetail{
+			Code:   code,
+			Title:  "Conflict",
+			Detail: detail,
+			Status: http.StatusConflict,
+		},
+	}
+}
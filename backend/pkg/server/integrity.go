@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+// runIntegrityJob checks, or with repair repairs, the integrity of the
+// database every interval until ctx is done. Repairs bypass the cache, so it is
+// flushed after them, if not nil.
+func runIntegrityJob(
+	ctx context.Context,
+	db *data.MongoDb,
+	cache *data.CachedDb,
+	interval time.Duration,
+	repair bool,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var issues []data.IntegrityIssue
+		var err error
+		if repair {
+			issues, err = db.RepairIntegrity(ctx)
+		} else {
+			issues, err = db.CheckIntegrity(ctx)
+		}
+		if err != nil {
+			slog.Error("integrity job failed", "error", err.Error())
+		}
+		// a failed repair may have repaired some issues
+		if repair && cache != nil && (len(issues) > 0 || err != nil) {
+			cache.Flush(ctx)
+		}
+
+		for _, issue := range issues {
+			slog.Warn(
+				"integrity issue",
+				"kind", issue.Kind,
+				"collection", issue.Collection,
+				"documentId", issue.DocumentId,
+				"description", issue.Description,
+				"repaired", repair,
+			)
+		}
+		slog.Info("integrity job finished", "issues", len(issues), "repair", repair)
+	}
+}
@@ -0,0 +1,22 @@
+package server
+
+import (
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/app"
+)
+
+// listParams collects the params common to list endpoints, the sort of each
+// endpoint has its own type.
+func listParams[S ~string](sort *S, cursor *api.Cursor, limit *api.PageLimit) app.ListParams {
+	var params app.ListParams
+	if sort != nil {
+		params.Sort = string(*sort)
+	}
+	if cursor != nil {
+		params.Cursor = *cursor
+	}
+	if limit != nil {
+		params.Limit = *limit
+	}
+	return params
+}
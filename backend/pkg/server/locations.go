@@ -0,0 +1,98 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/app"
+)
+
+// CreateLocation implements api.ServerInterface.
+func (s Server) CreateLocation(w http.ResponseWriter, r *http.Request) {
+	req, decodeErr := Decode[api.NewLocation](w, r)
+	if decodeErr != nil {
+		encodeError(w, decodeErr)
+		return
+	}
+
+	location, err := s.app.CreateLocation(r.Context(), req)
+	if err != nil {
+		var valErr *app.ValidationError
+		if errors.As(err, &valErr) {
+			encodeError(w, fromValidationError(valErr))
+			return
+		} else if errors.Is(err, app.ErrForbidden) {
+			encodeError(w, forbidden(manageResourcesForbiddenDetail))
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "CreateLocation")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusCreated, location)
+}
+
+// Locations implements api.ServerInterface.
+func (s Server) Locations(w http.ResponseWriter, r *http.Request) {
+	locations, err := s.app.Locations(r.Context())
+	if err != nil {
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "Locations")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusOK, api.Locations{Locations: locations})
+}
+
+// Location implements api.ServerInterface.
+func (s Server) Location(w http.ResponseWriter, r *http.Request, locationId api.LocationId) {
+	location, err := s.app.LocationById(r.Context(), locationId)
+	if err != nil {
+		if errors.Is(err, app.ErrNotFound) {
+			encodeError(w, notFoundId("Location", locationId))
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "Location")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusOK, location)
+}
+
+// AssignResourceLocation implements api.ServerInterface.
+func (s Server) AssignResourceLocation(
+	w http.ResponseWriter,
+	r *http.Request,
+	resourceId api.ResourceId,
+) {
+	req, decodeErr := Decode[api.ResourceLocation](w, r)
+	if decodeErr != nil {
+		encodeError(w, decodeErr)
+		return
+	}
+
+	resource, err := s.app.AssignResourceLocation(r.Context(), resourceId, req)
+	if err != nil {
+		var valErr *app.ValidationError
+		switch {
+		case errors.As(err, &valErr):
+			encodeError(w, fromValidationError(valErr))
+			return
+		case errors.Is(err, app.ErrNotFound):
+			encodeError(w, notFoundId("Resource", resourceId))
+			return
+		case errors.Is(err, app.ErrForbidden):
+			encodeError(w, forbidden(manageResourcesForbiddenDetail))
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "AssignResourceLocation")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusOK, resource)
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	chi_middleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "wac",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of API requests by OpenAPI operation and response status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "status"})
+	apiErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "wac",
+		Subsystem: "http",
+		Name:      "api_errors_total",
+		Help:      "Error responses by their code and status.",
+	}, []string{"code", "status"})
+)
+
+// metricsMiddleware observes the duration of requests under their OpenAPI
+// operation id.
+func metricsMiddleware(spec *openapi3.T) func(http.Handler) http.Handler {
+	operations := operationIds(spec)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := chi_middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			operation := operations.of(r)
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			requestDuration.WithLabelValues(operation, strconv.Itoa(status)).
+				Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// operations are the operation ids of the spec under the method and the
+// route pattern of their handlers.
+type operations map[string]string
+
+func operationIds(spec *openapi3.T) operations {
+	ops := make(operations)
+	for path, item := range spec.Paths.Map() {
+		for method, op := range item.Operations() {
+			ops[method+" /api"+path] = op.OperationID
+		}
+	}
+	return ops
+}
+
+// of returns the operation id of the routed request, the method and the
+// route pattern if the spec doesn't have it.
+func (ops operations) of(r *http.Request) string {
+	route := r.Method + " " + chi.RouteContext(r.Context()).RoutePattern()
+	if operation, ok := ops[route]; ok {
+		return operation
+	}
+	return route
+}
+
+func countApiError(err *ApiError) {
+	apiErrors.WithLabelValues(err.Code, strconv.Itoa(err.Status)).Inc()
+}
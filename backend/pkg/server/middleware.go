@@ -1,6 +1,9 @@
 package server
 
 import (
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 
 	"github.com/getkin/kin-openapi/openapi3"
@@ -9,7 +12,20 @@ import (
 	"github.com/go-chi/httplog/v2"
 	validation_middleware "github.com/oapi-codegen/nethttp-middleware"
 
+	"github.com/google/uuid"
+
 	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/app"
+	"github.com/Nesquiko/wac/pkg/data"
+)
+
+const (
+	UserIdHeader   = "X-User-Id"
+	UserRoleHeader = "X-User-Role"
+	ClinicIdHeader = "X-Clinic-Id"
+
+	InvalidActorCode  = "invalid.actor"
+	InvalidClinicCode = "invalid.clinic"
 )
 
 type OapiValidationOptions struct {
@@ -18,24 +34,193 @@ type OapiValidationOptions struct {
 }
 
 func middleware(
+	a app.App,
 	logger *httplog.Logger,
 	opts OapiValidationOptions,
+	proxies TrustedProxies,
+	limiter *RateLimiter,
+	idempotency *Idempotency,
 ) []api.MiddlewareFunc {
-	return []api.MiddlewareFunc{
+	operations := operationIds(opts.spec)
+	middlewares := []api.MiddlewareFunc{
+		metricsMiddleware(opts.spec),
+		spanNameMiddleware(opts.spec),
 		chi_middleware.Recoverer,
 		cors.Handler(cors.Options{
 			AllowedOrigins: []string{"*"},
 			AllowedMethods: []string{"GET", "POST", "PATCH", "PUT", "DELETE", "OPTIONS"},
-			AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-			MaxAge:         300,
+			AllowedHeaders: []string{
+				"Accept",
+				"Authorization",
+				"Content-Type",
+				"X-CSRF-Token",
+				UserIdHeader,
+				UserRoleHeader,
+				ClinicIdHeader,
+				IdempotencyKeyHeader,
+				IfMatch,
+			},
+			ExposedHeaders: []string{
+				ETag,
+				IdempotentReplayedHeader,
+				LocationHeader,
+				RetryAfterHeader,
+			},
+			MaxAge: 300,
 		}),
-		chi_middleware.RealIP,
+		realIPMiddleware(proxies),
+	}
+	if limiter != nil {
+		middlewares = append(middlewares, limiter.middleware(operations))
+	}
+	middlewares = append(middlewares,
 		validation_middleware.OapiRequestValidatorWithOptions(
 			opts.spec,
 			&validation_middleware.Options{ErrorHandler: opts.errorHandler},
 		),
 		httplog.RequestLogger(logger),
+		traceLogMiddleware,
 		chi_middleware.AllowContentType(ApplicationJSON),
+		actorMiddleware(encodeError),
+		clinicMiddleware(a, func(r *http.Request) bool {
+			return clinicFreeOperations[operations.of(r)]
+		}, encodeError),
+	)
+	if idempotency != nil {
+		middlewares = append(middlewares, idempotency.middleware(opts.spec))
+	}
+	return middlewares
+}
+
+// actorMiddleware attributes the request to the user in the X-User-Id and
+// X-User-Role headers, writes made by it are recorded as done by that user.
+func actorMiddleware(
+	encode func(w http.ResponseWriter, err *ApiError),
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userId := r.Header.Get(UserIdHeader)
+			if userId == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			id, err := uuid.Parse(userId)
+			if err != nil {
+				encode(w, invalidActor(fmt.Sprintf("%s is not a valid uuid", UserIdHeader)))
+				return
+			}
+			role := api.UserRole(r.Header.Get(UserRoleHeader))
+			if role != api.UserRolePatient && role != api.UserRoleDoctor && !isStaffRole(role) {
+				encode(w, invalidActor(fmt.Sprintf(
+					"%s must be patient, doctor, nurse, receptionist or admin",
+					UserRoleHeader,
+				)))
+				return
+			}
+
+			ctx := data.WithActor(r.Context(), data.Actor{Id: id, Role: string(role)})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// clinicFreeOperations don't act in a clinic, they don't need the X-Clinic-Id
+// header.
+var clinicFreeOperations = map[string]bool{
+	"CreateClinic": true,
+	"Clinic":       true,
+	"SearchIcd10":  true,
+}
+
+// belongsToClinic reports whether users of the role are members of a clinic.
+func belongsToClinic(role api.UserRole) bool {
+	return role == api.UserRoleDoctor || isStaffRole(role)
+}
+
+// clinicMiddleware makes the request act in the clinic from the X-Clinic-Id
+// header, all data it reads and writes are limited to the clinic. Doctors and
+// staff must send the header unless clinicFree reports the request doesn't act
+// in a clinic, and can act only in their own clinic. Requests of others
+// without the header act in the default clinic. It must run after
+// actorMiddleware.
+func clinicMiddleware(
+	a app.App,
+	clinicFree func(r *http.Request) bool,
+	encode func(w http.ResponseWriter, err *ApiError),
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get(ClinicIdHeader)
+			if header == "" {
+				if clinicFree != nil && clinicFree(r) {
+					next.ServeHTTP(w, r)
+					return
+				}
+				actor, ok := data.ActorFromContext(r.Context())
+				if ok && belongsToClinic(api.UserRole(actor.Role)) {
+					encode(w, invalidClinic(fmt.Sprintf("%s header is required", ClinicIdHeader)))
+					return
+				}
+				// without a clinic in the context, data are of the default one
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			clinicId, err := uuid.Parse(header)
+			if err != nil {
+				encode(w, invalidClinic(fmt.Sprintf("%s is not a valid uuid", ClinicIdHeader)))
+				return
+			}
+			if _, err := a.ClinicById(r.Context(), clinicId); err != nil {
+				if !errors.Is(err, app.ErrNotFound) {
+					slog.Error(UnexpectedError, "error", err.Error(), "where", "clinicMiddleware")
+				}
+				encode(w, invalidClinic(fmt.Sprintf(
+					"Clinic '%s' from %s doesn't exist",
+					clinicId,
+					ClinicIdHeader,
+				)))
+				return
+			}
+
+			ctx := data.WithClinic(r.Context(), clinicId)
+			if err := a.AuthorizeClinic(ctx); err != nil {
+				if errors.Is(err, app.ErrForbidden) {
+					encode(w, forbidden(fmt.Sprintf(
+						"%s must be the clinic of the acting doctor or staff member",
+						ClinicIdHeader,
+					)))
+					return
+				}
+				slog.Error(UnexpectedError, "error", err.Error(), "where", "clinicMiddleware")
+				encode(w, internalServerError())
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func invalidClinic(detail string) *ApiError {
+	return &ApiError{
+		ErrorDetail: api.ErrorDetail{
+			Code:   InvalidClinicCode,
+			Title:  "Invalid clinic",
+			Detail: detail,
+			Status: http.StatusBadRequest,
+		},
+	}
+}
+
+func invalidActor(detail string) *ApiError {
+	return &ApiError{
+		ErrorDetail: api.ErrorDetail{
+			Code:   InvalidActorCode,
+			Title:  "Invalid acting user",
+			Detail: detail,
+			Status: http.StatusBadRequest,
+		},
 	}
 }
 
@@ -49,7 +234,7 @@ func optionsMiddleware(next http.Handler) http.Handler {
 			w.Header().Set("Access-Control-Allow-Origin", "*") // Or specific origins
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, PATCH, OPTIONS")
 			w.Header().
-				Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
+				Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID, If-Match, X-User-Id, X-User-Role, X-Clinic-Id, Idempotency-Key")
 				// Add any other headers your frontend sends
 			w.Header().
 				Set("Access-Control-Max-Age", "86400")
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/Nesquiko/wac/pkg/app"
+)
+
+const (
+	MetricsPath   = "/metrics"
+	LivenessPath  = "/api/monitoring/live"
+	ReadinessPath = "/api/monitoring/ready"
+
+	readinessTimeout = 2 * time.Second
+)
+
+type probeStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// monitoring serves metrics and the probes. The liveness probe only tells the
+// process handles requests, the readiness probe also pings the database.
+func monitoring(a app.App) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET "+MetricsPath, promhttp.Handler())
+	mux.HandleFunc("GET "+LivenessPath, func(w http.ResponseWriter, r *http.Request) {
+		encode(w, http.StatusOK, probeStatus{Status: "ok"})
+	})
+	mux.HandleFunc("GET "+ReadinessPath, func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+		if err := a.Ready(ctx); err != nil {
+			slog.Warn("not ready", slog.String("error", err.Error()))
+			encode(
+				w,
+				http.StatusServiceUnavailable,
+				probeStatus{Status: "unavailable", Error: err.Error()},
+			)
+			return
+		}
+		encode(w, http.StatusOK, probeStatus{Status: "ok"})
+	})
+	return mux
+}
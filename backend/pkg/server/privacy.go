@@ -0,0 +1,114 @@
+package server
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/app"
+)
+
+const ApplicationZip = "application/zip"
+
+// ExportPatientData implements api.ServerInterface.
+func (s Server) ExportPatientData(w http.ResponseWriter, r *http.Request, patientId api.PatientId) {
+	export, err := s.app.ExportPatientData(r.Context(), patientId)
+	if err != nil {
+		if errors.Is(err, app.ErrNotFound) {
+			encodeError(w, notFoundId("Patient", patientId))
+			return
+		}
+		if errors.Is(err, app.ErrForbidden) {
+			encodeError(w, forbidden(patientDataForbiddenDetail))
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "ExportPatientData")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	w.Header().Set(ContentType, ApplicationZip)
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("patient-%s.zip", patientId)),
+	)
+	w.WriteHeader(http.StatusOK)
+
+	if err := writePatientArchive(w, export); err != nil {
+		// headers are already sent, the client gets a truncated archive
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "ExportPatientData")
+	}
+}
+
+// ErasePatientData implements api.ServerInterface.
+func (s Server) ErasePatientData(w http.ResponseWriter, r *http.Request, patientId api.PatientId) {
+	req, decodeErr := Decode[api.PatientErasureRequest](w, r)
+	if decodeErr != nil {
+		encodeError(w, decodeErr)
+		return
+	}
+
+	report, err := s.app.ErasePatientData(r.Context(), patientId, req)
+	if err != nil {
+		if errors.Is(err, app.ErrNotFound) {
+			encodeError(w, notFoundId("Patient", patientId))
+			return
+		}
+		if errors.Is(err, app.ErrForbidden) {
+			encodeError(w, forbidden(patientDataForbiddenDetail))
+			return
+		}
+		slog.Error(
+			UnexpectedError,
+			"error",
+			err.Error(),
+			"where",
+			"ErasePatientData",
+			"patientId",
+			patientId.String(),
+		)
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusOK, report)
+}
+
+func writePatientArchive(w http.ResponseWriter, export app.PatientDataExport) error {
+	archive := zip.NewWriter(w)
+
+	entries := []struct {
+		name    string
+		content any
+	}{
+		{"patient.json", export.Patient},
+		{"appointments.json", export.Appointments},
+		{"conditions.json", export.Conditions},
+		{"prescriptions.json", export.Prescriptions},
+		{"reservations.json", export.Reservations},
+		{"visit-notes.json", export.VisitNotes},
+		{"referrals.json", export.Referrals},
+		{"medical-history/files.json", export.MedicalHistoryFiles},
+	}
+
+	for _, entry := range entries {
+		f, err := archive.Create(entry.name)
+		if err != nil {
+			return fmt.Errorf("writePatientArchive create %s: %w", entry.name, err)
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(entry.content); err != nil {
+			return fmt.Errorf("writePatientArchive encode %s: %w", entry.name, err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("writePatientArchive close: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nesquiko/wac/pkg/api"
+)
+
+const (
+	RateLimitedCode      = "rate.limited"
+	LoginLockedOutCode   = "auth.locked-out"
+	TooManyRequestedCode = "appointment.too-many-requested"
+	RetryAfterHeader     = "Retry-After"
+)
+
+// RateLimitStore counts hits of keys in fixed windows. MemoryRateLimitStore
+// counts them in process, a shared store like Redis can be used to limit
+// requests across replicas.
+type RateLimitStore interface {
+	// Hit adds a hit to the key's window, started by its first hit, and
+	// returns the hits in the window and when it ends.
+	Hit(ctx context.Context, key string, window time.Duration) (int, time.Time, error)
+	// Hits returns the hits in the key's window and when it ends, zero hits
+	// if the key has no window.
+	Hits(ctx context.Context, key string) (int, time.Time, error)
+	// Reset deletes the key's window.
+	Reset(ctx context.Context, key string) error
+}
+
+// RateLimiter limits requests per client IP, per acting user and per route,
+// and locks clients and emails out of logging in after repeated failures.
+// Requests are let through if the store fails.
+type RateLimiter struct {
+	cfg   RateLimitConfig
+	store RateLimitStore
+}
+
+func NewRateLimiter(cfg RateLimitConfig, store RateLimitStore) *RateLimiter {
+	return &RateLimiter{cfg: cfg, store: store}
+}
+
+type rateLimit struct {
+	key string
+	max int
+}
+
+// middleware rejects requests over any of their limits with 429. It must run
+// after realIPMiddleware, so requests are limited per client. Limits of
+// abusable operations are per client IP, X-User-Id is set by the client and
+// changing it must not reset them.
+func (l *RateLimiter) middleware(operations operations) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := clientIP(r)
+			user := r.Header.Get(UserIdHeader)
+			limits := []rateLimit{{key: "ip:" + ip, max: l.cfg.PerIP}}
+			if user != "" {
+				limits = append(limits, rateLimit{key: "user:" + user, max: l.cfg.PerUser})
+			}
+			switch operations.of(r) {
+			case "LoginUser", "RegisterUser":
+				limits = append(limits, rateLimit{key: "auth:" + ip, max: l.cfg.Auth})
+			case "RequestAppointment":
+				limits = append(limits, rateLimit{key: "booking:" + ip, max: l.cfg.Booking})
+			}
+
+			for _, limit := range limits {
+				if limit.max <= 0 {
+					continue
+				}
+				hits, ends, err := l.store.Hit(r.Context(), limit.key, l.cfg.Window)
+				if err != nil {
+					slog.Error(UnexpectedError, "error", err.Error(), "where", "RateLimiter")
+					continue
+				}
+				if hits > limit.max {
+					tooManyRequests(
+						w,
+						RateLimitedCode,
+						"Too many requests, retry in %d seconds",
+						ends,
+					)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// lockedOut returns when the login lockout of the client or the email ends,
+// zero if neither is locked out.
+func (l *RateLimiter) lockedOut(ctx context.Context, ip string, email string) time.Time {
+	if l == nil || l.cfg.LoginFailures <= 0 {
+		return time.Time{}
+	}
+
+	var until time.Time
+	for _, key := range loginKeys(ip, email) {
+		failures, ends, err := l.store.Hits(ctx, key)
+		if err != nil {
+			slog.Error(UnexpectedError, "error", err.Error(), "where", "RateLimiter.lockedOut")
+			continue
+		}
+		if failures >= l.cfg.LoginFailures && ends.After(until) {
+			until = ends
+		}
+	}
+	return until
+}
+
+// loginFailed counts a failed login of the client and the email, the first
+// failure starts a lockout window.
+func (l *RateLimiter) loginFailed(ctx context.Context, ip string, email string) {
+	if l == nil || l.cfg.LoginFailures <= 0 {
+		return
+	}
+	for _, key := range loginKeys(ip, email) {
+		if _, _, err := l.store.Hit(ctx, key, l.cfg.Lockout); err != nil {
+			slog.Error(UnexpectedError, "error", err.Error(), "where", "RateLimiter.loginFailed")
+		}
+	}
+}
+
+// loginSucceeded forgets the failed logins of the email. Failures of the
+// client are kept, so logging in to a known account doesn't let a client
+// keep guessing others.
+func (l *RateLimiter) loginSucceeded(ctx context.Context, email string) {
+	if l == nil || l.cfg.LoginFailures <= 0 {
+		return
+	}
+	if err := l.store.Reset(ctx, emailLoginKey(email)); err != nil {
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "RateLimiter.loginSucceeded")
+	}
+}
+
+func loginKeys(ip string, email string) []string {
+	return []string{"login:ip:" + ip, emailLoginKey(email)}
+}
+
+func emailLoginKey(email string) string {
+	return "login:email:" + strings.ToLower(email)
+}
+
+// tooManyRequests encodes a 429 error asking to retry at retryAt, the detail
+// is formatted with the seconds to wait.
+func tooManyRequests(w http.ResponseWriter, code string, detail string, retryAt time.Time) {
+	retryAfter := max(1, int(math.Ceil(time.Until(retryAt).Seconds())))
+	w.Header().Set(RetryAfterHeader, strconv.Itoa(retryAfter))
+	encodeError(w, &ApiError{
+		ErrorDetail: api.ErrorDetail{
+			Code:   code,
+			Title:  "Too Many Requests",
+			Detail: fmt.Sprintf(detail, retryAfter),
+			Status: http.StatusTooManyRequests,
+		},
+	})
+}
+
+// MemoryRateLimitStore is an in-process RateLimitStore. Ended windows are
+// swept once the number of windows doubles.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+	sweepAt int
+	now     func() time.Time
+}
+
+type rateWindow struct {
+	hits int
+	ends time.Time
+}
+
+const minSweepAt = 1024
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		windows: make(map[string]*rateWindow),
+		sweepAt: minSweepAt,
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryRateLimitStore) Hit(
+	_ context.Context,
+	key string,
+	window time.Duration,
+) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	w, ok := s.windows[key]
+	if !ok || !now.Before(w.ends) {
+		s.sweep(now)
+		w = &rateWindow{ends: now.Add(window)}
+		s.windows[key] = w
+	}
+	w.hits++
+	return w.hits, w.ends, nil
+}
+
+func (s *MemoryRateLimitStore) Hits(_ context.Context, key string) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.windows[key]
+	if !ok || !s.now().Before(w.ends) {
+		return 0, time.Time{}, nil
+	}
+	return w.hits, w.ends, nil
+}
+
+func (s *MemoryRateLimitStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.windows, key)
+	return nil
+}
+
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if len(s.windows) < s.sweepAt {
+		return
+	}
+	for key, w := range s.windows {
+		if !now.Before(w.ends) {
+			delete(s.windows, key)
+		}
+	}
+	s.sweepAt = max(minSweepAt, 2*len(s.windows))
+}
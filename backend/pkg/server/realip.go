@@ -0,0 +1,112 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+const (
+	ForwardedForHeader = "X-Forwarded-For"
+	RealIPHeader       = "X-Real-IP"
+)
+
+// TrustedProxies are the networks of reverse proxies in front of the server,
+// only they are trusted to forward the IPs of clients.
+type TrustedProxies []netip.Prefix
+
+// ParseTrustedProxies parses proxies given as IPs or networks in CIDR
+// notation.
+func ParseTrustedProxies(proxies []string) (TrustedProxies, error) {
+	trusted := make(TrustedProxies, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("ParseTrustedProxies: %w", err)
+			}
+			trusted = append(trusted, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("ParseTrustedProxies: %w", err)
+		}
+		trusted = append(trusted, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return trusted, nil
+}
+
+func (p TrustedProxies) trusts(addr netip.Addr) bool {
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// realIPMiddleware sets RemoteAddr to the IP of the client. Requests from
+// trusted proxies are from the last IP in X-Forwarded-For not of a trusted
+// proxy, or from X-Real-IP. Others can't spoof their IP with the headers.
+func realIPMiddleware(proxies TrustedProxies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if client, ok := proxies.forwardedClient(r); ok {
+				r.RemoteAddr = client.String()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedClient returns the client IP forwarded by a trusted proxy, false
+// if the request isn't from one or it forwarded no valid IP.
+func (p TrustedProxies) forwardedClient(r *http.Request) (netip.Addr, bool) {
+	remote, err := netip.ParseAddr(clientIP(r))
+	if err != nil || !p.trusts(remote.Unmap()) {
+		return netip.Addr{}, false
+	}
+
+	// each proxy appends the IP it got the request from, the entries before
+	// the last untrusted one could be set by the client
+	var forwarded []string
+	for _, header := range r.Header.Values(ForwardedForHeader) {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+	client, found := netip.Addr{}, false
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			break
+		}
+		client, found = addr.Unmap(), true
+		if !p.trusts(client) {
+			break
+		}
+	}
+	if found {
+		return client, true
+	}
+
+	addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get(RealIPHeader)))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// clientIP returns the IP of the client, RemoteAddr is set to it by
+// realIPMiddleware.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/app"
+)
+
+const ReferralNotActiveCode = "referral.not-active"
+
+// CreateReferral implements api.ServerInterface.
+func (s Server) CreateReferral(w http.ResponseWriter, r *http.Request) {
+	req, decodeErr := Decode[api.NewReferral](w, r)
+	if decodeErr != nil {
+		encodeError(w, decodeErr)
+		return
+	}
+
+	referral, err := s.app.CreateReferral(r.Context(), req)
+	if err != nil {
+		if apiErr := referralApiError(err, nil); apiErr != nil {
+			encodeError(w, apiErr)
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "CreateReferral")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusCreated, referral)
+}
+
+// Referral implements api.ServerInterface.
+func (s Server) Referral(w http.ResponseWriter, r *http.Request, referralId api.ReferralId) {
+	referral, err := s.app.ReferralById(r.Context(), referralId)
+	if err != nil {
+		if apiErr := referralApiError(err, &referralId); apiErr != nil {
+			encodeError(w, apiErr)
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "Referral")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusOK, referral)
+}
+
+// CancelReferral implements api.ServerInterface.
+func (s Server) CancelReferral(w http.ResponseWriter, r *http.Request, referralId api.ReferralId) {
+	referral, err := s.app.CancelReferral(r.Context(), referralId)
+	if err != nil {
+		if apiErr := referralApiError(err, &referralId); apiErr != nil {
+			encodeError(w, apiErr)
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "CancelReferral")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusOK, referral)
+}
+
+// PatientReferrals implements api.ServerInterface.
+func (s Server) PatientReferrals(w http.ResponseWriter, r *http.Request, patientId api.PatientId) {
+	referrals, err := s.app.PatientReferrals(r.Context(), patientId)
+	if err != nil {
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "PatientReferrals")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusOK, api.Referrals{Referrals: referrals})
+}
+
+// referralApiError maps referral errors, also of booking an appointment
+// against a referral.
+func referralApiError(err error, referralId *api.ReferralId) *ApiError {
+	var valErr *app.ValidationError
+	switch {
+	case errors.As(err, &valErr):
+		return fromValidationError(valErr)
+	case referralId != nil && errors.Is(err, app.ErrNotFound):
+		return notFoundId("Referral", *referralId)
+	case errors.Is(err, app.ErrForbidden):
+		return forbidden("Only the referring doctor can do this")
+	case errors.Is(err, app.ErrReferralNotActive):
+		return &ApiError{
+			ErrorDetail: api.ErrorDetail{
+				Code:   ReferralNotActiveCode,
+				Title:  "Conflict",
+				Detail: "Referral is no longer active",
+				Status: http.StatusConflict,
+			},
+		}
+	}
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/go-chi/httplog/v2"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/Nesquiko/wac/pkg/api"
 	"github.com/Nesquiko/wac/pkg/app"
@@ -21,7 +22,7 @@ func Run(ctx context.Context) error {
 	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
 	defer cancel()
 
-	cfg, err := loadConfig()
+	cfg, err := LoadConfig()
 	if err != nil {
 		slog.Error("failed to read config", slog.String("error", err.Error()))
 		os.Exit(1)
@@ -37,20 +38,67 @@ func Run(ctx context.Context) error {
 	httpLogger.Info("loaded timezone", slog.String("tz", loc.String()))
 	time.Local = loc
 
+	shutdownTracing, err := setupTracing(ctx, cfg)
+	if err != nil {
+		slog.Error("failed to set up tracing", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	defer func() {
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer shutdownCancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			slog.Error("error shutting down tracing", slog.String("error", err.Error()))
+		}
+	}()
+
 	db, err := data.ConnectMongo(ctx, cfg.MongoURI(), cfg.Mongo.Db)
 	if err != nil {
 		slog.Error("failed to connect to database", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 
+	stats := data.NewStatsCollector(db)
+	prometheus.MustRegister(stats)
+	defer prometheus.Unregister(stats)
+
 	spec, err := api.GetSwagger()
 	if err != nil {
 		slog.Error("failed to load OpenApi spec", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 
-	app := app.New(db)
-	srv := NewServer(app, spec, httpLogger)
+	var appDb data.Db = db
+	var cachedDb *data.CachedDb
+	if cfg.Cache.Enabled {
+		cachedDb = data.NewCachedDb(db, data.NewLRUCache(cfg.Cache.Size), cfg.Cache.TTL)
+		appDb = cachedDb
+		slog.Info(
+			"caching enabled",
+			slog.Int("size", cfg.Cache.Size),
+			slog.Duration("ttl", cfg.Cache.TTL),
+		)
+	}
+
+	if cfg.Integrity.Interval > 0 {
+		go runIntegrityJob(ctx, db, cachedDb, cfg.Integrity.Interval, cfg.Integrity.Repair)
+	}
+
+	proxies, err := ParseTrustedProxies(cfg.App.TrustedProxies)
+	if err != nil {
+		slog.Error("failed to parse trusted proxies", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	app := app.Traced(app.New(appDb, cfg.RateLimit.MaxRequested))
+	var limiter *RateLimiter
+	if cfg.RateLimit.Enabled {
+		limiter = NewRateLimiter(cfg.RateLimit, NewMemoryRateLimitStore())
+	}
+	var idempotency *Idempotency
+	if cfg.Idempotency.Enabled {
+		idempotency = NewIdempotency(cfg.Idempotency, db)
+	}
+	srv := NewServer(app, spec, httpLogger, proxies, limiter, idempotency)
 
 	httpServer := &http.Server{
 		Addr:    net.JoinHostPort(cfg.App.Host, cfg.App.Port),
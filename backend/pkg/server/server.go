@@ -27,7 +27,8 @@ const (
 )
 
 type Server struct {
-	app app.App
+	app     app.App
+	limiter *RateLimiter
 }
 
 type ApiError struct {
@@ -38,21 +39,37 @@ func (e *ApiError) Error() string {
 	return fmt.Sprintf("error %q, status %d", e.Title, e.Status)
 }
 
-func NewServer(app app.App, spec *openapi3.T, middlewareLogger *httplog.Logger) http.Handler {
+// NewServer returns the handler of the API, limiter may be nil to not limit
+// requests and idempotency nil to ignore idempotency keys. Client IPs are
+// taken from forwarding headers only of requests from proxies.
+func NewServer(
+	app app.App,
+	spec *openapi3.T,
+	middlewareLogger *httplog.Logger,
+	proxies TrustedProxies,
+	limiter *RateLimiter,
+	idempotency *Idempotency,
+) http.Handler {
 	r := chi.NewMux()
 	r.Use(heartbeat())
 	r.Use(optionsMiddleware)
-	srv := Server{app: app}
+	monitoringHandler := monitoring(app)
+	r.Handle(MetricsPath, monitoringHandler)
+	r.Handle(LivenessPath, monitoringHandler)
+	r.Handle(ReadinessPath, monitoringHandler)
+	r.Mount(FhirBasePath, fhirRouter(app, middlewareLogger, proxies))
+	srv := Server{app: app, limiter: limiter}
 
 	validationOpts := OapiValidationOptions{
 		spec:         spec,
 		errorHandler: validationErrorHandler,
 	}
 
-	return api.HandlerWithOptions(srv, api.ChiServerOptions{
+	middlewares := middleware(app, middlewareLogger, validationOpts, proxies, limiter, idempotency)
+	return traced(api.HandlerWithOptions(srv, api.ChiServerOptions{
 		BaseURL:     "/api",
 		BaseRouter:  r,
-		Middlewares: middleware(middlewareLogger, validationOpts),
+		Middlewares: middlewares,
 		ErrorHandlerFunc: func(w http.ResponseWriter, r *http.Request, err error) {
 			var invalidParamErr *api.InvalidParamFormatError
 			var requiredParamError *api.RequiredParamError
@@ -80,7 +97,7 @@ func NewServer(app app.App, spec *openapi3.T, middlewareLogger *httplog.Logger)
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 			}
 		},
-	})
+	}))
 }
 
 func validationErrorHandler(w http.ResponseWriter, message string, statusCode int) {
@@ -207,6 +224,19 @@ func notFound(resoure, id string) *ApiError {
 	}
 }
 
+const ForbiddenCode = "forbidden"
+
+func forbidden(detail string) *ApiError {
+	return &ApiError{
+		ErrorDetail: api.ErrorDetail{
+			Code:   ForbiddenCode,
+			Title:  "Forbidden",
+			Detail: detail,
+			Status: http.StatusForbidden,
+		},
+	}
+}
+
 func fromValidationError(e *app.ValidationError) *ApiError {
 	return &ApiError{
 		ErrorDetail: api.ErrorDetail{
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/app"
+)
+
+const (
+	bookingForbiddenDetail           = "Only the patient, a receptionist or an administrator can book for the patient"
+	staffBookingForbiddenDetail      = "Only a receptionist or an administrator can book on behalf of patients"
+	cancellationForbiddenDetail      = "Only the patient, the assigned doctor, a receptionist or an administrator can cancel"
+	manageResourcesForbiddenDetail   = "Only an administrator can manage resources and locations"
+	manageStaffForbiddenDetail       = "Only an administrator can manage staff"
+	manageClinicsForbiddenDetail     = "Only an administrator can create clinics"
+	searchPatientsForbiddenDetail    = "Only doctors and clinic staff can search patients"
+	editProfileForbiddenDetail       = "Only the patient, a receptionist or an administrator can update the profile"
+	editDoctorProfileForbiddenDetail = "Only the doctor or an administrator can update the profile"
+	patientDataForbiddenDetail       = "Only the patient or an administrator can manage the data"
+)
+
+// CreateStaff implements api.ServerInterface.
+func (s Server) CreateStaff(w http.ResponseWriter, r *http.Request) {
+	req, decodeErr := Decode[api.NewStaff](w, r)
+	if decodeErr != nil {
+		encodeError(w, decodeErr)
+		return
+	}
+
+	staff, err := s.app.CreateStaff(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, app.ErrForbidden):
+			encodeError(w, forbidden(manageStaffForbiddenDetail))
+			return
+		case errors.Is(err, app.ErrDuplicateEmail):
+			encodeError(w, &ApiError{
+				ErrorDetail: api.ErrorDetail{
+					Code:   "staff.email-exists",
+					Title:  "Conflict",
+					Detail: fmt.Sprintf("A staff member with email %q already exists.", req.Email),
+					Status: http.StatusConflict,
+				},
+			})
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "CreateStaff")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusCreated, staff)
+}
+
+// Staff implements api.ServerInterface.
+func (s Server) Staff(w http.ResponseWriter, r *http.Request) {
+	staff, err := s.app.ClinicStaff(r.Context())
+	if err != nil {
+		if errors.Is(err, app.ErrForbidden) {
+			encodeError(w, forbidden(manageStaffForbiddenDetail))
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "Staff")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusOK, api.StaffList{Staff: staff})
+}
+
+// StaffMember implements api.ServerInterface.
+func (s Server) StaffMember(w http.ResponseWriter, r *http.Request, staffId api.StaffId) {
+	staff, err := s.app.StaffById(r.Context(), staffId)
+	if err != nil {
+		switch {
+		case errors.Is(err, app.ErrForbidden):
+			encodeError(w, forbidden(manageStaffForbiddenDetail))
+			return
+		case errors.Is(err, app.ErrNotFound):
+			encodeError(w, notFoundId("Staff member", staffId))
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "StaffMember")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusOK, staff)
+}
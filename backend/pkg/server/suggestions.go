@@ -0,0 +1,31 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/app"
+)
+
+// SuggestAppointments implements api.ServerInterface.
+func (s Server) SuggestAppointments(
+	w http.ResponseWriter,
+	r *http.Request,
+	params api.SuggestAppointmentsParams,
+) {
+	suggestions, err := s.app.SuggestAppointments(r.Context(), params)
+	if err != nil {
+		var valErr *app.ValidationError
+		if errors.As(err, &valErr) {
+			encodeError(w, fromValidationError(valErr))
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "SuggestAppointments")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusOK, api.AppointmentSuggestions{Suggestions: suggestions})
+}
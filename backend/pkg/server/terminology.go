@@ -0,0 +1,19 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/Nesquiko/wac/pkg/api"
+)
+
+const defaultIcd10Limit = 20
+
+// SearchIcd10 implements api.ServerInterface.
+func (s Server) SearchIcd10(w http.ResponseWriter, r *http.Request, params api.SearchIcd10Params) {
+	limit := defaultIcd10Limit
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+
+	encode(w, http.StatusOK, api.Icd10Codes{Codes: s.app.SearchIcd10(params.Q, limit)})
+}
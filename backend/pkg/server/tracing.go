@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httplog/v2"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOtlp   = "otlp"
+
+	ServiceName = "wac"
+)
+
+// setupTracing sets the global W3C trace context propagator and the global
+// tracer provider exporting spans as configured. Without an exporter spans
+// aren't recorded, but incoming trace ids still reach the request logs. The
+// returned func flushes the spans and stops the provider.
+func setupTracing(ctx context.Context, cfg *Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Tracing.Exporter {
+	case TracingExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case TracingExporterStdout:
+		exporter, err = stdouttrace.New()
+	case TracingExporterOtlp:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Tracing.Endpoint)}
+		if cfg.Tracing.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("setupTracing: unknown exporter %q", cfg.Tracing.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("setupTracing: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(ServiceName),
+		)),
+		sdktrace.WithSampler(sdktrace.ParentBased(
+			sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// traced starts a span of every request, continuing the trace of the incoming
+// traceparent header. Monitoring requests aren't traced.
+func traced(handler http.Handler) http.Handler {
+	return otelhttp.NewHandler(
+		handler,
+		ServiceName,
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return r.URL.Path != MetricsPath && !strings.HasPrefix(r.URL.Path, "/api/monitoring/")
+		}),
+	)
+}
+
+// spanNameMiddleware names the request's span after its OpenAPI operation.
+func spanNameMiddleware(spec *openapi3.T) func(http.Handler) http.Handler {
+	operations := operationIds(spec)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(operations.of(r))
+			span.SetAttributes(semconv.HTTPRoute(chi.RouteContext(r.Context()).RoutePattern()))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// traceLogMiddleware adds the trace and span ids of the request to its log.
+func traceLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+			httplog.LogEntrySetField(r.Context(), "traceId", slog.StringValue(sc.TraceID().String()))
+			httplog.LogEntrySetField(r.Context(), "spanId", slog.StringValue(sc.SpanID().String()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/app"
+)
+
+const (
+	VisitNoteLockedCode         = "visit-note.locked"
+	AppointmentNotScheduledCode = "appointment.not-scheduled"
+)
+
+// VisitNote implements api.ServerInterface.
+func (s Server) VisitNote(w http.ResponseWriter, r *http.Request, appointmentId api.AppointmentId) {
+	note, err := s.app.VisitNote(r.Context(), appointmentId)
+	if err != nil {
+		if errors.Is(err, app.ErrNotFound) {
+			encodeError(w, notFoundId("Visit note of appointment", appointmentId))
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "VisitNote")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusOK, note)
+}
+
+// SaveVisitNote implements api.ServerInterface.
+func (s Server) SaveVisitNote(
+	w http.ResponseWriter,
+	r *http.Request,
+	appointmentId api.AppointmentId,
+) {
+	req, decodeErr := Decode[api.UpdateVisitNote](w, r)
+	if decodeErr != nil {
+		encodeError(w, decodeErr)
+		return
+	}
+
+	note, err := s.app.SaveVisitNote(r.Context(), appointmentId, req)
+	if err != nil {
+		if apiErr := visitNoteApiError(err, appointmentId); apiErr != nil {
+			encodeError(w, apiErr)
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "SaveVisitNote")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusOK, note)
+}
+
+// CompleteAppointment implements api.ServerInterface.
+func (s Server) CompleteAppointment(
+	w http.ResponseWriter,
+	r *http.Request,
+	appointmentId api.AppointmentId,
+) {
+	appointment, err := s.app.CompleteAppointment(r.Context(), appointmentId)
+	if err != nil {
+		if apiErr := visitNoteApiError(err, appointmentId); apiErr != nil {
+			encodeError(w, apiErr)
+			return
+		}
+		slog.Error(UnexpectedError, "error", err.Error(), "where", "CompleteAppointment")
+		encodeError(w, internalServerError())
+		return
+	}
+
+	encode(w, http.StatusOK, appointment)
+}
+
+func visitNoteApiError(err error, appointmentId api.AppointmentId) *ApiError {
+	switch {
+	case errors.Is(err, app.ErrNotFound):
+		return notFoundId("Appointment", appointmentId)
+	case errors.Is(err, app.ErrForbidden):
+		return forbidden("Only the doctor assigned to the appointment can do this")
+	case errors.Is(err, app.ErrVisitNoteLocked):
+		return &ApiError{
+			ErrorDetail: api.ErrorDetail{
+				Code:   VisitNoteLockedCode,
+				Title:  "Conflict",
+				Detail: "Visit note was signed and can't be changed",
+				Status: http.StatusConflict,
+			},
+		}
+	case errors.Is(err, app.ErrNotScheduled):
+		return &ApiError{
+			ErrorDetail: api.ErrorDetail{
+				Code:   AppointmentNotScheduledCode,
+				Title:  "Conflict",
+				Detail: "Appointment is not scheduled",
+				Status: http.StatusConflict,
+			},
+		}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+// Package terminology provides the medical code systems bundled with the
+// application.
+package terminology
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed icd10.tsv
+var icd10File string
+
+// ICD10 returns the bundled ICD-10 code system, loaded on first use.
+var ICD10 = sync.OnceValue(func() *CodeSystem {
+	system, err := parse(icd10File)
+	if err != nil {
+		panic(fmt.Sprintf("terminology: bundled ICD-10 file is invalid: %s", err))
+	}
+	return system
+})
+
+type Code struct {
+	Code    string
+	Display string
+}
+
+// CodeSystem is an immutable set of codes searchable by code and display.
+type CodeSystem struct {
+	codes  []Code
+	byCode map[string]int
+	// words of each code's display, lower cased
+	words [][]string
+}
+
+// parse reads lines of code<TAB>display, empty lines and lines starting with
+// # are skipped.
+func parse(file string) (*CodeSystem, error) {
+	system := &CodeSystem{byCode: make(map[string]int)}
+
+	scanner := bufio.NewScanner(strings.NewReader(file))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		code, display, ok := strings.Cut(text, "\t")
+		code, display = strings.TrimSpace(code), strings.TrimSpace(display)
+		if !ok || code == "" || display == "" {
+			return nil, fmt.Errorf("line %d: expected code and display separated by a tab", line)
+		}
+		key := codeKey(code)
+		if _, found := system.byCode[key]; found {
+			return nil, fmt.Errorf("line %d: duplicate code %q", line, code)
+		}
+
+		system.byCode[key] = len(system.codes)
+		system.codes = append(system.codes, Code{Code: code, Display: display})
+		system.words = append(system.words, words(display))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return system, nil
+}
+
+// Lookup finds the code, ignoring case and the dot, so "j209" finds "J20.9".
+func (s *CodeSystem) Lookup(code string) (Code, bool) {
+	i, ok := s.byCode[codeKey(code)]
+	if !ok {
+		return Code{}, false
+	}
+	return s.codes[i], true
+}
+
+// match ranks, from the best
+const (
+	matchExactCode = iota
+	matchCodePrefix
+	matchWordPrefix
+	matchFuzzy
+	noMatch
+)
+
+// Search returns at most limit codes matching the query, best matches first.
+// A code matches when the query is a prefix of it, or when every word of the
+// query is a prefix of a word in its display, allowing for typos in longer
+// words.
+func (s *CodeSystem) Search(query string, limit int) []Code {
+	key := codeKey(query)
+	queryWords := words(query)
+	if key == "" || limit <= 0 {
+		return []Code{}
+	}
+
+	type ranked struct {
+		index int
+		rank  int
+	}
+	matches := make([]ranked, 0)
+	for i, code := range s.codes {
+		rank := s.rank(i, code, key, queryWords)
+		if rank != noMatch {
+			matches = append(matches, ranked{index: i, rank: rank})
+		}
+	}
+
+	slices.SortStableFunc(matches, func(a, b ranked) int {
+		if a.rank != b.rank {
+			return a.rank - b.rank
+		}
+		return strings.Compare(s.codes[a.index].Code, s.codes[b.index].Code)
+	})
+
+	result := make([]Code, 0, min(limit, len(matches)))
+	for _, m := range matches[:min(limit, len(matches))] {
+		result = append(result, s.codes[m.index])
+	}
+	return result
+}
+
+func (s *CodeSystem) rank(i int, code Code, key string, queryWords []string) int {
+	codeKey := codeKey(code.Code)
+	switch {
+	case codeKey == key:
+		return matchExactCode
+	case strings.HasPrefix(codeKey, key):
+		return matchCodePrefix
+	case len(queryWords) == 0:
+		return noMatch
+	case allMatch(queryWords, s.words[i], strings.HasPrefix):
+		return matchWordPrefix
+	case allMatch(queryWords, s.words[i], fuzzyPrefix):
+		return matchFuzzy
+	}
+	return noMatch
+}
+
+// allMatch reports whether every query word matches some of the words.
+func allMatch(queryWords, words []string, match func(word, query string) bool) bool {
+	for _, q := range queryWords {
+		if !slices.ContainsFunc(words, func(w string) bool { return match(w, q) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// fuzzyPrefix reports whether query is within a few edits of the word, or of
+// its beginning. Short queries must match exactly.
+func fuzzyPrefix(word, query string) bool {
+	allowed := allowedEdits(len(query))
+	if allowed == 0 {
+		return strings.HasPrefix(word, query)
+	}
+	if editDistance(word, query) <= allowed {
+		return true
+	}
+	if len(word) > len(query) {
+		return editDistance(word[:len(query)], query) <= allowed
+	}
+	return false
+}
+
+func allowedEdits(length int) int {
+	switch {
+	case length < 4:
+		return 0
+	case length < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance is the Levenshtein distance of a and b, in bytes.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func codeKey(code string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), ".", ""))
+}
+
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,97 @@
+package terminology
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+)
+
+func TestICD10_Loads(t *testing.T) {
+	system := ICD10()
+	assert.Greater(t, len(system.codes), 100)
+}
+
+func TestLookup(t *testing.T) {
+	system := ICD10()
+
+	for _, code := range []string{"J20.9", "j20.9", "J209", " I10 "} {
+		found, ok := system.Lookup(code)
+		assert.True(t, ok, "code %q", code)
+		assert.NotEmpty(t, found.Display, "code %q", code)
+	}
+
+	found, ok := system.Lookup("j209")
+	require.True(t, ok)
+	assert.Equal(t, Code{Code: "J20.9", Display: "Acute bronchitis, unspecified"}, found)
+
+	for _, code := range []string{"", "J20", "X99.9", "bronchitis"} {
+		_, ok := system.Lookup(code)
+		assert.False(t, ok, "code %q", code)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	system, err := parse(`
+# test codes
+J20.9	Acute bronchitis, unspecified
+J40	Bronchitis, not specified as acute or chronic
+J45.0	Predominantly allergic asthma
+J45.9	Asthma, unspecified
+I10	Essential (primary) hypertension
+`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		query string
+		codes []string
+	}{
+		{name: "exact code first", query: "J40", codes: []string{"J40"}},
+		{name: "code prefix", query: "j45", codes: []string{"J45.0", "J45.9"}},
+		{name: "code with dot", query: "J45.", codes: []string{"J45.0", "J45.9"}},
+		{name: "word prefix", query: "bronch", codes: []string{"J20.9", "J40"}},
+		{name: "all words", query: "acute bronch", codes: []string{"J20.9", "J40"}},
+		{name: "any order", query: "asthma allerg", codes: []string{"J45.0"}},
+		{name: "typo", query: "hypertenson", codes: []string{"I10"}},
+		{name: "typo in prefix", query: "astm", codes: []string{"J45.0", "J45.9"}},
+		{name: "short words exact", query: "atm", codes: []string{}},
+		{name: "blank", query: "  ", codes: []string{}},
+		{name: "no match", query: "fracture", codes: []string{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			found := system.Search(tc.query, 10)
+			codes := make([]string, len(found))
+			for i, c := range found {
+				codes[i] = c.Code
+			}
+			assert.Equal(t, tc.codes, codes)
+		})
+	}
+}
+
+func TestSearch_RanksCodesBeforeDisplays(t *testing.T) {
+	system, err := parse("A10\tSomething about b20\nB20\tOther\nB20.1\tMore")
+	require.NoError(t, err)
+
+	found := system.Search("b20", 10)
+	require.Len(t, found, 3)
+	assert.Equal(t, "B20", found[0].Code)
+	assert.Equal(t, "B20.1", found[1].Code)
+	assert.Equal(t, "A10", found[2].Code)
+}
+
+func TestSearch_Limit(t *testing.T) {
+	found := ICD10().Search("J", 5)
+	assert.Len(t, found, 5)
+	assert.Empty(t, ICD10().Search("J", 0))
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, file := range []string{"J20.9", "J20.9\t", "J20.9\tA\nj209\tB"} {
+		_, err := parse(file)
+		assert.Error(t, err, "file %q", file)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	netUrl "net/url"
+	"sync"
 	"testing"
 	"time"
 
@@ -270,6 +271,7 @@ func TestDoctorsCalendar(t *testing.T) {
 
 	patientEmail := fmt.Sprintf("test.doctor.calendar.%s@patient.com", uuid.NewString())
 	patient := mustCreatePatient(t, newPatient(patientEmail))
+	asDoctor := actorHeaders(http.Header{}, doctor.Id, api.UserRoleDoctor)
 
 	appointmentIds := make(map[uuid.UUID]bool)
 	appointmentTimes := make(map[uuid.UUID]time.Time)
@@ -282,6 +284,11 @@ func TestDoctorsCalendar(t *testing.T) {
 			AppointmentDateTime: appointmentTime,
 		}
 		createdAppointment := mustCreateAppointment(t, newAppointmentReq)
+		// accepted, so the patient stays under the cap of requested appointments
+		res := mustSendWithHeaders(t, http.MethodPost,
+			fmt.Sprintf("%s/appointments/%s", ServerUrl, *createdAppointment.Id), asDoctor,
+			api.AppointmentDecision{Action: api.Accept}, nil)
+		require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
 		appointmentIds[*createdAppointment.Id] = true
 		appointmentTimes[*createdAppointment.Id] = appointmentTime
 	}
@@ -326,6 +333,177 @@ func TestDoctorsCalendar(t *testing.T) {
 	}
 }
 
+func TestDoctorsCalendar_FilterAndPagination(t *testing.T) {
+	t.Parallel()
+
+	doctorEmail := fmt.Sprintf("test.doctor.calendar.%s@doctor.com", uuid.NewString())
+	doctor := mustCreateDoctor(t, newDoctor(doctorEmail))
+	patientEmail := fmt.Sprintf("test.doctor.calendar.%s@patient.com", uuid.NewString())
+	patient := mustCreatePatient(t, newPatient(patientEmail))
+	asDoctor := actorHeaders(http.Header{}, doctor.Id, api.UserRoleDoctor)
+
+	startDate := time.Now().Add(24 * time.Hour).Truncate(24 * time.Hour)
+	scheduled := make([]uuid.UUID, 0, 3)
+	for i := range 6 {
+		appt := mustCreateAppointment(t, api.NewAppointmentRequest{
+			PatientId:           patient.Id,
+			DoctorId:            doctor.Id,
+			AppointmentDateTime: startDate.Add(time.Duration(i) * 24 * time.Hour),
+		})
+		if i%2 == 0 {
+			continue
+		}
+		res := mustSendWithHeaders(t, http.MethodPost,
+			fmt.Sprintf("%s/appointments/%s", ServerUrl, *appt.Id), asDoctor,
+			api.AppointmentDecision{Action: api.Accept}, nil)
+		require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+		scheduled = append([]uuid.UUID{*appt.Id}, scheduled...)
+	}
+
+	calendarUrl := fmt.Sprintf(
+		"%s/doctors/%s/calendar?from=%s&status=scheduled&sort=-appointmentDateTime&limit=2",
+		ServerUrl,
+		doctor.Id,
+		netUrl.QueryEscape(startDate.Format("2006-01-02")),
+	)
+	var firstPage api.DoctorCalendar
+	res := mustSendWithHeaders(t, http.MethodGet, calendarUrl, nil, nil, &firstPage)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Equal(t, 3, firstPage.Pagination.Total, "Only scheduled appointments are counted")
+	require.NotNil(t, firstPage.Pagination.NextCursor)
+
+	var lastPage api.DoctorCalendar
+	res = mustSendWithHeaders(t, http.MethodGet,
+		calendarUrl+"&cursor="+*firstPage.Pagination.NextCursor, nil, nil, &lastPage)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Nil(t, lastPage.Pagination.NextCursor, "Last page has no next cursor")
+
+	ids := make([]uuid.UUID, 0, 3)
+	for _, appt := range append(*firstPage.Appointments, *lastPage.Appointments...) {
+		assert.Equal(t, api.Scheduled, appt.Status)
+		ids = append(ids, appt.Id)
+	}
+	assert.Equal(t, scheduled, ids, "Latest appointments come first")
+}
+
+func TestRequestAppointment_TooManyRequested(t *testing.T) {
+	t.Parallel()
+
+	doctorEmail := fmt.Sprintf("test.too.many.requested.%s@doctor.com", uuid.NewString())
+	doctor := mustCreateDoctor(t, newDoctor(doctorEmail))
+	patientEmail := fmt.Sprintf("test.too.many.requested.%s@patient.com", uuid.NewString())
+	patient := mustCreatePatient(t, newPatient(patientEmail))
+
+	startDate := time.Now().Add(24 * time.Hour).Truncate(24 * time.Hour)
+	request := func(day int) api.NewAppointmentRequest {
+		return api.NewAppointmentRequest{
+			PatientId:           patient.Id,
+			DoctorId:            doctor.Id,
+			AppointmentDateTime: startDate.Add(time.Duration(day) * 24 * time.Hour),
+		}
+	}
+	for i := range server.RateLimitMaxRequestedDefault {
+		mustCreateAppointment(t, request(i))
+	}
+
+	res := mustSendWithHeaders(t, http.MethodPost, ServerUrl+"/appointments", nil,
+		request(server.RateLimitMaxRequestedDefault), nil)
+	require.Equal(t, http.StatusConflict, res.StatusCode, "Expected '409 Conflict' status code")
+
+	var calendar api.DoctorCalendar
+	res = mustSendWithHeaders(t, http.MethodGet,
+		fmt.Sprintf("%s/doctors/%s/calendar?from=%s&status=requested", ServerUrl, doctor.Id,
+			netUrl.QueryEscape(startDate.Format("2006-01-02"))), nil, nil, &calendar)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Equal(t, server.RateLimitMaxRequestedDefault, calendar.Pagination.Total,
+		"The rejected request wasn't created")
+}
+
+func TestRequestAppointment_ConcurrentTooManyRequested(t *testing.T) {
+	t.Parallel()
+
+	doctorEmail := fmt.Sprintf("test.concurrent.requested.%s@doctor.com", uuid.NewString())
+	doctor := mustCreateDoctor(t, newDoctor(doctorEmail))
+	patientEmail := fmt.Sprintf("test.concurrent.requested.%s@patient.com", uuid.NewString())
+	patient := mustCreatePatient(t, newPatient(patientEmail))
+
+	startDate := time.Now().Add(24 * time.Hour).Truncate(24 * time.Hour)
+	requests := 2 * server.RateLimitMaxRequestedDefault
+	statuses := make([]int, requests)
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := mustSendWithHeaders(t, http.MethodPost, ServerUrl+"/appointments", nil,
+				api.NewAppointmentRequest{
+					PatientId:           patient.Id,
+					DoctorId:            doctor.Id,
+					AppointmentDateTime: startDate.Add(time.Duration(i) * 24 * time.Hour),
+				}, nil)
+			statuses[i] = res.StatusCode
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for _, status := range statuses {
+		if status == http.StatusCreated {
+			created++
+			continue
+		}
+		assert.Equal(t, http.StatusConflict, status, "Expected '409 Conflict' status code")
+	}
+	assert.Equal(t, server.RateLimitMaxRequestedDefault, created,
+		"Concurrent requests can't exceed the limit")
+}
+
+func TestRequestAppointment_RateLimitedPerClient(t *testing.T) {
+	// runs after the restore of the environment, so the next tests aren't limited
+	t.Cleanup(func() { restartServer(t) })
+	t.Setenv("WAC_RATELIMIT_ENABLED", "true")
+	t.Setenv("WAC_RATELIMIT_BOOKING", "2")
+	restartServer(t)
+
+	doctorEmail := fmt.Sprintf("test.booking.limited.%s@doctor.com", uuid.NewString())
+	doctor := mustCreateDoctor(t, newDoctor(doctorEmail))
+	startDate := time.Now().Add(24 * time.Hour).Truncate(24 * time.Hour)
+
+	// every request acts as another user and claims another client IP, the
+	// server isn't behind a trusted proxy so the IP can't be spoofed
+	request := func(day int) *http.Response {
+		patientEmail := fmt.Sprintf("test.booking.limited.%s@patient.com", uuid.NewString())
+		patient := mustCreatePatient(t, newPatient(patientEmail))
+		headers := actorHeaders(http.Header{
+			server.ForwardedForHeader: {fmt.Sprintf("203.0.113.%d", day+1)},
+			server.RealIPHeader:       {fmt.Sprintf("198.51.100.%d", day+1)},
+			"Origin":                  {"http://localhost:3333"},
+		}, patient.Id, api.UserRolePatient)
+		return mustSendWithHeaders(t, http.MethodPost, ServerUrl+"/appointments", headers,
+			api.NewAppointmentRequest{
+				PatientId:           patient.Id,
+				DoctorId:            doctor.Id,
+				AppointmentDateTime: startDate.Add(time.Duration(day) * 24 * time.Hour),
+			}, nil)
+	}
+
+	for day := range 2 {
+		res := request(day)
+		require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	}
+
+	res := request(2)
+	require.Equal(
+		t,
+		http.StatusTooManyRequests,
+		res.StatusCode,
+		"Changing the user or forwarded IPs doesn't reset the limit",
+	)
+	assert.NotEmpty(t, res.Header.Get(server.RetryAfterHeader), "Expected Retry-After header")
+	assert.Contains(t, res.Header.Get("Access-Control-Expose-Headers"), server.RetryAfterHeader,
+		"Browsers can read when to retry")
+}
+
 func TestDecideAppointmentApprove(t *testing.T) {
 	t.Parallel()
 
@@ -272,3 +272,51 @@ func TestCreateDoctor_Conflict(t *testing.T) {
 		"Error detail should mention the conflicting email",
 	)
 }
+
+func TestLoginUser_LockedOut(t *testing.T) {
+	// runs after the restore of the environment, so the next tests aren't limited
+	t.Cleanup(func() { restartServer(t) })
+	t.Setenv("WAC_RATELIMIT_ENABLED", "true")
+	t.Setenv("WAC_RATELIMIT_LOGIN_FAILURES", "3")
+	restartServer(t)
+
+	patientEmail := fmt.Sprintf("test.login.locked.%s@patient.com", uuid.NewString())
+	patient := mustCreatePatient(t, newPatient(patientEmail))
+
+	login := func(email types.Email) *http.Response {
+		body, err := json.Marshal(api.LoginUserJSONRequestBody{
+			Email: email,
+			Role:  api.UserRolePatient,
+		})
+		require.NoError(t, err, "Failed to marshal login request")
+		res, err := http.Post(
+			ServerUrl+"/auth/login",
+			server.ApplicationJSON,
+			bytes.NewBuffer(body),
+		)
+		require.NoError(t, err, "http.Post failed for /login")
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+
+	for range 3 {
+		missing := types.Email(fmt.Sprintf("test.login.missing.%s@patient.com", uuid.NewString()))
+		res := login(missing)
+		require.Equal(t, http.StatusNotFound, res.StatusCode, "Expected '404 Not Found'")
+	}
+
+	res := login(patient.Email)
+	require.Equal(
+		t,
+		http.StatusTooManyRequests,
+		res.StatusCode,
+		"Client is locked out even for known accounts",
+	)
+	assert.NotEmpty(t, res.Header.Get(server.RetryAfterHeader), "Expected Retry-After header")
+
+	var errorResponse api.ErrorDetail
+	err := json.NewDecoder(res.Body).Decode(&errorResponse)
+	require.NoError(t, err, "Failed to decode error response body")
+	assert.Equal(t, server.LoginLockedOutCode, errorResponse.Code)
+	assert.Equal(t, http.StatusTooManyRequests, errorResponse.Status)
+}
@@ -0,0 +1,107 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oapi-codegen/runtime/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+
+	"github.com/Nesquiko/wac/pkg/api"
+)
+
+func TestStaffBooking(t *testing.T) {
+	t.Parallel()
+
+	clinic := mustCreateClinic(t, "Booking clinic")
+	inClinic := clinicHeaders(clinic.Id)
+	admin := mustProvisionAdmin(t, clinic.Id)
+	receptionist := mustCreateStaff(t, actorHeaders(inClinic, admin.Id, api.UserRoleAdmin),
+		api.StaffRoleReceptionist)
+	asReceptionist := actorHeaders(inClinic, receptionist.Id, api.UserRoleReceptionist)
+
+	var doctor api.Doctor
+	res := mustSendWithHeaders(t, http.MethodPost, fmt.Sprintf("%s/auth/register", ServerUrl),
+		inClinic, newDoctor(fmt.Sprintf("test.booking.%s@doctor.com", uuid.NewString())), &doctor)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+
+	bookingsUrl := fmt.Sprintf("%s/appointments/bookings", ServerUrl)
+	email := types.Email(fmt.Sprintf("test.booking.%s@patient.com", uuid.NewString()))
+	at := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	booking := api.StaffBooking{
+		Patient: api.BookingPatient{
+			Email:     &email,
+			FirstName: asPtr("Jana"),
+			LastName:  asPtr("Nováková"),
+		},
+		DoctorId:            doctor.Id,
+		AppointmentDateTime: at,
+		Reason:              asPtr("Called the clinic about back pain"),
+		Schedule:            asPtr(true),
+	}
+
+	res = mustSendWithHeaders(t, http.MethodPost, bookingsUrl, inClinic, booking, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Anonymous requests can't book")
+
+	var booked api.DoctorAppointment
+	res = mustSendWithHeaders(t, http.MethodPost, bookingsUrl, asReceptionist, booking, &booked)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	assert.Equal(t, api.Scheduled, booked.Status, "Booking must skip the request")
+	assert.Equal(t, email, booked.Patient.Email, "Patient must be registered inline")
+	require.NotNil(t, booked.BookedBy)
+	assert.Equal(t, receptionist.Id, booked.BookedBy.Id)
+	assert.Equal(t, api.UserRoleReceptionist, booked.BookedBy.Role)
+	require.NotNil(t, booked.BookedBy.FirstName)
+	assert.Equal(t, receptionist.FirstName, *booked.BookedBy.FirstName)
+	patient := booked.Patient
+	asPatient := actorHeaders(inClinic, patient.Id, api.UserRolePatient)
+
+	res = mustSendWithHeaders(t, http.MethodPost, bookingsUrl, asPatient, booking, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Patients book with RequestAppointment")
+	res = mustSendWithHeaders(t, http.MethodPost, bookingsUrl, asReceptionist, booking, nil)
+	assert.Equal(t, http.StatusConflict, res.StatusCode, "Doctor is already booked then")
+
+	unknown := booking
+	unknown.AppointmentDateTime = at.Add(2 * time.Hour)
+	unknown.Patient = api.BookingPatient{
+		Email: asPtr(types.Email(fmt.Sprintf("test.booking.%s@patient.com", uuid.NewString()))),
+	}
+	res = mustSendWithHeaders(t, http.MethodPost, bookingsUrl, asReceptionist, unknown, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Unknown patient needs names")
+
+	byEmail := booking
+	byEmail.AppointmentDateTime = at.Add(time.Hour)
+	byEmail.Patient = api.BookingPatient{Email: &email}
+	byEmail.Schedule = nil
+	var requested api.DoctorAppointment
+	res = mustSendWithHeaders(t, http.MethodPost, bookingsUrl, asReceptionist, byEmail, &requested)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	assert.Equal(t, patient.Id, requested.Patient.Id, "Patient must be found by email")
+	assert.Equal(t, api.Requested, requested.Status)
+
+	appointmentUrl := fmt.Sprintf("%s/appointments/%s", ServerUrl, *requested.Id)
+	cancellation := api.AppointmentCancellation{
+		By:     api.UserRoleReceptionist,
+		Reason: asPtr("Patient called to cancel"),
+	}
+	other := mustCreatePatient(t, newPatient(fmt.Sprintf("test.booking.%s@patient.com",
+		uuid.NewString())))
+	res = mustSendWithHeaders(t, http.MethodDelete, appointmentUrl,
+		actorHeaders(inClinic, other.Id, api.UserRolePatient), cancellation, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Other patient can't cancel")
+	res = mustSendWithHeaders(t, http.MethodDelete, appointmentUrl, asReceptionist, cancellation,
+		nil)
+	require.Equal(t, http.StatusNoContent, res.StatusCode, "Receptionist cancels for the patient")
+
+	var cancelled api.PatientAppointment
+	res = mustSendWithHeaders(t, http.MethodGet, fmt.Sprintf("%s/patients/%s/appointment/%s",
+		ServerUrl, patient.Id, *requested.Id), inClinic, nil, &cancelled)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Equal(t, api.Cancelled, cancelled.Status)
+}
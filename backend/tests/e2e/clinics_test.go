@@ -0,0 +1,218 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+	"github.com/Nesquiko/wac/pkg/server"
+)
+
+func TestClinicIsolation(t *testing.T) {
+	t.Parallel()
+
+	clinicA := mustCreateClinic(t, "Clinic A")
+	clinicB := mustCreateClinic(t, "Clinic B")
+	inA := clinicHeaders(clinicA.Id)
+	inB := clinicHeaders(clinicB.Id)
+
+	registerUrl := fmt.Sprintf("%s/auth/register", ServerUrl)
+	var doctor api.Doctor
+	res := mustSendWithHeaders(t, http.MethodPost, registerUrl, inA,
+		newDoctor(fmt.Sprintf("test.clinic.%s@doctor.com", uuid.NewString())), &doctor)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	var patient api.Patient
+	res = mustSendWithHeaders(t, http.MethodPost, registerUrl, inA,
+		newPatient(fmt.Sprintf("test.clinic.%s@patient.com", uuid.NewString())), &patient)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	require.NotNil(t, patient.ClinicIds)
+	assert.Equal(t, []uuid.UUID{clinicA.Id}, *patient.ClinicIds)
+
+	doctorUrl := fmt.Sprintf("%s/doctors/%s", ServerUrl, doctor.Id)
+	patientUrl := fmt.Sprintf("%s/patients/%s", ServerUrl, patient.Id)
+	res = mustSendWithHeaders(t, http.MethodGet, doctorUrl, inA, nil, nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode, "Doctor is visible in their clinic")
+	res = mustSendWithHeaders(t, http.MethodGet, doctorUrl, inB, nil, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "Doctor isn't visible in another clinic")
+	res = mustGetJson(t, doctorUrl, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "Doctor isn't visible in the default clinic")
+	res = mustSendWithHeaders(t, http.MethodGet, patientUrl,
+		actorHeaders(inB, doctor.Id, api.UserRoleDoctor), nil, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Doctor can't act in another clinic")
+	res = mustSendWithHeaders(t, http.MethodGet, fmt.Sprintf("%s/doctors", ServerUrl),
+		actorHeaders(inB, doctor.Id, api.UserRoleDoctor), nil, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Doctor can't list another clinic")
+	fhirPatientUrl := fmt.Sprintf("%s/fhir/Patient/%s", ServerUrl, patient.Id)
+	res = mustSendWithHeaders(t, http.MethodGet, fhirPatientUrl,
+		actorHeaders(inA, doctor.Id, api.UserRoleDoctor), nil, nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode, "Doctor reads FHIR of their clinic")
+	res = mustSendWithHeaders(t, http.MethodGet, fhirPatientUrl,
+		actorHeaders(inB, doctor.Id, api.UserRoleDoctor), nil, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Doctor can't read FHIR elsewhere")
+	res = mustSendWithHeaders(t, http.MethodGet, patientUrl, inB, nil, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "Patient isn't shared with another clinic")
+
+	var doctorsInB api.Doctors
+	res = mustSendWithHeaders(t, http.MethodGet, fmt.Sprintf("%s/doctors", ServerUrl), inB, nil,
+		&doctorsInB)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.False(t, slices.ContainsFunc(doctorsInB.Doctors, func(d api.Doctor) bool {
+		return d.Id == doctor.Id
+	}), "Doctors of another clinic aren't listed")
+
+	shareUrl := fmt.Sprintf("%s/clinics/%s", patientUrl, clinicB.Id)
+	res = mustSendWithHeaders(t, http.MethodPut, shareUrl,
+		actorHeaders(inA, doctor.Id, api.UserRoleDoctor), nil, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Only the patient can share")
+
+	patientActor := actorHeaders(inA, patient.Id, api.UserRolePatient)
+	res = mustSendWithHeaders(t, http.MethodPut,
+		fmt.Sprintf("%s/clinics/%s", patientUrl, uuid.New()), patientActor, nil, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "Clinic must exist")
+	var shared api.Patient
+	res = mustSendWithHeaders(t, http.MethodPut, shareUrl, patientActor, nil, &shared)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.NotNil(t, shared.ClinicIds)
+	assert.ElementsMatch(t, []uuid.UUID{clinicA.Id, clinicB.Id}, *shared.ClinicIds)
+
+	res = mustSendWithHeaders(t, http.MethodGet, patientUrl, inB, nil, nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode, "Shared patient is visible in another clinic")
+
+	notSharedUrl := fmt.Sprintf("%s/clinics/%s", patientUrl, data.DefaultClinicId)
+	res = mustSendWithHeaders(t, http.MethodDelete, notSharedUrl, patientActor, nil, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "Patient isn't shared with the clinic")
+	var unchanged api.Patient
+	res = mustSendWithHeaders(t, http.MethodGet, patientUrl, inA, nil, &unchanged)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Equal(t, shared.Version, unchanged.Version, "Patient wasn't modified")
+
+	res = mustSendWithHeaders(t, http.MethodDelete, shareUrl, patientActor, nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	res = mustSendWithHeaders(t, http.MethodGet, patientUrl, inB, nil, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "Unshared patient isn't visible")
+
+	lastUrl := fmt.Sprintf("%s/clinics/%s", patientUrl, clinicA.Id)
+	res = mustSendWithHeaders(t, http.MethodDelete, lastUrl, patientActor, nil, nil)
+	assert.Equal(t, http.StatusConflict, res.StatusCode, "Patient can't leave their last clinic")
+}
+
+func TestClinicHeader_Invalid(t *testing.T) {
+	t.Parallel()
+
+	doctorsUrl := fmt.Sprintf("%s/doctors", ServerUrl)
+	res := mustSendWithHeaders(t, http.MethodGet, doctorsUrl,
+		http.Header{server.ClinicIdHeader: {"not-a-uuid"}}, nil, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Expected '400 Bad Request' status code")
+
+	res = mustSendWithHeaders(t, http.MethodGet, doctorsUrl, clinicHeaders(uuid.New()), nil, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Clinic must exist")
+
+	req, err := http.NewRequest(http.MethodGet, doctorsUrl, nil)
+	require.NoError(t, err)
+	res, err = http.DefaultTransport.RoundTrip(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode, "Anonymous requests act in the default clinic")
+
+	doctor := mustCreateDoctor(t, newDoctor(fmt.Sprintf("test.clinic.header.%s@doctor.com",
+		uuid.NewString())))
+	req, err = http.NewRequest(http.MethodGet, doctorsUrl, nil)
+	require.NoError(t, err)
+	req.Header = actorHeaders(http.Header{}, doctor.Id, api.UserRoleDoctor)
+	res, err = http.DefaultTransport.RoundTrip(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Doctors must send the clinic header")
+
+	clinicUrl := fmt.Sprintf("%s/clinics/%s", ServerUrl, data.DefaultClinicId)
+	req, err = http.NewRequest(http.MethodGet, clinicUrl, nil)
+	require.NoError(t, err)
+	res, err = http.DefaultTransport.RoundTrip(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode, "Clinics are read without the header")
+}
+
+func TestCreateClinic_RequiresAdmin(t *testing.T) {
+	t.Parallel()
+
+	clinicsUrl := fmt.Sprintf("%s/clinics", ServerUrl)
+	newClinic := api.NewClinic{Name: "Unauthorized clinic"}
+	res := mustSendWithHeaders(t, http.MethodPost, clinicsUrl, nil, newClinic, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Anonymous requests can't add clinics")
+
+	doctor := mustCreateDoctor(t, newDoctor(fmt.Sprintf("test.clinic.%s@doctor.com",
+		uuid.NewString())))
+	res = mustSendWithHeaders(t, http.MethodPost, clinicsUrl,
+		actorHeaders(http.Header{}, doctor.Id, api.UserRoleDoctor), newClinic, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Doctors can't add clinics")
+}
+
+func mustCreateClinic(t *testing.T, name string) api.Clinic {
+	t.Helper()
+
+	var clinic api.Clinic
+	res := mustSendWithHeaders(t, http.MethodPost, fmt.Sprintf("%s/clinics", ServerUrl),
+		asDefaultClinicAdmin(t), api.NewClinic{Name: name}, &clinic)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "mustCreateClinic: unexpected status code")
+	return clinic
+}
+
+func clinicHeaders(clinicId uuid.UUID) http.Header {
+	return http.Header{server.ClinicIdHeader: {clinicId.String()}}
+}
+
+func actorHeaders(headers http.Header, userId uuid.UUID, role api.UserRole) http.Header {
+	headers = headers.Clone()
+	headers.Set(server.UserIdHeader, userId.String())
+	headers.Set(server.UserRoleHeader, string(role))
+	return headers
+}
+
+// mustSendWithHeaders sends the request with the headers and decodes the body
+// of a successful response into dst, if not nil.
+func mustSendWithHeaders(
+	t *testing.T,
+	method, url string,
+	headers http.Header,
+	body any,
+	dst any,
+) *http.Response {
+	t.Helper()
+	require := require.New(t)
+
+	var reqBody bytes.Buffer
+	if body != nil {
+		err := json.NewEncoder(&reqBody).Encode(body)
+		require.NoError(err, "mustSendWithHeaders: Failed to marshal request")
+	}
+
+	req, err := http.NewRequest(method, url, &reqBody)
+	require.NoError(err, "mustSendWithHeaders: Failed to create request")
+	for key, values := range headers {
+		req.Header[key] = values
+	}
+	req.Header.Set(server.ContentType, server.ApplicationJSON)
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(err, "mustSendWithHeaders: request failed")
+	defer res.Body.Close()
+
+	success := res.StatusCode == http.StatusOK || res.StatusCode == http.StatusCreated
+	if dst != nil && success {
+		err = json.NewDecoder(res.Body).Decode(dst)
+		require.NoError(err, "mustSendWithHeaders: Failed to decode response")
+	}
+	return res
+}
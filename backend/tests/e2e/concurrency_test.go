@@ -0,0 +1,160 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/server"
+)
+
+func TestUpdatePrescription_IfMatch(t *testing.T) {
+	t.Parallel()
+
+	patientEmail := fmt.Sprintf("test.patient.etag.%s@example.com", uuid.NewString())
+	patient := mustCreatePatient(t, newPatient(patientEmail))
+
+	start := time.Now().Truncate(time.Second)
+	prescription := mustCreatePrescription(t, api.NewPrescription{
+		Name:      "Ibuprofen 400mg",
+		PatientId: patient.Id,
+		Start:     start,
+		End:       start.AddDate(0, 0, 7),
+	})
+	url := fmt.Sprintf("%s/prescriptions/%s", ServerUrl, *prescription.Id)
+
+	res, err := http.Get(url)
+	require.NoError(t, err, "http.Get failed for PrescriptionDetail")
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	firstETag := res.Header.Get(server.ETag)
+	require.Equal(t, `"1"`, firstETag)
+
+	update := api.UpdatePrescription{Name: asPtr("Ibuprofen 600mg")}
+	res = mustSendWithIfMatch(t, http.MethodPatch, url, firstETag, update)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	secondETag := res.Header.Get(server.ETag)
+	assert.Equal(t, `"2"`, secondETag)
+
+	update = api.UpdatePrescription{Name: asPtr("Ibuprofen 800mg")}
+	res = mustSendWithIfMatch(t, http.MethodPatch, url, firstETag, update)
+	assert.Equal(t, http.StatusPreconditionFailed, res.StatusCode, "Stale update must fail")
+
+	res = mustSendWithIfMatch(t, http.MethodDelete, url, firstETag, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, res.StatusCode, "Stale delete must fail")
+
+	res = mustSendWithIfMatch(t, http.MethodDelete, url, secondETag, nil)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode, "Expected '204 No Content' status code")
+}
+
+func TestUpdateCondition_IfMatch(t *testing.T) {
+	t.Parallel()
+
+	patientEmail := fmt.Sprintf("test.patient.etag.%s@example.com", uuid.NewString())
+	patient := mustCreatePatient(t, newPatient(patientEmail))
+	condition := mustCreateCondition(t, api.NewCondition{
+		Name:      "Migraine",
+		PatientId: patient.Id,
+		Start:     time.Now().Truncate(time.Second),
+	})
+	url := fmt.Sprintf("%s/conditions/%s", ServerUrl, *condition.Id)
+
+	update := map[string]any{"name": "Chronic migraine"}
+	res := mustSendWithIfMatch(t, http.MethodPatch, url, `"1"`, update)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Equal(t, `"2"`, res.Header.Get(server.ETag))
+
+	res = mustSendWithIfMatch(t, http.MethodPatch, url, `"1"`, update)
+	assert.Equal(t, http.StatusPreconditionFailed, res.StatusCode, "Stale update must fail")
+
+	res = mustSendWithIfMatch(t, http.MethodPatch, url, "*", update)
+	assert.Equal(t, http.StatusOK, res.StatusCode, "Wildcard must match any version")
+}
+
+func TestUpdate_WithoutIfMatch(t *testing.T) {
+	t.Parallel()
+
+	patientEmail := fmt.Sprintf("test.patient.etag.%s@example.com", uuid.NewString())
+	patient := mustCreatePatient(t, newPatient(patientEmail))
+	start := time.Now().Truncate(time.Second)
+	prescription := mustCreatePrescription(t, api.NewPrescription{
+		Name:      "Ibuprofen 400mg",
+		PatientId: patient.Id,
+		Start:     start,
+		End:       start.AddDate(0, 0, 7),
+	})
+	condition := mustCreateCondition(t, api.NewCondition{
+		Name:      "Migraine",
+		PatientId: patient.Id,
+		Start:     start,
+	})
+	prescriptionUrl := fmt.Sprintf("%s/prescriptions/%s", ServerUrl, *prescription.Id)
+	conditionUrl := fmt.Sprintf("%s/conditions/%s", ServerUrl, *condition.Id)
+	asPatient := actorHeaders(http.Header{}, patient.Id, api.UserRolePatient)
+
+	for _, request := range []struct {
+		method, url string
+		body        any
+		status      int
+	}{
+		{http.MethodPatch, prescriptionUrl,
+			api.UpdatePrescription{Name: asPtr("Ibuprofen 600mg")}, http.StatusOK},
+		{http.MethodPatch, conditionUrl, map[string]any{"name": "Chronic migraine"}, http.StatusOK},
+		{http.MethodPut, fmt.Sprintf("%s/patients/%s", ServerUrl, patient.Id),
+			api.PatientProfileUpdate{FirstName: patient.FirstName, LastName: "Changed"},
+			http.StatusOK},
+		{http.MethodDelete, prescriptionUrl, nil, http.StatusNoContent},
+	} {
+		res := mustSendWithHeaders(t, request.method, request.url, asPatient, request.body, nil)
+		assert.Equal(t, request.status, res.StatusCode,
+			"%s %s without If-Match is unconditional", request.method, request.url)
+	}
+
+	res := mustSendWithHeaders(t, http.MethodGet, conditionUrl, nil, nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Equal(t, `"2"`, res.Header.Get(server.ETag), "Condition was modified")
+	res = mustSendWithHeaders(t, http.MethodGet, prescriptionUrl, nil, nil, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "Prescription was deleted")
+}
+
+// withIfMatch returns the headers with an If-Match of the entity tag.
+func withIfMatch(headers http.Header, etag string) http.Header {
+	headers = headers.Clone()
+	if headers == nil {
+		headers = http.Header{}
+	}
+	headers.Set(server.IfMatch, etag)
+	return headers
+}
+
+func mustSendWithIfMatch(t *testing.T, method, url, ifMatch string, body any) *http.Response {
+	t.Helper()
+	require := require.New(t)
+
+	var reqBody bytes.Buffer
+	if body != nil {
+		err := json.NewEncoder(&reqBody).Encode(body)
+		require.NoError(err, "mustSendWithIfMatch: Failed to marshal request")
+	}
+
+	req, err := http.NewRequest(method, url, &reqBody)
+	require.NoError(err, "mustSendWithIfMatch: Failed to create request")
+	req.Header.Set(server.ContentType, server.ApplicationJSON)
+	req.Header.Set(server.IfMatch, ifMatch)
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(err, "mustSendWithIfMatch: request failed")
+	res.Body.Close()
+
+	return res
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/oapi-codegen/runtime/types"
@@ -19,6 +20,144 @@ import (
 	"github.com/Nesquiko/wac/pkg/server"
 )
 
+func TestDoctorProfiles(t *testing.T) {
+	t.Parallel()
+
+	clinic := mustCreateClinic(t, "Doctor profiles clinic")
+	inClinic := clinicHeaders(clinic.Id)
+	admin := mustProvisionAdmin(t, clinic.Id)
+	asAdmin := actorHeaders(inClinic, admin.Id, api.UserRoleAdmin)
+
+	registerUrl := fmt.Sprintf("%s/auth/register", ServerUrl)
+	register := func(specialization api.SpecializationEnum) api.Doctor {
+		request := newDoctor(fmt.Sprintf("test.profile.%s@doctor.com", uuid.NewString()))
+		request.Specialization = specialization
+		var doctor api.Doctor
+		res := mustSendWithHeaders(t, http.MethodPost, registerUrl, inClinic, request, &doctor)
+		require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+		return doctor
+	}
+	house := register(api.Diagnostician)
+	wilson := register(api.Oncologist)
+	assert.True(t, house.Active, "Doctors are active when registered")
+	assert.Equal(t, []api.SpecializationEnum{api.Diagnostician}, house.Specializations)
+
+	houseUrl := fmt.Sprintf("%s/doctors/%s", ServerUrl, house.Id)
+	res := mustSendWithHeaders(t, http.MethodGet, houseUrl, inClinic, nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	firstETag := res.Header.Get(server.ETag)
+
+	update := api.DoctorProfileUpdate{
+		FirstName:       house.FirstName,
+		LastName:        house.LastName,
+		Specializations: []api.SpecializationEnum{api.Diagnostician, api.Neurologist},
+		Languages:       &[]string{"en", "sk"},
+		Bio:             asPtr("Head of diagnostic medicine."),
+		Active:          true,
+	}
+	res = mustSendWithHeaders(t, http.MethodPut, houseUrl, withIfMatch(inClinic, firstETag),
+		update, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Anonymous requests can't update it")
+	asWilson := actorHeaders(inClinic, wilson.Id, api.UserRoleDoctor)
+	res = mustSendWithHeaders(t, http.MethodPut, houseUrl, withIfMatch(asWilson, firstETag),
+		update, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Doctors can't update others' profiles")
+
+	asHouse := actorHeaders(inClinic, house.Id, api.UserRoleDoctor)
+	asHouseAtFirst := withIfMatch(asHouse, firstETag)
+	var updated api.Doctor
+	res = mustSendWithHeaders(t, http.MethodPut, houseUrl, asHouseAtFirst, update, &updated)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Doctor updates their own profile")
+	assert.Equal(t, api.Diagnostician, updated.Specialization, "First specialization is primary")
+	assert.Equal(t, update.Specializations, updated.Specializations)
+	require.NotNil(t, updated.Languages)
+	assert.Equal(t, []string{"en", "sk"}, *updated.Languages)
+
+	res = mustSendWithHeaders(t, http.MethodPut, houseUrl, asHouseAtFirst, update, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, res.StatusCode, "Stale version is rejected")
+
+	var doctors api.Doctors
+	doctorsUrl := fmt.Sprintf("%s/doctors?specialization=%s&language=en", ServerUrl, api.Neurologist)
+	res = mustSendWithHeaders(t, http.MethodGet, doctorsUrl, inClinic, nil, &doctors)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.Len(t, doctors.Doctors, 1, "Any specialization of the doctor matches")
+	assert.Equal(t, house.Id, doctors.Doctors[0].Id)
+
+	wilsonUrl := fmt.Sprintf("%s/doctors/%s", ServerUrl, wilson.Id)
+	deactivate := api.DoctorProfileUpdate{
+		FirstName:       wilson.FirstName,
+		LastName:        wilson.LastName,
+		Specializations: wilson.Specializations,
+		Active:          false,
+	}
+	res = mustSendWithHeaders(t, http.MethodPut, wilsonUrl, withIfMatch(asAdmin, "*"), deactivate,
+		nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Admin deactivates the doctor")
+
+	res = mustSendWithHeaders(t, http.MethodGet, fmt.Sprintf("%s/doctors?active=false", ServerUrl),
+		inClinic, nil, &doctors)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.Len(t, doctors.Doctors, 1)
+	assert.Equal(t, wilson.Id, doctors.Doctors[0].Id)
+
+	var patient api.Patient
+	res = mustSendWithHeaders(t, http.MethodPost, registerUrl, inClinic,
+		newPatient(fmt.Sprintf("test.profile.%s@patient.com", uuid.NewString())), &patient)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	res = mustSendWithHeaders(t, http.MethodPost, fmt.Sprintf("%s/appointments", ServerUrl),
+		inClinic, api.NewAppointmentRequest{
+			PatientId:           patient.Id,
+			DoctorId:            wilson.Id,
+			AppointmentDateTime: time.Now().Add(48 * time.Hour).Truncate(time.Hour),
+		}, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Inactive doctors can't be booked")
+}
+
+func TestGetDoctors_Pagination(t *testing.T) {
+	t.Parallel()
+
+	clinic := mustCreateClinic(t, "Doctors pagination clinic")
+	inClinic := clinicHeaders(clinic.Id)
+	registerUrl := fmt.Sprintf("%s/auth/register", ServerUrl)
+	registered := make([]uuid.UUID, 0, 5)
+	for _, lastName := range []string{"Cuddy", "Wilson", "Chase", "Foreman", "Cuddy"} {
+		request := newDoctor(fmt.Sprintf("test.doctors.page.%s@doctor.com", uuid.NewString()))
+		request.LastName = lastName
+		var doctor api.Doctor
+		res := mustSendWithHeaders(t, http.MethodPost, registerUrl, inClinic, request, &doctor)
+		require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+		registered = append(registered, doctor.Id)
+	}
+
+	var listed []api.Doctor
+	doctorsUrl := fmt.Sprintf("%s/doctors?sort=-lastName&limit=2", ServerUrl)
+	for pageUrl := doctorsUrl; pageUrl != ""; {
+		var page api.Doctors
+		res := mustSendWithHeaders(t, http.MethodGet, pageUrl, inClinic, nil, &page)
+		require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+		assert.Equal(t, 5, page.Pagination.Total)
+		assert.LessOrEqual(t, len(page.Doctors), 2)
+		listed = append(listed, page.Doctors...)
+
+		pageUrl = ""
+		if page.Pagination.NextCursor != nil {
+			pageUrl = doctorsUrl + "&cursor=" + *page.Pagination.NextCursor
+		}
+	}
+
+	lastNames := make([]string, len(listed))
+	listedIds := make([]uuid.UUID, len(listed))
+	for i, doctor := range listed {
+		lastNames[i], listedIds[i] = doctor.LastName, doctor.Id
+	}
+	assert.Equal(t, []string{"Wilson", "Foreman", "Cuddy", "Cuddy", "Chase"}, lastNames)
+	assert.ElementsMatch(t, registered, listedIds, "Pages don't skip or repeat doctors")
+
+	res := mustSendWithHeaders(t, http.MethodGet, fmt.Sprintf("%s/doctors?cursor=invalid", ServerUrl),
+		inClinic, nil, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Invalid cursor is rejected")
+}
+
 func TestGetDoctorById_OK(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,139 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/fhir"
+	"github.com/Nesquiko/wac/pkg/server"
+)
+
+func TestFhirImportBundle(t *testing.T) {
+	t.Parallel()
+
+	doctorEmail := fmt.Sprintf("test.fhir.import.%s@doctor.com", uuid.NewString())
+	doctor := mustCreateDoctor(t, newDoctor(doctorEmail))
+
+	source := uuid.NewString()
+	patientEmail := fmt.Sprintf("test.fhir.import.%s@patient.com", source)
+	apptTime := time.Now().Add(96 * time.Hour).Truncate(time.Hour)
+	bundle := fmt.Sprintf(`{
+		"resourceType": "Bundle",
+		"type": "collection",
+		"entry": [
+			{"fullUrl": "urn:uuid:p1", "resource": {
+				"resourceType": "Patient",
+				"identifier": [{"system": "urn:test:%[1]s", "value": "P-1"}],
+				"name": [{"family": "Transferred", "given": ["Jane"]}],
+				"telecom": [{"system": "email", "value": %[2]q}]
+			}},
+			{"fullUrl": "urn:uuid:c1", "resource": {
+				"resourceType": "Condition",
+				"identifier": [{"system": "urn:test:%[1]s", "value": "C-1"}],
+				"code": {"text": "Asthma"},
+				"subject": {"reference": "urn:uuid:p1"},
+				"onsetDateTime": "2024-01-01T00:00:00Z"
+			}},
+			{"fullUrl": "urn:uuid:a1", "resource": {
+				"resourceType": "Appointment",
+				"identifier": [{"system": "urn:test:%[1]s", "value": "A-1"}],
+				"status": "booked",
+				"start": %[3]q,
+				"participant": [
+					{"actor": {"reference": "urn:uuid:p1"}, "status": "accepted"},
+					{"actor": {"reference": "Practitioner/%[4]s"}, "status": "accepted"}
+				]
+			}},
+			{"resource": {"resourceType": "Observation"}}
+		]
+	}`, source, patientEmail, apptTime.Format(time.RFC3339), doctor.Id)
+
+	asAdmin := asDefaultClinicAdmin(t)
+	var report fhir.ImportReport
+	res := mustImportFhir(t, asAdmin, bundle, true, &report)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.True(t, report.Valid)
+	assert.False(t, report.Imported, "Dry run must not import")
+
+	res = mustImportFhir(t, asAdmin, bundle, false, &report)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.True(t, report.Imported)
+
+	expected := []string{
+		fhir.ImportOutcomeCreated,
+		fhir.ImportOutcomeCreated,
+		fhir.ImportOutcomeCreated,
+		fhir.ImportOutcomeIgnored,
+	}
+	require.Len(t, report.Entries, len(expected))
+	for i, outcome := range expected {
+		assert.Equal(t, outcome, report.Entries[i].Outcome, "entry %d", i)
+	}
+
+	patientId := report.Entries[0].LocalId
+	require.NotNil(t, patientId)
+	patient := mustGetPatient(t, *patientId)
+	assert.Equal(t, patientEmail, string(patient.Email))
+
+	// Importing the same bundle again must not duplicate anything.
+	res = mustImportFhir(t, asAdmin, bundle, false, &report)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	for i := range 3 {
+		assert.Equal(t, fhir.ImportOutcomeExisting, report.Entries[i].Outcome, "entry %d", i)
+	}
+}
+
+func TestFhirImportBundle_RequiresAdmin(t *testing.T) {
+	t.Parallel()
+
+	bundle := `{"resourceType": "Bundle", "type": "collection", "entry": []}`
+	var outcome fhir.OperationOutcome
+	res := mustImportFhir(t, http.Header{}, bundle, false, &outcome)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Anonymous requests can't import")
+	assert.Equal(t, fhir.ResourceTypeOperationOutcome, outcome.ResourceType)
+
+	doctor := mustCreateDoctor(t, newDoctor(fmt.Sprintf("test.fhir.import.%s@doctor.com",
+		uuid.NewString())))
+	asDoctor := actorHeaders(http.Header{}, doctor.Id, api.UserRoleDoctor)
+	res = mustImportFhir(t, asDoctor, bundle, true, &outcome)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Doctors can't import")
+}
+
+func mustImportFhir(
+	t *testing.T,
+	headers http.Header,
+	bundle string,
+	dryRun bool,
+	dst any,
+) *http.Response {
+	t.Helper()
+	require := require.New(t)
+
+	url := fmt.Sprintf("%s/fhir/$import?dryRun=%t", ServerUrl, dryRun)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBufferString(bundle))
+	require.NoError(err, "mustImportFhir: Failed to create request")
+	for key, values := range headers {
+		req.Header[key] = values
+	}
+	req.Header.Set(server.ContentType, server.ApplicationFhirJSON)
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(err, "mustImportFhir: request failed")
+	defer res.Body.Close()
+
+	err = json.NewDecoder(res.Body).Decode(dst)
+	require.NoError(err, "mustImportFhir: Failed to decode response")
+
+	return res
+}
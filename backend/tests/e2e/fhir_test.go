@@ -0,0 +1,127 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/fhir"
+	"github.com/Nesquiko/wac/pkg/server"
+)
+
+func TestFhirReadPatient(t *testing.T) {
+	t.Parallel()
+
+	patientEmail := fmt.Sprintf("test.fhir.%s@patient.com", uuid.NewString())
+	patient := mustCreatePatient(t, newPatient(patientEmail))
+
+	var resource fhir.Patient
+	res := mustGetFhir(t, fmt.Sprintf("/Patient/%s", patient.Id), &resource)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+
+	mapped, err := fhir.PatientToData(resource)
+	require.NoError(t, err, "Failed to map FHIR patient")
+
+	assert := assert.New(t)
+	assert.Equal(patient.Id, mapped.Id)
+	assert.Equal(string(patient.Email), mapped.Email)
+	assert.Equal(patient.FirstName, mapped.FirstName)
+	assert.Equal(patient.LastName, mapped.LastName)
+
+	var outcome fhir.OperationOutcome
+	res = mustGetFhir(t, fmt.Sprintf("/Patient/%s", uuid.New()), &outcome)
+	assert.Equal(http.StatusNotFound, res.StatusCode, "Expected '404 Not Found' status code")
+	assert.Equal(fhir.ResourceTypeOperationOutcome, outcome.ResourceType)
+}
+
+func TestFhirSearchAppointments(t *testing.T) {
+	t.Parallel()
+
+	patientEmail := fmt.Sprintf("test.fhir.%s@patient.com", uuid.NewString())
+	patient := mustCreatePatient(t, newPatient(patientEmail))
+
+	doctorEmail := fmt.Sprintf("test.fhir.%s@doctor.com", uuid.NewString())
+	doctor := mustCreateDoctor(t, newDoctor(doctorEmail))
+
+	apptTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	appt := mustCreateAppointment(t, api.NewAppointmentRequest{
+		PatientId:           patient.Id,
+		DoctorId:            doctor.Id,
+		AppointmentDateTime: apptTime,
+	})
+
+	tests := []struct {
+		name     string
+		query    string
+		expected int
+	}{
+		{name: "by patient", query: "patient=" + patient.Id.String(), expected: 1},
+		{
+			name:     "by patient reference and status",
+			query:    fmt.Sprintf("patient=Patient/%s&status=%s", patient.Id, fhir.AppointmentStatusPending),
+			expected: 1,
+		},
+		{
+			name:     "other status",
+			query:    fmt.Sprintf("patient=%s&status=%s", patient.Id, fhir.AppointmentStatusBooked),
+			expected: 0,
+		},
+		{
+			name:     "by practitioner and date",
+			query:    fmt.Sprintf("practitioner=%s&date=%s", doctor.Id, apptTime.Format("2006-01-02")),
+			expected: 1,
+		},
+		{
+			name:     "before the appointment",
+			query:    fmt.Sprintf("patient=%s&date=lt%s", patient.Id, apptTime.Format("2006-01-02")),
+			expected: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var bundle struct {
+				fhir.Bundle
+				Entry []struct {
+					Resource fhir.Appointment `json:"resource"`
+				} `json:"entry"`
+			}
+			res := mustGetFhir(t, "/Appointment?"+tc.query, &bundle)
+			require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+
+			assert.Equal(t, fhir.BundleTypeSearchset, bundle.Type)
+			require.Len(t, bundle.Entry, tc.expected)
+			if tc.expected > 0 {
+				assert.Equal(t, appt.Id.String(), bundle.Entry[0].Resource.Id)
+			}
+		})
+	}
+
+	var outcome fhir.OperationOutcome
+	res := mustGetFhir(t, "/Appointment", &outcome)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Search without patient must fail")
+}
+
+func mustGetFhir(t *testing.T, path string, dst any) *http.Response {
+	t.Helper()
+	require := require.New(t)
+
+	res, err := http.Get(ServerUrl + "/fhir" + path)
+	require.NoError(err, "mustGetFhir: http.Get failed")
+	defer res.Body.Close()
+
+	require.Equal(server.ApplicationFhirJSON, res.Header.Get(server.ContentType))
+	err = json.NewDecoder(res.Body).Decode(dst)
+	require.NoError(err, "mustGetFhir: Failed to decode response")
+
+	return res
+}
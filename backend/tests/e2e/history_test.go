@@ -0,0 +1,252 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/server"
+)
+
+func TestConditionHistory(t *testing.T) {
+	t.Parallel()
+
+	patientEmail := fmt.Sprintf("test.patient.history.%s@example.com", uuid.NewString())
+	patient := mustCreatePatient(t, newPatient(patientEmail))
+	doctorEmail := fmt.Sprintf("test.doctor.history.%s@example.com", uuid.NewString())
+	doctor := mustCreateDoctor(t, newDoctor(doctorEmail))
+
+	start := time.Now().Truncate(time.Second).AddDate(0, 0, -14)
+	condition := mustCreateCondition(t, api.NewCondition{
+		Name:      "Bronchitis",
+		PatientId: patient.Id,
+		Start:     start,
+	})
+	conditionUrl := fmt.Sprintf("%s/conditions/%s", ServerUrl, *condition.Id)
+
+	beforeEnd := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	end := start.AddDate(0, 0, 10)
+	asDoctor := actorHeaders(http.Header{}, doctor.Id, api.UserRoleDoctor)
+	res := mustSendWithHeaders(t, http.MethodPatch, conditionUrl, withIfMatch(asDoctor, `"1"`),
+		map[string]any{"end": end}, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+
+	var history api.ConditionHistory
+	res = mustGetJson(t, conditionUrl+"/history", &history)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.Len(t, history.Revisions, 2)
+
+	created, ended := history.Revisions[0], history.Revisions[1]
+	assert.Equal(t, api.RevisionOperation("create"), created.Operation)
+	assert.Equal(t, int64(1), created.Version)
+	assert.Nil(t, created.Condition.End)
+	assert.Nil(t, created.ChangedBy)
+
+	assert.Equal(t, api.RevisionOperation("update"), ended.Operation)
+	assert.Equal(t, int64(2), ended.Version)
+	require.NotNil(t, ended.Condition.End)
+	assert.True(t, end.Equal(*ended.Condition.End))
+	require.NotNil(t, ended.ChangedBy)
+	assert.Equal(t, doctor.Id, ended.ChangedBy.Id)
+	assert.Equal(t, api.UserRoleDoctor, ended.ChangedBy.Role)
+
+	var asOf api.Condition
+	res = mustGetJson(
+		t,
+		conditionUrl+"?asOf="+url.QueryEscape(beforeEnd.Format(time.RFC3339Nano)),
+		&asOf,
+	)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Nil(t, asOf.End, "Condition wasn't ended yet")
+	assert.Empty(t, res.Header.Get(server.ETag), "Past version must not have an ETag")
+
+	beforeCreate := start.Format(time.RFC3339)
+	res = mustGetJson(t, conditionUrl+"?asOf="+url.QueryEscape(beforeCreate), nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "Condition didn't exist yet")
+}
+
+func TestConditionHistory_ConcurrentUpdates(t *testing.T) {
+	t.Parallel()
+
+	patientEmail := fmt.Sprintf("test.patient.history.%s@example.com", uuid.NewString())
+	patient := mustCreatePatient(t, newPatient(patientEmail))
+	condition := mustCreateCondition(t, api.NewCondition{
+		Name:      "Bronchitis",
+		PatientId: patient.Id,
+		Start:     time.Now().Truncate(time.Second),
+	})
+	conditionUrl := fmt.Sprintf("%s/conditions/%s", ServerUrl, *condition.Id)
+	asPatient := actorHeaders(http.Header{}, patient.Id, api.UserRolePatient)
+
+	// updates losing the race get 409 and leave no revision behind
+	const updates = 10
+	var wg sync.WaitGroup
+	statuses := make([]int, updates)
+	for i := range updates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := mustSendWithHeaders(t, http.MethodPatch, conditionUrl, asPatient,
+				map[string]any{"name": fmt.Sprintf("Bronchitis %d", i)}, nil)
+			statuses[i] = res.StatusCode
+		}()
+	}
+	wg.Wait()
+
+	updated := 0
+	for _, status := range statuses {
+		if status == http.StatusOK {
+			updated++
+			continue
+		}
+		assert.Equal(t, http.StatusConflict, status, "Expected '409 Conflict' status code")
+	}
+	require.NotZero(t, updated, "At least one update must succeed")
+
+	var history api.ConditionHistory
+	res := mustGetJson(t, conditionUrl+"/history", &history)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.Len(t, history.Revisions, 1+updated, "Every successful write has one revision")
+	for i, revision := range history.Revisions {
+		assert.Equal(t, int64(i+1), revision.Version, "Revisions have consecutive versions")
+	}
+
+	var current api.Condition
+	res = mustGetJson(t, conditionUrl, &current)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	last := history.Revisions[len(history.Revisions)-1]
+	assert.Equal(t, current.Name, last.Condition.Name, "Last revision is the current state")
+}
+
+func TestPrescriptionHistory(t *testing.T) {
+	t.Parallel()
+
+	patientEmail := fmt.Sprintf("test.patient.history.%s@example.com", uuid.NewString())
+	patient := mustCreatePatient(t, newPatient(patientEmail))
+
+	start := time.Now().Truncate(time.Second)
+	prescription := mustCreatePrescription(t, api.NewPrescription{
+		Name:      "Amoxicillin 500mg",
+		PatientId: patient.Id,
+		Start:     start,
+		End:       start.AddDate(0, 0, 7),
+	})
+	prescriptionUrl := fmt.Sprintf("%s/prescriptions/%s", ServerUrl, *prescription.Id)
+
+	extended := start.AddDate(0, 0, 14)
+	asPatient := actorHeaders(http.Header{}, patient.Id, api.UserRolePatient)
+	res := mustSendWithHeaders(t, http.MethodPatch, prescriptionUrl,
+		withIfMatch(asPatient, `"1"`), api.UpdatePrescription{End: &extended}, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+
+	beforeDelete := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	res = mustSendWithHeaders(t, http.MethodDelete, prescriptionUrl,
+		withIfMatch(asPatient, `"2"`), nil, nil)
+	require.Equal(t, http.StatusNoContent, res.StatusCode, "Expected '204 No Content' status code")
+
+	var history api.PrescriptionHistory
+	res = mustGetJson(t, prescriptionUrl+"/history", &history)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.Len(t, history.Revisions, 3)
+
+	operations := make([]api.RevisionOperation, len(history.Revisions))
+	for i, revision := range history.Revisions {
+		operations[i] = revision.Operation
+	}
+	assert.Equal(t, []api.RevisionOperation{"create", "update", "delete"}, operations)
+	assert.True(t, extended.Equal(history.Revisions[1].Prescription.End))
+
+	res = mustGetJson(t, prescriptionUrl, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "Prescription was deleted")
+
+	var asOf api.Prescription
+	res = mustGetJson(
+		t,
+		prescriptionUrl+"?asOf="+url.QueryEscape(beforeDelete.Format(time.RFC3339Nano)),
+		&asOf,
+	)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.True(t, extended.Equal(asOf.End))
+}
+
+func TestActorHeaders_Invalid(t *testing.T) {
+	t.Parallel()
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/doctors", ServerUrl), nil)
+	require.NoError(t, err)
+	req.Header.Set(server.UserIdHeader, "not-a-uuid")
+	req.Header.Set(server.UserRoleHeader, string(api.UserRoleDoctor))
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Expected '400 Bad Request' status code")
+}
+
+// mustSendAs sends the request as the given user and decodes the body of a
+// successful response into dst, if not nil.
+func mustSendAs(
+	t *testing.T,
+	method, url string,
+	userId uuid.UUID,
+	role api.UserRole,
+	body any,
+	dst any,
+) *http.Response {
+	t.Helper()
+	require := require.New(t)
+
+	var reqBody bytes.Buffer
+	if body != nil {
+		err := json.NewEncoder(&reqBody).Encode(body)
+		require.NoError(err, "mustSendAs: Failed to marshal request")
+	}
+
+	req, err := http.NewRequest(method, url, &reqBody)
+	require.NoError(err, "mustSendAs: Failed to create request")
+	req.Header.Set(server.ContentType, server.ApplicationJSON)
+	req.Header.Set(server.UserIdHeader, userId.String())
+	req.Header.Set(server.UserRoleHeader, string(role))
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(err, "mustSendAs: request failed")
+	defer res.Body.Close()
+
+	success := res.StatusCode == http.StatusOK || res.StatusCode == http.StatusCreated
+	if dst != nil && success {
+		err = json.NewDecoder(res.Body).Decode(dst)
+		require.NoError(err, "mustSendAs: Failed to decode response")
+	}
+	return res
+}
+
+// mustGetJson decodes the body of a successful response into dst, if not nil.
+func mustGetJson(t *testing.T, url string, dst any) *http.Response {
+	t.Helper()
+
+	res, err := http.Get(url)
+	require.NoError(t, err, "mustGetJson: http.Get failed")
+	defer res.Body.Close()
+
+	if dst != nil && res.StatusCode == http.StatusOK {
+		err = json.NewDecoder(res.Body).Decode(dst)
+		require.NoError(t, err, "mustGetJson: Failed to decode response")
+	}
+	return res
+}
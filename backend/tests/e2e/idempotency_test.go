@@ -0,0 +1,140 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	netUrl "net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/server"
+)
+
+func TestIdempotencyKey_Replay(t *testing.T) {
+	t.Parallel()
+
+	doctor, patient := mustCreateDoctorAndPatient(t, "replay")
+	request := api.NewAppointmentRequest{
+		PatientId:           patient.Id,
+		DoctorId:            doctor.Id,
+		AppointmentDateTime: time.Now().Add(24 * time.Hour).Truncate(time.Hour),
+	}
+	withKey := http.Header{
+		server.IdempotencyKeyHeader: {uuid.NewString()},
+		"Origin":                    {"http://localhost:3333"},
+	}
+
+	var first, retry api.PatientAppointment
+	res := mustSendWithHeaders(t, http.MethodPost, ServerUrl+"/appointments", withKey,
+		request, &first)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	assert.Empty(t, res.Header.Get(server.IdempotentReplayedHeader))
+
+	res = mustSendWithHeaders(t, http.MethodPost, ServerUrl+"/appointments", withKey,
+		request, &retry)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected the replayed '201 Created'")
+	assert.Equal(t, "true", res.Header.Get(server.IdempotentReplayedHeader))
+	assert.Contains(t, res.Header.Get("Access-Control-Expose-Headers"),
+		server.IdempotentReplayedHeader, "Browsers can read whether it was replayed")
+	assert.Equal(t, server.ApplicationJSON, res.Header.Get(server.ContentType))
+	assert.Equal(t, *first.Id, *retry.Id, "Retry gets the first appointment")
+
+	assert.Equal(t, 1, requestedAppointments(t, doctor.Id, request.AppointmentDateTime))
+}
+
+func TestIdempotencyKey_ReusedForDifferentRequest(t *testing.T) {
+	t.Parallel()
+
+	doctor, patient := mustCreateDoctorAndPatient(t, "reused")
+	request := api.NewAppointmentRequest{
+		PatientId:           patient.Id,
+		DoctorId:            doctor.Id,
+		AppointmentDateTime: time.Now().Add(24 * time.Hour).Truncate(time.Hour),
+	}
+	withKey := http.Header{server.IdempotencyKeyHeader: {uuid.NewString()}}
+
+	res := mustSendWithHeaders(t, http.MethodPost, ServerUrl+"/appointments", withKey,
+		request, nil)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+
+	request.AppointmentDateTime = request.AppointmentDateTime.Add(time.Hour)
+	res = mustSendWithHeaders(t, http.MethodPost, ServerUrl+"/appointments", withKey,
+		request, nil)
+	assert.Equal(t, http.StatusConflict, res.StatusCode, "Expected '409 Conflict' status code")
+
+	res = mustSendWithHeaders(t, http.MethodPost, ServerUrl+"/prescriptions", withKey,
+		api.NewPrescription{
+			Name:      "Ibuprofen",
+			PatientId: patient.Id,
+			Start:     request.AppointmentDateTime,
+			End:       request.AppointmentDateTime.Add(7 * 24 * time.Hour),
+		}, nil)
+	assert.Equal(t, http.StatusConflict, res.StatusCode, "Key is reused on another endpoint")
+}
+
+func TestIdempotencyKey_ConcurrentDuplicates(t *testing.T) {
+	t.Parallel()
+
+	doctor, patient := mustCreateDoctorAndPatient(t, "concurrent")
+	request := api.NewAppointmentRequest{
+		PatientId:           patient.Id,
+		DoctorId:            doctor.Id,
+		AppointmentDateTime: time.Now().Add(24 * time.Hour).Truncate(time.Hour),
+	}
+	withKey := http.Header{server.IdempotencyKeyHeader: {uuid.NewString()}}
+
+	const duplicates = 8
+	var wg sync.WaitGroup
+	statuses := make([]int, duplicates)
+	created := make([]api.PatientAppointment, duplicates)
+	for i := range duplicates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := mustSendWithHeaders(t, http.MethodPost, ServerUrl+"/appointments", withKey,
+				request, &created[i])
+			statuses[i] = res.StatusCode
+		}()
+	}
+	wg.Wait()
+
+	var id *uuid.UUID
+	for i, status := range statuses {
+		if status == http.StatusConflict {
+			continue
+		}
+		require.Equal(t, http.StatusCreated, status, "Expected '201 Created' or '409 Conflict'")
+		if id == nil {
+			id = created[i].Id
+		}
+		assert.Equal(t, *id, *created[i].Id, "Duplicates get the same appointment")
+	}
+	require.NotNil(t, id, "One of the duplicates is handled")
+
+	assert.Equal(t, 1, requestedAppointments(t, doctor.Id, request.AppointmentDateTime))
+}
+
+func mustCreateDoctorAndPatient(t *testing.T, test string) (api.Doctor, api.Patient) {
+	t.Helper()
+	doctorEmail := fmt.Sprintf("test.idempotency.%s.%s@doctor.com", test, uuid.NewString())
+	patientEmail := fmt.Sprintf("test.idempotency.%s.%s@patient.com", test, uuid.NewString())
+	return mustCreateDoctor(t, newDoctor(doctorEmail)), mustCreatePatient(t, newPatient(patientEmail))
+}
+
+func requestedAppointments(t *testing.T, doctorId uuid.UUID, on time.Time) int {
+	t.Helper()
+	var calendar api.DoctorCalendar
+	res := mustSendWithHeaders(t, http.MethodGet,
+		fmt.Sprintf("%s/doctors/%s/calendar?from=%s&status=requested", ServerUrl, doctorId,
+			netUrl.QueryEscape(on.Format("2006-01-02"))), nil, nil, &calendar)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	return calendar.Pagination.Total
+}
@@ -0,0 +1,185 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+
+	"github.com/Nesquiko/wac/pkg/api"
+)
+
+func TestLocations(t *testing.T) {
+	t.Parallel()
+
+	// a random far away day, opening hours of the site are set for its weekday
+	day := time.Date(2040, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, rand.IntN(3650))
+	weekday := map[time.Weekday]api.Weekday{
+		time.Monday:    api.Monday,
+		time.Tuesday:   api.Tuesday,
+		time.Wednesday: api.Wednesday,
+		time.Thursday:  api.Thursday,
+		time.Friday:    api.Friday,
+		time.Saturday:  api.Saturday,
+		time.Sunday:    api.Sunday,
+	}[day.Weekday()]
+
+	clinic := mustCreateClinic(t, "Locations clinic")
+	inClinic := clinicHeaders(clinic.Id)
+	admin := mustProvisionAdmin(t, clinic.Id)
+	asAdmin := actorHeaders(inClinic, admin.Id, api.UserRoleAdmin)
+	locationsUrl := fmt.Sprintf("%s/locations", ServerUrl)
+
+	res := mustSendWithHeaders(t, http.MethodPost, locationsUrl, asAdmin,
+		api.NewLocation{Name: "Orphan room", Kind: api.LocationRoom}, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Room must be within a building")
+
+	var site, building, room api.Location
+	res = mustSendWithHeaders(t, http.MethodPost, locationsUrl, asAdmin, api.NewLocation{
+		Name: "Main campus",
+		Kind: api.LocationSite,
+		OpeningHours: &[]api.OpeningHours{
+			{Weekday: weekday, Opens: "08:00", Closes: "12:00"},
+		},
+	}, &site)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	res = mustSendWithHeaders(t, http.MethodPost, locationsUrl, asAdmin, api.NewLocation{
+		Name:     "Building B",
+		Kind:     api.LocationBuilding,
+		ParentId: &site.Id,
+	}, &building)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	res = mustSendWithHeaders(t, http.MethodPost, locationsUrl, asAdmin, api.NewLocation{
+		Name:     "Operating Room 2",
+		Kind:     api.LocationRoom,
+		ParentId: &building.Id,
+	}, &room)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	assert.Equal(t, []string{"Main campus", "Building B", "Operating Room 2"}, room.Path)
+
+	resourcesUrl := fmt.Sprintf("%s/resources", ServerUrl)
+	var roomFacility, otherFacility api.NewResource
+	res = mustSendWithHeaders(t, http.MethodPost, resourcesUrl, asAdmin, api.NewResource{
+		Name:       "Operating Room 2",
+		Type:       api.ResourceTypeFacility,
+		LocationId: &room.Id,
+	}, &roomFacility)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	res = mustSendWithHeaders(t, http.MethodPost, resourcesUrl, asAdmin, api.NewResource{
+		Name: "Doctor's office",
+		Type: api.ResourceTypeFacility,
+	}, &otherFacility)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+
+	registerUrl := fmt.Sprintf("%s/auth/register", ServerUrl)
+	var doctor api.Doctor
+	res = mustSendWithHeaders(t, http.MethodPost, registerUrl, inClinic,
+		newDoctor(fmt.Sprintf("test.locations.%s@doctor.com", uuid.NewString())), &doctor)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	var patient api.Patient
+	res = mustSendWithHeaders(t, http.MethodPost, registerUrl, inClinic,
+		newPatient(fmt.Sprintf("test.locations.%s@patient.com", uuid.NewString())), &patient)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+
+	appointmentsUrl := fmt.Sprintf("%s/appointments", ServerUrl)
+	closed := api.NewAppointmentRequest{
+		PatientId:           patient.Id,
+		DoctorId:            doctor.Id,
+		AppointmentDateTime: day.Add(12 * time.Hour),
+		LocationId:          &building.Id,
+	}
+	res = mustSendWithHeaders(t, http.MethodPost, appointmentsUrl, inClinic, closed, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Building is closed after noon")
+
+	open := closed
+	open.AppointmentDateTime = day.Add(9 * time.Hour)
+	var appointment api.PatientAppointment
+	res = mustSendWithHeaders(t, http.MethodPost, appointmentsUrl, inClinic, open, &appointment)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	require.NotNil(t, appointment.Location)
+	assert.Equal(t, []string{"Main campus", "Building B"}, appointment.Location.Path)
+
+	availableUrl := fmt.Sprintf(
+		"%s/resources/available?date-time=%s&locationId=%s",
+		ServerUrl,
+		url.QueryEscape(open.AppointmentDateTime.Format(time.RFC3339)),
+		building.Id,
+	)
+	var available api.AvailableResources
+	res = mustSendWithHeaders(t, http.MethodGet, availableUrl, inClinic, nil, &available)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Equal(
+		t,
+		[]api.Facility{{Id: *roomFacility.Id, Name: roomFacility.Name}},
+		available.Facilities,
+		"Only facilities at the location are offered",
+	)
+
+	decideUrl := fmt.Sprintf("%s/appointments/%s", ServerUrl, *appointment.Id)
+	elsewhere := api.AppointmentDecision{
+		Action:     api.Accept,
+		Facilities: &[]api.Facility{{Id: *otherFacility.Id, Name: otherFacility.Name}},
+	}
+	res = mustSendWithHeaders(t, http.MethodPost, decideUrl, inClinic, elsewhere, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Facility isn't at the location")
+
+	atLocation := api.AppointmentDecision{
+		Action:     api.Accept,
+		Facilities: &[]api.Facility{{Id: *roomFacility.Id, Name: roomFacility.Name}},
+	}
+	var decided api.DoctorAppointment
+	res = mustSendWithHeaders(t, http.MethodPost, decideUrl, inClinic, atLocation, &decided)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Equal(t, api.Scheduled, decided.Status)
+
+	calendarUrl := fmt.Sprintf(
+		"%s/doctors/%s/calendar?from=%s&to=%s",
+		ServerUrl,
+		doctor.Id,
+		day.Format(time.DateOnly),
+		day.AddDate(0, 0, 1).Format(time.DateOnly),
+	)
+	var calendar api.DoctorCalendar
+	res = mustSendWithHeaders(t, http.MethodGet, calendarUrl, inClinic, nil, &calendar)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.NotNil(t, calendar.Appointments)
+	require.Len(t, *calendar.Appointments, 1)
+	require.NotNil(t, (*calendar.Appointments)[0].Location)
+	assert.Equal(t, building.Id, (*calendar.Appointments)[0].Location.Id)
+}
+
+func TestManageResourcesRequiresActor(t *testing.T) {
+	t.Parallel()
+
+	asAdmin := asDefaultClinicAdmin(t)
+	var site api.Location
+	locationsUrl := fmt.Sprintf("%s/locations", ServerUrl)
+	newSite := api.NewLocation{Name: "Anonymous campus", Kind: api.LocationSite}
+	res := mustSendWithHeaders(t, http.MethodPost, locationsUrl, nil, newSite, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Anonymous requests can't add locations")
+	res = mustSendWithHeaders(t, http.MethodPost, locationsUrl, asAdmin, newSite, &site)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+
+	resourcesUrl := fmt.Sprintf("%s/resources", ServerUrl)
+	newResource := api.NewResource{Name: "Anonymous ECG", Type: api.ResourceTypeEquipment}
+	res = mustSendWithHeaders(t, http.MethodPost, resourcesUrl, nil, newResource, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Anonymous requests can't add resources")
+	resource := mustCreateResource(t, newResource)
+
+	locationUrl := fmt.Sprintf("%s/resources/%s/location", ServerUrl, *resource.Id)
+	assign := api.ResourceLocation{LocationId: &site.Id}
+	res = mustSendWithHeaders(t, http.MethodPut, locationUrl, nil, assign, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Anonymous requests can't move resources")
+	var moved api.NewResource
+	res = mustSendWithHeaders(t, http.MethodPut, locationUrl, asAdmin, assign, &moved)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Equal(t, &site.Id, moved.LocationId)
+}
@@ -0,0 +1,49 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func TestReindex(t *testing.T) {
+	ctx := context.Background()
+	db := mustConnectDb(t)
+	defer db.Disconnect(ctx)
+
+	indexes := db.Database.Collection("staff").Indexes()
+	_, err := indexes.CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "reindexTest", Value: 1}},
+		Options: options.Index().SetName("idx_staff_stale"),
+	})
+	require.NoError(t, err)
+	require.NoError(t, indexes.DropOne(ctx, "idx_staff_email_unique"))
+	_, err = indexes.CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetName("idx_staff_email"),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, db.Reindex(ctx))
+
+	specs, err := indexes.ListSpecifications(ctx)
+	require.NoError(t, err)
+	unique := make(map[string]bool)
+	for _, spec := range specs {
+		unique[spec.Name] = spec.Unique != nil && *spec.Unique
+	}
+	assert.Equal(t, map[string]bool{
+		"_id_":                        false,
+		"idx_staff_email_unique":      true,
+		"idx_staff_clinicId_lastName": false,
+	}, unique, "Stale and renamed indexes are dropped, changed ones recreated")
+
+	require.NoError(t, db.Reindex(ctx), "Reindexing is repeatable")
+}
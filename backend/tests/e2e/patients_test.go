@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"testing"
 	"time"
 
@@ -20,6 +21,185 @@ import (
 	"github.com/Nesquiko/wac/pkg/server"
 )
 
+func TestSearchPatients(t *testing.T) {
+	t.Parallel()
+
+	clinic := mustCreateClinic(t, "Search clinic")
+	inClinic := clinicHeaders(clinic.Id)
+	admin := mustProvisionAdmin(t, clinic.Id)
+	asAdmin := actorHeaders(inClinic, admin.Id, api.UserRoleAdmin)
+	receptionist := mustCreateStaff(t, asAdmin, api.StaffRoleReceptionist)
+	asReceptionist := actorHeaders(inClinic, receptionist.Id, api.UserRoleReceptionist)
+
+	registerUrl := fmt.Sprintf("%s/auth/register", ServerUrl)
+	register := func(firstName, lastName string, nationalId *string) api.Patient {
+		request := newPatient(fmt.Sprintf("test.search.%s@patient.com", uuid.NewString()))
+		request.FirstName = firstName
+		request.LastName = lastName
+		request.NationalId = nationalId
+		var patient api.Patient
+		res := mustSendWithHeaders(t, http.MethodPost, registerUrl, inClinic, request, &patient)
+		require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+		return patient
+	}
+	novakova := register("Jana", "Nováková", asPtr("805412/1240"))
+	register("Jana", "Novák", nil)
+	register("Ján", "Kováč", nil)
+
+	search := func(headers http.Header, query url.Values, dst *api.PatientList) *http.Response {
+		if !query.Has("page") {
+			query.Set("page", "0")
+			query.Set("pageSize", "10")
+		}
+		searchUrl := fmt.Sprintf("%s/patients?%s", ServerUrl, query.Encode())
+		return mustSendWithHeaders(t, http.MethodGet, searchUrl, headers, nil, dst)
+	}
+
+	var found api.PatientList
+	res := search(asReceptionist, url.Values{"name": {"jana nov"}}, &found)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.Len(t, found.Patients, 2, "Names match regardless of case and diacritics")
+	assert.Equal(t, "Novák", found.Patients[0].LastName, "Patients are sorted by name")
+
+	res = search(asReceptionist, url.Values{"name": {"kovac jan"}}, &found)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Len(t, found.Patients, 1, "Words match the first or the last name")
+
+	res = search(asReceptionist, url.Values{"name": {"jana"}, "pageSize": {"1"}, "page": {"1"}},
+		&found)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.Len(t, found.Patients, 1)
+	assert.Equal(t, novakova.Id, found.Patients[0].Id)
+	assert.Equal(t, 2, found.Pagination.Total)
+
+	res = search(asReceptionist, url.Values{"nationalId": {"8054121240"}}, &found)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.Len(t, found.Patients, 1, "Slash in the national id is ignored")
+	assert.Equal(t, novakova.Id, found.Patients[0].Id)
+
+	res = search(asReceptionist, url.Values{}, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "At least one criterion is required")
+	res = search(actorHeaders(inClinic, novakova.Id, api.UserRolePatient),
+		url.Values{"name": {"jana"}}, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Patients can't search patients")
+
+	var doctor api.Doctor
+	res = mustSendWithHeaders(t, http.MethodPost, registerUrl, inClinic,
+		newDoctor(fmt.Sprintf("test.search.%s@doctor.com", uuid.NewString())), &doctor)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	asDoctor := actorHeaders(inClinic, doctor.Id, api.UserRoleDoctor)
+
+	res = search(asDoctor, url.Values{"name": {"jana"}}, &found)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Empty(t, found.Patients, "Doctor doesn't care for any patient yet")
+
+	res = mustSendWithHeaders(t, http.MethodPost, fmt.Sprintf("%s/appointments", ServerUrl),
+		asReceptionist, api.NewAppointmentRequest{
+			PatientId:           novakova.Id,
+			DoctorId:            doctor.Id,
+			AppointmentDateTime: time.Now().Add(48 * time.Hour).Truncate(time.Hour),
+		}, nil)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+
+	res = search(asDoctor, url.Values{"name": {"jana"}}, &found)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.Len(t, found.Patients, 1, "Doctor finds only patients they care for")
+	assert.Equal(t, novakova.Id, found.Patients[0].Id)
+	require.NotNil(t, found.Patients[0].NationalId)
+	assert.Equal(t, "8054121240", *found.Patients[0].NationalId)
+}
+
+func TestUpdatePatientProfile(t *testing.T) {
+	t.Parallel()
+
+	clinic := mustCreateClinic(t, "Profile clinic")
+	inClinic := clinicHeaders(clinic.Id)
+	admin := mustProvisionAdmin(t, clinic.Id)
+	asAdmin := actorHeaders(inClinic, admin.Id, api.UserRoleAdmin)
+	receptionist := mustCreateStaff(t, asAdmin, api.StaffRoleReceptionist)
+	asReceptionist := actorHeaders(inClinic, receptionist.Id, api.UserRoleReceptionist)
+
+	registerUrl := fmt.Sprintf("%s/auth/register", ServerUrl)
+	registration := newPatient(fmt.Sprintf("test.profile.%s@patient.com", uuid.NewString()))
+	registration.NationalId = asPtr("805412/1234")
+	res := mustSendWithHeaders(t, http.MethodPost, registerUrl, inClinic, registration, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Birth number checksum must be valid")
+
+	registration.NationalId = asPtr("805412/1240")
+	registration.DateOfBirth = &types.Date{Time: time.Date(1980, 4, 12, 0, 0, 0, 0, time.UTC)}
+	registration.Sex = asPtr(api.SexFemale)
+	registration.Phone = asPtr("0905 123 456")
+	registration.Insurance = &api.Insurance{Company: "Dôvera", PolicyNumber: "80412345"}
+	var patient api.Patient
+	res = mustSendWithHeaders(t, http.MethodPost, registerUrl, inClinic, registration, &patient)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	require.NotNil(t, patient.Phone)
+	assert.Equal(t, "+421905123456", *patient.Phone, "Slovak numbers are made international")
+	require.NotNil(t, patient.NationalId)
+	assert.Equal(t, "8054121240", *patient.NationalId)
+
+	patientUrl := fmt.Sprintf("%s/patients/%s", ServerUrl, patient.Id)
+	res = mustSendWithHeaders(t, http.MethodGet, patientUrl, inClinic, nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	firstETag := res.Header.Get(server.ETag)
+
+	update := api.PatientProfileUpdate{
+		FirstName:   patient.FirstName,
+		LastName:    "Nováková",
+		DateOfBirth: registration.DateOfBirth,
+		NationalId:  asPtr("8054121240"),
+		Phone:       asPtr("+421 2 123 456 78"),
+		Address: &api.Address{
+			Street:     "Mlynská dolina 1",
+			City:       "Bratislava",
+			PostalCode: "842 48",
+		},
+		EmergencyContact: &api.EmergencyContact{Name: "Ján Novák", Phone: "0911 222 333"},
+	}
+	res = mustSendWithHeaders(t, http.MethodPut, patientUrl, withIfMatch(inClinic, firstETag),
+		update, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Anonymous requests can't update it")
+	otherPatient := actorHeaders(inClinic, uuid.New(), api.UserRolePatient)
+	res = mustSendWithHeaders(t, http.MethodPut, patientUrl, withIfMatch(otherPatient, firstETag),
+		update, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Other patients can't update the profile")
+
+	asReceptionistAtFirst := withIfMatch(asReceptionist, firstETag)
+	invalid := update
+	invalid.DateOfBirth = &types.Date{Time: time.Date(1980, 4, 13, 0, 0, 0, 0, time.UTC)}
+	res = mustSendWithHeaders(t, http.MethodPut, patientUrl, asReceptionistAtFirst, invalid, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Birth number must match date of birth")
+	invalid = update
+	invalid.Phone = asPtr("12345")
+	res = mustSendWithHeaders(t, http.MethodPut, patientUrl, asReceptionistAtFirst, invalid, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Phone number must be valid")
+
+	var updated api.Patient
+	res = mustSendWithHeaders(t, http.MethodPut, patientUrl, asReceptionistAtFirst, update,
+		&updated)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Receptionist updates the profile")
+	assert.NotEqual(t, firstETag, res.Header.Get(server.ETag))
+	assert.Equal(t, "Nováková", updated.LastName)
+	assert.Nil(t, updated.Insurance, "Profile fields which aren't given are removed")
+	assert.Nil(t, updated.Sex)
+	require.NotNil(t, updated.Address)
+	assert.Equal(t, "SK", *updated.Address.Country)
+	require.NotNil(t, updated.EmergencyContact)
+	assert.Equal(t, "+421911222333", updated.EmergencyContact.Phone)
+
+	res = mustSendWithHeaders(t, http.MethodPut, patientUrl,
+		withIfMatch(actorHeaders(inClinic, patient.Id, api.UserRolePatient), firstETag), update, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, res.StatusCode, "Stale version is rejected")
+
+	var found api.PatientList
+	res = mustSendWithHeaders(t, http.MethodGet,
+		fmt.Sprintf("%s/patients?name=novakova&page=0&pageSize=10", ServerUrl), asReceptionist,
+		nil, &found)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.Len(t, found.Patients, 1, "Updated name is searchable")
+	assert.Equal(t, patient.Id, found.Patients[0].Id)
+}
+
 func TestCreatePatientPrescription(t *testing.T) {
 	t.Parallel()
 
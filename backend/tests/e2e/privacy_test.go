@@ -0,0 +1,285 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+	"github.com/Nesquiko/wac/pkg/server"
+)
+
+func TestExportPatientData(t *testing.T) {
+	t.Parallel()
+
+	patientEmail := fmt.Sprintf("test.export.%s@patient.com", uuid.NewString())
+	patient := mustCreatePatient(t, newPatient(patientEmail))
+	mustCreateCondition(t, api.NewCondition{
+		Name:      "Migraine",
+		PatientId: patient.Id,
+		Start:     time.Now().Truncate(time.Second),
+	})
+
+	asPatient := actorHeaders(http.Header{}, patient.Id, api.UserRolePatient)
+	body := mustExportPatientData(t, asPatient, patient.Id)
+
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err, "Export is not a valid zip archive")
+
+	files := make(map[string]*zip.File)
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	assert := assert.New(t)
+	for _, name := range []string{
+		"patient.json",
+		"appointments.json",
+		"conditions.json",
+		"prescriptions.json",
+		"reservations.json",
+		"visit-notes.json",
+		"referrals.json",
+		"medical-history/files.json",
+	} {
+		assert.Contains(files, name, "Archive is missing %s", name)
+	}
+
+	conditionsFile, err := files["conditions.json"].Open()
+	require.NoError(t, err, "Failed to open conditions.json")
+	defer conditionsFile.Close()
+
+	var conditions []map[string]any
+	err = json.NewDecoder(conditionsFile).Decode(&conditions)
+	require.NoError(t, err, "Failed to decode conditions.json")
+	require.Len(t, conditions, 1, "Expected exactly one exported condition")
+	assert.Equal("Migraine", conditions[0]["name"], "Exported condition name mismatch")
+}
+
+func TestExportPatientData_MedicalHistoryFiles(t *testing.T) {
+	t.Parallel()
+
+	patientEmail := fmt.Sprintf("test.export.files.%s@patient.com", uuid.NewString())
+	patient := mustCreatePatient(t, newPatient(patientEmail))
+
+	db := mustConnectDb(t)
+	defer db.Disconnect(context.Background())
+
+	// more files than are read at once, some uploaded at the same time
+	const count = 250
+	uploadedAt := time.Now().Truncate(time.Hour)
+	names := make([]string, count)
+	for i := range count {
+		names[i] = fmt.Sprintf("lab_result_%d.pdf", i)
+		_, err := db.CreateMedicalHistoryFile(context.Background(), data.MedicalHistoryFile{
+			PatientId:  patient.Id,
+			Name:       names[i],
+			UploadedAt: uploadedAt.Add(-time.Duration(i/2) * time.Hour),
+		})
+		require.NoError(t, err, "Failed to create medical history file")
+	}
+
+	asPatient := actorHeaders(http.Header{}, patient.Id, api.UserRolePatient)
+	first := mustExportPatientData(t, asPatient, patient.Id)
+	second := mustExportPatientData(t, asPatient, patient.Id)
+	assert.Equal(t, first, second, "Exports of unchanged data must be identical")
+
+	archive, err := zip.NewReader(bytes.NewReader(first), int64(len(first)))
+	require.NoError(t, err, "Export is not a valid zip archive")
+
+	var files []data.MedicalHistoryFile
+	for _, f := range archive.File {
+		if f.Name != "medical-history/files.json" {
+			continue
+		}
+		content, err := f.Open()
+		require.NoError(t, err, "Failed to open medical-history/files.json")
+		defer content.Close()
+		err = json.NewDecoder(content).Decode(&files)
+		require.NoError(t, err, "Failed to decode medical-history/files.json")
+	}
+
+	require.Len(t, files, count, "Every file is exported")
+	exported := make([]string, len(files))
+	for i, file := range files {
+		exported[i] = file.Name
+	}
+	assert.ElementsMatch(t, names, exported, "Every file is exported once")
+}
+
+func mustExportPatientData(t *testing.T, headers http.Header, patientId uuid.UUID) []byte {
+	t.Helper()
+	url := fmt.Sprintf("%s/patients/%s/export", ServerUrl, patientId)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err, "Failed to create ExportPatientData request")
+	for key, values := range headers {
+		req.Header[key] = values
+	}
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err, "Request failed for ExportPatientData")
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.Equal(t, server.ApplicationZip, res.Header.Get(server.ContentType))
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err, "Failed to read export body")
+	return body
+}
+
+func TestErasePatientData(t *testing.T) {
+	t.Parallel()
+
+	patientEmail := fmt.Sprintf("test.erasure.%s@patient.com", uuid.NewString())
+	patient := mustCreatePatient(t, newPatient(patientEmail))
+
+	doctorEmail := fmt.Sprintf("test.erasure.%s@doctor.com", uuid.NewString())
+	doctor := mustCreateDoctor(t, newDoctor(doctorEmail))
+
+	upcoming := mustCreateAppointment(t, api.NewAppointmentRequest{
+		PatientId:           patient.Id,
+		DoctorId:            doctor.Id,
+		AppointmentDateTime: time.Now().Add(48 * time.Hour).Truncate(time.Hour),
+	})
+	mustCreateCondition(t, api.NewCondition{
+		Name:      "Asthma",
+		PatientId: patient.Id,
+		Start:     time.Now().Truncate(time.Second),
+	})
+
+	asAdmin := asDefaultClinicAdmin(t)
+	dryRun := mustErasePatientData(t, asAdmin, patient.Id,
+		api.PatientErasureRequest{DryRun: asPtr(true)})
+
+	assert := assert.New(t)
+	assert.True(dryRun.DryRun, "Report should be a dry run")
+	assert.Nil(dryRun.ErasedAt, "Dry run must not erase the patient")
+	assert.Equal([]uuid.UUID{*upcoming.Id}, dryRun.CancelledAppointments)
+	assert.Equal(1, dryRun.Retained.Conditions, "Condition should be retained")
+
+	fetched := mustGetPatient(t, patient.Id)
+	assert.Equal(patient.Email, fetched.Email, "Dry run must not change the patient")
+
+	asPatient := actorHeaders(http.Header{}, patient.Id, api.UserRolePatient)
+	report := mustErasePatientData(t, asPatient, patient.Id, api.PatientErasureRequest{})
+	assert.False(report.DryRun, "Report should not be a dry run")
+	assert.NotNil(report.ErasedAt, "Erasure time should be reported")
+	assert.Equal(dryRun.CancelledAppointments, report.CancelledAppointments)
+	assert.ElementsMatch([]string{
+		"email", "firstName", "lastName", "dateOfBirth", "sex", "phone", "nationalId",
+		"address", "insurance", "emergencyContact",
+	}, report.PseudonymizedFields, "Every personal field should be reported")
+
+	fetched = mustGetPatient(t, patient.Id)
+	assert.NotEqual(patient.Email, fetched.Email, "Email should be pseudonymized")
+	assert.NotEqual(patient.FirstName, fetched.FirstName, "First name should be pseudonymized")
+	assert.NotEqual(patient.LastName, fetched.LastName, "Last name should be pseudonymized")
+
+	apptUrl := fmt.Sprintf("%s/patients/%s/appointment/%s", ServerUrl, patient.Id, *upcoming.Id)
+	apptRes, err := http.Get(apptUrl)
+	require.NoError(t, err, "Failed to fetch appointment after erasure")
+	defer apptRes.Body.Close()
+
+	var appointment api.PatientAppointment
+	err = json.NewDecoder(apptRes.Body).Decode(&appointment)
+	require.NoError(t, err, "Failed to decode appointment after erasure")
+	assert.Equal(api.Cancelled, appointment.Status, "Upcoming appointment should be cancelled")
+}
+
+func TestPatientData_Forbidden(t *testing.T) {
+	t.Parallel()
+
+	patientEmail := fmt.Sprintf("test.privacy.%s@patient.com", uuid.NewString())
+	patient := mustCreatePatient(t, newPatient(patientEmail))
+	otherEmail := fmt.Sprintf("test.privacy.other.%s@patient.com", uuid.NewString())
+	other := mustCreatePatient(t, newPatient(otherEmail))
+
+	exportUrl := fmt.Sprintf("%s/patients/%s/export", ServerUrl, patient.Id)
+	erasureUrl := fmt.Sprintf("%s/patients/%s/erasure", ServerUrl, patient.Id)
+	erasure := api.PatientErasureRequest{}
+
+	assert := assert.New(t)
+	for name, headers := range map[string]http.Header{
+		"anonymous":       {},
+		"foreign patient": actorHeaders(http.Header{}, other.Id, api.UserRolePatient),
+	} {
+		res := mustSendWithHeaders(t, http.MethodGet, exportUrl, headers, nil, nil)
+		assert.Equal(http.StatusForbidden, res.StatusCode, "%s can't export", name)
+
+		res = mustSendWithHeaders(t, http.MethodPost, erasureUrl, headers, erasure, nil)
+		assert.Equal(http.StatusForbidden, res.StatusCode, "%s can't erase", name)
+	}
+
+	fetched := mustGetPatient(t, patient.Id)
+	assert.Equal(patient.Email, fetched.Email, "Forbidden erasure must not change the patient")
+}
+
+func mustErasePatientData(
+	t *testing.T,
+	headers http.Header,
+	patientId uuid.UUID,
+	request api.PatientErasureRequest,
+) api.PatientErasureReport {
+	t.Helper()
+	require := require.New(t)
+
+	reqBodyBytes, err := json.Marshal(request)
+	require.NoError(err, "mustErasePatientData: Failed to marshal request")
+
+	url := fmt.Sprintf("%s/patients/%s/erasure", ServerUrl, patientId)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(reqBodyBytes))
+	require.NoError(err, "mustErasePatientData: Failed to create request")
+	for key, values := range headers {
+		req.Header[key] = values
+	}
+	req.Header.Set(server.ContentType, server.ApplicationJSON)
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(err, "mustErasePatientData: request failed")
+	defer res.Body.Close()
+
+	bodyBytes, readErr := io.ReadAll(res.Body)
+	require.NoError(readErr, "mustErasePatientData: Failed to read response body")
+	require.Equal(
+		http.StatusOK,
+		res.StatusCode,
+		"mustErasePatientData: Expected '200 OK'. Body: %s",
+		string(bodyBytes),
+	)
+
+	var report api.PatientErasureReport
+	err = json.Unmarshal(bodyBytes, &report)
+	require.NoError(err, "mustErasePatientData: Failed to decode response")
+
+	return report
+}
+
+func mustGetPatient(t *testing.T, patientId uuid.UUID) api.Patient {
+	t.Helper()
+	require := require.New(t)
+
+	url := fmt.Sprintf("%s/patients/%s", ServerUrl, patientId)
+	res, err := http.Get(url)
+	require.NoError(err, "mustGetPatient: http.Get failed")
+	defer res.Body.Close()
+
+	require.Equal(http.StatusOK, res.StatusCode, "mustGetPatient: Expected '200 OK'")
+
+	var patient api.Patient
+	err = json.NewDecoder(res.Body).Decode(&patient)
+	require.NoError(err, "mustGetPatient: Failed to decode response")
+
+	return patient
+}
@@ -0,0 +1,143 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+
+	"github.com/Nesquiko/wac/pkg/api"
+)
+
+func TestReferral(t *testing.T) {
+	t.Parallel()
+
+	patientEmail := fmt.Sprintf("test.referral.%s@patient.com", uuid.NewString())
+	patient := mustCreatePatient(t, newPatient(patientEmail))
+	otherPatientEmail := fmt.Sprintf("test.referral.other.%s@patient.com", uuid.NewString())
+	otherPatient := mustCreatePatient(t, newPatient(otherPatientEmail))
+
+	gpRegistration := newDoctor(fmt.Sprintf("test.referral.gp.%s@doctor.com", uuid.NewString()))
+	gpRegistration.Specialization = api.GeneralPractitioner
+	gp := mustCreateDoctor(t, gpRegistration)
+	cardiologistRegistration := newDoctor(
+		fmt.Sprintf("test.referral.cardio.%s@doctor.com", uuid.NewString()),
+	)
+	cardiologistRegistration.Specialization = api.Cardiologist
+	cardiologist := mustCreateDoctor(t, cardiologistRegistration)
+	urologist := mustCreateDoctor(
+		t,
+		newDoctor(fmt.Sprintf("test.referral.uro.%s@doctor.com", uuid.NewString())),
+	)
+
+	condition := mustCreateCondition(t, api.NewCondition{
+		Name:      "Palpitations",
+		PatientId: patient.Id,
+		Start:     time.Now().Truncate(time.Second),
+	})
+
+	referralsUrl := fmt.Sprintf("%s/referrals", ServerUrl)
+	newReferral := api.NewReferral{
+		PatientId:        patient.Id,
+		FromDoctorId:     gp.Id,
+		ToSpecialization: asPtr(api.Cardiologist),
+		ConditionId:      condition.Id,
+		Urgency:          api.ReferralUrgent,
+		Note:             asPtr("Occasional extrasystoles on ECG"),
+	}
+
+	res := mustSendAs(t, http.MethodPost, referralsUrl, patient.Id, api.UserRolePatient,
+		newReferral, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Only the referring doctor can refer")
+
+	both := newReferral
+	both.ToDoctorId = &cardiologist.Id
+	res = mustSendAs(t, http.MethodPost, referralsUrl, gp.Id, api.UserRoleDoctor, both, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Target must be a doctor or a specialization")
+
+	var referral api.Referral
+	res = mustSendAs(t, http.MethodPost, referralsUrl, gp.Id, api.UserRoleDoctor,
+		newReferral, &referral)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	assert.Equal(t, api.ReferralActive, referral.Status)
+	assert.Equal(t, gp.Id, referral.FromDoctor.Id)
+	assert.True(t, referral.ExpiresAt.After(time.Now().AddDate(0, 1, 0)), "Default expiry")
+	referralUrl := fmt.Sprintf("%s/%s", referralsUrl, referral.Id)
+
+	booking := api.NewAppointmentRequest{
+		PatientId:           patient.Id,
+		DoctorId:            urologist.Id,
+		AppointmentDateTime: time.Now().Add(48 * time.Hour).Truncate(time.Hour),
+		ReferralId:          &referral.Id,
+	}
+	appointmentsUrl := fmt.Sprintf("%s/appointments", ServerUrl)
+	res = mustSendAs(t, http.MethodPost, appointmentsUrl, patient.Id, api.UserRolePatient,
+		booking, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Urologist isn't a cardiologist")
+
+	otherBooking := booking
+	otherBooking.PatientId = otherPatient.Id
+	otherBooking.DoctorId = cardiologist.Id
+	res = mustSendAs(t, http.MethodPost, appointmentsUrl, otherPatient.Id, api.UserRolePatient,
+		otherBooking, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Referral is for another patient")
+
+	booking.DoctorId = cardiologist.Id
+	var appointment api.PatientAppointment
+	res = mustSendAs(t, http.MethodPost, appointmentsUrl, patient.Id, api.UserRolePatient,
+		booking, &appointment)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+
+	res = mustGetJson(t, referralUrl, &referral)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Equal(t, api.ReferralBooked, referral.Status)
+	assert.Equal(t, appointment.Id, referral.AppointmentId)
+
+	booking.AppointmentDateTime = booking.AppointmentDateTime.Add(time.Hour)
+	res = mustSendAs(t, http.MethodPost, appointmentsUrl, patient.Id, api.UserRolePatient,
+		booking, nil)
+	assert.Equal(t, http.StatusConflict, res.StatusCode, "Referral was already booked")
+
+	var doctorAppt api.DoctorAppointment
+	url := fmt.Sprintf("%s/doctors/%s/appointment/%s", ServerUrl, cardiologist.Id, *appointment.Id)
+	res = mustGetJson(t, url, &doctorAppt)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.NotNil(t, doctorAppt.Referral, "Receiving doctor must see the referral")
+	assert.Equal(t, gp.Id, doctorAppt.Referral.FromDoctor.Id)
+	assert.Equal(t, newReferral.Note, doctorAppt.Referral.Note)
+	require.NotNil(t, doctorAppt.Condition, "Referral's condition is used for the appointment")
+	assert.Equal(t, *condition.Id, *doctorAppt.Condition.Id)
+
+	res = mustSendAs(t, http.MethodPost, referralUrl+"/cancel", gp.Id, api.UserRoleDoctor, nil, nil)
+	assert.Equal(t, http.StatusConflict, res.StatusCode, "Booked referral can't be cancelled")
+
+	res = mustSendAs(t, http.MethodDelete, fmt.Sprintf("%s/%s", appointmentsUrl, *appointment.Id),
+		patient.Id, api.UserRolePatient, api.AppointmentCancellation{By: api.UserRolePatient}, nil)
+	require.Equal(t, http.StatusNoContent, res.StatusCode, "Expected '204 No Content' status code")
+
+	res = mustGetJson(t, referralUrl, &referral)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Equal(t, api.ReferralActive, referral.Status, "Cancelled appointment releases referral")
+	assert.Nil(t, referral.AppointmentId)
+
+	res = mustSendAs(t, http.MethodPost, referralUrl+"/cancel", cardiologist.Id,
+		api.UserRoleDoctor, nil, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Only the referring doctor can cancel")
+
+	res = mustSendAs(t, http.MethodPost, referralUrl+"/cancel", gp.Id, api.UserRoleDoctor,
+		nil, &referral)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Equal(t, api.ReferralCancelled, referral.Status)
+
+	var referrals api.Referrals
+	res = mustGetJson(t, fmt.Sprintf("%s/patients/%s/referrals", ServerUrl, patient.Id), &referrals)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.Len(t, referrals.Referrals, 1)
+	assert.Equal(t, referral.Id, referrals.Referrals[0].Id)
+}
@@ -181,29 +181,14 @@ func TestCreateResource(t *testing.T) {
 		Type: api.ResourceType(resourceType),
 	}
 
-	reqBodyBytes, err := json.Marshal(newResourceRequest)
-	require.NoError(t, err, "Failed to marshal NewResource request")
-
 	url := fmt.Sprintf("%s/resources", ServerUrl)
-	res, err := http.Post(url, server.ApplicationJSON, bytes.NewBuffer(reqBodyBytes))
-	require.NoError(t, err, "http.Post failed for CreateResource")
-	defer res.Body.Close()
-
-	bodyBytes, readErr := io.ReadAll(res.Body)
-	require.NoError(t, readErr, "Failed to read response body")
-	res.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-
-	require.Equal(
-		t,
-		http.StatusCreated,
-		res.StatusCode,
-		"Expected '201 Created' status code. Response body: %s",
-		string(bodyBytes),
-	)
+	res := mustSendWithHeaders(t, http.MethodPost, url, nil, newResourceRequest, nil)
+	require.Equal(t, http.StatusForbidden, res.StatusCode, "Anonymous requests are forbidden")
 
 	var createdResource api.NewResource
-	err = json.NewDecoder(res.Body).Decode(&createdResource)
-	require.NoError(t, err, "Failed to decode successful response body into NewResource")
+	res = mustSendWithHeaders(t, http.MethodPost, url, asDefaultClinicAdmin(t),
+		newResourceRequest, &createdResource)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
 
 	assert := assert.New(t)
 	assert.NotNil(createdResource.Id, "Response resource ID should not be nil")
@@ -220,28 +205,11 @@ func mustCreateResource(t *testing.T, request api.NewResource) api.NewResource {
 	t.Helper()
 	require := require.New(t)
 
-	reqBodyBytes, err := json.Marshal(request)
-	require.NoError(err, "mustCreateResource: Failed to marshal request")
-
-	url := fmt.Sprintf("%s/resources", ServerUrl)
-	res, err := http.Post(url, server.ApplicationJSON, bytes.NewBuffer(reqBodyBytes))
-	require.NoError(err, "mustCreateResource: http.Post failed")
-	defer res.Body.Close()
-
-	bodyBytes, readErr := io.ReadAll(res.Body)
-	require.NoError(readErr, "mustCreateResource: Failed to read response body")
-	res.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-
-	require.Equal(
-		http.StatusCreated,
-		res.StatusCode,
-		"mustCreateResource: Expected '201 Created'. Body: %s",
-		string(bodyBytes),
-	)
-
 	var createdResource api.NewResource
-	err = json.NewDecoder(res.Body).Decode(&createdResource)
-	require.NoError(err, "mustCreateResource: Failed to decode response")
+	url := fmt.Sprintf("%s/resources", ServerUrl)
+	res := mustSendWithHeaders(t, http.MethodPost, url, asDefaultClinicAdmin(t), request,
+		&createdResource)
+	require.Equal(http.StatusCreated, res.StatusCode, "mustCreateResource: Expected '201 Created'")
 	require.NotNil(createdResource.Id, "mustCreateResource: Response ID is nil")
 	require.NotEmpty(*createdResource.Id, "mustCreateResource: Response ID is empty UUID")
 
@@ -5,7 +5,9 @@ package e2e
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
@@ -36,3 +38,37 @@ func TestRestartPersistence(t *testing.T) {
 	assert.Equal(patient.Id, fetchedPatient.Id, "Patient ID mismatch")
 	assert.Equal(patient.Email, fetchedPatient.Email, "Patient email mismatch")
 }
+
+func TestMonitoringProbes(t *testing.T) {
+	for _, probe := range []string{"live", "ready"} {
+		res, err := http.Get(fmt.Sprintf("%s/monitoring/%s", ServerUrl, probe))
+		require.NoError(t, err, "http.Get failed for %s probe", probe)
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' from %s probe", probe)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	patientEmail := fmt.Sprintf("test.patient.metrics.%s@patient.com", uuid.NewString())
+	mustCreatePatient(t, newPatient(patientEmail))
+	res, err := http.Get(fmt.Sprintf("%s/patients/%s", ServerUrl, uuid.New()))
+	require.NoError(t, err, "http.Get failed for missing patient")
+	res.Body.Close()
+
+	res, err = http.Get(strings.TrimSuffix(ServerUrl, "/api") + "/metrics")
+	require.NoError(t, err, "http.Get failed for metrics")
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err, "Failed to read metrics")
+
+	metrics := string(body)
+	assert := assert.New(t)
+	assert.Contains(
+		metrics,
+		`wac_http_request_duration_seconds_count{operation="GetPatientById",status="404"}`,
+	)
+	assert.Contains(metrics, `wac_http_api_errors_total{code="not.found",status="404"}`)
+	assert.Contains(metrics, `wac_mongo_command_duration_seconds_count{command="insert"`)
+	assert.Contains(metrics, "wac_appointments_awaiting_decision")
+}
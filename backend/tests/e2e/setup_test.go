@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"testing"
 	"time"
@@ -14,6 +15,7 @@ import (
 	"github.com/test-go/testify/require"
 	"github.com/testcontainers/testcontainers-go/modules/mongodb"
 
+	"github.com/Nesquiko/wac/pkg/data"
 	"github.com/Nesquiko/wac/pkg/server"
 )
 
@@ -65,6 +67,8 @@ func TestMain(m *testing.M) {
 		"WAC_MONGO_PASSWORD": "wac",
 		"WAC_MONGO_DB":       "wac-test",
 		"WAC_LOG_LEVEL":      fmt.Sprintf("%d", logLevel),
+		// all tests share one client IP, tests of the limits enable them
+		"WAC_RATELIMIT_ENABLED": "false",
 	}
 
 	for key, value := range envVars {
@@ -79,6 +83,7 @@ func TestMain(m *testing.M) {
 	}
 
 	ServerUrl = fmt.Sprintf("http://%s:%s", appHost, appPort)
+	http.DefaultClient.Transport = defaultClinicTransport{base: http.DefaultTransport}
 
 	go func() {
 		if err := server.Run(serverCtx); err != nil {
@@ -104,6 +109,22 @@ func TestMain(m *testing.M) {
 	os.Exit(exitCode)
 }
 
+// defaultClinicTransport sends requests without the X-Clinic-Id header to the
+// default clinic, most tests don't care about clinics. Requests sent through
+// http.DefaultTransport are sent as they are.
+type defaultClinicTransport struct {
+	base http.RoundTripper
+}
+
+func (t defaultClinicTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get(server.ClinicIdHeader) != "" {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set(server.ClinicIdHeader, data.DefaultClinicId.String())
+	return t.base.RoundTrip(req)
+}
+
 func prepareMongo(ctx context.Context) (*mongodb.MongoDBContainer, func()) {
 	mongoDBContainer, err := mongodb.Run(
 		ctx,
@@ -0,0 +1,212 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oapi-codegen/runtime/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+	"github.com/Nesquiko/wac/pkg/server"
+)
+
+func TestStaffRoles(t *testing.T) {
+	t.Parallel()
+
+	clinic := mustCreateClinic(t, "Staff clinic")
+	inClinic := clinicHeaders(clinic.Id)
+	admin := mustProvisionAdmin(t, clinic.Id)
+	asAdmin := actorHeaders(inClinic, admin.Id, api.UserRoleAdmin)
+
+	registerUrl := fmt.Sprintf("%s/auth/register", ServerUrl)
+	res := mustSendWithHeaders(t, http.MethodPost, registerUrl, inClinic, map[string]string{
+		"email":     fmt.Sprintf("test.staff.%s@nurse.com", uuid.NewString()),
+		"firstName": "Joy",
+		"lastName":  "Smith",
+		"role":      "nurse",
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Staff can't register themselves")
+
+	staffUrl := fmt.Sprintf("%s/staff", ServerUrl)
+	newNurse := api.NewStaff{
+		Email:     types.Email(fmt.Sprintf("test.staff.%s@nurse.com", uuid.NewString())),
+		FirstName: "Joy",
+		LastName:  "Smith",
+		Role:      api.StaffRoleNurse,
+	}
+	res = mustSendWithHeaders(t, http.MethodPost, staffUrl, inClinic, newNurse, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Anonymous requests can't create staff")
+
+	var nurse api.Staff
+	res = mustSendWithHeaders(t, http.MethodPost, staffUrl, asAdmin, newNurse, &nurse)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	assert.Equal(t, api.StaffRoleNurse, nurse.Role)
+	res = mustSendWithHeaders(t, http.MethodPost, staffUrl, asAdmin, newNurse, nil)
+	assert.Equal(t, http.StatusConflict, res.StatusCode, "Staff email must be unique")
+
+	asNurse := actorHeaders(inClinic, nurse.Id, api.UserRoleNurse)
+	res = mustSendWithHeaders(t, http.MethodPost, staffUrl, asNurse, api.NewStaff{
+		Email:     types.Email(fmt.Sprintf("test.staff.%s@admin.com", uuid.NewString())),
+		FirstName: "Ann",
+		LastName:  "Admin",
+		Role:      api.StaffRoleAdmin,
+	}, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Nurse can't create staff")
+
+	var receptionist api.Staff
+	res = mustSendWithHeaders(t, http.MethodPost, staffUrl, asAdmin, api.NewStaff{
+		Email:     types.Email(fmt.Sprintf("test.staff.%s@reception.com", uuid.NewString())),
+		FirstName: "Rita",
+		LastName:  "Desk",
+		Role:      api.StaffRoleReceptionist,
+	}, &receptionist)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	asReceptionist := actorHeaders(inClinic, receptionist.Id, api.UserRoleReceptionist)
+
+	var staff api.StaffList
+	res = mustSendWithHeaders(t, http.MethodGet, staffUrl, asAdmin, nil, &staff)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Len(t, staff.Staff, 3, "Admin, nurse and receptionist are the clinic's staff")
+	res = mustSendWithHeaders(t, http.MethodGet, staffUrl, asReceptionist, nil, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Only admins list staff")
+
+	var doctor api.Doctor
+	res = mustSendWithHeaders(t, http.MethodPost, registerUrl, inClinic,
+		newDoctor(fmt.Sprintf("test.staff.%s@doctor.com", uuid.NewString())), &doctor)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	var patient api.Patient
+	res = mustSendWithHeaders(t, http.MethodPost, registerUrl, inClinic,
+		newPatient(fmt.Sprintf("test.staff.%s@patient.com", uuid.NewString())), &patient)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Expected '201 Created' status code")
+	asDoctor := actorHeaders(inClinic, doctor.Id, api.UserRoleDoctor)
+
+	appointmentsUrl := fmt.Sprintf("%s/appointments", ServerUrl)
+	booking := api.NewAppointmentRequest{
+		PatientId:           patient.Id,
+		DoctorId:            doctor.Id,
+		AppointmentDateTime: time.Now().Add(48 * time.Hour).Truncate(time.Hour),
+	}
+	res = mustSendWithHeaders(t, http.MethodPost, appointmentsUrl, asNurse, booking, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Nurse can't book for patients")
+	res = mustSendWithHeaders(t, http.MethodPost, appointmentsUrl,
+		actorHeaders(inClinic, uuid.New(), api.UserRoleReceptionist), booking, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Unknown staff can't book for patients")
+
+	var appointment api.PatientAppointment
+	res = mustSendWithHeaders(t, http.MethodPost, appointmentsUrl, asReceptionist, booking,
+		&appointment)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "Receptionist books for the patient")
+	appointmentUrl := fmt.Sprintf("%s/appointments/%s", ServerUrl, *appointment.Id)
+
+	reschedule := api.AppointmentReschedule{
+		NewAppointmentDateTime: booking.AppointmentDateTime.Add(time.Hour),
+	}
+	res = mustSendWithHeaders(t, http.MethodPatch, appointmentUrl, asDoctor, reschedule, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Doctor can't reschedule for patients")
+	res = mustSendWithHeaders(t, http.MethodPatch, appointmentUrl, asReceptionist, reschedule, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Receptionist reschedules for the patient")
+
+	res = mustSendWithHeaders(t, http.MethodPost, appointmentUrl, asDoctor,
+		api.AppointmentDecision{Action: api.Accept}, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+
+	noteUrl := appointmentUrl + "/note"
+	res = mustSendWithHeaders(t, http.MethodPut, noteUrl, asDoctor, api.UpdateVisitNote{
+		Text: asPtr("Patient feels fine"),
+	}, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+
+	vitals := api.UpdateVisitNote{
+		Text: asPtr("Nurses can't change the text"),
+		Vitals: &api.Vitals{
+			Temperature: &api.Temperature{Value: 36.6, Unit: api.Celsius},
+		},
+	}
+	res = mustSendWithHeaders(t, http.MethodPut, noteUrl, asReceptionist, vitals, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Receptionist can't record vitals")
+
+	var note api.VisitNote
+	res = mustSendWithHeaders(t, http.MethodPut, noteUrl, asNurse, vitals, &note)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Nurse records vitals")
+	require.NotNil(t, note.Vitals.Temperature)
+	assert.Equal(t, 36.6, note.Vitals.Temperature.Value)
+	require.NotNil(t, note.Text)
+	assert.Equal(t, "Patient feels fine", *note.Text, "Nurse's text must be ignored")
+
+	resourcesUrl := fmt.Sprintf("%s/resources", ServerUrl)
+	resource := api.NewResource{Name: "Staff test ECG", Type: api.ResourceTypeEquipment}
+	res = mustSendWithHeaders(t, http.MethodPost, resourcesUrl, asDoctor, resource, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Doctor can't manage resources")
+	res = mustSendWithHeaders(t, http.MethodPost, resourcesUrl, asAdmin, resource, nil)
+	assert.Equal(t, http.StatusCreated, res.StatusCode, "Admin manages resources")
+
+	var loggedIn api.Staff
+	res = mustSendWithHeaders(t, http.MethodPost, fmt.Sprintf("%s/auth/login", ServerUrl),
+		inClinic, map[string]string{"email": string(nurse.Email), "role": "nurse"}, &loggedIn)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Equal(t, nurse.Id, loggedIn.Id)
+}
+
+// mustProvisionAdmin creates an administrator of the clinic directly in the
+// database, as wacctl staff does, since only administrators create staff.
+func mustProvisionAdmin(t *testing.T, clinicId uuid.UUID) data.Staff {
+	t.Helper()
+	require := require.New(t)
+
+	ctx := context.Background()
+	db := mustConnectDb(t)
+	defer db.Disconnect(ctx)
+
+	admin, err := db.CreateStaff(data.WithClinic(ctx, clinicId), data.Staff{
+		Email:     fmt.Sprintf("test.staff.%s@admin.com", uuid.NewString()),
+		FirstName: "Ada",
+		LastName:  "Admin",
+		Role:      data.StaffAdmin,
+	})
+	require.NoError(err, "mustProvisionAdmin: failed to create admin")
+	return admin
+}
+
+// mustConnectDb connects to the database of the server.
+func mustConnectDb(t *testing.T) *data.MongoDb {
+	t.Helper()
+	require := require.New(t)
+
+	cfg, err := server.LoadConfig()
+	require.NoError(err, "mustConnectDb: failed to read config")
+	db, err := data.ConnectMongo(context.Background(), cfg.MongoURI(), cfg.Mongo.Db)
+	require.NoError(err, "mustConnectDb: failed to connect to database")
+	return db
+}
+
+// asDefaultClinicAdmin provisions an administrator of the default clinic and
+// returns headers of requests acting as them.
+func asDefaultClinicAdmin(t *testing.T) http.Header {
+	t.Helper()
+	admin := mustProvisionAdmin(t, data.DefaultClinicId)
+	return actorHeaders(http.Header{}, admin.Id, api.UserRoleAdmin)
+}
+
+func mustCreateStaff(t *testing.T, asAdmin http.Header, role api.StaffRole) api.Staff {
+	t.Helper()
+
+	var staff api.Staff
+	res := mustSendWithHeaders(t, http.MethodPost, fmt.Sprintf("%s/staff", ServerUrl), asAdmin,
+		api.NewStaff{
+			Email:     types.Email(fmt.Sprintf("test.staff.%s@%s.com", uuid.NewString(), role)),
+			FirstName: "Rita",
+			LastName:  "Desk",
+			Role:      role,
+		}, &staff)
+	require.Equal(t, http.StatusCreated, res.StatusCode, "mustCreateStaff: unexpected status code")
+	return staff
+}
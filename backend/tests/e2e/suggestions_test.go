@@ -0,0 +1,138 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/data"
+	"github.com/Nesquiko/wac/pkg/server"
+)
+
+func TestSuggestAppointments(t *testing.T) {
+	t.Parallel()
+
+	// a random far away day, so that other tests don't book it
+	day := time.Date(2040, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, rand.IntN(3650))
+
+	patient := mustCreatePatient(
+		t,
+		newPatient(fmt.Sprintf("test.suggestions.%s@patient.com", uuid.NewString())),
+	)
+	registration := newDoctor(fmt.Sprintf("test.suggestions.%s@doctor.com", uuid.NewString()))
+	registration.Specialization = api.Endocrinologist
+	doctor := mustCreateDoctor(t, registration)
+	otherRegistration := newDoctor(
+		fmt.Sprintf("test.suggestions.other.%s@doctor.com", uuid.NewString()),
+	)
+	otherRegistration.Specialization = api.Other
+	otherDoctor := mustCreateDoctor(t, otherRegistration)
+
+	mustCreateAppointment(t, api.NewAppointmentRequest{
+		PatientId:           patient.Id,
+		DoctorId:            doctor.Id,
+		AppointmentDateTime: day.Add(12 * time.Hour),
+	})
+
+	facility := mustCreateResource(t, api.NewResource{
+		Name: fmt.Sprintf("Suggestions Room %s", uuid.NewString()),
+		Type: api.ResourceType(data.ResourceTypeFacility),
+	})
+	otherAppt := mustCreateAppointment(t, api.NewAppointmentRequest{
+		PatientId:           patient.Id,
+		DoctorId:            otherDoctor.Id,
+		AppointmentDateTime: day.Add(13 * time.Hour),
+	})
+	body, err := json.Marshal(api.ResourceReservation{
+		AppointmentId: *otherAppt.Id,
+		Start:         day.Add(13 * time.Hour),
+		End:           day.Add(14 * time.Hour),
+	})
+	require.NoError(t, err)
+	res, err := http.Post(
+		fmt.Sprintf("%s/resources/%s", ServerUrl, *facility.Id),
+		server.ApplicationJSON,
+		bytes.NewBuffer(body),
+	)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusNoContent, res.StatusCode, "Facility must be reserved")
+
+	suggest := func(query url.Values) ([]api.AppointmentSuggestion, []time.Time) {
+		t.Helper()
+		query.Set("specialization", string(api.Endocrinologist))
+		query.Set("from", day.Format(time.DateOnly))
+		query.Set("to", day.Format(time.DateOnly))
+		query.Set("preferredDoctorId", doctor.Id.String())
+		query.Set("limit", "50")
+
+		var found api.AppointmentSuggestions
+		res := mustGetJson(
+			t,
+			fmt.Sprintf("%s/appointments/suggestions?%s", ServerUrl, query.Encode()),
+			&found,
+		)
+		require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+
+		var slots []time.Time
+		for _, s := range found.Suggestions {
+			assert.Equal(t, api.Endocrinologist, s.Doctor.Specialization)
+			if s.Doctor.Id == doctor.Id {
+				slots = append(slots, s.AppointmentDateTime.UTC())
+			}
+		}
+		return found.Suggestions, slots
+	}
+
+	_, slots := suggest(url.Values{"timesOfDay": {string(api.Afternoon)}})
+	assert.Equal(
+		t,
+		[]time.Time{day.Add(13 * time.Hour), day.Add(14 * time.Hour)},
+		slots,
+		"Booked slot must be skipped",
+	)
+
+	_, slots = suggest(url.Values{
+		"timesOfDay":  {string(api.Afternoon)},
+		"facilityIds": {facility.Id.String()},
+	})
+	assert.Equal(t, []time.Time{day.Add(14 * time.Hour)}, slots, "Reserved facility must be skipped")
+
+	suggestions, slots := suggest(url.Values{"timesOfDay": {string(api.Morning)}})
+	require.NotEmpty(t, suggestions)
+	assert.Equal(t, doctor.Id, suggestions[0].Doctor.Id, "Preferred doctor must come first")
+	assert.Equal(
+		t,
+		[]time.Time{
+			day.Add(8 * time.Hour),
+			day.Add(9 * time.Hour),
+			day.Add(10 * time.Hour),
+			day.Add(11 * time.Hour),
+		},
+		slots,
+	)
+
+	query := url.Values{
+		"specialization": {string(api.Endocrinologist)},
+		"from":           {day.Format(time.DateOnly)},
+		"to":             {day.AddDate(0, 2, 0).Format(time.DateOnly)},
+	}
+	res = mustGetJson(
+		t,
+		fmt.Sprintf("%s/appointments/suggestions?%s", ServerUrl, query.Encode()),
+		nil,
+	)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Range over 31 days must be rejected")
+}
@@ -0,0 +1,97 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+
+	"github.com/Nesquiko/wac/pkg/api"
+	"github.com/Nesquiko/wac/pkg/app"
+	"github.com/Nesquiko/wac/pkg/server"
+)
+
+func TestSearchIcd10(t *testing.T) {
+	t.Parallel()
+
+	var found api.Icd10Codes
+	res := mustGetJson(t, fmt.Sprintf("%s/terminology/icd10?q=j20", ServerUrl), &found)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.NotEmpty(t, found.Codes)
+	assert.Equal(t, "J20.9", found.Codes[0].Code)
+
+	query := url.Values{"q": {"bronchits"}, "limit": {"1"}}
+	res = mustGetJson(t, fmt.Sprintf("%s/terminology/icd10?%s", ServerUrl, query.Encode()), &found)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.Len(t, found.Codes, 1, "Typo must be tolerated and limit respected")
+	assert.Contains(t, found.Codes[0].Display, "ronchitis")
+
+	res = mustGetJson(t, fmt.Sprintf("%s/terminology/icd10", ServerUrl), nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Query is required")
+}
+
+func TestCondition_Diagnosis(t *testing.T) {
+	t.Parallel()
+
+	patientEmail := fmt.Sprintf("test.patient.diagnosis.%s@example.com", uuid.NewString())
+	patient := mustCreatePatient(t, newPatient(patientEmail))
+	doctorEmail := fmt.Sprintf("test.doctor.diagnosis.%s@example.com", uuid.NewString())
+	doctor := mustCreateDoctor(t, newDoctor(doctorEmail))
+
+	condition := mustCreateCondition(t, api.NewCondition{
+		Name:          "Bronchitis",
+		PatientId:     patient.Id,
+		Start:         time.Now().Truncate(time.Second),
+		DiagnosisCode: asPtr("j209"),
+	})
+	require.NotNil(t, condition.Diagnosis)
+	assert.Equal(t, "J20.9", condition.Diagnosis.Code, "Code must be normalized")
+	assert.Equal(t, "Acute bronchitis, unspecified", condition.Diagnosis.Display)
+
+	unknown := api.NewCondition{
+		Name:          "Bronchitis",
+		PatientId:     patient.Id,
+		Start:         time.Now(),
+		DiagnosisCode: asPtr("X99.9"),
+	}
+	body, err := json.Marshal(unknown)
+	require.NoError(t, err)
+	res, err := http.Post(
+		fmt.Sprintf("%s/conditions", ServerUrl),
+		server.ApplicationJSON,
+		bytes.NewBuffer(body),
+	)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusBadRequest, res.StatusCode, "Unknown code must be rejected")
+	var apiErr api.ErrorDetail
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&apiErr))
+	assert.Equal(t, app.UnknownDiagnosisCode, apiErr.Code)
+
+	conditionUrl := fmt.Sprintf("%s/conditions/%s", ServerUrl, *condition.Id)
+	asDoctor := actorHeaders(http.Header{}, doctor.Id, api.UserRoleDoctor)
+	res = mustSendWithHeaders(t, http.MethodPatch, conditionUrl, withIfMatch(asDoctor, `"1"`),
+		map[string]any{"end": nil, "diagnosisCode": "X99.9"}, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Unknown code must be rejected")
+
+	var updated api.Condition
+	res = mustSendWithHeaders(t, http.MethodPatch, conditionUrl, withIfMatch(asDoctor, `"1"`),
+		map[string]any{"end": nil, "diagnosisCode": "J40"}, &updated)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.NotNil(t, updated.Diagnosis)
+	assert.Equal(t, "J40", updated.Diagnosis.Code)
+
+	res = mustSendWithHeaders(t, http.MethodPatch, conditionUrl, withIfMatch(asDoctor, `"2"`),
+		map[string]any{"end": nil, "diagnosisCode": nil}, &updated)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Nil(t, updated.Diagnosis, "Null must remove the diagnosis")
+}
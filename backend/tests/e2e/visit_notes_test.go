@@ -0,0 +1,102 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+
+	"github.com/Nesquiko/wac/pkg/api"
+)
+
+func TestVisitNote(t *testing.T) {
+	t.Parallel()
+
+	patientEmail := fmt.Sprintf("test.visit.note.%s@patient.com", uuid.NewString())
+	patient := mustCreatePatient(t, newPatient(patientEmail))
+	doctorEmail := fmt.Sprintf("test.visit.note.%s@doctor.com", uuid.NewString())
+	doctor := mustCreateDoctor(t, newDoctor(doctorEmail))
+	otherDoctorEmail := fmt.Sprintf("test.visit.note.other.%s@doctor.com", uuid.NewString())
+	otherDoctor := mustCreateDoctor(t, newDoctor(otherDoctorEmail))
+
+	appointment := mustCreateAppointment(t, api.NewAppointmentRequest{
+		PatientId:           patient.Id,
+		DoctorId:            doctor.Id,
+		AppointmentDateTime: time.Now().Add(24 * time.Hour).Truncate(time.Hour),
+	})
+	appointmentUrl := fmt.Sprintf("%s/appointments/%s", ServerUrl, *appointment.Id)
+	noteUrl := appointmentUrl + "/note"
+
+	note := api.UpdateVisitNote{
+		Sections: &api.VisitNoteSections{
+			Anamnesis:     asPtr("Cough for two weeks"),
+			Diagnosis:     asPtr("Acute bronchitis"),
+			DiagnosisCode: asPtr("J20.9"),
+		},
+		Vitals: &api.Vitals{
+			BloodPressure: &api.BloodPressure{
+				Systolic:  128,
+				Diastolic: 84,
+				Unit:      api.MillimetersOfMercury,
+			},
+			Temperature: &api.Temperature{Value: 37.8, Unit: api.Celsius},
+		},
+	}
+
+	res := mustSendAs(t, http.MethodPut, noteUrl, doctor.Id, api.UserRoleDoctor, note, nil)
+	assert.Equal(t, http.StatusConflict, res.StatusCode, "Requested appointment can't have a note")
+
+	res = mustSendAs(t, http.MethodPost, appointmentUrl, doctor.Id, api.UserRoleDoctor,
+		api.AppointmentDecision{Action: api.Accept}, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+
+	res = mustSendAs(t, http.MethodPut, noteUrl, patient.Id, api.UserRolePatient, note, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Patient can't write the note")
+	res = mustSendAs(t, http.MethodPut, noteUrl, otherDoctor.Id, api.UserRoleDoctor, note, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Other doctor can't write the note")
+
+	var saved api.VisitNote
+	res = mustSendAs(t, http.MethodPut, noteUrl, doctor.Id, api.UserRoleDoctor, note, &saved)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Equal(t, note.Sections.DiagnosisCode, saved.Sections.DiagnosisCode)
+	require.NotNil(t, saved.Vitals.BloodPressure)
+	assert.Equal(t, 128, saved.Vitals.BloodPressure.Systolic)
+	assert.Nil(t, saved.SignedAt)
+
+	invalid := api.UpdateVisitNote{Vitals: &api.Vitals{BloodPressure: &api.BloodPressure{
+		Systolic:  500,
+		Diastolic: 84,
+		Unit:      api.MillimetersOfMercury,
+	}}}
+	res = mustSendAs(t, http.MethodPut, noteUrl, doctor.Id, api.UserRoleDoctor, invalid, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "Implausible vitals must be rejected")
+
+	res = mustSendAs(t, http.MethodPost, appointmentUrl+"/complete", otherDoctor.Id,
+		api.UserRoleDoctor, nil, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Other doctor can't complete")
+
+	var completed api.DoctorAppointment
+	res = mustSendAs(t, http.MethodPost, appointmentUrl+"/complete", doctor.Id,
+		api.UserRoleDoctor, nil, &completed)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	assert.Equal(t, api.Completed, completed.Status)
+	require.NotNil(t, completed.VisitNote, "Completed appointment must include its note")
+	require.NotNil(t, completed.VisitNote.SignedAt, "Note must be signed on completion")
+	assert.Equal(t, doctor.Id, *completed.VisitNote.SignedBy)
+
+	res = mustSendAs(t, http.MethodPut, noteUrl, doctor.Id, api.UserRoleDoctor, note, nil)
+	assert.Equal(t, http.StatusConflict, res.StatusCode, "Signed note must be locked")
+
+	var fetched api.DoctorAppointment
+	url := fmt.Sprintf("%s/doctors/%s/appointment/%s", ServerUrl, doctor.Id, *appointment.Id)
+	res = mustGetJson(t, url, &fetched)
+	require.Equal(t, http.StatusOK, res.StatusCode, "Expected '200 OK' status code")
+	require.NotNil(t, fetched.VisitNote)
+	assert.Equal(t, note.Sections.Diagnosis, fetched.VisitNote.Sections.Diagnosis)
+}